@@ -6,10 +6,9 @@ import (
 	"net/http"
 	"time"
 	"traceGo/preDefine"
-	"traceGo/utils"
 )
 
-func UploadMessage(writer http.ResponseWriter, request *http.Request) {
+func (s *Service) UploadMessage(writer http.ResponseWriter, request *http.Request) {
 	var result preDefine.UploadResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
@@ -21,7 +20,7 @@ func UploadMessage(writer http.ResponseWriter, request *http.Request) {
 	}
 	start := time.Now()
 	args := [][]byte{uploadRequest.Content}
-	response, err := utils.ExecuteCC(preDefine.TRCCID, "recordContent", args, channalClient)
+	response, err := s.ledger.Execute(preDefine.TRCCID, "recordContent", args)
 	if err != nil {
 		result.Code = "400"
 		result.Msg = err.Error()
@@ -34,7 +33,7 @@ func UploadMessage(writer http.ResponseWriter, request *http.Request) {
 	result.Msg = string(response.TransactionID)
 }
 
-func QueryMessage(writer http.ResponseWriter, request *http.Request) {
+func (s *Service) QueryMessage(writer http.ResponseWriter, request *http.Request) {
 	var result preDefine.QueryContentResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
@@ -46,7 +45,7 @@ func QueryMessage(writer http.ResponseWriter, request *http.Request) {
 	}
 	start := time.Now()
 	args := [][]byte{[]byte(queryRequest.Txid)}
-	response, err := utils.ExecuteCC(preDefine.TRCCID, "queryContent", args, channalClient)
+	response, err := s.ledger.Execute(preDefine.TRCCID, "queryContent", args)
 	if err != nil {
 		result.Code = "400"
 		fmt.Println(err)
@@ -5,18 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-amcl/amcl"
 	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
-	"log"
+	"github.com/pkg/errors"
 	"net/http"
 	"time"
 	"traceGo/idemixplus"
 	"traceGo/preDefine"
-	"traceGo/utils"
 )
 
 type UserInfo struct {
 	Pub          string   `json:"pub"`
-	Pri          string   `json:"pri"`
 	Trace        string   `json:"trace"`
 	Attributions []string `json:"attributions"`
 	Cr           string   `json:"cr"`
@@ -35,17 +34,19 @@ type Record struct {
 	Content string `json:"content"`
 }
 
-var Rng = idemixplus.GetRand(32)
-var Attrs []*FP256BN.BIG
-var traces = new(idemixplus.Traces)
-var issuerKey *idemixplus.IssuerKey
-var attributions []string
-var UserInfoMap = make(map[string]UserInfo)
-var UserTraceInfoArray []UserTraceInfo
+// revocationHandleAttribute is the attribute the issuer appends to the attributes of its key. Every credential
+// holds a random revocation handle in it, which signatures keep hidden and prove unrevoked with the CRI of the epoch.
+const revocationHandleAttribute = "RevocationHandle"
+
+// credentialLifetime is how long a credential stays valid after it is issued
+const credentialLifetime = 365 * 24 * time.Hour
+
+// issuerNonceTTL is how long a nonce from IssuerNonce can be used for a credential request
+const issuerNonceTTL = 5 * time.Minute
 
 // ZJ init issuer
 
-func InitIssuer(writer http.ResponseWriter, request *http.Request) {
+func (s *Service) InitIssuer(writer http.ResponseWriter, request *http.Request) {
 	var result preDefine.IssuerKeyResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
@@ -57,8 +58,35 @@ func InitIssuer(writer http.ResponseWriter, request *http.Request) {
 		result.Msg = "解码失败"
 		return
 	}
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	// an initialized issuer changes its key with RotateIssuer only, which keeps the old keys
+	if s.currentIssuerKey() != nil {
+		result.Code = "400"
+		result.Msg = "CA已初始化"
+		return
+	}
+	rng, err := newRand()
+	if err != nil {
+		result.Code = "400"
+		result.Msg = "初始化失败"
+		return
+	}
+	if initIssuerRequest.Issuers < 0 || (initIssuerRequest.Issuers > 0 && (initIssuerRequest.Threshold < 1 || initIssuerRequest.Threshold > initIssuerRequest.Issuers)) {
+		result.Code = "400"
+		result.Msg = "门限参数无效"
+		return
+	}
+	for _, name := range initIssuerRequest.Attributions {
+		if name == revocationHandleAttribute {
+			result.Code = "400"
+			result.Msg = "属性名已被撤销句柄占用"
+			return
+		}
+	}
 	st := time.Now()
-	IssuerKey, err := idemixplus.NewIssuerKey(initIssuerRequest.Attributions, Rng)
+	names := append(append([]string{}, initIssuerRequest.Attributions...), revocationHandleAttribute)
+	IssuerKey, shares, err := newIssuerKey(names, initIssuerRequest.Threshold, initIssuerRequest.Issuers, rng)
 	if err != nil {
 		result.Code = "400"
 		result.Msg = "初始化失败"
@@ -66,53 +94,176 @@ func InitIssuer(writer http.ResponseWriter, request *http.Request) {
 	}
 	spend := time.Now().Sub(st).Nanoseconds()
 
-	Attrs = make([]*FP256BN.BIG, len(initIssuerRequest.Attributions))
-	for i := range initIssuerRequest.Attributions {
-		Attrs[i] = FP256BN.NewBIGint(i)
+	pub, err := s.installIssuerKey(IssuerKey, shares)
+	if err != nil {
+		fmt.Println(err)
+		result.Code = "400"
+		result.Msg = "初始化失败"
+		return
+	}
+	if shares != nil {
+		result.Tpk, result.Shares = encodeThresholdKey(shares)
+	} else {
+		priKeyBytes, _ := proto.Marshal(IssuerKey.Isk)
+		result.Pri = base64.StdEncoding.EncodeToString(priKeyBytes)
+	}
+	result.Code = "200"
+	result.Msg = "初始化成功"
+	result.Pub = pub
+	result.KeyId = IssuerKey.Ipk.GetKeyId()
+	result.Spend = spend
+}
+
+// RotateIssuer replaces the issuer key by a new key for the same attributes, a threshold issuer key by
+// a threshold issuer key of as many issuers. Credentials are issued under the new key from now on, while
+// credentials and signatures made under the old keys still verify.
+func (s *Service) RotateIssuer(writer http.ResponseWriter, request *http.Request) {
+	var result preDefine.IssuerKeyResponse
+	defer func() {
+		_ = json.NewEncoder(writer).Encode(result)
+	}()
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	current := s.currentIssuerKey()
+	if current == nil {
+		result.Code = "400"
+		result.Msg = "CA尚未初始化"
+		return
+	}
+	rng, err := newRand()
+	if err != nil {
+		result.Code = "400"
+		result.Msg = "密钥轮换失败"
+		return
+	}
+	s.mu.RLock()
+	currentShares := s.keyShares[current.Ipk.GetKeyId()]
+	s.mu.RUnlock()
+	var threshold, issuers int
+	if currentShares != nil {
+		threshold, issuers = int(currentShares[0].GetTpk().GetThreshold()), int(currentShares[0].GetTpk().GetIssuers())
 	}
+	st := time.Now()
+	IssuerKey, shares, err := newIssuerKey(current.Ipk.GetAttributeNames(), threshold, issuers, rng)
+	if err != nil {
+		result.Code = "400"
+		result.Msg = "密钥轮换失败"
+		return
+	}
+	spend := time.Now().Sub(st).Nanoseconds()
 
-	ipkBytes, _ := proto.Marshal(IssuerKey.Ipk)
-	_, err = utils.ExecuteCC(preDefine.ZJCCID, "ipkinit", [][]byte{ipkBytes}, channalClient)
+	pub, err := s.installIssuerKey(IssuerKey, shares)
 	if err != nil {
 		fmt.Println(err)
+		result.Code = "400"
+		result.Msg = "密钥轮换失败"
 		return
 	}
-	priKeyBytes, _ := proto.Marshal(IssuerKey.Isk)
-	pubKeyBytes, _ := proto.Marshal(IssuerKey.Ipk)
-	priEncodeString := base64.StdEncoding.EncodeToString(priKeyBytes)
-	pubEncodeString := base64.StdEncoding.EncodeToString(pubKeyBytes)
+	if shares != nil {
+		result.Tpk, result.Shares = encodeThresholdKey(shares)
+	} else {
+		priKeyBytes, _ := proto.Marshal(IssuerKey.Isk)
+		result.Pri = base64.StdEncoding.EncodeToString(priKeyBytes)
+	}
 	result.Code = "200"
-	result.Msg = "初始化成功"
-	result.Pri = priEncodeString
-	result.Pub = pubEncodeString
+	result.Msg = "密钥轮换成功"
+	result.Pub = pub
+	result.KeyId = IssuerKey.Ipk.GetKeyId()
 	result.Spend = spend
-	issuerKey = IssuerKey
-	attributions = initIssuerRequest.Attributions
-	UserInfoMap["CA"] = UserInfo{
-		Pub: pubEncodeString,
+}
+
+// newIssuerKey creates an issuer key for the given attribute names, or a threshold issuer key and its key shares
+// if issuers is above 0
+func newIssuerKey(AttributeNames []string, threshold int, issuers int, rng *amcl.RAND) (*idemixplus.IssuerKey, []*idemixplus.IssuerKeyShare, error) {
+	if issuers == 0 {
+		key, err := idemixplus.NewIssuerKey(AttributeNames, rng)
+		return key, nil, err
+	}
+	shares, err := newThresholdIssuerKey(AttributeNames, threshold, issuers, rng)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &idemixplus.IssuerKey{Ipk: shares[0].GetTpk().GetIpk()}, shares, nil
+}
+
+// installIssuerKey records the issuer public key on chain through ipkinit and makes the key the current one,
+// it returns the encoded issuer public key. The key is kept in the store before the keyring and the service
+// take it, so that a failed write leaves the service as it was. The caller holds keyMu.
+func (s *Service) installIssuerKey(key *idemixplus.IssuerKey, shares []*idemixplus.IssuerKeyShare) (string, error) {
+	ipkBytes, err := proto.Marshal(key.Ipk)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.ledger.Execute(preDefine.ZJCCID, "ipkinit", [][]byte{ipkBytes})
+	if err != nil {
+		return "", err
+	}
+	pub := base64.StdEncoding.EncodeToString(ipkBytes)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.issuerKeys[key.Ipk.GetKeyId()]; exists {
+		return "", errors.Errorf("issuer key %s is already installed", key.Ipk.GetKeyId())
+	}
+	if err = s.saveIssuerKey(key, shares); err != nil {
+		return "", err
+	}
+	if err = s.keyring.Rotate(key.Ipk); err != nil {
+		return "", err
+	}
+	if shares != nil {
+		s.keyShares[key.Ipk.GetKeyId()] = shares
+	}
+	s.setIssuerKey(key, pub)
+	return pub, nil
+}
+
+// setIssuerKey makes the key the current one, the caller holds mu.
+// The last attribute of the key is the revocation handle, the others are disclosed.
+func (s *Service) setIssuerKey(key *idemixplus.IssuerKey, pub string) {
+	s.issuerKeys[key.Ipk.GetKeyId()] = key
+	s.issuerKey = key
+	s.users["CA"] = UserInfo{
+		Pub: pub,
+	}
+	names := key.Ipk.GetAttributeNames()
+	s.rhIndex = len(names) - 1
+	s.attributions = names[:s.rhIndex]
+	s.attrs = make([]*FP256BN.BIG, len(names))
+	s.disclosure = make([]byte, len(names))
+	for i := range s.attributions {
+		s.attrs[i] = FP256BN.NewBIGint(i)
+		s.disclosure[i] = 1
 	}
 }
 
-func GetAttributions(writer http.ResponseWriter, request *http.Request) {
+func (s *Service) GetAttributions(writer http.ResponseWriter, request *http.Request) {
 	var result preDefine.AttributionsResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
 	}()
-	if attributions == nil {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.attributions == nil {
 		result.Code = "400"
 		result.Msg = "CA尚未初始化"
+		return
 	}
 	result.Code = "200"
 	result.Msg = "初始化成功"
-	result.Attributions = attributions
+	result.Attributions = s.attributions
+	result.Disclosure = s.disclosure
+	result.RhIndex = s.rhIndex
 }
 
-func InitUser(writer http.ResponseWriter, request *http.Request) {
+// InitUser registers a user with the trace of the key it generated in its wallet,
+// the secret key of the user never reaches the server
+func (s *Service) InitUser(writer http.ResponseWriter, request *http.Request) {
 	var result preDefine.UserKeyResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
 	}()
-	var initUserRequest preDefine.InitRequest
+	var initUserRequest preDefine.InitUserRequest
 	if err := json.NewDecoder(request.Body).Decode(&initUserRequest); err != nil {
 		_ = request.Body.Close()
 		result.Code = "400"
@@ -120,88 +271,129 @@ func InitUser(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 	start := time.Now()
-	keys, trace, err := idemixplus.NewUserKey(initUserRequest.Attributions, Rng)
+	trace := &idemixplus.Trace{}
+	decodeBytes, err := base64.StdEncoding.DecodeString(initUserRequest.Trace)
+	if err == nil {
+		err = proto.Unmarshal(decodeBytes, trace)
+	}
+	if err == nil {
+		err = trace.Check()
+	}
 	spend := time.Now().Sub(start).Nanoseconds()
 	if err != nil {
 		result.Code = "400"
 		result.Msg = fmt.Sprintf("%v", err)
 		return
 	}
-	priKeyBytes, _ := proto.Marshal(keys.Usk)
-	pubKeyBytes, _ := proto.Marshal(keys.Upk)
-	traceBytes, _ := proto.Marshal(trace)
-	priEncodeString := base64.StdEncoding.EncodeToString(priKeyBytes)
+	pubKeyBytes, _ := proto.Marshal(trace.Upk)
 	pubEncodeString := base64.StdEncoding.EncodeToString(pubKeyBytes)
-	traceEncodeString := base64.StdEncoding.EncodeToString(traceBytes)
+	names := trace.GetUpk().GetAttributeNames()
+	userInfo := UserInfo{
+		Pub:          pubEncodeString,
+		Trace:        initUserRequest.Trace,
+		Attributions: names,
+	}
+
+	// the registration is kept in the store before the service takes it, a user registers once under one name
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.users[initUserRequest.User]; exists {
+		result.Code = "400"
+		result.Msg = "用户已存在"
+		return
+	}
+	if s.traces.Contains(trace.GetUpk()) {
+		result.Code = "400"
+		result.Msg = "用户公钥已注册"
+		return
+	}
+	if err = s.saveUser(initUserRequest.User, userInfo, true); err != nil {
+		result.Code = "400"
+		result.Msg = fmt.Sprintf("%v", err)
+		return
+	}
+	if err = s.traces.Add(trace); err != nil {
+		result.Code = "400"
+		result.Msg = fmt.Sprintf("%v", err)
+		return
+	}
 	result.Code = "200"
 	result.Msg = "初始化成功"
-	result.Pri = priEncodeString
 	result.Pub = pubEncodeString
-	result.Trace = traceEncodeString
+	result.Trace = initUserRequest.Trace
 	result.Spend = spend
-	traces.TraceList = append(traces.TraceList, trace)
-	UserInfoMap[initUserRequest.User] = UserInfo{
-		Pri:          priEncodeString,
-		Pub:          pubEncodeString,
-		Trace:        traceEncodeString,
-		Attributions: initUserRequest.Attributions,
-	}
-	UserTraceInfoArray = append(UserTraceInfoArray, UserTraceInfo{
+	s.users[initUserRequest.User] = userInfo
+	s.traceInfos = append(s.traceInfos, UserTraceInfo{
 		User:         initUserRequest.User,
 		Pub:          pubEncodeString,
-		Attributions: initUserRequest.Attributions,
+		Attributions: names,
 	})
 }
 
-func GetUserInfo(writer http.ResponseWriter, request *http.Request) {
+func (s *Service) GetUserInfo(writer http.ResponseWriter, request *http.Request) {
 	var userInfoRequest preDefine.UserInfoRequest
 	if err := json.NewDecoder(request.Body).Decode(&userInfoRequest); err != nil {
 		_ = request.Body.Close()
 		return
 	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if userInfoRequest.Trace == "" {
 		var result UserInfo
-		result = UserInfoMap[userInfoRequest.User]
+		result = s.users[userInfoRequest.User]
 		_ = json.NewEncoder(writer).Encode(result)
 		return
 	}
 	if userInfoRequest.Trace == "trace" {
-		_ = json.NewEncoder(writer).Encode(UserTraceInfoArray)
+		_ = json.NewEncoder(writer).Encode(s.traceInfos)
 	}
 }
 
-func CreateCredentialRequest(writer http.ResponseWriter, request *http.Request) {
-	var result preDefine.CreateCredentialRequestResponse
+// IssuerNonce hands out the nonce a credential request is bound to, which is the first round of issuance.
+// CreateCredential accepts each nonce once and only within issuerNonceTTL. In threshold mode it hands out
+// the values of the credential as well, as the request of the user is bound to them.
+func (s *Service) IssuerNonce(writer http.ResponseWriter, request *http.Request) {
+	var result preDefine.IssuerNonceResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
 	}()
-	var createCredentialRequestRequest preDefine.CreateCredentialRequestRequest
-	if err := json.NewDecoder(request.Body).Decode(&createCredentialRequestRequest); err != nil {
-		_ = request.Body.Close()
+	s.mu.RLock()
+	issuerKey, attrs, rhIndex := s.issuerKey, s.attrs, s.rhIndex
+	var shares []*idemixplus.IssuerKeyShare
+	if issuerKey != nil {
+		shares = s.keyShares[issuerKey.Ipk.GetKeyId()]
+	}
+	s.mu.RUnlock()
+	if issuerKey == nil {
+		result.Code = "400"
+		result.Msg = "CA尚未初始化"
 		return
 	}
-
-	start := time.Now()
-	pri := &idemixplus.UserSecretKey{}
-	decodeBytes, _ := base64.StdEncoding.DecodeString(createCredentialRequestRequest.Pri)
-	spend := time.Now().Sub(start).Nanoseconds()
-	_ = proto.Unmarshal(decodeBytes, pri)
-
-	usk := FP256BN.FromBytes(pri.X)
-	ni := idemixplus.RandModOrder(Rng)
-	cr := idemixplus.NewCredRequest(usk, idemixplus.BigToBytes(ni), issuerKey.Ipk, Rng)
-	crBytes, _ := proto.Marshal(cr)
-	crEncodeString := base64.StdEncoding.EncodeToString(crBytes)
-	userInfo := UserInfoMap[createCredentialRequestRequest.User]
-	userInfo.Cr = crEncodeString
-	UserInfoMap[createCredentialRequestRequest.User] = userInfo
+	rng, err := newRand()
+	if err != nil {
+		result.Code = "400"
+		result.Msg = fmt.Sprintf("%v", err)
+		return
+	}
+	nonce := s.nonces.NewNonce(rng)
+	if shares != nil {
+		values := append([]*FP256BN.BIG{}, attrs...)
+		values[rhIndex] = thresholdHandle(nonce)
+		for _, value := range values {
+			result.Attrs = append(result.Attrs, base64.StdEncoding.EncodeToString(idemixplus.BigToBytes(value)))
+		}
+		notBefore := time.Now()
+		result.NotBefore = notBefore.Unix()
+		result.NotAfter = notBefore.Add(credentialLifetime).Unix()
+	}
 	result.Code = "200"
-	result.Spend = spend
-	result.Msg = "证书请求创建成功"
-	result.Cr = crEncodeString
+	result.Msg = "随机数创建成功"
+	result.Nonce = base64.StdEncoding.EncodeToString(nonce)
 }
 
-func CreateCredential(writer http.ResponseWriter, request *http.Request) {
+// CreateCredential issues a credential on the credential request of a registered user. In threshold mode
+// every issuer issues a partial credential on the threshold credential request, which the user aggregates.
+func (s *Service) CreateCredential(writer http.ResponseWriter, request *http.Request) {
 	var result preDefine.CreateCredentialResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
@@ -212,42 +404,195 @@ func CreateCredential(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 	start := time.Now()
-	cr := &idemixplus.CredRequest{}
-	decodeBytes, _ := base64.StdEncoding.DecodeString(createCredentialRequest.Cr)
+	crBytes, _ := base64.StdEncoding.DecodeString(createCredentialRequest.Cr)
 	spend := time.Now().Sub(start).Nanoseconds()
-	_ = proto.Unmarshal(decodeBytes, cr)
 
+	// the credential is issued to the user public key the user registered with InitUser
+	s.mu.RLock()
+	issuerKey, attrs, rhIndex := s.issuerKey, s.attrs, s.rhIndex
+	var shares []*idemixplus.IssuerKeyShare
+	if issuerKey != nil {
+		shares = s.keyShares[issuerKey.Ipk.GetKeyId()]
+	}
+	userInfo, exists := s.users[createCredentialRequest.User]
+	s.mu.RUnlock()
+	if issuerKey == nil {
+		result.Code = "400"
+		result.Msg = "CA尚未初始化"
+		return
+	}
+	if !exists {
+		result.Code = "400"
+		result.Msg = "用户未注册"
+		return
+	}
 	upk := &idemixplus.UserPublicKey{}
-	decodeBytes, _ = base64.StdEncoding.DecodeString(UserInfoMap[createCredentialRequest.User].Pub)
+	decodeBytes, _ := base64.StdEncoding.DecodeString(userInfo.Pub)
 	_ = proto.Unmarshal(decodeBytes, upk)
 
-	cred, err := idemixplus.NewCredential(issuerKey, cr, upk, Attrs, Rng)
-	if err != nil {
+	var credEncodeString string
+	var rh *FP256BN.BIG
+	var notBefore, notAfter int64
+	if shares != nil {
+		m := &idemixplus.ThresholdCredRequest{}
+		_ = proto.Unmarshal(crBytes, m)
+		var partials []*idemixplus.PartialCredential
+		var err error
+		notBefore = createCredentialRequest.NotBefore
+		partials, rh, notAfter, err = s.issueThreshold(shares, m, upk, attrs, rhIndex, notBefore)
+		if err != nil {
+			result.Code = "400"
+			result.Msg = fmt.Sprintf("%v", err)
+			return
+		}
+		for _, partial := range partials {
+			partialBytes, _ := proto.Marshal(partial)
+			result.Partials = append(result.Partials, base64.StdEncoding.EncodeToString(partialBytes))
+		}
+	} else {
+		cr := &idemixplus.CredRequest{}
+		_ = proto.Unmarshal(crBytes, cr)
+		rng, err := newRand()
+		if err != nil {
+			result.Code = "400"
+			result.Msg = fmt.Sprintf("%v", err)
+			return
+		}
+		// every credential gets a revocation handle of its own
+		rh = idemixplus.RandModOrder(rng)
+		values := append([]*FP256BN.BIG{}, attrs...)
+		values[rhIndex] = rh
+		now := time.Now()
+		cred, err := idemixplus.NewCredential(issuerKey, s.nonces, cr, upk, values, now.Unix(), now.Add(credentialLifetime).Unix(), rng)
+		if err != nil {
+			result.Code = "400"
+			result.Msg = fmt.Sprintf("%v", err)
+			return
+		}
+		credBytes, _ := proto.Marshal(cred)
+		credEncodeString = base64.StdEncoding.EncodeToString(credBytes)
+		notBefore, notAfter = cred.GetNotBefore(), cred.GetNotAfter()
+	}
+	// the issuer never sees a threshold credential, only the partial credentials it aggregates from
+	record := CredentialRecord{
+		User:             createCredentialRequest.User,
+		KeyId:            issuerKey.Ipk.GetKeyId(),
+		NotBefore:        notBefore,
+		NotAfter:         notAfter,
+		Cred:             credEncodeString,
+		RevocationHandle: base64.StdEncoding.EncodeToString(idemixplus.BigToBytes(rh)),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	userInfo = s.users[createCredentialRequest.User]
+	userInfo.Cr = createCredentialRequest.Cr
+	userInfo.Cred = credEncodeString
+	if err := s.saveCredential(record, userInfo); err != nil {
 		result.Code = "400"
 		result.Msg = fmt.Sprintf("%v", err)
 		return
 	}
-	usk := &idemixplus.UserSecretKey{}
-	decodeBytes, _ = base64.StdEncoding.DecodeString(UserInfoMap[createCredentialRequest.User].Pri)
-	_ = proto.Unmarshal(decodeBytes, usk)
-	err = cred.Ver(FP256BN.FromBytes(usk.GetX()), issuerKey.Ipk)
+	s.users[createCredentialRequest.User] = userInfo
+	// the CRI has to sign the handle of the new credential
+	s.cri = nil
+	s.criVersion++
+	result.Code = "200"
+	result.Msg = "证书请求创建成功"
+	result.Cred = credEncodeString
+	result.Spend = spend
+}
+
+// GetCRI hands out the credential revocation information of the current epoch, with which a user proves
+// that its credential is not revoked. The CRI is made for the credentials that are not revoked and reused
+// until a credential is issued or revoked.
+func (s *Service) GetCRI(writer http.ResponseWriter, request *http.Request) {
+	var result preDefine.CRIResponse
+	defer func() {
+		_ = json.NewEncoder(writer).Encode(result)
+	}()
+	s.mu.RLock()
+	cri, revKey, epoch, version := s.cri, s.revocationKey, s.epoch, s.criVersion
+	var handles []*FP256BN.BIG
+	var err error
+	if cri == nil {
+		handles, err = s.unrevokedHandles()
+	}
+	s.mu.RUnlock()
 	if err != nil {
-		log.Fatal(err)
+		result.Code = "400"
+		result.Msg = fmt.Sprintf("%v", err)
+		return
 	}
 
-	credBytes, _ := proto.Marshal(cred)
-	credEncodeString := base64.StdEncoding.EncodeToString(credBytes)
+	if cri == nil {
+		rng, err := newRand()
+		if err == nil {
+			cri, err = idemixplus.CreateCRI(revKey, handles, epoch, idemixplus.ALG_PLAIN_SIGNATURE, rng)
+		}
+		if err != nil {
+			result.Code = "400"
+			result.Msg = fmt.Sprintf("%v", err)
+			return
+		}
+		s.mu.Lock()
+		if s.criVersion == version {
+			s.cri = cri
+		}
+		s.mu.Unlock()
+	}
+	criBytes, _ := proto.Marshal(cri)
+	result.Code = "200"
+	result.Msg = "success"
+	result.Cri = base64.StdEncoding.EncodeToString(criBytes)
+	result.Epoch = epoch
+}
 
-	userInfo := UserInfoMap[createCredentialRequest.User]
-	userInfo.Cred = credEncodeString
-	UserInfoMap[createCredentialRequest.User] = userInfo
+// RevokeUser revokes the credentials of a user. The revocation starts a new epoch, whose CRI leaves
+// the revocation handles of the credentials out, signatures of earlier epochs are expired.
+func (s *Service) RevokeUser(writer http.ResponseWriter, request *http.Request) {
+	var result preDefine.RevokeUserResponse
+	defer func() {
+		_ = json.NewEncoder(writer).Encode(result)
+	}()
+	var revokeRequest preDefine.RevokeUserRequest
+	if err := json.NewDecoder(request.Body).Decode(&revokeRequest); err != nil {
+		_ = request.Body.Close()
+		result.Code = "400"
+		result.Msg = fmt.Sprintf("%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var revoked []int
+	for n, record := range s.credentials {
+		if record.User == revokeRequest.User && !record.Revoked {
+			revoked = append(revoked, n)
+		}
+	}
+	if len(revoked) == 0 {
+		result.Code = "400"
+		result.Msg = "用户没有可撤销的证书"
+		return
+	}
+	if err := s.saveRevocation(revoked, s.epoch+1); err != nil {
+		result.Code = "400"
+		result.Msg = fmt.Sprintf("%v", err)
+		return
+	}
+	for _, n := range revoked {
+		s.credentials[n].Revoked = true
+	}
+	s.epoch++
+	s.cri = nil
+	s.criVersion++
 	result.Code = "200"
-	result.Msg = "证书请求创建成功"
-	result.Cred = credEncodeString
-	result.Spend = spend
+	result.Msg = "撤销成功"
+	result.Epoch = s.epoch
 }
 
-func Verify(writer http.ResponseWriter, request *http.Request) {
+func (s *Service) Verify(writer http.ResponseWriter, request *http.Request) {
 	var result preDefine.VerifyResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
@@ -262,11 +607,18 @@ func Verify(writer http.ResponseWriter, request *http.Request) {
 	decodeBytes, _ := base64.StdEncoding.DecodeString(verifyRequest.Random)
 	_ = proto.Unmarshal(decodeBytes, sig)
 	start := time.Now()
-	err := sig.Ver(issuerKey.Ipk, []byte(verifyRequest.Msg), nil, 0)
+	// every credential is issued with the values in attrs, so the disclosed ones must match them,
+	// and its revocation handle must be signed by the CRI of the current epoch
+	s.mu.RLock()
+	opts := &idemixplus.VerifyOpts{Scope: []byte(verifyRequest.Scope), ValidAt: verifyRequest.ValidAt, Disclosure: s.disclosure,
+		AttributeValues: s.attrs, RhIndex: s.rhIndex, RevPk: &s.revocationKey.PublicKey, Epoch: s.epoch}
+	keyring := s.keyring
+	s.mu.RUnlock()
+	err := keyring.Verify(sig, []byte(verifyRequest.Msg), opts)
 	spend := time.Now().Sub(start).Nanoseconds()
 	if err != nil {
 		result.Code = "200"
-		result.Msg = "fail"
+		result.Msg = verifyErrorMsg(err)
 		return
 	}
 	result.Code = "200"
@@ -274,7 +626,19 @@ func Verify(writer http.ResponseWriter, request *http.Request) {
 	result.Spend = spend
 }
 
-func Trace(writer http.ResponseWriter, request *http.Request) {
+// verifyErrorMsg tells revoked and expired signatures apart from forged ones
+func verifyErrorMsg(err error) string {
+	switch {
+	case idemixplus.IsRevoked(err):
+		return "revoked"
+	case idemixplus.IsExpired(err):
+		return "expired"
+	default:
+		return "fail"
+	}
+}
+
+func (s *Service) Trace(writer http.ResponseWriter, request *http.Request) {
 	var result preDefine.CredentialTraceResponse
 	defer func() {
 		_ = json.NewEncoder(writer).Encode(result)
@@ -285,24 +649,18 @@ func Trace(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 	sig := &idemixplus.NymSignature{}
+	var msg []byte
 	start := time.Now()
 
-	upk := &idemixplus.UserPublicKey{}
 	if traceRequest.Sig != "" {
 		decodeBytes, _ := base64.StdEncoding.DecodeString(traceRequest.Sig)
 		_ = proto.Unmarshal(decodeBytes, sig)
-
-		var err error
-		upk, err = idemixplus.Arbitration(traces, sig)
-		if err != nil {
-			result.Code = "400"
-			result.Msg = "追踪失败"
-		}
+		msg = []byte(traceRequest.Msg)
 	} else {
 		fmt.Println("=================链上追踪开始===================")
 		fmt.Println(traceRequest.TransactionID)
 		queryArgs := [][]byte{[]byte(traceRequest.TransactionID)}
-		response, err := utils.ExecuteCC(preDefine.ZJCCID, "queryIdemix", queryArgs, channalClient)
+		response, err := s.ledger.Execute(preDefine.ZJCCID, "queryIdemix", queryArgs)
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -313,14 +671,78 @@ func Trace(writer http.ResponseWriter, request *http.Request) {
 			fmt.Println(err)
 			return
 		}
-		nymSignature := &idemixplus.NymSignature{}
-		err = proto.Unmarshal(record.NymCred, nymSignature)
-		// fmt.Println(nymBlock)
-		upk, err = idemixplus.Arbitration(traces, nymSignature)
+		_ = proto.Unmarshal(record.NymCred, sig)
+		msg = []byte(record.Content)
+	}
+
+	// the signature names the issuer key it is made under
+	s.mu.RLock()
+	key, exists := s.issuerKeys[sig.GetKeyId()]
+	shares := s.keyShares[sig.GetKeyId()]
+	s.mu.RUnlock()
+	if !exists {
+		result.Code = "400"
+		result.Msg = "未知的CA密钥"
+		return
+	}
+
+	// only a valid signature ties its tracing tag to the signer, a signature is traced
+	// for what it proved when it was made, so it is checked at its own scope, time and epoch
+	s.mu.RLock()
+	opts := &idemixplus.VerifyOpts{Scope: sig.GetScope(), ValidAt: sig.GetValidAt(), Disclosure: s.disclosure,
+		AttributeValues: s.attrs, RhIndex: s.rhIndex, RevPk: &s.revocationKey.PublicKey, Epoch: int(sig.GetEpoch())}
+	keyring, traces := s.keyring, s.traces
+	s.mu.RUnlock()
+	err := keyring.Verify(sig, msg, opts)
+	if err != nil {
+		result.Code = "400"
+		result.Msg = verifyErrorMsg(err)
+		return
+	}
+
+	// anyone can check an opening with the public keys
+	if traceRequest.Proof != "" {
+		upk := &idemixplus.UserPublicKey{}
+		decodeBytes, _ := base64.StdEncoding.DecodeString(traceRequest.Pub)
+		_ = proto.Unmarshal(decodeBytes, upk)
+		decodeBytes, _ = base64.StdEncoding.DecodeString(traceRequest.Proof)
+		if shares != nil {
+			opening := &idemixplus.ThresholdOpening{}
+			_ = proto.Unmarshal(decodeBytes, opening)
+			err = idemixplus.VerifyThresholdOpening(shares[0].GetTpk().ArbitrationPublicKey(), key.Ipk, upk, sig, msg, opts, opening)
+		} else {
+			opening := &idemixplus.OpeningProof{}
+			_ = proto.Unmarshal(decodeBytes, opening)
+			err = idemixplus.VerifyOpening(key.Ipk, upk, sig, msg, opts, opening)
+		}
+		result.Spend = time.Now().Sub(start).Nanoseconds()
+		result.Code = "200"
 		if err != nil {
-			result.Code = "400"
-			result.Msg = "追踪失败"
+			result.Msg = "fail"
+			return
 		}
+		result.Msg = "success"
+		return
+	}
+
+	rng, err := newRand()
+	if err != nil {
+		result.Code = "400"
+		result.Msg = "追踪失败"
+		return
+	}
+	// a threshold issuer key is traced by threshold issuers together
+	var upk *idemixplus.UserPublicKey
+	var opening proto.Message
+	if shares != nil {
+		upk, opening, err = traceThreshold(shares, key.Ipk, traces, sig, msg, opts, rng)
+	} else {
+		upk, opening, err = idemixplus.Arbitration(key, traces, sig, msg, opts, rng)
+	}
+	if err != nil {
+		result.Code = "400"
+		result.Msg = "追踪失败"
+		return
 	}
 
 	spend := time.Now().Sub(start).Nanoseconds()
@@ -329,6 +751,8 @@ func Trace(writer http.ResponseWriter, request *http.Request) {
 	result.Msg = "证书请求创建成功"
 	upkBytes, _ := proto.Marshal(upk)
 	upkEncodeString := base64.StdEncoding.EncodeToString(upkBytes)
+	openingBytes, _ := proto.Marshal(opening)
 	result.Pub = upkEncodeString
+	result.Proof = base64.StdEncoding.EncodeToString(openingBytes)
 	result.Spend = spend
 }
@@ -1,9 +1,25 @@
 package httpHandler
 
-import "github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+import (
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"traceGo/utils"
+)
 
-var channalClient *channel.Client
+// Ledger executes the chaincode the handlers record issuer keys and messages with
+type Ledger interface {
+	Execute(ccID string, fcn string, args [][]byte) (channel.Response, error)
+}
+
+// channelLedger is a Ledger that executes the chaincode through a Fabric channel client
+type channelLedger struct {
+	client *channel.Client
+}
+
+// NewChannelLedger creates a Ledger that executes the chaincode through client
+func NewChannelLedger(client *channel.Client) Ledger {
+	return &channelLedger{client: client}
+}
 
-func SetClient(client *channel.Client) {
-	channalClient = client
+func (l *channelLedger) Execute(ccID string, fcn string, args [][]byte) (channel.Response, error) {
+	return utils.ExecuteCC(ccID, fcn, args, l.client)
 }
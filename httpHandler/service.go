@@ -0,0 +1,116 @@
+package httpHandler
+
+import (
+	"crypto/ecdsa"
+	"net/http"
+	"sync"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+	"traceGo/idemixplus"
+	"traceGo/store"
+)
+
+// Service serves the issuer, the arbitrator and the message handlers over HTTP.
+// It is safe for concurrent use: the handlers of every request share the state of the service under mu,
+// and every request draws its randomness from an RNG of its own, as an amcl.RAND is not safe for concurrent use.
+// The expensive computations run outside of mu on a snapshot of what they need, mu is held while the state
+// is read or changed only.
+type Service struct {
+	ledger Ledger
+	nonces *idemixplus.IssuerNonceStore
+
+	keyMu sync.Mutex // serializes the changes of the issuer key
+
+	mu           sync.RWMutex // guards the fields below
+	store        store.Store
+	issuerKey    *idemixplus.IssuerKey
+	issuerKeys   map[string]*idemixplus.IssuerKey
+	keyShares    map[string][]*idemixplus.IssuerKeyShare // the key shares of the threshold issuer keys, by key ID
+	keyring      *idemixplus.IssuerKeyring
+	attributions []string
+	attrs        []*FP256BN.BIG // the attribute values of every credential, nil for the revocation handle
+	disclosure   []byte         // the disclosure a verifier expects, every attribute but the revocation handle
+	rhIndex      int
+	users        map[string]UserInfo
+	traceInfos   []UserTraceInfo
+	traces       *idemixplus.TraceIndex
+	credentials  []CredentialRecord
+
+	revocationKey *ecdsa.PrivateKey
+	epoch         int
+	cri           *idemixplus.CredentialRevocationInformation // the CRI of the epoch, nil until it is made
+	criVersion    int                                         // counts the changes that make the CRI out of date
+}
+
+// NewService creates a service that keeps its state in s and records on ledger, it loads the state kept in s
+func NewService(s store.Store, ledger Ledger) (*Service, error) {
+	if s == nil || ledger == nil {
+		return nil, errors.Errorf("cannot create service: received nil input")
+	}
+	service := &Service{
+		ledger: ledger,
+		nonces: idemixplus.NewIssuerNonceStore(issuerNonceTTL),
+	}
+	if err := service.load(s); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// OpenService creates a service that keeps its state in the file-backed store at path
+func OpenService(path string, ledger Ledger) (*Service, error) {
+	s, err := store.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	service, err := NewService(s, ledger)
+	if err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return service, nil
+}
+
+// Close closes the store of the service
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store.Close()
+}
+
+// Handler routes the requests to the handlers of the service
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/initIssuer", s.InitIssuer)
+	mux.HandleFunc("/rotateIssuer", s.RotateIssuer)
+	mux.HandleFunc("/getAttributions", s.GetAttributions)
+	mux.HandleFunc("/issuerNonce", s.IssuerNonce)
+	mux.HandleFunc("/initUser", s.InitUser)
+	mux.HandleFunc("/getUserInfo", s.GetUserInfo)
+	mux.HandleFunc("/createCredential", s.CreateCredential)
+	mux.HandleFunc("/getCRI", s.GetCRI)
+	mux.HandleFunc("/revokeUser", s.RevokeUser)
+	mux.HandleFunc("/verify", s.Verify)
+	mux.HandleFunc("/trace", s.Trace)
+	mux.HandleFunc("/uploadMessage", s.UploadMessage)
+	mux.HandleFunc("/queryMessage", s.QueryMessage)
+	return mux
+}
+
+// currentIssuerKey returns the issuer key credentials are issued under, nil before InitIssuer
+func (s *Service) currentIssuerKey() *idemixplus.IssuerKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.issuerKey
+}
+
+// newRand creates the RNG of a request
+func newRand() (*amcl.RAND, error) {
+	rng := idemixplus.GetRand(32)
+	if rng == nil {
+		return nil, errors.Errorf("failed to seed random number generator")
+	}
+	return rng, nil
+}
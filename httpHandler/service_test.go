@@ -0,0 +1,474 @@
+package httpHandler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/stretchr/testify/assert"
+	"traceGo/idemixplus"
+	"traceGo/preDefine"
+	"traceGo/store"
+	"traceGo/wallet"
+)
+
+// failingStore is a MemoryStore whose writes fail while fail is set
+type failingStore struct {
+	*store.MemoryStore
+	fail int32
+}
+
+func (s *failingStore) Write(entries ...store.Entry) error {
+	if atomic.LoadInt32(&s.fail) != 0 {
+		return fmt.Errorf("write failed")
+	}
+	return s.MemoryStore.Write(entries...)
+}
+
+// memoryLedger is a Ledger that keeps what the chaincode records in memory
+type memoryLedger struct {
+	mu      sync.Mutex
+	records map[string][]byte
+}
+
+func (l *memoryLedger) Execute(ccID string, fcn string, args [][]byte) (channel.Response, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch fcn {
+	case "ipkinit", "recordContent":
+		txID := fmt.Sprintf("tx%d", len(l.records))
+		l.records[txID] = args[0]
+		return channel.Response{TransactionID: fab.TransactionID(txID)}, nil
+	case "queryContent":
+		return channel.Response{Payload: l.records[string(args[0])]}, nil
+	}
+	return channel.Response{}, fmt.Errorf("unknown function %s", fcn)
+}
+
+func post(t *testing.T, server *httptest.Server, path string, request interface{}, response interface{}) {
+	body, err := json.Marshal(request)
+	assert.NoError(t, err)
+	resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(response))
+}
+
+func encode(t *testing.T, m proto.Message) string {
+	raw, err := proto.Marshal(m)
+	assert.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// enroll registers a new user and has a credential issued to it, as the wallet of a client does
+func enroll(t *testing.T, server *httptest.Server, name string, pub string) (*wallet.Wallet, string) {
+	rng := idemixplus.GetRand(32)
+	ipk := &idemixplus.IssuerPublicKey{}
+	raw, _ := base64.StdEncoding.DecodeString(pub)
+	assert.NoError(t, proto.Unmarshal(raw, ipk))
+	w, err := wallet.NewWallet(ipk.GetAttributeNames(), rng)
+	if !assert.NoError(t, err) {
+		return nil, ""
+	}
+
+	var user preDefine.UserKeyResponse
+	post(t, server, "/initUser", preDefine.InitUserRequest{User: name, Trace: encode(t, w.Trace)}, &user)
+	assert.Equal(t, "200", user.Code, user.Msg)
+	var nonce preDefine.IssuerNonceResponse
+	post(t, server, "/issuerNonce", nil, &nonce)
+	assert.Equal(t, "200", nonce.Code, nonce.Msg)
+	issuerNonce, _ := base64.StdEncoding.DecodeString(nonce.Nonce)
+	cr, err := w.NewCredRequest(ipk, issuerNonce)
+	if !assert.NoError(t, err) {
+		return nil, ""
+	}
+	var cred preDefine.CreateCredentialResponse
+	post(t, server, "/createCredential", preDefine.CreateCredentialRequest{User: name, Cr: encode(t, cr)}, &cred)
+	if !assert.Equal(t, "200", cred.Code, cred.Msg) {
+		return nil, ""
+	}
+	credential := &idemixplus.Credential{}
+	raw, _ = base64.StdEncoding.DecodeString(cred.Cred)
+	assert.NoError(t, proto.Unmarshal(raw, credential))
+	assert.NoError(t, w.SetCredential(credential))
+	return w, user.Pub
+}
+
+// enrollThreshold registers a new user with a threshold issuer and aggregates its credential from the partial
+// credentials of the issuers, as the wallet of a client does. It returns the credential request as well.
+func enrollThreshold(t *testing.T, server *httptest.Server, name string, encodedTpk string) (*wallet.Wallet, string, preDefine.CreateCredentialRequest) {
+	rng := idemixplus.GetRand(32)
+	tpk := &idemixplus.ThresholdIssuerPublicKey{}
+	raw, _ := base64.StdEncoding.DecodeString(encodedTpk)
+	assert.NoError(t, proto.Unmarshal(raw, tpk))
+	w, err := wallet.NewWallet(tpk.GetIpk().GetAttributeNames(), rng)
+	if !assert.NoError(t, err) {
+		return nil, "", preDefine.CreateCredentialRequest{}
+	}
+
+	var user preDefine.UserKeyResponse
+	post(t, server, "/initUser", preDefine.InitUserRequest{User: name, Trace: encode(t, w.Trace)}, &user)
+	assert.Equal(t, "200", user.Code, user.Msg)
+	var nonce preDefine.IssuerNonceResponse
+	post(t, server, "/issuerNonce", nil, &nonce)
+	assert.Equal(t, "200", nonce.Code, nonce.Msg)
+	issuerNonce, _ := base64.StdEncoding.DecodeString(nonce.Nonce)
+	var attrs []*FP256BN.BIG
+	for _, attr := range nonce.Attrs {
+		raw, _ = base64.StdEncoding.DecodeString(attr)
+		attrs = append(attrs, FP256BN.FromBytes(raw))
+	}
+	m, err := w.NewThresholdCredRequest(tpk, issuerNonce, attrs, nonce.NotBefore, nonce.NotAfter)
+	if !assert.NoError(t, err) {
+		return nil, "", preDefine.CreateCredentialRequest{}
+	}
+	request := preDefine.CreateCredentialRequest{User: name, Cr: encode(t, m), NotBefore: nonce.NotBefore}
+	var cred preDefine.CreateCredentialResponse
+	post(t, server, "/createCredential", request, &cred)
+	if !assert.Equal(t, "200", cred.Code, cred.Msg) {
+		return nil, "", request
+	}
+	assert.Empty(t, cred.Cred, "no issuer sees the credential")
+	var partials []*idemixplus.PartialCredential
+	for _, p := range cred.Partials {
+		partial := &idemixplus.PartialCredential{}
+		raw, _ = base64.StdEncoding.DecodeString(p)
+		assert.NoError(t, proto.Unmarshal(raw, partial))
+		partials = append(partials, partial)
+	}
+	assert.Len(t, partials, int(tpk.GetIssuers()))
+	assert.NoError(t, w.SetThresholdCredential(tpk, m, attrs, nonce.NotBefore, nonce.NotAfter, partials))
+	return w, user.Pub, request
+}
+
+// signOpts returns the options a wallet signs with for the service: the disclosure the service expects
+// and the CRI of the current epoch
+func signOpts(t *testing.T, server *httptest.Server, now int64) *idemixplus.SignOpts {
+	var attributions preDefine.AttributionsResponse
+	post(t, server, "/getAttributions", nil, &attributions)
+	assert.Equal(t, "200", attributions.Code, attributions.Msg)
+	var cri preDefine.CRIResponse
+	post(t, server, "/getCRI", nil, &cri)
+	assert.Equal(t, "200", cri.Code, cri.Msg)
+	opts := &idemixplus.SignOpts{Scope: []byte("scope"), ValidAt: now, Disclosure: attributions.Disclosure, RhIndex: attributions.RhIndex,
+		Cri: &idemixplus.CredentialRevocationInformation{}}
+	raw, _ := base64.StdEncoding.DecodeString(cri.Cri)
+	assert.NoError(t, proto.Unmarshal(raw, opts.Cri))
+	return opts
+}
+
+// verify has the service verify sig on msg at the time now and returns the message of the response
+func verify(t *testing.T, server *httptest.Server, sig *idemixplus.NymSignature, msg string, now int64) string {
+	var result preDefine.VerifyResponse
+	post(t, server, "/verify", preDefine.VerifyRequest{Msg: msg, Random: encode(t, sig), Scope: "scope", ValidAt: now}, &result)
+	return result.Msg
+}
+
+// signAndTrace has the service verify a signature of w and trace it back to the user public key pub
+func signAndTrace(t *testing.T, server *httptest.Server, w *wallet.Wallet, pub string, msg string) {
+	now := time.Now().Unix()
+	sig, err := w.Sign([]byte(msg), signOpts(t, server, now))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "success", verify(t, server, sig, msg, now))
+
+	var trace preDefine.CredentialTraceResponse
+	post(t, server, "/trace", preDefine.CredentialTraceRequest{Sig: encode(t, sig), Msg: msg}, &trace)
+	assert.Equal(t, "200", trace.Code, trace.Msg)
+	assert.Equal(t, pub, trace.Pub, "the signature is traced to its signer")
+	var opening preDefine.CredentialTraceResponse
+	post(t, server, "/trace", preDefine.CredentialTraceRequest{Sig: encode(t, sig), Msg: msg, Pub: trace.Pub, Proof: trace.Proof}, &opening)
+	assert.Equal(t, "success", opening.Msg)
+
+	// a signature that does not verify on the message is not traced
+	var forged preDefine.CredentialTraceResponse
+	post(t, server, "/trace", preDefine.CredentialTraceRequest{Sig: encode(t, sig), Msg: msg + " forged"}, &forged)
+	assert.Equal(t, "400", forged.Code)
+	assert.Empty(t, forged.Pub)
+}
+
+func TestServiceConcurrent(t *testing.T) {
+	const users = 6
+	path := filepath.Join(t.TempDir(), "state")
+	ledger := &memoryLedger{records: make(map[string][]byte)}
+	service, err := OpenService(path, ledger)
+	assert.NoError(t, err)
+	server := httptest.NewServer(service.Handler())
+	defer server.Close()
+
+	var issuer preDefine.IssuerKeyResponse
+	post(t, server, "/initIssuer", preDefine.InitRequest{Attributions: []string{"Attr1", "Attr2"}}, &issuer)
+	assert.Equal(t, "200", issuer.Code, issuer.Msg)
+	var again preDefine.IssuerKeyResponse
+	post(t, server, "/initIssuer", preDefine.InitRequest{Attributions: []string{"Attr1"}}, &again)
+	assert.Equal(t, "400", again.Code, "an issuer is initialized once")
+
+	// users enroll at the same time while others read the state of the service
+	wallets := make([]*wallet.Wallet, users)
+	pubs := make([]string, users)
+	var wg sync.WaitGroup
+	for i := 0; i < users; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			wallets[i], pubs[i] = enroll(t, server, fmt.Sprintf("user%d", i), issuer.Pub)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			var attributions preDefine.AttributionsResponse
+			post(t, server, "/getAttributions", nil, &attributions)
+			assert.Equal(t, []string{"Attr1", "Attr2"}, attributions.Attributions)
+			var infos []UserTraceInfo
+			post(t, server, "/getUserInfo", preDefine.UserInfoRequest{Trace: "trace"}, &infos)
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+	var infos []UserTraceInfo
+	post(t, server, "/getUserInfo", preDefine.UserInfoRequest{Trace: "trace"}, &infos)
+	assert.Len(t, infos, users)
+
+	// a name and a user key are registered once
+	other, err := wallet.NewWallet([]string{"Attr1", "Attr2", revocationHandleAttribute}, idemixplus.GetRand(32))
+	assert.NoError(t, err)
+	var user preDefine.UserKeyResponse
+	post(t, server, "/initUser", preDefine.InitUserRequest{User: "user0", Trace: encode(t, other.Trace)}, &user)
+	assert.Equal(t, "400", user.Code, "an existing user is not replaced")
+	post(t, server, "/initUser", preDefine.InitUserRequest{User: "again", Trace: encode(t, wallets[0].Trace)}, &user)
+	assert.Equal(t, "400", user.Code, "a user key is not registered twice")
+	var info UserInfo
+	post(t, server, "/getUserInfo", preDefine.UserInfoRequest{User: "user0"}, &info)
+	assert.Equal(t, pubs[0], info.Pub)
+
+	// signatures made under the old issuer key are verified and traced while the key rotates
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			signAndTrace(t, server, wallets[i], pubs[i], fmt.Sprintf("message %d", i))
+		}(i)
+	}
+	var rotated preDefine.IssuerKeyResponse
+	post(t, server, "/rotateIssuer", nil, &rotated)
+	assert.Equal(t, "200", rotated.Code, rotated.Msg)
+	assert.NotEqual(t, issuer.KeyId, rotated.KeyId)
+	wg.Wait()
+
+	var upload preDefine.UploadResponse
+	post(t, server, "/uploadMessage", preDefine.UploadContentRequest{Content: []byte("content")}, &upload)
+	assert.Equal(t, "200", upload.Code)
+	var query preDefine.QueryContentResponse
+	post(t, server, "/queryMessage", preDefine.QueryContentRequest{Txid: upload.Msg}, &query)
+	assert.Equal(t, []byte("content"), query.Content)
+
+	// the state survives a restart
+	server.Close()
+	assert.NoError(t, service.Close())
+	service, err = OpenService(path, ledger)
+	assert.NoError(t, err)
+	defer service.Close()
+	server = httptest.NewServer(service.Handler())
+	defer server.Close()
+	signAndTrace(t, server, wallets[0], pubs[0], "after restart")
+	w, pub := enroll(t, server, "late", rotated.Pub)
+	signAndTrace(t, server, w, pub, "under the new key")
+}
+
+func TestServiceRevocation(t *testing.T) {
+	st := store.NewMemoryStore()
+	ledger := &memoryLedger{records: make(map[string][]byte)}
+	service, err := NewService(st, ledger)
+	assert.NoError(t, err)
+	defer service.Close()
+	server := httptest.NewServer(service.Handler())
+	defer server.Close()
+
+	var issuer preDefine.IssuerKeyResponse
+	post(t, server, "/initIssuer", preDefine.InitRequest{Attributions: []string{"Attr1", "Attr2"}}, &issuer)
+	assert.Equal(t, "200", issuer.Code, issuer.Msg)
+	revoked, revokedPub := enroll(t, server, "revoked", issuer.Pub)
+	w, pub := enroll(t, server, "user", issuer.Pub)
+	if t.Failed() {
+		return
+	}
+
+	// the service expects the attributes disclosed and the revocation handle proven unrevoked
+	now := time.Now().Unix()
+	opts := signOpts(t, server, now)
+	assert.Equal(t, []byte{1, 1, 0}, opts.Disclosure)
+	sig, err := revoked.Sign([]byte("msg"), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", verify(t, server, sig, "msg", now))
+	noCri := *opts
+	noCri.Cri = nil
+	unproven, err := revoked.Sign([]byte("msg"), &noCri)
+	assert.NoError(t, err)
+	assert.Equal(t, "revoked", verify(t, server, unproven, "msg", now), "a signature without non-revocation proof is rejected")
+	hiding := *opts
+	hiding.Disclosure = []byte{1, 0, 0}
+	hidden, err := revoked.Sign([]byte("msg"), &hiding)
+	assert.NoError(t, err)
+	assert.Equal(t, "fail", verify(t, server, hidden, "msg", now), "the service decides what is disclosed")
+
+	var revocation preDefine.RevokeUserResponse
+	post(t, server, "/revokeUser", preDefine.RevokeUserRequest{User: "revoked"}, &revocation)
+	assert.Equal(t, "200", revocation.Code, revocation.Msg)
+	assert.Equal(t, 1, revocation.Epoch)
+	post(t, server, "/revokeUser", preDefine.RevokeUserRequest{User: "revoked"}, &revocation)
+	assert.Equal(t, "400", revocation.Code, "a user is revoked once")
+
+	// signatures of the last epoch are expired and the revoked user cannot prove its handle unrevoked
+	assert.Equal(t, "expired", verify(t, server, sig, "msg", now))
+	newOpts := signOpts(t, server, now)
+	assert.Equal(t, int64(1), newOpts.Cri.GetEpoch())
+	_, err = revoked.Sign([]byte("msg"), newOpts)
+	assert.Error(t, err, "the CRI does not sign the handle of a revoked credential")
+	// what a user signed before its revocation is still traced to it
+	var trace preDefine.CredentialTraceResponse
+	post(t, server, "/trace", preDefine.CredentialTraceRequest{Sig: encode(t, sig), Msg: "msg"}, &trace)
+	assert.Equal(t, "200", trace.Code, trace.Msg)
+	assert.Equal(t, revokedPub, trace.Pub)
+	signAndTrace(t, server, w, pub, "not revoked")
+
+	// the revocation key, the epoch and the revoked credentials are kept in the store
+	reloaded, err := NewService(st, ledger)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, service.revocationKey.Equal(reloaded.revocationKey))
+	assert.Equal(t, 1, reloaded.epoch)
+	assert.Equal(t, []bool{true, false}, []bool{reloaded.credentials[0].Revoked, reloaded.credentials[1].Revoked})
+}
+
+// TestServiceWriteFailure checks that a request whose state cannot be kept in the store leaves the service as it was
+func TestServiceWriteFailure(t *testing.T) {
+	st := &failingStore{MemoryStore: store.NewMemoryStore()}
+	service, err := NewService(st, &memoryLedger{records: make(map[string][]byte)})
+	assert.NoError(t, err)
+	defer service.Close()
+	server := httptest.NewServer(service.Handler())
+	defer server.Close()
+
+	var issuer preDefine.IssuerKeyResponse
+	post(t, server, "/initIssuer", preDefine.InitRequest{Attributions: []string{"Attr1", "Attr2"}}, &issuer)
+	assert.Equal(t, "200", issuer.Code, issuer.Msg)
+	w, err := wallet.NewWallet([]string{"Attr1", "Attr2", revocationHandleAttribute}, idemixplus.GetRand(32))
+	assert.NoError(t, err)
+
+	atomic.StoreInt32(&st.fail, 1)
+	var user preDefine.UserKeyResponse
+	post(t, server, "/initUser", preDefine.InitUserRequest{User: "user", Trace: encode(t, w.Trace)}, &user)
+	assert.Equal(t, "400", user.Code)
+	var rotated preDefine.IssuerKeyResponse
+	post(t, server, "/rotateIssuer", nil, &rotated)
+	assert.Equal(t, "400", rotated.Code)
+	assert.Equal(t, []string{issuer.KeyId}, service.keyring.IDs())
+
+	// the user registers and the key rotates once the store takes writes again
+	atomic.StoreInt32(&st.fail, 0)
+	post(t, server, "/initUser", preDefine.InitUserRequest{User: "user", Trace: encode(t, w.Trace)}, &user)
+	assert.Equal(t, "200", user.Code, user.Msg)
+	post(t, server, "/rotateIssuer", nil, &rotated)
+	assert.Equal(t, "200", rotated.Code, rotated.Msg)
+	reloaded, err := NewService(st, &memoryLedger{records: make(map[string][]byte)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{issuer.KeyId, rotated.KeyId}, reloaded.keyring.IDs())
+	assert.Equal(t, 1, reloaded.traces.Len())
+}
+
+func TestServiceThreshold(t *testing.T) {
+	st := store.NewMemoryStore()
+	ledger := &memoryLedger{records: make(map[string][]byte)}
+	service, err := NewService(st, ledger)
+	assert.NoError(t, err)
+	defer service.Close()
+	server := httptest.NewServer(service.Handler())
+	defer server.Close()
+
+	var issuer preDefine.IssuerKeyResponse
+	for _, invalid := range []preDefine.InitRequest{{Threshold: 0, Issuers: 3}, {Threshold: 4, Issuers: 3}, {Threshold: 1, Issuers: -1}} {
+		post(t, server, "/initIssuer", invalid, &issuer)
+		assert.Equal(t, "400", issuer.Code, "threshold %d of %d issuers is rejected", invalid.Threshold, invalid.Issuers)
+	}
+	post(t, server, "/initIssuer", preDefine.InitRequest{Attributions: []string{"Attr1", "Attr2"}, Threshold: 2, Issuers: 3}, &issuer)
+	assert.Equal(t, "200", issuer.Code, issuer.Msg)
+	assert.Empty(t, issuer.Pri, "no one holds the issuer secret")
+	assert.Len(t, issuer.Shares, 3)
+
+	w, pub, request := enrollThreshold(t, server, "user", issuer.Tpk)
+	if t.Failed() {
+		return
+	}
+
+	// a request is accepted once and for the validity window handed out with its nonce
+	var cred preDefine.CreateCredentialResponse
+	post(t, server, "/createCredential", request, &cred)
+	assert.Equal(t, "400", cred.Code, "a credential request is not replayed")
+	tpk := &idemixplus.ThresholdIssuerPublicKey{}
+	raw, _ := base64.StdEncoding.DecodeString(issuer.Tpk)
+	assert.NoError(t, proto.Unmarshal(raw, tpk))
+	var nonce preDefine.IssuerNonceResponse
+	post(t, server, "/issuerNonce", nil, &nonce)
+	issuerNonce, _ := base64.StdEncoding.DecodeString(nonce.Nonce)
+	var attrs []*FP256BN.BIG
+	for _, attr := range nonce.Attrs {
+		raw, _ = base64.StdEncoding.DecodeString(attr)
+		attrs = append(attrs, FP256BN.FromBytes(raw))
+	}
+	m, err := w.NewThresholdCredRequest(tpk, issuerNonce, attrs, nonce.NotBefore-1, nonce.NotAfter-1)
+	assert.NoError(t, err)
+	post(t, server, "/createCredential", preDefine.CreateCredentialRequest{User: "user", Cr: encode(t, m), NotBefore: nonce.NotBefore}, &cred)
+	assert.Equal(t, "400", cred.Code, "a request for another validity window is rejected")
+	past := nonce.NotBefore - int64(issuerNonceTTL/time.Second) - 1
+	m, err = w.NewThresholdCredRequest(tpk, issuerNonce, attrs, past, past+int64(credentialLifetime/time.Second))
+	assert.NoError(t, err)
+	post(t, server, "/createCredential", preDefine.CreateCredentialRequest{User: "user", Cr: encode(t, m), NotBefore: past}, &cred)
+	assert.Equal(t, "400", cred.Code, "a validity window is not moved back")
+	m, err = w.NewThresholdCredRequest(tpk, issuerNonce, attrs, nonce.NotBefore, nonce.NotAfter)
+	assert.NoError(t, err)
+	post(t, server, "/createCredential", preDefine.CreateCredentialRequest{User: "user", Cr: encode(t, m), NotBefore: nonce.NotBefore}, &cred)
+	assert.Equal(t, "200", cred.Code, "a rejected request leaves the nonce unused")
+
+	// the key rotates to a threshold issuer key of as many issuers
+	var rotated preDefine.IssuerKeyResponse
+	post(t, server, "/rotateIssuer", nil, &rotated)
+	assert.Equal(t, "200", rotated.Code, rotated.Msg)
+	assert.Empty(t, rotated.Pri)
+	assert.Len(t, rotated.Shares, 3)
+	late, latePub, _ := enrollThreshold(t, server, "late", rotated.Tpk)
+	if t.Failed() {
+		return
+	}
+
+	// the key shares are kept in the store
+	reloaded, err := NewService(st, ledger)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, reloaded.keyShares, 2)
+	assert.Nil(t, reloaded.issuerKey.GetIsk())
+	server.Close()
+	server = httptest.NewServer(reloaded.Handler())
+	defer server.Close()
+	signAndTrace(t, server, w, pub, "threshold")
+	signAndTrace(t, server, late, latePub, "under the new key")
+}
@@ -0,0 +1,326 @@
+package httpHandler
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+	"traceGo/idemixplus"
+	"traceGo/store"
+)
+
+// A Service keeps its state in a store.Store, so that it survives a restart of the server:
+// NewService loads the issuer keys, the registered users with their traces and the issued credentials from it.
+// The store holds the secret issuer keys, its file must be kept as safe as them. The state is kept in the buckets
+//   state           - the key ID of the current issuer key under currentIssuerKey, the revocation key
+//                     under revocationKey and the revocation epoch under epoch
+//   issuerKeys      - the issuer keys in the order they were made, by sequence number, a threshold issuer key
+//                     by its issuer public key
+//   issuerKeyShares - the key shares of every threshold issuer key, by the sequence number of the key
+//   users           - the UserInfo of every user by its name
+//   registrations   - the registrations of the users in the order they registered, by sequence number
+//   credentials     - a CredentialRecord of every issued credential, by sequence number
+// The values in memory are derived from them: the attributes are the ones of the current issuer key.
+// A store without revocation key gets a new one when it is loaded.
+
+const (
+	bucketState         = "state"
+	bucketIssuerKeys    = "issuerKeys"
+	bucketKeyShares     = "issuerKeyShares"
+	bucketUsers         = "users"
+	bucketRegistrations = "registrations"
+	bucketCredentials   = "credentials"
+
+	currentIssuerKey = "currentIssuerKey"
+	revocationKey    = "revocationKey"
+	revocationEpoch  = "epoch"
+)
+
+// CredentialRecord records a credential the issuer issued, RevocationHandle is the encoded revocation handle
+// attribute of the credential, which the CRI leaves out once the credential is revoked
+type CredentialRecord struct {
+	User             string `json:"user"`
+	KeyId            string `json:"keyId"`
+	NotBefore        int64  `json:"notBefore"`
+	NotAfter         int64  `json:"notAfter"`
+	Cred             string `json:"cred"`
+	RevocationHandle string `json:"revocationHandle"`
+	Revoked          bool   `json:"revoked"`
+}
+
+// registration records a user registering with the trace of its key
+type registration struct {
+	User  string `json:"user"`
+	Trace string `json:"trace"`
+}
+
+// seqKey is the key of the entry with sequence number n, keys in order are entries in order
+func seqKey(n int) string {
+	return fmt.Sprintf("%016d", n)
+}
+
+// load sets the state of the service to the one kept in st, the service keeps its state in st from now on
+func (s *Service) load(st store.Store) error {
+	current, err := st.Get(bucketState, currentIssuerKey)
+	if err != nil && !store.IsNotFound(err) {
+		return err
+	}
+	keyring := idemixplus.NewIssuerKeyring()
+	keys := make(map[string]*idemixplus.IssuerKey)
+	keyShares := make(map[string][]*idemixplus.IssuerKeyShare)
+	sharesBySeq := make(map[string][]byte)
+	err = st.ForEach(bucketKeyShares, func(seq string, value []byte) error {
+		sharesBySeq[seq] = value
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to load issuer key shares")
+	}
+	err = st.ForEach(bucketIssuerKeys, func(seq string, value []byte) error {
+		key, err := idemixplus.IssuerKeyFromBytes(value)
+		if sharesBytes, threshold := sharesBySeq[seq]; threshold {
+			// a threshold issuer key is kept as its issuer public key and the key shares
+			var ipk *idemixplus.IssuerPublicKey
+			ipk, err = idemixplus.IssuerPublicKeyFromBytes(value)
+			if err == nil {
+				keyShares[ipk.GetKeyId()], err = decodeKeyShares(sharesBytes, ipk)
+			}
+			key = &idemixplus.IssuerKey{Ipk: ipk}
+		}
+		if err != nil {
+			return err
+		}
+		if names := key.Ipk.GetAttributeNames(); len(names) == 0 || names[len(names)-1] != revocationHandleAttribute {
+			return errors.Errorf("issuer key %s has no revocation handle attribute", key.Ipk.GetKeyId())
+		}
+		if key.Ipk.GetKeyId() == string(current) {
+			err = keyring.Rotate(key.Ipk)
+		} else {
+			err = keyring.Add(key.Ipk)
+		}
+		keys[key.Ipk.GetKeyId()] = key
+		return err
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed to load issuer keys")
+	}
+	key := keys[string(current)]
+	if current != nil && key == nil {
+		return errors.Errorf("failed to load issuer keys: current issuer key %s is missing", current)
+	}
+
+	users := make(map[string]UserInfo)
+	err = st.ForEach(bucketUsers, func(name string, value []byte) error {
+		var info UserInfo
+		if err := json.Unmarshal(value, &info); err != nil {
+			return err
+		}
+		users[name] = info
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to load users")
+	}
+	index := idemixplus.NewTraceIndex()
+	var registrations []UserTraceInfo
+	err = st.ForEach(bucketRegistrations, func(_ string, value []byte) error {
+		var r registration
+		if err := json.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		trace := &idemixplus.Trace{}
+		raw, err := base64.StdEncoding.DecodeString(r.Trace)
+		if err == nil {
+			err = proto.Unmarshal(raw, trace)
+		}
+		if err == nil {
+			err = index.Add(trace)
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "trace of user %s invalid", r.User)
+		}
+		pubKeyBytes, err := proto.Marshal(trace.Upk)
+		if err != nil {
+			return err
+		}
+		registrations = append(registrations, UserTraceInfo{
+			User:         r.User,
+			Pub:          base64.StdEncoding.EncodeToString(pubKeyBytes),
+			Attributions: trace.GetUpk().GetAttributeNames(),
+		})
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed to load registrations")
+	}
+	var credentials []CredentialRecord
+	err = st.ForEach(bucketCredentials, func(_ string, value []byte) error {
+		var record CredentialRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		credentials = append(credentials, record)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to load credentials")
+	}
+	revKey, err := loadRevocationKey(st)
+	if err != nil {
+		return err
+	}
+	revEpoch := 0
+	raw, err := st.Get(bucketState, revocationEpoch)
+	if err == nil {
+		revEpoch, err = strconv.Atoi(string(raw))
+	}
+	if err != nil && !store.IsNotFound(err) {
+		return errors.Wrap(err, "failed to load revocation epoch")
+	}
+
+	var pub string
+	if key != nil {
+		ipkBytes, err := proto.Marshal(key.Ipk)
+		if err != nil {
+			return err
+		}
+		pub = base64.StdEncoding.EncodeToString(ipkBytes)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = st
+	s.keyring, s.issuerKeys, s.keyShares = keyring, keys, keyShares
+	s.users, s.traces, s.traceInfos, s.credentials = users, index, registrations, credentials
+	s.revocationKey, s.epoch = revKey, revEpoch
+	if key != nil {
+		s.setIssuerKey(key, pub)
+	}
+	return nil
+}
+
+// saveIssuerKey keeps a new issuer key in the store as the current one, together with its key shares
+// if it is a threshold issuer key. The caller holds mu.
+func (s *Service) saveIssuerKey(key *idemixplus.IssuerKey, shares []*idemixplus.IssuerKeyShare) error {
+	seq := seqKey(len(s.issuerKeys))
+	entries := []store.Entry{{Bucket: bucketState, Key: currentIssuerKey, Value: []byte(key.Ipk.GetKeyId())}}
+	if shares == nil {
+		keyBytes, err := key.Bytes()
+		if err != nil {
+			return err
+		}
+		return s.store.Write(append(entries, store.Entry{Bucket: bucketIssuerKeys, Key: seq, Value: keyBytes})...)
+	}
+	ipkBytes, err := key.Ipk.Bytes()
+	if err != nil {
+		return err
+	}
+	sharesBytes, err := encodeKeyShares(shares)
+	if err != nil {
+		return err
+	}
+	return s.store.Write(append(entries,
+		store.Entry{Bucket: bucketIssuerKeys, Key: seq, Value: ipkBytes},
+		store.Entry{Bucket: bucketKeyShares, Key: seq, Value: sharesBytes})...)
+}
+
+// saveUser keeps the UserInfo of a user in the store, together with its registration if it registers.
+// The caller holds mu.
+func (s *Service) saveUser(name string, info UserInfo, registers bool) error {
+	infoBytes, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	entries := []store.Entry{{Bucket: bucketUsers, Key: name, Value: infoBytes}}
+	if registers {
+		registrationBytes, err := json.Marshal(registration{User: name, Trace: info.Trace})
+		if err != nil {
+			return err
+		}
+		entries = append(entries, store.Entry{Bucket: bucketRegistrations, Key: seqKey(len(s.traceInfos)), Value: registrationBytes})
+	}
+	return s.store.Write(entries...)
+}
+
+// saveCredential keeps the record of an issued credential in the store, together with the UserInfo of its user.
+// The caller holds mu.
+func (s *Service) saveCredential(record CredentialRecord, info UserInfo) error {
+	infoBytes, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	err = s.store.Write(
+		store.Entry{Bucket: bucketUsers, Key: record.User, Value: infoBytes},
+		store.Entry{Bucket: bucketCredentials, Key: seqKey(len(s.credentials)), Value: recordBytes})
+	if err != nil {
+		return err
+	}
+	s.credentials = append(s.credentials, record)
+	return nil
+}
+
+// saveRevocation keeps the records of the credentials revoked, by sequence number, in the store
+// together with the epoch that starts with their revocation. The caller holds mu.
+func (s *Service) saveRevocation(revoked []int, newEpoch int) error {
+	entries := []store.Entry{{Bucket: bucketState, Key: revocationEpoch, Value: []byte(strconv.Itoa(newEpoch))}}
+	for _, n := range revoked {
+		record := s.credentials[n]
+		record.Revoked = true
+		recordBytes, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, store.Entry{Bucket: bucketCredentials, Key: seqKey(n), Value: recordBytes})
+	}
+	return s.store.Write(entries...)
+}
+
+// loadRevocationKey reads the long term revocation key from st, or generates it and keeps it in st if there is none
+func loadRevocationKey(st store.Store) (*ecdsa.PrivateKey, error) {
+	raw, err := st.Get(bucketState, revocationKey)
+	if err == nil {
+		key, err := x509.ParseECPrivateKey(raw)
+		return key, errors.Wrap(err, "failed to load revocation key")
+	}
+	if !store.IsNotFound(err) {
+		return nil, err
+	}
+	key, err := idemixplus.GenerateLongTermRevocationKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate revocation key")
+	}
+	raw, err = x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal revocation key")
+	}
+	if err = st.Write(store.Entry{Bucket: bucketState, Key: revocationKey, Value: raw}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// unrevokedHandles returns the revocation handles of the credentials that are not revoked, the caller holds mu
+func (s *Service) unrevokedHandles() ([]*FP256BN.BIG, error) {
+	var handles []*FP256BN.BIG
+	for _, record := range s.credentials {
+		if record.Revoked {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(record.RevocationHandle)
+		if err != nil {
+			return nil, errors.Wrapf(err, "revocation handle of a credential of user %s invalid", record.User)
+		}
+		handles = append(handles, FP256BN.FromBytes(raw))
+	}
+	return handles, nil
+}
@@ -0,0 +1,173 @@
+package httpHandler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+	"traceGo/idemixplus"
+)
+
+// In threshold mode the issuer key is shared among n issuers, threshold of which issue a credential or
+// trace a signature, so that no single issuer holds the issuer secret. The service runs the distributed key
+// generation of all issuers and keeps every key share, as it plays all issuers here. In a deployment each issuer
+// keeps its own share, the service hands the shares out in the IssuerKeyResponse for that.
+// The user aggregates the partial credentials of the issuers to its credential, which it can only do for
+// the values it requested the credential for, so IssuerNonce hands out the values together with the nonce.
+// The revocation handle of the credential is derived from the nonce, which is used once.
+
+// thresholdHandleLabel is the label the revocation handle of a threshold credential is derived with
+const thresholdHandleLabel = "thresholdRevocationHandle"
+
+// newThresholdIssuerKey runs the distributed key generation of n issuers, threshold of which issue together,
+// for the given attribute names and returns the key share of every issuer
+func newThresholdIssuerKey(AttributeNames []string, threshold int, n int, rng *amcl.RAND) ([]*idemixplus.IssuerKeyShare, error) {
+	deals := make([]*idemixplus.DKGDeal, n)
+	for j := range deals {
+		deal, err := idemixplus.NewDKGDeal(j+1, threshold, n, AttributeNames, rng)
+		if err != nil {
+			return nil, err
+		}
+		deals[j] = deal
+	}
+	shares := make([]*idemixplus.IssuerKeyShare, n)
+	partials := make([]*idemixplus.PartialIssuerPublicKey, n)
+	for j := range shares {
+		share, err := idemixplus.CompleteDKG(j+1, deals)
+		if err != nil {
+			return nil, err
+		}
+		shares[j], partials[j] = share, share.GetPartial()
+	}
+	// every issuer would combine the same partial keys and proofs to the same issuer public key,
+	// the service plays all of them and combines once
+	tpk := shares[0].GetTpk()
+	if err := tpk.CombinePartialKeys(partials); err != nil {
+		return nil, err
+	}
+	proofs := make([]*idemixplus.PartialKeyProof, n)
+	for j, share := range shares {
+		share.Tpk = tpk
+		proof, err := share.ProveKey()
+		if err != nil {
+			return nil, err
+		}
+		proofs[j] = proof
+	}
+	if err := tpk.Combine(proofs); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// thresholdHandle derives the revocation handle of the threshold credential requested with IssuerNonce
+func thresholdHandle(IssuerNonce []byte) *FP256BN.BIG {
+	return idemixplus.HashModOrder(append([]byte(thresholdHandleLabel), IssuerNonce...))
+}
+
+// encodeKeyShares encodes the key shares of a threshold issuer key to keep them in the store
+func encodeKeyShares(shares []*idemixplus.IssuerKeyShare) ([]byte, error) {
+	encoded := make([]string, len(shares))
+	for j, share := range shares {
+		shareBytes, err := proto.Marshal(share)
+		if err != nil {
+			return nil, err
+		}
+		encoded[j] = base64.StdEncoding.EncodeToString(shareBytes)
+	}
+	return json.Marshal(encoded)
+}
+
+// decodeKeyShares decodes the key shares of the threshold issuer key ipk and checks that they belong to it
+func decodeKeyShares(raw []byte, ipk *idemixplus.IssuerPublicKey) ([]*idemixplus.IssuerKeyShare, error) {
+	var encoded []string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, err
+	}
+	shares := make([]*idemixplus.IssuerKeyShare, len(encoded))
+	for j, e := range encoded {
+		share := &idemixplus.IssuerKeyShare{}
+		shareBytes, err := base64.StdEncoding.DecodeString(e)
+		if err == nil {
+			err = proto.Unmarshal(shareBytes, share)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "key share %d invalid", j+1)
+		}
+		if share.GetIndex() != int64(j+1) || share.GetIsk() == nil || !bytes.Equal(share.GetTpk().GetIpk().GetHash(), ipk.GetHash()) {
+			return nil, errors.Errorf("key share %d does not belong to issuer key %s", j+1, ipk.GetKeyId())
+		}
+		shares[j] = share
+	}
+	if len(shares) == 0 || len(shares) != int(shares[0].GetTpk().GetIssuers()) {
+		return nil, errors.Errorf("issuer key %s misses key shares", ipk.GetKeyId())
+	}
+	return shares, nil
+}
+
+// encodeThresholdKey encodes the threshold issuer public key and the key shares of a threshold issuer key
+// for the IssuerKeyResponse
+func encodeThresholdKey(shares []*idemixplus.IssuerKeyShare) (string, []string) {
+	tpkBytes, _ := proto.Marshal(shares[0].GetTpk())
+	encoded := make([]string, len(shares))
+	for j, share := range shares {
+		shareBytes, _ := proto.Marshal(share)
+		encoded[j] = base64.StdEncoding.EncodeToString(shareBytes)
+	}
+	return base64.StdEncoding.EncodeToString(tpkBytes), encoded
+}
+
+// issueThreshold has every issuer issue its partial credential on the threshold credential request m of the user
+// with public key upk. The request is bound to the values IssuerNonce handed out with its nonce: the attribute
+// values with the revocation handle derived from the nonce, and a validity window from notBefore, which IssuerNonce
+// set less than issuerNonceTTL ago. It accepts the nonce once and returns the partial credentials, the revocation
+// handle and the end of the validity window.
+func (s *Service) issueThreshold(shares []*idemixplus.IssuerKeyShare, m *idemixplus.ThresholdCredRequest, upk *idemixplus.UserPublicKey,
+	attrs []*FP256BN.BIG, rhIndex int, notBefore int64) ([]*idemixplus.PartialCredential, *FP256BN.BIG, int64, error) {
+	now := time.Now()
+	if notBefore > now.Unix() || notBefore < now.Add(-issuerNonceTTL).Unix() {
+		return nil, nil, 0, errors.Errorf("validity window of the credential request is not the one handed out with the nonce")
+	}
+	notAfter := time.Unix(notBefore, 0).Add(credentialLifetime).Unix()
+	rh := thresholdHandle(m.GetIssuerNonce())
+	values := append([]*FP256BN.BIG{}, attrs...)
+	values[rhIndex] = rh
+
+	partials := make([]*idemixplus.PartialCredential, len(shares))
+	for j, share := range shares {
+		partial, err := idemixplus.NewPartialCredential(share, m, upk, values, notBefore, notAfter)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		partials[j] = partial
+	}
+	if err := s.nonces.Consume(m.GetIssuerNonce()); err != nil {
+		return nil, nil, 0, err
+	}
+	return partials, rh, notAfter, nil
+}
+
+// traceThreshold has threshold issuers open the tracing tag of a signature with their key shares,
+// it returns the user public key of the signer and the opening shares that prove it
+func traceThreshold(shares []*idemixplus.IssuerKeyShare, ipk *idemixplus.IssuerPublicKey, index *idemixplus.TraceIndex,
+	sig *idemixplus.NymSignature, msg []byte, opts *idemixplus.VerifyOpts, rng *amcl.RAND) (*idemixplus.UserPublicKey, *idemixplus.ThresholdOpening, error) {
+	tpk := shares[0].GetTpk()
+	opening := &idemixplus.ThresholdOpening{}
+	for _, share := range shares[:tpk.GetThreshold()] {
+		openingShare, err := idemixplus.NewOpeningShare(share.ArbitratorKey(), sig, rng)
+		if err != nil {
+			return nil, nil, err
+		}
+		opening.Shares = append(opening.Shares, openingShare)
+	}
+	upk, err := idemixplus.CombineOpeningShares(tpk.ArbitrationPublicKey(), ipk, index, sig, msg, opts, opening.Shares)
+	if err != nil {
+		return nil, nil, err
+	}
+	return upk, opening, nil
+}
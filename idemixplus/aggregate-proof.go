@@ -0,0 +1,359 @@
+package idemixplus
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// In formats 2 and 3 of a NymSignature one proof of knowledge covers all hidden attributes and the validity window.
+// The randomized credential is blinded as Sigma2 = B^v \cdot Sigma1^{t}, so that the hidden values m_j
+// (the hidden attributes followed by NotBefore and NotAfter) satisfy
+// E = e(Sigma2, g_2) / (e(Sigma1, BarX \prod_{disclosed} BarAttr_i^{attr_i}) e(Sigma3, BarY)) = e(Sigma1, g_2^{t} \prod_j BarM_j^{m_j})
+// where BarM_j is BarAttr_i or BarValidity_v. The prover sends the responses s_t, s_j of a Schnorr proof
+// of this equation in GT, whose commitment T = e(Sigma1, g_2^{r_t} \prod_j BarM_j^{r_j}) is hashed into the
+// challenge of the signature, and the verifier recomputes T = e(Sigma1, g_2^{s_t} \prod_j BarM_j^{s_j}) E^{-c}
+// with three Miller loops and one final exponentiation, whatever the number of hidden values.
+// Only the hidden values that range, set or equality proofs refer to are committed as
+// Com_j = Sigma1^{m_j} \cdot g_1^{rho_j}, the proof of their opening shares the response s_j.
+// Formats 1 and 2 derive their challenges from legacy proof data, formats 3 and 4 have the proofs of
+// formats 2 and 1 and derive all their challenges from transcripts, see transcript.go.
+// Signatures are only made in formats 3 and 4, formats 1 and 2 are verified when the verifier asks for it.
+
+// Format versions of a NymSignature
+const (
+	// NymSignatureV1 proves every hidden attribute on its own, signatures without version are in format 1
+	NymSignatureV1 uint32 = 1
+	// NymSignatureV2 proves all hidden attributes with one proof
+	NymSignatureV2 uint32 = 2
+	// NymSignatureV3 is format 2 with the challenges of all its proofs derived from transcripts
+	NymSignatureV3 uint32 = 3
+	// NymSignatureV4 is format 1 with the challenges of all its proofs derived from transcripts
+	NymSignatureV4 uint32 = 4
+)
+
+// aggregateProofLabel is the label used in ZKP to identify the proof of the hidden values of format 2
+const aggregateProofLabel = "nymSignatureV2"
+
+// gtBytes is the length of the encoding of an element of GT
+var gtBytes = 12 * FieldBytes
+
+// formatVersion returns the format version of a NymSignature
+func (nym *NymSignature) formatVersion() uint32 {
+	if nym.GetVersion() == 0 {
+		return NymSignatureV1
+	}
+	return nym.GetVersion()
+}
+
+// knownFormat reports whether version is a format of NymSignature that is verified
+func knownFormat(version uint32) bool {
+	return version >= NymSignatureV1 && version <= NymSignatureV4
+}
+
+// legacyFormat reports whether version is a format of NymSignature whose challenges are derived from legacy proof data
+func legacyFormat(version uint32) bool {
+	return version == NymSignatureV1 || version == NymSignatureV2
+}
+
+// aggregated reports whether a NymSignature proves all hidden attributes with one proof, as formats 2 and 3 do
+func (nym *NymSignature) aggregated() bool {
+	return nym.formatVersion() == NymSignatureV2 || nym.formatVersion() == NymSignatureV3
+}
+
+// newTranscript starts the transcript of a proof of the NymSignature, which is bound to the format of the signature,
+// signatures in formats 1 and 2 derive their challenges from legacy proof data
+func (nym *NymSignature) newTranscript(label string) *transcript {
+	if legacyFormat(nym.formatVersion()) {
+		return newLegacyTranscript()
+	}
+	t := newTranscript(label)
+	t.appendInt64("format", int64(nym.GetVersion()))
+	return t
+}
+
+// hiddenValueIndices returns the indices of the hidden attributes followed by those of the validity values,
+// which follow the numAttrs attributes of the credential
+func hiddenValueIndices(disclosure []byte, numAttrs int) []int {
+	hidden := hiddenIndices(disclosure)
+	for v := 0; v < numValidityKeys; v++ {
+		hidden = append(hidden, numAttrs+v)
+	}
+	return hidden
+}
+
+// aggregateProver holds the randomness of the proof of the hidden values of formats 2 and 3
+// between computing its contribution to the challenge and its responses
+type aggregateProver struct {
+	values    []*FP256BN.BIG // the hidden values, in the order of hiddenValueIndices
+	t, rT     *FP256BN.BIG
+	r         []*FP256BN.BIG
+	committed []int // positions in values of the committed values
+	rhos      []*FP256BN.BIG
+	rRhos     []*FP256BN.BIG
+	coms      []*FP256BN.ECP
+	contrib   *aggregateContribution
+}
+
+// newAggregateProver blinds Sigma2 and computes the contribution of the proof of the hidden values to the
+// challenge of the signature, committing to the hidden attributes in commit and to the validity window
+func newAggregateProver(nymSign *NymSignature, cred *Credential, ipk *IssuerPublicKey, Sigma1 *FP256BN.ECP, Sigma2 *FP256BN.ECP, commit []int, rhIndex int, revocationAlg RevocationAlgorithm, prover nonRevokedProver, cri *CredentialRevocationInformation, rng *amcl.RAND) (*aggregateProver, error) {
+	NumAttrs := len(cred.Attrs)
+	hidden := hiddenValueIndices(nymSign.Disclosure, NumAttrs)
+	validity := validityValues(cred.GetNotBefore(), cred.GetNotAfter())
+
+	p := &aggregateProver{t: RandModOrder(rng), rT: RandModOrder(rng)}
+	Sigma2.Add(Sigma1.Mul(p.t))
+
+	// T = e(Sigma1, g_2^{r_t} \prod_j BarM_j^{r_j})
+	Q := genG2().Mul(p.rT)
+	var attributes []int
+	var tComs []*FP256BN.ECP
+	var nonRevokedPoints []*FP256BN.ECP
+	for j, index := range hidden {
+		var value *FP256BN.BIG
+		var BarM *FP256BN.ECP2
+		if index < NumAttrs {
+			value = FP256BN.FromBytes(cred.Attrs[index])
+			BarM = Ecp2FromProto(ipk.BarAttrs[index])
+		} else {
+			value = validity[index-NumAttrs]
+			BarM = Ecp2FromProto(ipk.BarValidity[index-NumAttrs])
+		}
+		r := RandModOrder(rng)
+		p.values = append(p.values, value)
+		p.r = append(p.r, r)
+		Q.Add(BarM.Mul(r))
+
+		if index >= NumAttrs || isIn(commit, index) {
+			rho := RandModOrder(rng)
+			rRho := RandModOrder(rng)
+			Com := Sigma1.Mul2(value, genG1(), rho)
+			p.committed = append(p.committed, j)
+			p.rhos = append(p.rhos, rho)
+			p.rRhos = append(p.rRhos, rRho)
+			p.coms = append(p.coms, Com)
+			attributes = append(attributes, index)
+			tComs = append(tComs, Sigma1.Mul2(r, genG1(), rRho))
+		}
+
+		// the revocation handle shares its randomness with the non-revocation proof
+		if revocationAlg != ALG_NO_REVOCATION && index == rhIndex {
+			var err error
+			nonRevokedPoints, err = prover.getFSContribution(value, r, cri, rng)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to compute non-revoked proof")
+			}
+		}
+	}
+	Q.Affine()
+	T := FP256BN.Fexp(FP256BN.Ate(Q, Sigma1))
+
+	p.contrib = &aggregateContribution{T: T, attributes: attributes, coms: p.coms, tComs: tComs, nonRevoked: nonRevokedPoints}
+	return p, nil
+}
+
+// respond adds the responses to the challenge c and the commitments to the signature
+func (p *aggregateProver) respond(nymSign *NymSignature, c *FP256BN.BIG) {
+	hidden := hiddenValueIndices(nymSign.Disclosure, len(nymSign.Disclosure))
+	for j, value := range p.values {
+		nymSign.ProofSHidden = append(nymSign.ProofSHidden, BigToBytes(Modadd(p.r[j], FP256BN.Modmul(c, value, GroupOrder), GroupOrder)))
+	}
+	nymSign.ProofSBlind = BigToBytes(Modadd(p.rT, FP256BN.Modmul(c, p.t, GroupOrder), GroupOrder))
+	for k, j := range p.committed {
+		com := new(AttributeCommitment)
+		com.Attribute = int64(hidden[j])
+		com.Com = EcpToProto(p.coms[k])
+		com.ProofSRand = BigToBytes(Modadd(p.rRhos[k], FP256BN.Modmul(c, p.rhos[k], GroupOrder), GroupOrder))
+		nymSign.Commitments = append(nymSign.Commitments, com)
+	}
+}
+
+// newCommittedProofs proves the predicates over the committed hidden attributes and that the validity window
+// contains validAt, and returns the randomness rho_i of the commitment to every committed attribute by its index
+func (p *aggregateProver) newCommittedProofs(nymSign *NymSignature, Sigma1 *FP256BN.ECP, validAt int64, predicates []*RangePredicate, sets []*SetPredicate, msg []byte, rng *amcl.RAND) (map[int]*FP256BN.BIG, error) {
+	NumAttrs := len(nymSign.Disclosure)
+	hidden := hiddenValueIndices(nymSign.Disclosure, NumAttrs)
+	validity := validityPredicates(NumAttrs, validAt)
+	rhos := make(map[int]*FP256BN.BIG)
+	for k, j := range p.committed {
+		index := hidden[j]
+		if index >= NumAttrs {
+			proof, err := newRangeProof(nymSign, validity[index-NumAttrs], p.values[j], p.rhos[k], Sigma1, p.coms[k], msg, rng)
+			if err != nil {
+				return nil, err
+			}
+			nymSign.ValidityProofs = append(nymSign.ValidityProofs, proof)
+			continue
+		}
+		if err := newPredicateProofs(nymSign, index, predicates, sets, p.values[j], p.rhos[k], Sigma1, p.coms[k], msg, rng); err != nil {
+			return nil, err
+		}
+		rhos[index] = p.rhos[k]
+	}
+	return rhos, nil
+}
+
+// aggregateContribution is the contribution of the proof of the hidden values of formats 2 and 3 to the challenge of a NymSignature
+type aggregateContribution struct {
+	T          *FP256BN.FP12
+	attributes []int
+	coms       []*FP256BN.ECP
+	tComs      []*FP256BN.ECP
+	nonRevoked []*FP256BN.ECP // the points of the non-revocation proof
+}
+
+// appendTo appends the contribution to the transcript of the signature
+func (contrib *aggregateContribution) appendTo(t *transcript) {
+	TBytes := make([]byte, gtBytes)
+	contrib.T.ToBytes(TBytes)
+
+	t.appendLegacyLabel(aggregateProofLabel)
+	t.appendBytes("T", TBytes)
+	for k, Com := range contrib.coms {
+		t.appendInt64("attribute", int64(contrib.attributes[k]))
+		t.appendG1("Com", Com)
+		t.appendG1("tCom", contrib.tComs[k])
+	}
+	for _, P := range contrib.nonRevoked {
+		t.appendG1("nonRevocation", P)
+	}
+}
+
+// verifyAggregateProof recomputes the contribution of the proof of the hidden values of a NymSignature in format 2 or 3
+// to its challenge ProofC and returns it with the commitments to the hidden values by their index.
+// disclosed holds the value of every disclosed attribute by its index and nil for hidden ones.
+func verifyAggregateProof(nym *NymSignature, key *preparedKey, workers int, Sigma1, Sigma2, Sigma3 *FP256BN.ECP, ProofC *FP256BN.BIG, disclosed []*FP256BN.BIG, rhIndex int, revocationAlg RevocationAlgorithm, verifier nonRevocationVerifier, epochPK *FP256BN.ECP2) (*aggregateContribution, map[int]*FP256BN.ECP, error) {
+	NumAttrs := len(key.BarAttrs)
+	hidden := hiddenValueIndices(nym.GetDisclosure(), NumAttrs)
+	if len(nym.GetHides()) != 0 || len(nym.GetValidityHides()) != 0 || len(nym.GetProofSHidden()) != len(hidden) {
+		return nil, nil, verificationErrorf(ErrKindInvalid, "NymSignature is not fit with the NymSignature format")
+	}
+	s := make([]*FP256BN.BIG, len(hidden))
+	var err error
+	for j, b := range nym.ProofSHidden {
+		s[j], err = BigFromBytesChecked(b)
+		if err != nil {
+			return nil, nil, wrapVerificationError(ErrKindInvalid, err, "proof of the hidden attributes is malformed")
+		}
+	}
+	sT, err := BigFromBytesChecked(nym.GetProofSBlind())
+	if err != nil {
+		return nil, nil, wrapVerificationError(ErrKindInvalid, err, "proof of the hidden attributes is malformed")
+	}
+	negC := FP256BN.Modneg(ProofC, GroupOrder)
+
+	// Recompute the proofs of the openings of the commitments, which share s_j
+	coms := make(map[int]*FP256BN.ECP)
+	var attributes []int
+	var comList, tComs []*FP256BN.ECP
+	previous := -1
+	for _, com := range nym.GetCommitments() {
+		index := int(com.GetAttribute())
+		j := indexOf(hidden, index)
+		if j < 0 || index <= previous {
+			return nil, nil, verificationErrorf(ErrKindInvalid, "NymSignature commits to attribute %d that is not hidden", index)
+		}
+		previous = index
+		Com, err := EcpFromProtoChecked(com.GetCom())
+		if err != nil {
+			return nil, nil, wrapVerificationError(ErrKindInvalid, err, fmt.Sprintf("commitment to hidden attribute %d is malformed", index))
+		}
+		sRho, err := BigFromBytesChecked(com.GetProofSRand())
+		if err != nil {
+			return nil, nil, wrapVerificationError(ErrKindInvalid, err, fmt.Sprintf("commitment to hidden attribute %d is malformed", index))
+		}
+		t := Sigma1.Mul2(s[j], genG1(), sRho)
+		t.Add(Com.Mul(negC)) // t = Sigma1^{s_j} \cdot g_1^{s_rho} \cdot Com^{-c}
+		coms[index] = Com
+		attributes = append(attributes, index)
+		comList = append(comList, Com)
+		tComs = append(tComs, t)
+	}
+
+	// Q = g_2^{s_t} \prod_j BarM_j^{s_j} (BarX \prod_{disclosed} BarAttr_i^{attr_i})^{c}
+	terms := make([]*FP256BN.ECP2, len(hidden)+len(disclosed)+2)
+	_ = parallelFor(len(terms), workers, func(k int) error {
+		switch {
+		case k < len(hidden):
+			if hidden[k] < NumAttrs {
+				terms[k] = ecp2Copy(key.BarAttrs[hidden[k]]).Mul(s[k])
+			} else {
+				terms[k] = ecp2Copy(key.BarValidity[hidden[k]-NumAttrs]).Mul(s[k])
+			}
+		case k < len(hidden)+len(disclosed):
+			if value := disclosed[k-len(hidden)]; value != nil {
+				terms[k] = ecp2Copy(key.BarAttrs[k-len(hidden)]).Mul(FP256BN.Modmul(ProofC, value, GroupOrder))
+			}
+		case k == len(hidden)+len(disclosed):
+			terms[k] = genG2().Mul(sT)
+		default:
+			terms[k] = ecp2Copy(key.BarX).Mul(ProofC)
+		}
+		return nil
+	})
+	Q := FP256BN.NewECP2()
+	for _, term := range terms {
+		if term != nil {
+			Q.Add(term)
+		}
+	}
+	Q.Affine()
+
+	// T = e(Sigma1, Q) e(Sigma3^{c}, BarY) e(Sigma2^{-c}, g_2)
+	T := FP256BN.Fexp(multiPairing([]*FP256BN.ECP2{Q, key.BarY, genG2()}, []*FP256BN.ECP{Sigma1, Sigma3.Mul(ProofC), Sigma2.Mul(negC)}, workers))
+
+	var nonRevokedPoints []*FP256BN.ECP
+	if revocationAlg != ALG_NO_REVOCATION {
+		nonRevokedPoints, err = verifier.recomputeFSContribution(nym.GetNonRevocationProof(), ProofC, epochPK, s[indexOf(hidden, rhIndex)])
+		if err != nil {
+			return nil, nil, wrapVerificationError(ErrKindRevoked, err, "non-revocation proof is invalid")
+		}
+	}
+	return &aggregateContribution{T: T, attributes: attributes, coms: comList, tComs: tComs, nonRevoked: nonRevokedPoints}, coms, nil
+}
+
+// verifyCommittedProofs checks the predicate proofs over the committed hidden attributes and that the
+// validity window contains validAt on up to workers goroutines, after the challenge of the signature is checked
+func verifyCommittedProofs(nym *NymSignature, coms map[int]*FP256BN.ECP, workers int, Sigma1 *FP256BN.ECP, validAt int64, predicates []*RangePredicate, sets []*SetPredicate, msg []byte) error {
+	NumAttrs := len(nym.GetDisclosure())
+	for _, predicate := range predicates {
+		if coms[predicate.Attribute] == nil {
+			return verificationErrorf(ErrKindInvalid, "NymSignature has no commitment to attribute %d of a range predicate", predicate.Attribute)
+		}
+	}
+	for _, predicate := range sets {
+		if coms[predicate.Attribute] == nil {
+			return verificationErrorf(ErrKindInvalid, "NymSignature has no commitment to attribute %d of a set predicate", predicate.Attribute)
+		}
+	}
+	if len(nym.GetValidityProofs()) != numValidityKeys || coms[NumAttrs+validityNotBefore] == nil || coms[NumAttrs+validityNotAfter] == nil {
+		return verificationErrorf(ErrKindInvalid, "NymSignature carries no proof of its validity window")
+	}
+
+	indices := make([]int, 0, len(coms))
+	for index := range coms {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	return parallelFor(len(indices), workers, func(k int) error {
+		index := indices[k]
+		if index >= NumAttrs {
+			return verifyValidityRange(nym, index-NumAttrs, NumAttrs, Sigma1, coms[index], validAt, msg)
+		}
+		return verifyPredicateProofs(nym, index, predicates, sets, Sigma1, coms[index], msg)
+	})
+}
+
+// indexOf returns the position of value in arr, or -1 if it is not in arr
+func indexOf(arr []int, value int) int {
+	for k, v := range arr {
+		if v == value {
+			return k
+		}
+	}
+	return -1
+}
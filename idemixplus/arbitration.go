@@ -1,29 +1,263 @@
 package idemixplus
 
 import (
+	"sync"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
 	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
 	"github.com/pkg/errors"
 )
 
+// TraceIndex indexes the traces of the registered users by their user public key,
+// so that the user behind a NymSignature is found with a single lookup. It is safe for concurrent use,
+// users register while signatures are traced.
+type TraceIndex struct {
+	mu     sync.RWMutex
+	traces map[string]*Trace
+}
+
+// NewTraceIndex creates an empty TraceIndex
+func NewTraceIndex() *TraceIndex {
+	return &TraceIndex{traces: make(map[string]*Trace)}
+}
+
+// traceKey is the map key of a user public key g1^{usk}
+func traceKey(upk *FP256BN.ECP) string {
+	return string(EcpToBytes(upk))
+}
+
+// Add indexes the trace of a registered user
+func (index *TraceIndex) Add(trace *Trace) error {
+	if trace == nil || trace.GetUpk() == nil || trace.GetUpk().GetUPK() == nil {
+		return errors.Errorf("cannot index trace: received nil input")
+	}
+	key := traceKey(EcpFromProto(trace.GetUpk().GetUPK()))
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if _, exists := index.traces[key]; exists {
+		return errors.Errorf("trace of the user public key is already indexed")
+	}
+	index.traces[key] = trace
+	return nil
+}
+
+// AddTraces indexes every trace in traces
+func (index *TraceIndex) AddTraces(traces *Traces) error {
+	for _, trace := range traces.GetTraceList() {
+		if err := index.Add(trace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contains reports whether the trace of the user public key upk is indexed
+func (index *TraceIndex) Contains(upk *UserPublicKey) bool {
+	if upk.GetUPK() == nil {
+		return false
+	}
+	key := traceKey(EcpFromProto(upk.GetUPK()))
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	_, exists := index.traces[key]
+	return exists
+}
+
+// Check checks that the trace belongs to a valid user public key, i.e. that the public key verifies,
+// that T is its W = g_2^{usk} and that the user public key g_1^{usk} is made with the same secret, e(T, g_1) = e(g_2, upk).
+// A server checks the trace a user registers, as it never sees the secret key of the user.
+func (trace *Trace) Check() error {
+	if trace == nil || trace.GetT() == nil || trace.GetUpk() == nil {
+		return errors.Errorf("cannot check trace: received nil input")
+	}
+	if err := trace.GetUpk().Check(); err != nil {
+		return errors.WithMessage(err, "trace invalid")
+	}
+	T, err := Ecp2FromProtoChecked(trace.GetT())
+	if err != nil {
+		return errors.Wrap(err, "trace invalid")
+	}
+	if !T.Equals(Ecp2FromProto(trace.GetUpk().GetW())) {
+		return errors.Errorf("trace invalid: T is not the W of the user public key")
+	}
+	if !FP256BN.Fexp(FP256BN.Ate(T, genG1())).Equals(FP256BN.Fexp(FP256BN.Ate(genG2(), EcpFromProto(trace.GetUpk().GetUPK())))) {
+		return errors.Errorf("trace invalid: user public key is not made with the secret of T")
+	}
+	return nil
+}
+
+// Len returns the number of indexed traces
+func (index *TraceIndex) Len() int {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	return len(index.traces)
+}
+
 //Arbitration is arbitrating the anonymous credential by CA
-func Arbitration(traces *Traces, anonymity *NymSignature) (*UserPublicKey, error) {
-	if traces == nil || anonymity == nil {
-		return nil, errors.Errorf("Cannot Arbitration AnonymousCredential: received nil input")
+// The tracing tag of the signature is decrypted with the tracing secret of the issuer,
+// which gives the user public key g1^{usk} = C_2 \cdot C_1^{-z}, and looked up in the index.
+// The signature is verified on msg with opts first, as only the proof in a valid signature binds the tag to the signer:
+// anyone can encrypt the public key of another user and would have the issuer open it to that user.
+// Next to the user public key it returns a proof of the opening for the message msg of the signature,
+// which anyone can check with VerifyOpening.
+func Arbitration(key *IssuerKey, index *TraceIndex, anonymity *NymSignature, msg []byte, opts *VerifyOpts, rng *amcl.RAND) (*UserPublicKey, *OpeningProof, error) {
+	if key == nil || index == nil || anonymity == nil || rng == nil {
+		return nil, nil, errors.Errorf("Cannot Arbitration AnonymousCredential: received nil input")
+	}
+	if key.GetIsk().GetTracingSk() == nil || anonymity.GetTraceC1() == nil || anonymity.GetTraceC2() == nil {
+		return nil, nil, errors.Errorf("Cannot Arbitration AnonymousCredential: no tracing tag")
+	}
+	if err := anonymity.Ver(key.GetIpk(), msg, opts); err != nil {
+		return nil, nil, errors.WithMessage(err, "Cannot Arbitration AnonymousCredential")
+	}
+
+	z := FP256BN.FromBytes(key.GetIsk().GetTracingSk())
+	TraceC1, err := EcpFromProtoChecked(anonymity.GetTraceC1())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Cannot Arbitration AnonymousCredential: tracing tag is malformed")
+	}
+	TraceC2, err := EcpFromProtoChecked(anonymity.GetTraceC2())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Cannot Arbitration AnonymousCredential: tracing tag is malformed")
 	}
 
-	Eta := EcpFromProto(anonymity.GetEta())
-	Xi := EcpFromProto(anonymity.GetXi())
+	upk := TraceC1.Mul(FP256BN.Modneg(z, GroupOrder))
+	upk.Add(TraceC2)
 
-	for _, trace := range traces.TraceList {
-		t := Ecp2FromProto(trace.GetT())
-		left := FP256BN.Fexp(FP256BN.Ate(GenG2, Eta))
-		right := FP256BN.Fexp(FP256BN.Ate(t, Xi))
+	trace, err := index.lookup(upk)
+	if err != nil {
+		return nil, nil, err
+	}
+	if trace.GetT() == nil {
+		return nil, nil, errors.Errorf("Cannot Arbitration AnonymousCredential: trace of the user is undefined")
+	}
+
+	return trace.GetUpk(), newOpeningProof(key, z, trace, upk, TraceC1, anonymity, msg, rng), nil
+}
+
+// newOpeningProof proves that C_2 \cdot upk^{-1} = C_1^z for the z of the tracing key
+func newOpeningProof(key *IssuerKey, z *FP256BN.BIG, trace *Trace, upk, TraceC1 *FP256BN.ECP, anonymity *NymSignature, msg []byte, rng *amcl.RAND) *OpeningProof {
+	r := RandModOrder(rng)
+	t1 := genG1().Mul(r)
+	t2 := TraceC1.Mul(r)
+
+	proof := &OpeningProof{
+		T:            trace.GetT(),
+		ProofVersion: ProofVersionTranscript,
+	}
+	proofC := openingChallenge(proof, false, t1, t2, EcpFromProto(key.GetIpk().GetTracingPk()), upk, anonymity, msg)
+	proofS := Modadd(r, FP256BN.Modmul(proofC, z, GroupOrder), GroupOrder) // s = r + C \cdot z
+	proof.ProofC = BigToBytes(proofC)
+	proof.ProofS = BigToBytes(proofS)
+	return proof
+}
 
-		if left.Equals(right) {
-			return trace.GetUpk(), nil
+// VerifyOpening checks that the tracing tag of the NymSignature on msg opens to the user public key upk.
+// The opening of a signature that does not verify with opts is rejected, it does not tie the user to anything.
+// An opening proof made before transcripts is only accepted if opts.AllowLegacy is set.
+func VerifyOpening(ipk *IssuerPublicKey, upk *UserPublicKey, anonymity *NymSignature, msg []byte, opts *VerifyOpts, proof *OpeningProof) error {
+	if ipk.GetTracingPk() == nil || upk.GetUPK() == nil || proof.GetT() == nil || anonymity == nil ||
+		anonymity.GetTraceC1() == nil || anonymity.GetTraceC2() == nil || anonymity.GetEta() == nil || anonymity.GetXi() == nil {
+		return errors.Errorf("opening proof invalid: received nil input")
+	}
+	if err := anonymity.Ver(ipk, msg, opts); err != nil {
+		return errors.WithMessage(err, "opening proof invalid")
+	}
+
+	TracingPk := EcpFromProto(ipk.GetTracingPk())
+	points := make([]*FP256BN.ECP, 5)
+	for k, p := range []*ECP{upk.GetUPK(), anonymity.GetTraceC1(), anonymity.GetTraceC2(), anonymity.GetEta(), anonymity.GetXi()} {
+		P, err := EcpFromProtoChecked(p)
+		if err != nil {
+			return errors.Wrap(err, "opening proof invalid: malformed input")
+		}
+		points[k] = P
+	}
+	UPK, TraceC1, TraceC2, Eta, Xi := points[0], points[1], points[2], points[3], points[4]
+	T, err := Ecp2FromProtoChecked(proof.GetT())
+	if err != nil {
+		return errors.Wrap(err, "opening proof invalid: trace is malformed")
+	}
+	legacy := opts.allowLegacy() && proof.GetProofVersion() == 0
+	if !legacy {
+		if err := checkProofVersion(proof.GetProofVersion()); err != nil {
+			return errors.WithMessage(err, "opening proof invalid")
 		}
+	}
+	ProofC, err := BigFromBytesChecked(proof.GetProofC())
+	if err != nil {
+		return errors.Wrap(err, "opening proof invalid: malformed proof")
+	}
+	ProofS, err := BigFromBytesChecked(proof.GetProofS())
+	if err != nil {
+		return errors.Wrap(err, "opening proof invalid: malformed proof")
+	}
+
+	// Check that T is the trace of upk, e(T, g_1) = e(g_2, upk)
+	if !FP256BN.Fexp(FP256BN.Ate(T, genG1())).Equals(FP256BN.Fexp(FP256BN.Ate(genG2(), UPK))) {
+		return errors.Errorf("opening proof invalid: trace does not belong to the user public key")
+	}
+
+	// Check that the pseudonym is made with the secret of the user, e(g_2, Eta) = e(T, Xi)
+	left := FP256BN.Fexp(FP256BN.Ate(genG2(), Eta))
+	right := FP256BN.Fexp(FP256BN.Ate(T, Xi))
+	if !left.Equals(right) {
+		return errors.Errorf("opening proof invalid: pseudonym does not belong to the user")
+	}
+
+	// Recompute t-values using s-values
+	D := FP256BN.NewECP()
+	D.Copy(TraceC2)
+	D.Sub(UPK)
+
+	t1 := genG1().Mul(ProofS)
+	t1.Add(TracingPk.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t1 = g_1^s \cdot Z^{-C}
+
+	t2 := TraceC1.Mul(ProofS)
+	t2.Add(D.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t2 = C_1^s \cdot (C_2 \cdot upk^{-1})^{-C}
 
+	if *ProofC != *openingChallenge(proof, legacy, t1, t2, TracingPk, UPK, anonymity, msg) {
+		return errors.Errorf("opening proof invalid: zero knowledge proof does not verify")
 	}
+	return nil
+}
+
+// openingProofLabel is the label of the transcript of an opening proof
+const openingProofLabel = "openingProof"
+
+// openingChallenge derives the challenge of an opening proof, from the proof data of proofs made before
+// transcripts if legacy is set
+func openingChallenge(proof *OpeningProof, legacy bool, t1, t2, tracingPk, upk *FP256BN.ECP, anonymity *NymSignature, msg []byte) *FP256BN.BIG {
+	t := newTranscript(openingProofLabel)
+	if legacy {
+		t = newLegacyTranscript()
+	}
+	t.appendG1("t1", t1)
+	t.appendG1("t2", t2)
+	t.appendG1("TracingPk", tracingPk)
+	t.appendG1("UPK", upk)
+	if !t.legacy {
+		t.appendG2("T", Ecp2FromProto(proof.GetT()))
+	}
+	t.appendG1("TraceC1", EcpFromProto(anonymity.GetTraceC1()))
+	t.appendG1("TraceC2", EcpFromProto(anonymity.GetTraceC2()))
+	t.appendG1("Eta", EcpFromProto(anonymity.GetEta()))
+	t.appendG1("Xi", EcpFromProto(anonymity.GetXi()))
+	if !t.legacy {
+		t.appendBytes("signature", anonymity.GetProofC())
+	}
+	t.appendBytes("msg", msg)
+	return t.challengeWithNonce(anonymity.GetNonce())
+}
 
-	return nil, errors.Errorf("Not find the user")
+// lookup returns the trace of a registered user by the user public key g1^{usk}
+func (index *TraceIndex) lookup(upk *FP256BN.ECP) (*Trace, error) {
+	key := traceKey(upk)
+	index.mu.RLock()
+	trace, ok := index.traces[key]
+	index.mu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("Not find the user")
+	}
+	return trace, nil
 }
@@ -0,0 +1,173 @@
+package idemixplus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// A batch of NymSignatures is verified by checking the proofs of every signature on its own and
+// merging the pairing equations of all signatures into one: every equation is raised to a random r_k,
+// and the equations of signatures under the same issuer key share their G2 arguments, so that
+// \prod_k (e(BarX, Sigma1_k) e(BarY, Sigma3_k) \prod_i e(BarAttr_i, A_{k,i}) \prod_v e(BarValidity_v, Com_{k,v}) e(g_2, Sigma2_k)^{-1})^{r_k}
+// = e(BarX, \sum_k r_k Sigma1_k) e(BarY, \sum_k r_k Sigma3_k) \prod_i e(BarAttr_i, \sum_k r_k A_{k,i}) ... e(g_2, -\sum_k r_k Sigma2_k)
+// takes one pairing per G2 argument and issuer key and a single final exponentiation, where
+// A_{k,i} is Sigma1_k^{attr_i} for a disclosed attribute and Com_{k,i} for a hidden one.
+// A forged signature passes the merged equation with probability 1/p only.
+// Only signatures in format 4, made with SignOpts.Version set to NymSignatureV4, and in the legacy format 1
+// leave a pairing equation to merge.
+
+// BatchItem is a NymSignature together with what its verifier expects, see NymSignature.Ver
+type BatchItem struct {
+	Signature *NymSignature
+	Ipk       *IssuerPublicKey
+	Msg       []byte
+	Opts      *VerifyOpts
+}
+
+// BatchVerificationError reports the signatures of a batch that do not verify
+type BatchVerificationError struct {
+	// Failed holds the error of every signature that does not verify by its index in the batch
+	Failed map[int]error
+}
+
+func (e *BatchVerificationError) Error() string {
+	indices := make([]int, 0, len(e.Failed))
+	for index := range e.Failed {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	messages := make([]string, len(indices))
+	for k, index := range indices {
+		messages[k] = fmt.Sprintf("signature %d: %v", index, e.Failed[index])
+	}
+	return fmt.Sprintf("%d of the signatures do not verify: %s", len(indices), strings.Join(messages, "; "))
+}
+
+// BatchVerify verifies the NymSignatures of all items with a single merged pairing equation.
+// When the merged equation fails, the pairing equations are checked one by one to find the bad signatures.
+// Signatures in formats 2 and 3 prove their pairing equation in GT and are checked on their own.
+// It returns a *BatchVerificationError with the error of every signature that does not verify.
+func BatchVerify(items []*BatchItem, rng *amcl.RAND) error {
+	if rng == nil {
+		return errors.Errorf("cannot verify batch: received nil input")
+	}
+
+	failed := make(map[int]error)
+	checks := make([]*nymPairingCheck, len(items))
+	// keys are prepared once per issuer public key and per choice of accepting legacy keys
+	type keyOpts struct {
+		ipk         *IssuerPublicKey
+		allowLegacy bool
+	}
+	keys := make(map[keyOpts]*preparedKey)
+	for k, item := range items {
+		if item == nil || item.Signature == nil || item.Ipk == nil {
+			failed[k] = verificationErrorf(ErrKindInvalid, "batch item is undefined")
+			continue
+		}
+		ko := keyOpts{ipk: item.Ipk, allowLegacy: item.Opts.allowLegacy()}
+		key, exists := keys[ko]
+		if !exists {
+			var err error
+			key, err = prepareKey(item.Ipk, ko.allowLegacy)
+			if err != nil {
+				failed[k] = wrapVerificationError(ErrKindInvalid, err, "cannot verify NymSignature")
+				continue
+			}
+			keys[ko] = key
+		}
+		check, err := item.Signature.verifyProofs(key, 1, item.Msg, item.Opts)
+		if err != nil {
+			failed[k] = err
+			continue
+		}
+		checks[k] = check
+	}
+
+	if !batchPairingCheck(checks, rng) {
+		for k, check := range checks {
+			if check == nil {
+				continue
+			}
+			if err := check.verify(1); err != nil {
+				failed[k] = err
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return &BatchVerificationError{Failed: failed}
+	}
+	return nil
+}
+
+// batchGroup accumulates the G1 arguments of the merged pairing equation of the signatures under one issuer key
+type batchGroup struct {
+	key      *preparedKey
+	Sigma1   *FP256BN.ECP
+	Sigma3   *FP256BN.ECP
+	attrs    []*FP256BN.ECP
+	validity []*FP256BN.ECP
+}
+
+// batchPairingCheck checks the pairing equations of all checks that are not nil with one merged equation
+func batchPairingCheck(checks []*nymPairingCheck, rng *amcl.RAND) bool {
+	groups := make(map[*preparedKey]*batchGroup)
+	var order []*batchGroup
+	Sigma2 := FP256BN.NewECP()
+	for _, check := range checks {
+		if check == nil {
+			continue
+		}
+		group, exists := groups[check.key]
+		if !exists {
+			group = &batchGroup{key: check.key, Sigma1: FP256BN.NewECP(), Sigma3: FP256BN.NewECP()}
+			for range check.disclosed {
+				group.attrs = append(group.attrs, FP256BN.NewECP())
+			}
+			for range check.validity {
+				group.validity = append(group.validity, FP256BN.NewECP())
+			}
+			groups[check.key] = group
+			order = append(order, group)
+		}
+
+		r := RandModOrder(rng)
+		group.Sigma1.Add(check.Sigma1.Mul(r))
+		group.Sigma3.Add(check.Sigma3.Mul(r))
+		Sigma2.Add(check.Sigma2.Mul(r))
+		for index := range group.attrs {
+			if check.disclosed[index] != nil {
+				group.attrs[index].Add(check.Sigma1.Mul(FP256BN.Modmul(r, check.disclosed[index], GroupOrder)))
+			} else {
+				group.attrs[index].Add(check.hidden[index].Mul(r))
+			}
+		}
+		for v := range group.validity {
+			group.validity[v].Add(check.validity[v].Mul(r))
+		}
+	}
+	if len(order) == 0 {
+		return true
+	}
+
+	// \prod_{groups} e(BarX, Sigma1) e(BarY, Sigma3) \prod_i e(BarAttr_i, A_i) \prod_v e(BarValidity_v, Com_v) \cdot e(g_2, -Sigma2) = 1
+	negSigma2 := FP256BN.NewECP()
+	negSigma2.Sub(Sigma2)
+	res := FP256BN.Ate(genG2(), negSigma2)
+	for _, group := range order {
+		res.Mul(FP256BN.Ate2(group.key.BarX, group.Sigma1, group.key.BarY, group.Sigma3))
+		for index, A := range group.attrs {
+			res.Mul(FP256BN.Ate(group.key.BarAttrs[index], A))
+		}
+		for v, Com := range group.validity {
+			res.Mul(FP256BN.Ate(group.key.BarValidity[v], Com))
+		}
+	}
+	return FP256BN.Fexp(res).Isunity()
+}
@@ -21,12 +21,12 @@ import (
 // The issuer takes its secret and public keys and user attribute values as input
 // The user takes the issuer public key and user secret as input
 // The issuance protocol consists of the following steps:
-// 1) The issuer sends a random nonce to the user
+// 1) The issuer sends a random nonce to the user, and keeps it in its IssuerNonceStore until it is used or expires
 // 2) The user creates a Credential Request using the public key of the issuer, user secret, and the nonce as input
 //    The request consists of a commitment to the user secret (can be seen as a public key) and a zero-knowledge proof
 //     of knowledge of the user secret key
 //    The user sends the credential request to the issuer
-// 3) The issuer verifies the credential request by verifying the zero-knowledge proof
+// 3) The issuer verifies the credential request by verifying the zero-knowledge proof and consuming the nonce
 //    If the request is valid, the issuer issues a credential to the user by signing the commitment to the secret key
 //    together with the attribute values and sends the credential back to the user
 // 4) The user verifies the issuer's signature and stores the credential that consists of
@@ -35,7 +35,11 @@ import (
 // NewCredential issues a new credential, which is the last step of the interactive issuance protocol
 // All attribute values are added by the issuer at this step and then signed together with a commitment to
 // the user's secret key from a credential request
-func NewCredential(key *IssuerKey, m *CredRequest, upk *UserPublicKey, attrs []*FP256BN.BIG, rng *amcl.RAND) (*Credential, error) {
+// The signature is a Pointcheval-Sanders signature on (usk, attr_1, ..., attr_n, notBefore, notAfter):
+// A = g_1^r, B = A^{x + y \cdot usk + \sum_i y_i \cdot attr_i + y_{nb} \cdot notBefore + y_{na} \cdot notAfter}
+// The credential is valid from notBefore to notAfter, both in seconds since the epoch
+// The nonce of the credential request must have been handed out by nonces, it is consumed when the request is checked
+func NewCredential(key *IssuerKey, nonces *IssuerNonceStore, m *CredRequest, upk *UserPublicKey, attrs []*FP256BN.BIG, notBefore int64, notAfter int64, rng *amcl.RAND) (*Credential, error) {
 	fmt.Println("NewCredential")
 	if attrs == nil || rng == nil || key == nil {
 		return nil, errors.Errorf("cannot create NewCredential: received nil input")
@@ -45,55 +49,112 @@ func NewCredential(key *IssuerKey, m *CredRequest, upk *UserPublicKey, attrs []*
 		return nil, errors.Errorf("incorrect number of attribute values passed")
 	}
 
-	err := m.Check(key.Ipk)
+	if len(attrs) != len(key.Ipk.AttributeNames) || len(attrs) != len(key.Isk.Attrs) {
+		return nil, errors.Errorf("issuer key does not match the number of attribute values passed")
+	}
+
+	if len(key.Isk.Validity) != numValidityKeys {
+		return nil, errors.Errorf("issuer key has no validity key")
+	}
+
+	if err := checkValidityWindow(notBefore, notAfter); err != nil {
+		return nil, err
+	}
+
+	err := m.Check(key.Ipk, nonces)
 	if err != nil {
 		return nil, err
 	}
 
-	creds := new(Credential)
-	// The signature is now generated.
+	// Compute the exponent x + \sum_i y_i \cdot attr_i + y_{nb} \cdot notBefore + y_{na} \cdot notAfter
+	exp := FP256BN.NewBIGcopy(FP256BN.FromBytes(key.Isk.X))
 	for index, attribute := range attrs {
-		r := RandModOrder(rng)
-		signed := new(SignedAttribute)
-		signed.A = EcpToProto(GenG1.Mul(r))
+		exp = Modadd(exp, FP256BN.Modmul(FP256BN.FromBytes(key.Isk.Attrs[index]), attribute, GroupOrder), GroupOrder)
+	}
+	for v, value := range validityValues(notBefore, notAfter) {
+		exp = Modadd(exp, FP256BN.Modmul(FP256BN.FromBytes(key.Isk.Validity[v]), value, GroupOrder), GroupOrder)
+	}
 
-		signed.B = EcpToProto(GenG1.Mul(r).Mul2(FP256BN.FromBytes(key.Isk.X), EcpFromProto(upk.UPK).Mul(r), FP256BN.FromBytes(key.Isk.Y)))
+	// The signature is now generated.
+	r := RandModOrder(rng)
+	A := genG1().Mul(r)
+	B := A.Mul2(exp, EcpFromProto(upk.UPK).Mul(r), FP256BN.FromBytes(key.Isk.Y))
 
-		creds.Creds = append(creds.Creds, signed)
+	creds := new(Credential)
+	creds.A = EcpToProto(A)
+	creds.B = EcpToProto(B)
+	for index, attribute := range attrs {
 		creds.Attrs = append(creds.Attrs, BigToBytes(attribute))
 		creds.AttributeNames = append(creds.AttributeNames, key.Ipk.AttributeNames[index])
 	}
+	creds.NotBefore = notBefore
+	creds.NotAfter = notAfter
+	creds.KeyId = key.Ipk.GetKeyId()
 	return creds, nil
 }
 
 //Ver checks the credential is valid
 func (cred *Credential) Ver(sk *FP256BN.BIG, ipk *IssuerPublicKey) error {
 	fmt.Println("NewCredential  Ver")
+	key, err := prepareKey(ipk, false)
+	if err != nil {
+		return err
+	}
+	return cred.verify(sk, key)
+}
+
+// verify checks the credential under the prepared issuer public key
+func (cred *Credential) verify(sk *FP256BN.BIG, key *preparedKey) error {
 	// Validate Input
+	ipk := key.ipk
 
 	if len(cred.Attrs) == 0 {
 		return errors.Errorf("credential has no value for attribute")
 	}
 
+	if len(cred.Attrs) != len(key.BarAttrs) {
+		return errors.Errorf("credential has %d attributes, issuer public key expects %d", len(cred.Attrs), len(key.BarAttrs))
+	}
+
+	if cred.GetKeyId() != ipk.GetKeyId() {
+		return errors.Errorf("credential is issued under issuer key %s, not %s", cred.GetKeyId(), ipk.GetKeyId())
+	}
+
+	if err := checkValidityWindow(cred.NotBefore, cred.NotAfter); err != nil {
+		return errors.WithMessage(err, "credential is malformed")
+	}
+
 	// - parse the credential
 	for i, attr := range cred.Attrs {
 		if attr == nil {
 			return errors.Errorf("credential has no value for attribute %s", cred.AttributeNames[i])
 		}
 	}
-	for i, signedAttr := range cred.Creds {
-		A := EcpFromProto(signedAttr.A)
-		B := EcpFromProto(signedAttr.B)
+	// decoding rejects points that are not on the curve, and with them the point at infinity
+	A, err := EcpFromProtoChecked(cred.GetA())
+	if err != nil {
+		return errors.Wrap(err, "credential signature is malformed")
+	}
+	B, err := EcpFromProtoChecked(cred.GetB())
+	if err != nil {
+		return errors.Wrap(err, "credential signature is malformed")
+	}
 
-		BarY := Ecp2FromProto(ipk.BarY).Mul(sk)
-		BarY.Add(Ecp2FromProto(ipk.BarX))
-		BarY.Affine()
-		left := FP256BN.Fexp(FP256BN.Ate(BarY, A))
-		right := FP256BN.Fexp(FP256BN.Ate(GenG2, B))
+	// - check e(A, BarX \cdot BarY^{sk} \cdot \prod_i BarAttr_i^{attr_i} \cdot \prod_v BarValidity_v^{validity_v}) = e(B, g_2)
+	BarY := ecp2Copy(key.BarY).Mul(sk)
+	BarY.Add(key.BarX)
+	for i, attr := range cred.Attrs {
+		BarY.Add(ecp2Copy(key.BarAttrs[i]).Mul(FP256BN.FromBytes(attr)))
+	}
+	for v, value := range validityValues(cred.NotBefore, cred.NotAfter) {
+		BarY.Add(ecp2Copy(key.BarValidity[v]).Mul(value))
+	}
+	BarY.Affine()
+	left := FP256BN.Fexp(FP256BN.Ate(BarY, A))
+	right := FP256BN.Fexp(FP256BN.Ate(genG2(), B))
 
-		if !left.Equals(right) {
-			return errors.Errorf("credential is not cryptographically valid %s", ipk.AttributeNames[i])
-		}
+	if !left.Equals(right) {
+		return errors.Errorf("credential is not cryptographically valid")
 	}
 
 	return nil
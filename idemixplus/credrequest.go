@@ -44,48 +44,64 @@ func NewCredRequest(sk *FP256BN.BIG, IssuerNonce []byte, ipk *IssuerPublicKey, r
 	//t := HSk.Mul(rSk) // t = h_{sk}^{r_{sk}}, cover Nym
 
 	// Step 2: Compute the Fiat-Shamir hash, forming the challenge of the ZKP.
-	// proofData is the data being hashed, it consists of:
-	// the credential request label
-	// 3 elements of G1 each taking 2*FieldBytes+1 bytes
-	// hash of the issuer public key of length FieldBytes
-	// issuer nonce of length FieldBytes
-	proofData := make([]byte, len([]byte(credRequestLabel))+3*(2*FieldBytes+1)+2*FieldBytes)
-	index := 0
-	index = appendBytesString(proofData, index, credRequestLabel)
-	index = appendBytesG1(proofData, index, t)
-	index = appendBytesG1(proofData, index, HSk)
-	index = appendBytesG1(proofData, index, Nym)
-	index = appendBytes(proofData, index, IssuerNonce)
-	copy(proofData[index:], ipk.Hash)
-	proofC := HashModOrder(proofData)
+	proofC := credRequestChallenge(t, HSk, Nym, IssuerNonce, ipk)
 
 	// Step 3: reply to the challenge message (s-values)
 	proofS := Modadd(FP256BN.Modmul(proofC, sk, GroupOrder), rSk, GroupOrder) // s = r_{sk} + C \cdot sk
 	proofS2 := Modadd(FP256BN.Modmul(proofC, creds, GroupOrder), rd, GroupOrder)
 	// Done
 	return &CredRequest{
-		Nym:         EcpToProto(Nym),
-		IssuerNonce: IssuerNonce,
-		ProofC:      BigToBytes(proofC),
-		ProofS1:     BigToBytes(proofS),
-		ProofS2:     BigToBytes(proofS2)}
+		Nym:          EcpToProto(Nym),
+		IssuerNonce:  IssuerNonce,
+		ProofC:       BigToBytes(proofC),
+		ProofS1:      BigToBytes(proofS),
+		ProofS2:      BigToBytes(proofS2),
+		ProofVersion: ProofVersionTranscript}
 }
 
-// Check cryptographically verifies the credential request
-func (m *CredRequest) Check(ipk *IssuerPublicKey) error {
+// Check cryptographically verifies the credential request and consumes its nonce from the nonces the issuer handed out,
+// a request whose nonce is unknown, expired or already used is rejected
+func (m *CredRequest) Check(ipk *IssuerPublicKey, nonces *IssuerNonceStore) error {
 	fmt.Println("NewCredRequest Check")
-	Nym := EcpFromProto(m.GetNym())
+	if nonces == nil {
+		return errors.Errorf("cannot check credential request: received nil issuer nonce store")
+	}
+	if err := m.checkProof(ipk); err != nil {
+		return err
+	}
+	return nonces.Consume(m.GetIssuerNonce())
+}
+
+// checkProof verifies the zero-knowledge proof of the credential request
+func (m *CredRequest) checkProof(ipk *IssuerPublicKey) error {
 	IssuerNonce := m.GetIssuerNonce()
-	ProofC := FP256BN.FromBytes(m.GetProofC())
-	ProofS1 := FP256BN.FromBytes(m.GetProofS1())
-	ProofS2 := FP256BN.FromBytes(m.GetProofS2())
+	if m.GetNym() == nil || IssuerNonce == nil || m.GetProofC() == nil || m.GetProofS1() == nil || m.GetProofS2() == nil {
+		return errors.Errorf("one of the proof values is undefined")
+	}
 
-	HSk := EcpFromProto(ipk.HSk)
+	Nym, err := EcpFromProtoChecked(m.GetNym())
+	if err != nil {
+		return errors.Wrap(err, "credential request nym invalid")
+	}
+	ProofC, err := BigFromBytesChecked(m.GetProofC())
+	if err != nil {
+		return errors.Wrap(err, "credential request proof invalid")
+	}
+	ProofS1, err := BigFromBytesChecked(m.GetProofS1())
+	if err != nil {
+		return errors.Wrap(err, "credential request proof invalid")
+	}
+	ProofS2, err := BigFromBytesChecked(m.GetProofS2())
+	if err != nil {
+		return errors.Wrap(err, "credential request proof invalid")
+	}
 
-	if Nym == nil || IssuerNonce == nil || ProofC == nil || ProofS1 == nil || ProofS2 == nil {
-		return errors.Errorf("one of the proof values is undefined")
+	if err := checkProofVersion(m.GetProofVersion()); err != nil {
+		return errors.WithMessage(err, "credential request proof invalid")
 	}
 
+	HSk := EcpFromProto(ipk.HSk)
+
 	// Verify Proof
 
 	// Recompute t-values using s-values
@@ -93,18 +109,20 @@ func (m *CredRequest) Check(ipk *IssuerPublicKey) error {
 	t.Sub(Nym.Mul(ProofC)) // t = h_{sk}^s / Nym^C
 
 	// Recompute challenge
-	proofData := make([]byte, len([]byte(credRequestLabel))+3*(2*FieldBytes+1)+2*FieldBytes)
-	index := 0
-	index = appendBytesString(proofData, index, credRequestLabel)
-	index = appendBytesG1(proofData, index, t)
-	index = appendBytesG1(proofData, index, HSk)
-	index = appendBytesG1(proofData, index, Nym)
-	index = appendBytes(proofData, index, IssuerNonce)
-	copy(proofData[index:], ipk.Hash)
-
-	if *ProofC != *HashModOrder(proofData) {
+	if *ProofC != *credRequestChallenge(t, HSk, Nym, IssuerNonce, ipk) {
 		return errors.Errorf("zero knowledge proof is invalid")
 	}
 
 	return nil
 }
+
+// credRequestChallenge derives the challenge of a credential request
+func credRequestChallenge(t, HSk, Nym *FP256BN.ECP, IssuerNonce []byte, ipk *IssuerPublicKey) *FP256BN.BIG {
+	tr := newTranscript(credRequestLabel)
+	tr.appendG1("t", t)
+	tr.appendG1("HSk", HSk)
+	tr.appendG1("Nym", Nym)
+	tr.appendBytes("IssuerNonce", IssuerNonce)
+	tr.appendBytes("ipk", ipk.GetHash())
+	return tr.challenge()
+}
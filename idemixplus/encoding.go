@@ -0,0 +1,723 @@
+package idemixplus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/pem"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// Keys, traces, credentials, signatures and wallet files have a canonical binary encoding
+//   magic "IDMX" | type (1 byte) | version (1 byte) | length (4 bytes) | payload | checksum (4 bytes)
+// where the payload is the deterministic protobuf encoding of the object, length is the length of the payload
+// in big endian and checksum holds the first 4 bytes of the SHA-256 hash of everything before it.
+// The text encoding is the PEM encoding of the binary encoding with a block type naming the object type.
+// The payload of version 1 holds the coordinates of every point, the payload of version 2 holds
+// the compressed encoding of every point. Both are read, Bytes and Text write version 2.
+
+// EncodingType identifies the type of an encoded object
+type EncodingType byte
+
+const (
+	TypeIssuerKey EncodingType = iota + 1
+	TypeIssuerPublicKey
+	TypeUserKey
+	TypeUserPublicKey
+	TypeUserSecretKey
+	TypeTrace
+	TypeCredential
+	TypeNymSignature
+	TypeCredRequest
+	TypeWalletFile
+)
+
+// pemTypes holds the PEM block type of every encoding type
+var pemTypes = map[EncodingType]string{
+	TypeIssuerKey:       "IDEMIXPLUS ISSUER KEY",
+	TypeIssuerPublicKey: "IDEMIXPLUS ISSUER PUBLIC KEY",
+	TypeUserKey:         "IDEMIXPLUS USER KEY",
+	TypeUserPublicKey:   "IDEMIXPLUS USER PUBLIC KEY",
+	TypeUserSecretKey:   "IDEMIXPLUS USER SECRET KEY",
+	TypeTrace:           "IDEMIXPLUS TRACE",
+	TypeCredential:      "IDEMIXPLUS CREDENTIAL",
+	TypeNymSignature:    "IDEMIXPLUS NYM SIGNATURE",
+	TypeCredRequest:     "IDEMIXPLUS CREDENTIAL REQUEST",
+	TypeWalletFile:      "IDEMIXPLUS WALLET",
+}
+
+func (t EncodingType) String() string {
+	if name, ok := pemTypes[t]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// EncodingVersion is the version of the encoding written by Bytes and Text
+const EncodingVersion byte = 2
+
+// EncodingVersionUncompressed is the version of the encoding whose points are not compressed
+const EncodingVersionUncompressed byte = 1
+
+var encodingMagic = []byte("IDMX")
+
+const (
+	encodingHeaderBytes   = 10 // magic, type, version and length
+	encodingChecksumBytes = 4
+)
+
+// encodeBytes returns the binary encoding of msg with the given type, it compresses the points
+// of objects made before compression
+func encodeBytes(t EncodingType, msg proto.Message) ([]byte, error) {
+	if _, uncompressed := pointForms(msg); uncompressed > 0 {
+		msg = proto.Clone(msg)
+		compressPoints(msg)
+	}
+	return encodeBytesVersion(t, EncodingVersion, msg)
+}
+
+func encodeBytesVersion(t EncodingType, version byte, msg proto.Message) ([]byte, error) {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(msg); err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal %s", t)
+	}
+	payload := buf.Bytes()
+
+	raw := make([]byte, encodingHeaderBytes+len(payload)+encodingChecksumBytes)
+	index := 0
+	index = appendBytes(raw, index, encodingMagic)
+	raw[index] = byte(t)
+	raw[index+1] = version
+	binary.BigEndian.PutUint32(raw[index+2:], uint32(len(payload)))
+	index = appendBytes(raw, index+6, payload)
+	checksum := sha256.Sum256(raw[:index])
+	appendBytes(raw, index, checksum[:encodingChecksumBytes])
+	return raw, nil
+}
+
+// decodeBytes parses the binary encoding of an object of the given type into msg.
+// It rejects other types, unknown versions, bad checksums and payloads that are not canonical.
+func decodeBytes(t EncodingType, raw []byte, msg proto.Message) error {
+	if len(raw) < encodingHeaderBytes+encodingChecksumBytes || !bytes.Equal(raw[:len(encodingMagic)], encodingMagic) {
+		return errors.Errorf("cannot decode %s: not an idemixplus encoding", t)
+	}
+	if EncodingType(raw[4]) != t {
+		return errors.Errorf("cannot decode %s: encoding holds a %s", t, EncodingType(raw[4]))
+	}
+	version := raw[5]
+	if version != EncodingVersion && version != EncodingVersionUncompressed {
+		return errors.Errorf("cannot decode %s: unsupported encoding version %d", t, version)
+	}
+	length := binary.BigEndian.Uint32(raw[6:encodingHeaderBytes])
+	if uint64(len(raw)) != uint64(encodingHeaderBytes)+uint64(length)+encodingChecksumBytes {
+		return errors.Errorf("cannot decode %s: length does not match", t)
+	}
+	end := encodingHeaderBytes + int(length)
+	checksum := sha256.Sum256(raw[:end])
+	if !bytes.Equal(raw[end:], checksum[:encodingChecksumBytes]) {
+		return errors.Errorf("cannot decode %s: checksum does not match", t)
+	}
+
+	payload := raw[encodingHeaderBytes:end]
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return errors.Wrapf(err, "cannot decode %s", t)
+	}
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(msg); err != nil || !bytes.Equal(buf.Bytes(), payload) {
+		return errors.Errorf("cannot decode %s: payload is not canonical", t)
+	}
+	compressed, uncompressed := pointForms(msg)
+	if (version == EncodingVersion && uncompressed > 0) || (version == EncodingVersionUncompressed && compressed > 0) {
+		return errors.Errorf("cannot decode %s: points are not encoded as version %d requires", t, version)
+	}
+	return nil
+}
+
+// encodeText returns the text encoding of msg with the given type
+func encodeText(t EncodingType, msg proto.Message) (string, error) {
+	raw, err := encodeBytes(t, msg)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemTypes[t], Bytes: raw})), nil
+}
+
+// decodeText parses the text encoding of an object of the given type into msg
+func decodeText(t EncodingType, text string, msg proto.Message) error {
+	block, rest := pem.Decode([]byte(text))
+	if block == nil {
+		return errors.Errorf("cannot decode %s: no PEM block found", t)
+	}
+	if block.Type != pemTypes[t] {
+		return errors.Errorf("cannot decode %s: PEM block holds a %s", t, block.Type)
+	}
+	if len(block.Headers) != 0 || len(bytes.TrimSpace(rest)) != 0 {
+		return errors.Errorf("cannot decode %s: unexpected data around the PEM block", t)
+	}
+	return decodeBytes(t, block.Bytes, msg)
+}
+
+// checkEcp checks that either the coordinates or the compressed encoding of a proto G1 element are present
+func checkEcp(p *ECP) bool {
+	if p == nil {
+		return false
+	}
+	if len(p.Compressed) > 0 {
+		return len(p.Compressed) == FieldBytes+1 && len(p.X) == 0 && len(p.Y) == 0
+	}
+	return len(p.X) == FieldBytes && len(p.Y) == FieldBytes
+}
+
+// checkEcp2 checks that either the coordinates or the compressed encoding of a proto G2 element are present
+func checkEcp2(p *ECP2) bool {
+	if p == nil {
+		return false
+	}
+	if len(p.Compressed) > 0 {
+		return len(p.Compressed) == 2*FieldBytes+1 && len(p.Xa) == 0 && len(p.Xb) == 0 && len(p.Ya) == 0 && len(p.Yb) == 0
+	}
+	return len(p.Xa) == FieldBytes && len(p.Xb) == FieldBytes && len(p.Ya) == FieldBytes && len(p.Yb) == FieldBytes
+}
+
+// checkBig checks that a scalar is encoded with FieldBytes bytes
+func checkBig(b []byte) bool {
+	return len(b) == FieldBytes
+}
+
+// Bytes returns the binary encoding of the issuer key
+func (key *IssuerKey) Bytes() ([]byte, error) {
+	return encodeBytes(TypeIssuerKey, key)
+}
+
+// Text returns the text encoding of the issuer key
+func (key *IssuerKey) Text() (string, error) {
+	return encodeText(TypeIssuerKey, key)
+}
+
+// IssuerKeyFromBytes parses the binary encoding of an issuer key
+func IssuerKeyFromBytes(raw []byte) (*IssuerKey, error) {
+	key := new(IssuerKey)
+	if err := decodeBytes(TypeIssuerKey, raw, key); err != nil {
+		return nil, err
+	}
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// IssuerKeyFromText parses the text encoding of an issuer key
+func IssuerKeyFromText(text string) (*IssuerKey, error) {
+	key := new(IssuerKey)
+	if err := decodeText(TypeIssuerKey, text, key); err != nil {
+		return nil, err
+	}
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (key *IssuerKey) validate() error {
+	isk := key.GetIsk()
+	if isk == nil || !checkBig(isk.X) || !checkBig(isk.Y) || (isk.TracingSk != nil && !checkBig(isk.TracingSk)) {
+		return errors.Errorf("issuer secret key is malformed")
+	}
+	for _, attr := range append(append([][]byte{}, isk.Attrs...), isk.Validity...) {
+		if !checkBig(attr) {
+			return errors.Errorf("issuer secret key is malformed")
+		}
+	}
+	if key.GetIpk() == nil {
+		return errors.Errorf("issuer public key is undefined")
+	}
+	if len(isk.Attrs) != len(key.Ipk.AttributeNames) || len(isk.Validity) != numValidityKeys {
+		return errors.Errorf("issuer secret key does not match the issuer public key")
+	}
+	return key.Ipk.validate()
+}
+
+// Bytes returns the binary encoding of the issuer public key
+func (IPk *IssuerPublicKey) Bytes() ([]byte, error) {
+	return encodeBytes(TypeIssuerPublicKey, IPk)
+}
+
+// Text returns the text encoding of the issuer public key
+func (IPk *IssuerPublicKey) Text() (string, error) {
+	return encodeText(TypeIssuerPublicKey, IPk)
+}
+
+// IssuerPublicKeyFromBytes parses the binary encoding of an issuer public key
+func IssuerPublicKeyFromBytes(raw []byte) (*IssuerPublicKey, error) {
+	IPk := new(IssuerPublicKey)
+	if err := decodeBytes(TypeIssuerPublicKey, raw, IPk); err != nil {
+		return nil, err
+	}
+	if err := IPk.validate(); err != nil {
+		return nil, err
+	}
+	return IPk, nil
+}
+
+// IssuerPublicKeyFromText parses the text encoding of an issuer public key
+func IssuerPublicKeyFromText(text string) (*IssuerPublicKey, error) {
+	IPk := new(IssuerPublicKey)
+	if err := decodeText(TypeIssuerPublicKey, text, IPk); err != nil {
+		return nil, err
+	}
+	if err := IPk.validate(); err != nil {
+		return nil, err
+	}
+	return IPk, nil
+}
+
+func (IPk *IssuerPublicKey) validate() error {
+	NumAttrs := len(IPk.AttributeNames)
+	if !checkEcp(IPk.HSk) || !checkEcp(IPk.HRand) || !checkEcp2(IPk.BarX) || !checkEcp2(IPk.BarY) ||
+		!checkEcp(IPk.BarG1) || !checkEcp(IPk.BarG2) || !checkEcp(IPk.BarG3) || !checkEcp(IPk.TracingPk) ||
+		!checkBig(IPk.ProofCX) || !checkBig(IPk.ProofSX) || !checkBig(IPk.ProofCY) || !checkBig(IPk.ProofSY) ||
+		len(IPk.HAttrs) != NumAttrs || len(IPk.BarAttrs) != NumAttrs ||
+		len(IPk.HValidity) != numValidityKeys || len(IPk.BarValidity) != numValidityKeys {
+		return errors.Errorf("issuer public key is malformed")
+	}
+	for i := 0; i < NumAttrs; i++ {
+		if !checkEcp(IPk.HAttrs[i]) || !checkEcp2(IPk.BarAttrs[i]) {
+			return errors.Errorf("issuer public key is malformed")
+		}
+	}
+	for v := 0; v < numValidityKeys; v++ {
+		if !checkEcp(IPk.HValidity[v]) || !checkEcp2(IPk.BarValidity[v]) {
+			return errors.Errorf("issuer public key is malformed")
+		}
+	}
+	return nil
+}
+
+// Bytes returns the binary encoding of the user key
+func (key *UserKey) Bytes() ([]byte, error) {
+	return encodeBytes(TypeUserKey, key)
+}
+
+// Text returns the text encoding of the user key
+func (key *UserKey) Text() (string, error) {
+	return encodeText(TypeUserKey, key)
+}
+
+// UserKeyFromBytes parses the binary encoding of a user key
+func UserKeyFromBytes(raw []byte) (*UserKey, error) {
+	key := new(UserKey)
+	if err := decodeBytes(TypeUserKey, raw, key); err != nil {
+		return nil, err
+	}
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// UserKeyFromText parses the text encoding of a user key
+func UserKeyFromText(text string) (*UserKey, error) {
+	key := new(UserKey)
+	if err := decodeText(TypeUserKey, text, key); err != nil {
+		return nil, err
+	}
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (key *UserKey) validate() error {
+	if key.GetUsk() == nil || key.GetUpk() == nil {
+		return errors.Errorf("user key is malformed")
+	}
+	if err := key.Usk.validate(); err != nil {
+		return err
+	}
+	return key.Upk.validate()
+}
+
+// Bytes returns the binary encoding of the user public key
+func (UPk *UserPublicKey) Bytes() ([]byte, error) {
+	return encodeBytes(TypeUserPublicKey, UPk)
+}
+
+// Text returns the text encoding of the user public key
+func (UPk *UserPublicKey) Text() (string, error) {
+	return encodeText(TypeUserPublicKey, UPk)
+}
+
+// UserPublicKeyFromBytes parses the binary encoding of a user public key
+func UserPublicKeyFromBytes(raw []byte) (*UserPublicKey, error) {
+	UPk := new(UserPublicKey)
+	if err := decodeBytes(TypeUserPublicKey, raw, UPk); err != nil {
+		return nil, err
+	}
+	if err := UPk.validate(); err != nil {
+		return nil, err
+	}
+	return UPk, nil
+}
+
+// UserPublicKeyFromText parses the text encoding of a user public key
+func UserPublicKeyFromText(text string) (*UserPublicKey, error) {
+	UPk := new(UserPublicKey)
+	if err := decodeText(TypeUserPublicKey, text, UPk); err != nil {
+		return nil, err
+	}
+	if err := UPk.validate(); err != nil {
+		return nil, err
+	}
+	return UPk, nil
+}
+
+func (UPk *UserPublicKey) validate() error {
+	if !checkEcp(UPk.UPK) || !checkEcp2(UPk.W) || !checkEcp(UPk.HSk) || !checkEcp(UPk.HRand) ||
+		!checkEcp(UPk.BarG1) || !checkEcp(UPk.BarG2) || !checkBig(UPk.ProofC) || !checkBig(UPk.ProofS) {
+		return errors.Errorf("user public key is malformed")
+	}
+	return nil
+}
+
+// Bytes returns the binary encoding of the user secret key
+func (USk *UserSecretKey) Bytes() ([]byte, error) {
+	return encodeBytes(TypeUserSecretKey, USk)
+}
+
+// Text returns the text encoding of the user secret key
+func (USk *UserSecretKey) Text() (string, error) {
+	return encodeText(TypeUserSecretKey, USk)
+}
+
+// UserSecretKeyFromBytes parses the binary encoding of a user secret key
+func UserSecretKeyFromBytes(raw []byte) (*UserSecretKey, error) {
+	USk := new(UserSecretKey)
+	if err := decodeBytes(TypeUserSecretKey, raw, USk); err != nil {
+		return nil, err
+	}
+	if err := USk.validate(); err != nil {
+		return nil, err
+	}
+	return USk, nil
+}
+
+// UserSecretKeyFromText parses the text encoding of a user secret key
+func UserSecretKeyFromText(text string) (*UserSecretKey, error) {
+	USk := new(UserSecretKey)
+	if err := decodeText(TypeUserSecretKey, text, USk); err != nil {
+		return nil, err
+	}
+	if err := USk.validate(); err != nil {
+		return nil, err
+	}
+	return USk, nil
+}
+
+func (USk *UserSecretKey) validate() error {
+	if !checkBig(USk.X) {
+		return errors.Errorf("user secret key is malformed")
+	}
+	return nil
+}
+
+// Bytes returns the binary encoding of the trace
+func (trace *Trace) Bytes() ([]byte, error) {
+	return encodeBytes(TypeTrace, trace)
+}
+
+// Text returns the text encoding of the trace
+func (trace *Trace) Text() (string, error) {
+	return encodeText(TypeTrace, trace)
+}
+
+// TraceFromBytes parses the binary encoding of a trace
+func TraceFromBytes(raw []byte) (*Trace, error) {
+	trace := new(Trace)
+	if err := decodeBytes(TypeTrace, raw, trace); err != nil {
+		return nil, err
+	}
+	if err := trace.validate(); err != nil {
+		return nil, err
+	}
+	return trace, nil
+}
+
+// TraceFromText parses the text encoding of a trace
+func TraceFromText(text string) (*Trace, error) {
+	trace := new(Trace)
+	if err := decodeText(TypeTrace, text, trace); err != nil {
+		return nil, err
+	}
+	if err := trace.validate(); err != nil {
+		return nil, err
+	}
+	return trace, nil
+}
+
+func (trace *Trace) validate() error {
+	if !checkEcp2(trace.T) || trace.Upk == nil {
+		return errors.Errorf("trace is malformed")
+	}
+	return trace.Upk.validate()
+}
+
+// Bytes returns the binary encoding of the credential
+func (cred *Credential) Bytes() ([]byte, error) {
+	return encodeBytes(TypeCredential, cred)
+}
+
+// Text returns the text encoding of the credential
+func (cred *Credential) Text() (string, error) {
+	return encodeText(TypeCredential, cred)
+}
+
+// CredentialFromBytes parses the binary encoding of a credential
+func CredentialFromBytes(raw []byte) (*Credential, error) {
+	cred := new(Credential)
+	if err := decodeBytes(TypeCredential, raw, cred); err != nil {
+		return nil, err
+	}
+	if err := cred.validate(); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// CredentialFromText parses the text encoding of a credential
+func CredentialFromText(text string) (*Credential, error) {
+	cred := new(Credential)
+	if err := decodeText(TypeCredential, text, cred); err != nil {
+		return nil, err
+	}
+	if err := cred.validate(); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+func (cred *Credential) validate() error {
+	if !checkEcp(cred.A) || !checkEcp(cred.B) || len(cred.Attrs) != len(cred.AttributeNames) ||
+		checkValidityWindow(cred.NotBefore, cred.NotAfter) != nil {
+		return errors.Errorf("credential is malformed")
+	}
+	for _, attr := range cred.Attrs {
+		if !checkBig(attr) {
+			return errors.Errorf("credential is malformed")
+		}
+	}
+	return nil
+}
+
+// Bytes returns the binary encoding of the NymSignature
+func (nym *NymSignature) Bytes() ([]byte, error) {
+	return encodeBytes(TypeNymSignature, nym)
+}
+
+// Text returns the text encoding of the NymSignature
+func (nym *NymSignature) Text() (string, error) {
+	return encodeText(TypeNymSignature, nym)
+}
+
+// NymSignatureFromBytes parses the binary encoding of a NymSignature
+func NymSignatureFromBytes(raw []byte) (*NymSignature, error) {
+	nym := new(NymSignature)
+	if err := decodeBytes(TypeNymSignature, raw, nym); err != nil {
+		return nil, err
+	}
+	if err := nym.validate(); err != nil {
+		return nil, err
+	}
+	return nym, nil
+}
+
+// NymSignatureFromText parses the text encoding of a NymSignature
+func NymSignatureFromText(text string) (*NymSignature, error) {
+	nym := new(NymSignature)
+	if err := decodeText(TypeNymSignature, text, nym); err != nil {
+		return nil, err
+	}
+	if err := nym.validate(); err != nil {
+		return nil, err
+	}
+	return nym, nil
+}
+
+func (nym *NymSignature) validate() error {
+	if !checkEcp(nym.Eta) || !checkEcp(nym.Xi) || !checkEcp(nym.Sigma_1) || !checkEcp(nym.Sigma_2) || !checkEcp(nym.Sigma_3) ||
+		!checkEcp(nym.TraceC1) || !checkEcp(nym.TraceC2) || !checkBig(nym.Nonce) ||
+		!checkBig(nym.ProofC) || !checkBig(nym.ProofS) || !checkBig(nym.ProofSTrace) {
+		return errors.Errorf("NymSignature is malformed")
+	}
+	if (len(nym.Scope) > 0) != (nym.ScopeNym != nil) || (nym.ScopeNym != nil && !checkEcp(nym.ScopeNym)) {
+		return errors.Errorf("NymSignature is malformed")
+	}
+	if len(nym.ValidityProofs) != numValidityKeys {
+		return errors.Errorf("NymSignature is malformed")
+	}
+	switch nym.formatVersion() {
+	case NymSignatureV1, NymSignatureV4:
+		if err := nym.validateV1(); err != nil {
+			return err
+		}
+	case NymSignatureV2, NymSignatureV3:
+		if err := nym.validateV3(); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("NymSignature has unknown format %d", nym.GetVersion())
+	}
+	for _, attr := range nym.Attrs {
+		if !checkBig(attr) {
+			return errors.Errorf("NymSignature is malformed")
+		}
+	}
+	for _, proof := range nym.SetProofs {
+		if proof == nil || len(proof.Set) == 0 || len(proof.ProofC) != len(proof.Set) || len(proof.ProofS) != len(proof.Set) {
+			return errors.Errorf("NymSignature is malformed")
+		}
+	}
+	for _, proof := range nym.RangeProofs {
+		if !checkRangeProof(proof, RangeBits) {
+			return errors.Errorf("NymSignature is malformed")
+		}
+	}
+	for _, proof := range nym.ValidityProofs {
+		if !checkRangeProof(proof, validityRangeBits) {
+			return errors.Errorf("NymSignature is malformed")
+		}
+	}
+	return nil
+}
+
+// validateV1 checks the proofs of the hidden values of a NymSignature in format 1 or 4
+func (nym *NymSignature) validateV1() error {
+	if len(nym.Hides)+len(nym.Attrs) != len(nym.Disclosure) || len(nym.ValidityHides) != numValidityKeys ||
+		len(nym.ProofSHidden) != 0 || len(nym.ProofSBlind) != 0 || len(nym.Commitments) != 0 {
+		return errors.Errorf("NymSignature is malformed")
+	}
+	for _, hide := range append(append([]*HiddenAttribute{}, nym.Hides...), nym.ValidityHides...) {
+		if hide == nil || !checkEcp(hide.Com) || !checkBig(hide.ProofC) || !checkBig(hide.ProofSAttr) || !checkBig(hide.ProofSRand) {
+			return errors.Errorf("NymSignature is malformed")
+		}
+	}
+	return nil
+}
+
+// validateV3 checks the proof of the hidden values of a NymSignature in format 2 or 3
+func (nym *NymSignature) validateV3() error {
+	hidden := hiddenValueIndices(nym.Disclosure, len(nym.Disclosure))
+	if len(nym.Hides) != 0 || len(nym.ValidityHides) != 0 || len(nym.Attrs)+len(hidden)-numValidityKeys != len(nym.Disclosure) ||
+		len(nym.ProofSHidden) != len(hidden) || !checkBig(nym.ProofSBlind) {
+		return errors.Errorf("NymSignature is malformed")
+	}
+	for _, s := range nym.ProofSHidden {
+		if !checkBig(s) {
+			return errors.Errorf("NymSignature is malformed")
+		}
+	}
+	previous := int64(-1)
+	for _, com := range nym.Commitments {
+		if com == nil || com.Attribute <= previous || !isIn(hidden, int(com.Attribute)) || !checkEcp(com.Com) || !checkBig(com.ProofSRand) {
+			return errors.Errorf("NymSignature is malformed")
+		}
+		previous = com.Attribute
+	}
+	return nil
+}
+
+func checkRangeProof(proof *RangeProof, numBits int) bool {
+	return proof != nil && checkBig(proof.Bound) && checkBig(proof.ProofC) && checkBig(proof.ProofSAttr) &&
+		checkBig(proof.ProofSRand) && checkBig(proof.ProofSBits) && len(proof.Bits) == numBits &&
+		len(proof.BitC0) == numBits && len(proof.BitS0) == numBits && len(proof.BitS1) == numBits
+}
+
+// Bytes returns the binary encoding of the credential request
+func (m *CredRequest) Bytes() ([]byte, error) {
+	return encodeBytes(TypeCredRequest, m)
+}
+
+// Text returns the text encoding of the credential request
+func (m *CredRequest) Text() (string, error) {
+	return encodeText(TypeCredRequest, m)
+}
+
+// CredRequestFromBytes parses the binary encoding of a credential request
+func CredRequestFromBytes(raw []byte) (*CredRequest, error) {
+	m := new(CredRequest)
+	if err := decodeBytes(TypeCredRequest, raw, m); err != nil {
+		return nil, err
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CredRequestFromText parses the text encoding of a credential request
+func CredRequestFromText(text string) (*CredRequest, error) {
+	m := new(CredRequest)
+	if err := decodeText(TypeCredRequest, text, m); err != nil {
+		return nil, err
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *CredRequest) validate() error {
+	if !checkEcp(m.Nym) || len(m.IssuerNonce) == 0 || !checkBig(m.ProofC) || !checkBig(m.ProofS1) || !checkBig(m.ProofS2) {
+		return errors.Errorf("credential request is malformed")
+	}
+	return nil
+}
+
+// Bytes returns the binary encoding of the wallet file
+func (file *WalletFile) Bytes() ([]byte, error) {
+	return encodeBytes(TypeWalletFile, file)
+}
+
+// Text returns the text encoding of the wallet file
+func (file *WalletFile) Text() (string, error) {
+	return encodeText(TypeWalletFile, file)
+}
+
+// WalletFileFromBytes parses the binary encoding of a wallet file
+func WalletFileFromBytes(raw []byte) (*WalletFile, error) {
+	file := new(WalletFile)
+	if err := decodeBytes(TypeWalletFile, raw, file); err != nil {
+		return nil, err
+	}
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// WalletFileFromText parses the text encoding of a wallet file
+func WalletFileFromText(text string) (*WalletFile, error) {
+	file := new(WalletFile)
+	if err := decodeText(TypeWalletFile, text, file); err != nil {
+		return nil, err
+	}
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (file *WalletFile) validate() error {
+	if file.GetKdfTime() == 0 || file.GetKdfTime() > maxWalletKDFTime ||
+		file.GetKdfThreads() == 0 || file.GetKdfThreads() > 255 ||
+		file.GetKdfMemory() < 8*file.GetKdfThreads() || file.GetKdfMemory() > maxWalletKDFMemory {
+		return errors.Errorf("wallet file has unsupported key derivation parameters")
+	}
+	if len(file.GetSalt()) != walletSaltBytes || len(file.GetCiphertext()) == 0 {
+		return errors.Errorf("wallet file is malformed")
+	}
+	return nil
+}
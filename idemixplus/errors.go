@@ -0,0 +1,81 @@
+package idemixplus
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// VerificationErrorKind tells why a NymSignature is rejected
+type VerificationErrorKind int
+
+const (
+	// ErrKindInvalid means the signature is malformed or forged
+	ErrKindInvalid VerificationErrorKind = iota
+	// ErrKindRevoked means the signer could not prove that the credential is not revoked
+	ErrKindRevoked
+	// ErrKindExpired means the signature was made in a stale or different epoch, or with a credential that is not valid at the verification time
+	ErrKindExpired
+)
+
+func (kind VerificationErrorKind) String() string {
+	switch kind {
+	case ErrKindInvalid:
+		return "invalid"
+	case ErrKindRevoked:
+		return "revoked"
+	case ErrKindExpired:
+		return "expired"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(kind))
+	}
+}
+
+// VerificationError is the error returned by NymSignature.Ver
+type VerificationError struct {
+	Kind VerificationErrorKind
+	err  error
+}
+
+func (e *VerificationError) Error() string {
+	return e.err.Error()
+}
+
+// Cause returns the underlying error, see github.com/pkg/errors
+func (e *VerificationError) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the underlying error
+func (e *VerificationError) Unwrap() error {
+	return e.err
+}
+
+// verificationErrorf creates a VerificationError of the given kind
+func verificationErrorf(kind VerificationErrorKind, format string, args ...interface{}) error {
+	return &VerificationError{Kind: kind, err: errors.Errorf(format, args...)}
+}
+
+// wrapVerificationError wraps err into a VerificationError of the given kind
+func wrapVerificationError(kind VerificationErrorKind, err error, message string) error {
+	return &VerificationError{Kind: kind, err: errors.Wrap(err, message)}
+}
+
+// VerificationErrorKindOf returns the kind of a verification error,
+// errors that are not a VerificationError are reported as ErrKindInvalid
+func VerificationErrorKindOf(err error) VerificationErrorKind {
+	if verr, ok := err.(*VerificationError); ok {
+		return verr.Kind
+	}
+	return ErrKindInvalid
+}
+
+// IsRevoked reports whether err rejects a signature of a revoked credential
+func IsRevoked(err error) bool {
+	return err != nil && VerificationErrorKindOf(err) == ErrKindRevoked
+}
+
+// IsExpired reports whether err rejects a signature from a stale or different epoch
+func IsExpired(err error) bool {
+	return err != nil && VerificationErrorKindOf(err) == ErrKindExpired
+}
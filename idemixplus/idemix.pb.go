@@ -22,9 +22,12 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 // ECP is an elliptic curve point specified by its coordinates
 // ECP corresponds to an element of the first group (G1)
+// A point is either given by x and y, or by its compressed encoding
+// 0x02 or 0x03 (the parity of y) | x, in which case x and y are empty
 type ECP struct {
 	X                    []byte   `protobuf:"bytes,1,opt,name=x,proto3" json:"x,omitempty"`
 	Y                    []byte   `protobuf:"bytes,2,opt,name=y,proto3" json:"y,omitempty"`
+	Compressed           []byte   `protobuf:"bytes,3,opt,name=compressed,proto3" json:"compressed,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -69,13 +72,23 @@ func (m *ECP) GetY() []byte {
 	return nil
 }
 
+func (m *ECP) GetCompressed() []byte {
+	if m != nil {
+		return m.Compressed
+	}
+	return nil
+}
+
 // ECP2 is an elliptic curve point specified by its coordinates
 // ECP2 corresponds to an element of the second group (G2)
+// A point is either given by its four coordinates, or by its compressed encoding
+// 0x02 or 0x03 (the sign of y) | xa | xb, in which case the coordinates are empty
 type ECP2 struct {
 	Xa                   []byte   `protobuf:"bytes,1,opt,name=xa,proto3" json:"xa,omitempty"`
 	Xb                   []byte   `protobuf:"bytes,2,opt,name=xb,proto3" json:"xb,omitempty"`
 	Ya                   []byte   `protobuf:"bytes,3,opt,name=ya,proto3" json:"ya,omitempty"`
 	Yb                   []byte   `protobuf:"bytes,4,opt,name=yb,proto3" json:"yb,omitempty"`
+	Compressed           []byte   `protobuf:"bytes,5,opt,name=compressed,proto3" json:"compressed,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -134,26 +147,46 @@ func (m *ECP2) GetYb() []byte {
 	return nil
 }
 
+func (m *ECP2) GetCompressed() []byte {
+	if m != nil {
+		return m.Compressed
+	}
+	return nil
+}
+
 // IssuerPublicKey specifies an issuer public key that consists of
 // attribute_names - a list of the attribute names of a credential issued by the
 // issuer h_sk, h_rand, h_attrs, w, bar_g1, bar_g2 - group elements
 // corresponding to the signing key, randomness, and attributes proof_c, proof_s
 // compose a zero-knowledge proof of knowledge of the secret key hash is a hash
 // of the public key appended to it
+// h_attrs and bar_attrs hold g1^{y_i} and g2^{y_i} for every attribute i
+// tracing_pk - g1^{z}, the key the user public key is encrypted under for tracing
 type IssuerPublicKey struct {
-	AttributeNames       []string `protobuf:"bytes,1,rep,name=attribute_names,json=attributeNames,proto3" json:"attribute_names,omitempty"`
-	HSk                  *ECP     `protobuf:"bytes,2,opt,name=h_sk,json=hSk,proto3" json:"h_sk,omitempty"`
-	HRand                *ECP     `protobuf:"bytes,3,opt,name=h_rand,json=hRand,proto3" json:"h_rand,omitempty"`
-	BarX                 *ECP2    `protobuf:"bytes,4,opt,name=bar_x,json=barX,proto3" json:"bar_x,omitempty"`
-	BarY                 *ECP2    `protobuf:"bytes,5,opt,name=bar_y,json=barY,proto3" json:"bar_y,omitempty"`
-	BarG1                *ECP     `protobuf:"bytes,6,opt,name=bar_g1,json=barG1,proto3" json:"bar_g1,omitempty"`
-	BarG2                *ECP     `protobuf:"bytes,7,opt,name=bar_g2,json=barG2,proto3" json:"bar_g2,omitempty"`
-	BarG3                *ECP     `protobuf:"bytes,8,opt,name=bar_g3,json=barG3,proto3" json:"bar_g3,omitempty"`
-	ProofCX              []byte   `protobuf:"bytes,9,opt,name=proof_c_x,json=proofCX,proto3" json:"proof_c_x,omitempty"`
-	ProofSX              []byte   `protobuf:"bytes,10,opt,name=proof_s_x,json=proofSX,proto3" json:"proof_s_x,omitempty"`
-	ProofCY              []byte   `protobuf:"bytes,11,opt,name=proof_c_y,json=proofCY,proto3" json:"proof_c_y,omitempty"`
-	ProofSY              []byte   `protobuf:"bytes,12,opt,name=proof_s_y,json=proofSY,proto3" json:"proof_s_y,omitempty"`
-	Hash                 []byte   `protobuf:"bytes,13,opt,name=hash,proto3" json:"hash,omitempty"`
+	AttributeNames []string `protobuf:"bytes,1,rep,name=attribute_names,json=attributeNames,proto3" json:"attribute_names,omitempty"`
+	HSk            *ECP     `protobuf:"bytes,2,opt,name=h_sk,json=hSk,proto3" json:"h_sk,omitempty"`
+	HRand          *ECP     `protobuf:"bytes,3,opt,name=h_rand,json=hRand,proto3" json:"h_rand,omitempty"`
+	BarX           *ECP2    `protobuf:"bytes,4,opt,name=bar_x,json=barX,proto3" json:"bar_x,omitempty"`
+	BarY           *ECP2    `protobuf:"bytes,5,opt,name=bar_y,json=barY,proto3" json:"bar_y,omitempty"`
+	BarG1          *ECP     `protobuf:"bytes,6,opt,name=bar_g1,json=barG1,proto3" json:"bar_g1,omitempty"`
+	BarG2          *ECP     `protobuf:"bytes,7,opt,name=bar_g2,json=barG2,proto3" json:"bar_g2,omitempty"`
+	BarG3          *ECP     `protobuf:"bytes,8,opt,name=bar_g3,json=barG3,proto3" json:"bar_g3,omitempty"`
+	ProofCX        []byte   `protobuf:"bytes,9,opt,name=proof_c_x,json=proofCX,proto3" json:"proof_c_x,omitempty"`
+	ProofSX        []byte   `protobuf:"bytes,10,opt,name=proof_s_x,json=proofSX,proto3" json:"proof_s_x,omitempty"`
+	ProofCY        []byte   `protobuf:"bytes,11,opt,name=proof_c_y,json=proofCY,proto3" json:"proof_c_y,omitempty"`
+	ProofSY        []byte   `protobuf:"bytes,12,opt,name=proof_s_y,json=proofSY,proto3" json:"proof_s_y,omitempty"`
+	Hash           []byte   `protobuf:"bytes,13,opt,name=hash,proto3" json:"hash,omitempty"`
+	HAttrs         []*ECP   `protobuf:"bytes,14,rep,name=h_attrs,json=hAttrs,proto3" json:"h_attrs,omitempty"`
+	BarAttrs       []*ECP2  `protobuf:"bytes,15,rep,name=bar_attrs,json=barAttrs,proto3" json:"bar_attrs,omitempty"`
+	TracingPk      *ECP     `protobuf:"bytes,16,opt,name=tracing_pk,json=tracingPk,proto3" json:"tracing_pk,omitempty"`
+	// h_validity, bar_validity - the key components g1^{y_v}, g2^{y_v} that sign
+	// the validity window (not_before, not_after) of a credential
+	HValidity   []*ECP  `protobuf:"bytes,17,rep,name=h_validity,json=hValidity,proto3" json:"h_validity,omitempty"`
+	BarValidity []*ECP2 `protobuf:"bytes,18,rep,name=bar_validity,json=barValidity,proto3" json:"bar_validity,omitempty"`
+	// key_id - identifies the issuer key among the keys of an issuer, see IssuerKeyID
+	KeyId string `protobuf:"bytes,19,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	// proof_version - 1 if the proofs are made with a transcript, 0 for keys made before
+	ProofVersion         uint32   `protobuf:"varint,20,opt,name=proof_version,json=proofVersion,proto3" json:"proof_version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -275,9 +308,61 @@ func (m *IssuerPublicKey) GetHash() []byte {
 	return nil
 }
 
+func (m *IssuerPublicKey) GetHAttrs() []*ECP {
+	if m != nil {
+		return m.HAttrs
+	}
+	return nil
+}
+
+func (m *IssuerPublicKey) GetBarAttrs() []*ECP2 {
+	if m != nil {
+		return m.BarAttrs
+	}
+	return nil
+}
+
+func (m *IssuerPublicKey) GetTracingPk() *ECP {
+	if m != nil {
+		return m.TracingPk
+	}
+	return nil
+}
+
+func (m *IssuerPublicKey) GetHValidity() []*ECP {
+	if m != nil {
+		return m.HValidity
+	}
+	return nil
+}
+
+func (m *IssuerPublicKey) GetBarValidity() []*ECP2 {
+	if m != nil {
+		return m.BarValidity
+	}
+	return nil
+}
+
+func (m *IssuerPublicKey) GetKeyId() string {
+	if m != nil {
+		return m.KeyId
+	}
+	return ""
+}
+
+func (m *IssuerPublicKey) GetProofVersion() uint32 {
+	if m != nil {
+		return m.ProofVersion
+	}
+	return 0
+}
+
 type SecretKey struct {
 	X                    []byte   `protobuf:"bytes,1,opt,name=x,proto3" json:"x,omitempty"`
 	Y                    []byte   `protobuf:"bytes,2,opt,name=y,proto3" json:"y,omitempty"`
+	Attrs                [][]byte `protobuf:"bytes,3,rep,name=attrs,proto3" json:"attrs,omitempty"`
+	TracingSk            []byte   `protobuf:"bytes,4,opt,name=tracing_sk,json=tracingSk,proto3" json:"tracing_sk,omitempty"`
+	Validity             [][]byte `protobuf:"bytes,5,rep,name=validity,proto3" json:"validity,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -322,6 +407,27 @@ func (m *SecretKey) GetY() []byte {
 	return nil
 }
 
+func (m *SecretKey) GetAttrs() [][]byte {
+	if m != nil {
+		return m.Attrs
+	}
+	return nil
+}
+
+func (m *SecretKey) GetTracingSk() []byte {
+	if m != nil {
+		return m.TracingSk
+	}
+	return nil
+}
+
+func (m *SecretKey) GetValidity() [][]byte {
+	if m != nil {
+		return m.Validity
+	}
+	return nil
+}
+
 // IssuerKey specifies an issuer key pair that consists of
 // ISk - the issuer secret key and
 // IssuerPublicKey - the issuer public key
@@ -374,16 +480,18 @@ func (m *IssuerKey) GetIpk() *IssuerPublicKey {
 
 // ADD user key
 type UserPublicKey struct {
-	AttributeNames       []string `protobuf:"bytes,1,rep,name=attribute_names,json=attributeNames,proto3" json:"attribute_names,omitempty"`
-	HSk                  *ECP     `protobuf:"bytes,2,opt,name=h_sk,json=hSk,proto3" json:"h_sk,omitempty"`
-	HRand                *ECP     `protobuf:"bytes,3,opt,name=h_rand,json=hRand,proto3" json:"h_rand,omitempty"`
-	W                    *ECP2    `protobuf:"bytes,4,opt,name=w,proto3" json:"w,omitempty"`
-	BarG1                *ECP     `protobuf:"bytes,5,opt,name=bar_g1,json=barG1,proto3" json:"bar_g1,omitempty"`
-	BarG2                *ECP     `protobuf:"bytes,6,opt,name=bar_g2,json=barG2,proto3" json:"bar_g2,omitempty"`
-	ProofC               []byte   `protobuf:"bytes,7,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
-	ProofS               []byte   `protobuf:"bytes,8,opt,name=proof_s,json=proofS,proto3" json:"proof_s,omitempty"`
-	Hash                 []byte   `protobuf:"bytes,9,opt,name=hash,proto3" json:"hash,omitempty"`
-	UPK                  *ECP     `protobuf:"bytes,10,opt,name=UPK,proto3" json:"UPK,omitempty"`
+	AttributeNames []string `protobuf:"bytes,1,rep,name=attribute_names,json=attributeNames,proto3" json:"attribute_names,omitempty"`
+	HSk            *ECP     `protobuf:"bytes,2,opt,name=h_sk,json=hSk,proto3" json:"h_sk,omitempty"`
+	HRand          *ECP     `protobuf:"bytes,3,opt,name=h_rand,json=hRand,proto3" json:"h_rand,omitempty"`
+	W              *ECP2    `protobuf:"bytes,4,opt,name=w,proto3" json:"w,omitempty"`
+	BarG1          *ECP     `protobuf:"bytes,5,opt,name=bar_g1,json=barG1,proto3" json:"bar_g1,omitempty"`
+	BarG2          *ECP     `protobuf:"bytes,6,opt,name=bar_g2,json=barG2,proto3" json:"bar_g2,omitempty"`
+	ProofC         []byte   `protobuf:"bytes,7,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofS         []byte   `protobuf:"bytes,8,opt,name=proof_s,json=proofS,proto3" json:"proof_s,omitempty"`
+	Hash           []byte   `protobuf:"bytes,9,opt,name=hash,proto3" json:"hash,omitempty"`
+	UPK            *ECP     `protobuf:"bytes,10,opt,name=UPK,proto3" json:"UPK,omitempty"`
+	// proof_version - 1 if the proof is made with a transcript, 0 for keys made before
+	ProofVersion         uint32   `protobuf:"varint,11,opt,name=proof_version,json=proofVersion,proto3" json:"proof_version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -484,6 +592,13 @@ func (m *UserPublicKey) GetUPK() *ECP {
 	return nil
 }
 
+func (m *UserPublicKey) GetProofVersion() uint32 {
+	if m != nil {
+		return m.ProofVersion
+	}
+	return 0
+}
+
 type Trace struct {
 	T                    *ECP2          `protobuf:"bytes,1,opt,name=T,proto3" json:"T,omitempty"`
 	Upk                  *UserPublicKey `protobuf:"bytes,2,opt,name=upk,proto3" json:"upk,omitempty"`
@@ -657,59 +772,15 @@ func (m *UserKey) GetUpk() *UserPublicKey {
 	return nil
 }
 
-type SignedAttribute struct {
-	A                    *ECP     `protobuf:"bytes,1,opt,name=a,proto3" json:"a,omitempty"`
-	B                    *ECP     `protobuf:"bytes,2,opt,name=b,proto3" json:"b,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
-}
-
-func (m *SignedAttribute) Reset()         { *m = SignedAttribute{} }
-func (m *SignedAttribute) String() string { return proto.CompactTextString(m) }
-func (*SignedAttribute) ProtoMessage()    {}
-func (*SignedAttribute) Descriptor() ([]byte, []int) {
-	return fileDescriptor_28d23908e9a304c6, []int{10}
-}
-
-func (m *SignedAttribute) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_SignedAttribute.Unmarshal(m, b)
-}
-func (m *SignedAttribute) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_SignedAttribute.Marshal(b, m, deterministic)
-}
-func (m *SignedAttribute) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_SignedAttribute.Merge(m, src)
-}
-func (m *SignedAttribute) XXX_Size() int {
-	return xxx_messageInfo_SignedAttribute.Size(m)
-}
-func (m *SignedAttribute) XXX_DiscardUnknown() {
-	xxx_messageInfo_SignedAttribute.DiscardUnknown(m)
-}
-
-var xxx_messageInfo_SignedAttribute proto.InternalMessageInfo
-
-func (m *SignedAttribute) GetA() *ECP {
-	if m != nil {
-		return m.A
-	}
-	return nil
-}
-
-func (m *SignedAttribute) GetB() *ECP {
-	if m != nil {
-		return m.B
-	}
-	return nil
-}
-
+// HiddenAttribute specifies a hidden attribute of a NymSignature that consists of
+// com - a commitment sigma_1^{attr} * g1^{rand} to the attribute value
+// proof_c, proof_s_attr, proof_s_rand - a zero-knowledge proof of knowledge of
+// the opening of com
 type HiddenAttribute struct {
-	Sigma_1              *ECP     `protobuf:"bytes,1,opt,name=sigma_1,json=sigma1,proto3" json:"sigma_1,omitempty"`
-	Sigma_2              *ECP     `protobuf:"bytes,2,opt,name=sigma_2,json=sigma2,proto3" json:"sigma_2,omitempty"`
-	Sigma_3              *ECP     `protobuf:"bytes,3,opt,name=sigma_3,json=sigma3,proto3" json:"sigma_3,omitempty"`
-	ProofC               []byte   `protobuf:"bytes,4,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
-	ProofS               []byte   `protobuf:"bytes,5,opt,name=proof_s,json=proofS,proto3" json:"proof_s,omitempty"`
+	Com                  *ECP     `protobuf:"bytes,1,opt,name=com,proto3" json:"com,omitempty"`
+	ProofC               []byte   `protobuf:"bytes,2,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofSAttr           []byte   `protobuf:"bytes,3,opt,name=proof_s_attr,json=proofSAttr,proto3" json:"proof_s_attr,omitempty"`
+	ProofSRand           []byte   `protobuf:"bytes,4,opt,name=proof_s_rand,json=proofSRand,proto3" json:"proof_s_rand,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -719,7 +790,7 @@ func (m *HiddenAttribute) Reset()         { *m = HiddenAttribute{} }
 func (m *HiddenAttribute) String() string { return proto.CompactTextString(m) }
 func (*HiddenAttribute) ProtoMessage()    {}
 func (*HiddenAttribute) Descriptor() ([]byte, []int) {
-	return fileDescriptor_28d23908e9a304c6, []int{11}
+	return fileDescriptor_28d23908e9a304c6, []int{10}
 }
 
 func (m *HiddenAttribute) XXX_Unmarshal(b []byte) error {
@@ -740,58 +811,57 @@ func (m *HiddenAttribute) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_HiddenAttribute proto.InternalMessageInfo
 
-func (m *HiddenAttribute) GetSigma_1() *ECP {
+func (m *HiddenAttribute) GetCom() *ECP {
 	if m != nil {
-		return m.Sigma_1
-	}
-	return nil
-}
-
-func (m *HiddenAttribute) GetSigma_2() *ECP {
-	if m != nil {
-		return m.Sigma_2
+		return m.Com
 	}
 	return nil
 }
 
-func (m *HiddenAttribute) GetSigma_3() *ECP {
+func (m *HiddenAttribute) GetProofC() []byte {
 	if m != nil {
-		return m.Sigma_3
+		return m.ProofC
 	}
 	return nil
 }
 
-func (m *HiddenAttribute) GetProofC() []byte {
+func (m *HiddenAttribute) GetProofSAttr() []byte {
 	if m != nil {
-		return m.ProofC
+		return m.ProofSAttr
 	}
 	return nil
 }
 
-func (m *HiddenAttribute) GetProofS() []byte {
+func (m *HiddenAttribute) GetProofSRand() []byte {
 	if m != nil {
-		return m.ProofS
+		return m.ProofSRand
 	}
 	return nil
 }
 
 // Credential specifies a credential object that consists of
-// a, b, e, s - signature value
+// a, b - signature value on the user secret and all attribute values
 // attrs - attribute values
 type Credential struct {
-	Creds                []*SignedAttribute `protobuf:"bytes,1,rep,name=creds,proto3" json:"creds,omitempty"`
-	AttributeNames       []string           `protobuf:"bytes,2,rep,name=attribute_names,json=attributeNames,proto3" json:"attribute_names,omitempty"`
-	Attrs                [][]byte           `protobuf:"bytes,3,rep,name=attrs,proto3" json:"attrs,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
-	XXX_unrecognized     []byte             `json:"-"`
-	XXX_sizecache        int32              `json:"-"`
+	AttributeNames []string `protobuf:"bytes,2,rep,name=attribute_names,json=attributeNames,proto3" json:"attribute_names,omitempty"`
+	Attrs          [][]byte `protobuf:"bytes,3,rep,name=attrs,proto3" json:"attrs,omitempty"`
+	A              *ECP     `protobuf:"bytes,4,opt,name=a,proto3" json:"a,omitempty"`
+	B              *ECP     `protobuf:"bytes,5,opt,name=b,proto3" json:"b,omitempty"`
+	// not_before, not_after - the validity window of the credential in seconds since the epoch, signed in (A, B)
+	NotBefore int64 `protobuf:"varint,6,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"`
+	NotAfter  int64 `protobuf:"varint,7,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"`
+	// key_id - the identifier of the issuer key the credential is issued under
+	KeyId                string   `protobuf:"bytes,8,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Credential) Reset()         { *m = Credential{} }
 func (m *Credential) String() string { return proto.CompactTextString(m) }
 func (*Credential) ProtoMessage()    {}
 func (*Credential) Descriptor() ([]byte, []int) {
-	return fileDescriptor_28d23908e9a304c6, []int{12}
+	return fileDescriptor_28d23908e9a304c6, []int{11}
 }
 
 func (m *Credential) XXX_Unmarshal(b []byte) error {
@@ -812,27 +882,55 @@ func (m *Credential) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_Credential proto.InternalMessageInfo
 
-func (m *Credential) GetCreds() []*SignedAttribute {
+func (m *Credential) GetAttributeNames() []string {
 	if m != nil {
-		return m.Creds
+		return m.AttributeNames
 	}
 	return nil
 }
 
-func (m *Credential) GetAttributeNames() []string {
+func (m *Credential) GetAttrs() [][]byte {
 	if m != nil {
-		return m.AttributeNames
+		return m.Attrs
 	}
 	return nil
 }
 
-func (m *Credential) GetAttrs() [][]byte {
+func (m *Credential) GetA() *ECP {
 	if m != nil {
-		return m.Attrs
+		return m.A
+	}
+	return nil
+}
+
+func (m *Credential) GetB() *ECP {
+	if m != nil {
+		return m.B
 	}
 	return nil
 }
 
+func (m *Credential) GetNotBefore() int64 {
+	if m != nil {
+		return m.NotBefore
+	}
+	return 0
+}
+
+func (m *Credential) GetNotAfter() int64 {
+	if m != nil {
+		return m.NotAfter
+	}
+	return 0
+}
+
+func (m *Credential) GetKeyId() string {
+	if m != nil {
+		return m.KeyId
+	}
+	return ""
+}
+
 type NymSignature struct {
 	Eta   *ECP               `protobuf:"bytes,1,opt,name=eta,proto3" json:"eta,omitempty"`
 	Xi    *ECP               `protobuf:"bytes,2,opt,name=xi,proto3" json:"xi,omitempty"`
@@ -840,20 +938,63 @@ type NymSignature struct {
 	Attrs [][]byte           `protobuf:"bytes,4,rep,name=attrs,proto3" json:"attrs,omitempty"`
 	Nonce []byte             `protobuf:"bytes,5,opt,name=nonce,proto3" json:"nonce,omitempty"`
 	// TODO add code ========================
-	RevocationPkSig      []byte              `protobuf:"bytes,6,opt,name=revocation_pk_sig,json=revocationPkSig,proto3" json:"revocation_pk_sig,omitempty"`
-	Epoch                int64               `protobuf:"varint,7,opt,name=epoch,proto3" json:"epoch,omitempty"`
-	NonRevocationProof   *NonRevocationProof `protobuf:"bytes,8,opt,name=non_revocation_proof,json=nonRevocationProof,proto3" json:"non_revocation_proof,omitempty"`
-	RevocationEpochPk    *ECP2               `protobuf:"bytes,9,opt,name=revocation_epoch_pk,json=revocationEpochPk,proto3" json:"revocation_epoch_pk,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
+	RevocationPkSig    []byte              `protobuf:"bytes,6,opt,name=revocation_pk_sig,json=revocationPkSig,proto3" json:"revocation_pk_sig,omitempty"`
+	Epoch              int64               `protobuf:"varint,7,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	NonRevocationProof *NonRevocationProof `protobuf:"bytes,8,opt,name=non_revocation_proof,json=nonRevocationProof,proto3" json:"non_revocation_proof,omitempty"`
+	RevocationEpochPk  *ECP2               `protobuf:"bytes,9,opt,name=revocation_epoch_pk,json=revocationEpochPk,proto3" json:"revocation_epoch_pk,omitempty"`
+	// sigma_1, sigma_2 randomize the credential signature, sigma_3 = sigma_1^{sk}
+	// proof_c, proof_s prove that sigma_3 and eta share the same sk
+	Sigma_1 *ECP   `protobuf:"bytes,10,opt,name=sigma_1,json=sigma1,proto3" json:"sigma_1,omitempty"`
+	Sigma_2 *ECP   `protobuf:"bytes,11,opt,name=sigma_2,json=sigma2,proto3" json:"sigma_2,omitempty"`
+	Sigma_3 *ECP   `protobuf:"bytes,12,opt,name=sigma_3,json=sigma3,proto3" json:"sigma_3,omitempty"`
+	ProofC  []byte `protobuf:"bytes,13,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofS  []byte `protobuf:"bytes,14,opt,name=proof_s,json=proofS,proto3" json:"proof_s,omitempty"`
+	// disclosure marks the disclosed attributes with 1 and the hidden ones with 0
+	Disclosure []byte `protobuf:"bytes,15,opt,name=disclosure,proto3" json:"disclosure,omitempty"`
+	// trace_c1, trace_c2 - an ElGamal encryption (g1^k, upk * tracing_pk^k) of the
+	// user public key, proof_s_trace proves knowledge of k
+	TraceC1     *ECP   `protobuf:"bytes,16,opt,name=trace_c1,json=traceC1,proto3" json:"trace_c1,omitempty"`
+	TraceC2     *ECP   `protobuf:"bytes,17,opt,name=trace_c2,json=traceC2,proto3" json:"trace_c2,omitempty"`
+	ProofSTrace []byte `protobuf:"bytes,18,opt,name=proof_s_trace,json=proofSTrace,proto3" json:"proof_s_trace,omitempty"`
+	// scope, scope_nym - the scope the signature is made for and the pseudonym H(scope)^{sk}
+	// of the user in it, which is the same in every signature of the user in the scope.
+	// Both are empty for signatures without a scope.
+	Scope    []byte `protobuf:"bytes,19,opt,name=scope,proto3" json:"scope,omitempty"`
+	ScopeNym *ECP   `protobuf:"bytes,20,opt,name=scope_nym,json=scopeNym,proto3" json:"scope_nym,omitempty"`
+	// range_proofs prove predicates over hidden attributes without disclosing them
+	RangeProofs []*RangeProof `protobuf:"bytes,21,rep,name=range_proofs,json=rangeProofs,proto3" json:"range_proofs,omitempty"`
+	// set_proofs prove that hidden attributes belong to public sets
+	SetProofs []*SetMembershipProof `protobuf:"bytes,22,rep,name=set_proofs,json=setProofs,proto3" json:"set_proofs,omitempty"`
+	// valid_at - the time the signature proves the credential to be valid at
+	// validity_hides - commitments to not_before and not_after of the credential
+	// validity_proofs - proofs of not_before <= valid_at and valid_at <= not_after
+	ValidAt        int64              `protobuf:"varint,23,opt,name=valid_at,json=validAt,proto3" json:"valid_at,omitempty"`
+	ValidityHides  []*HiddenAttribute `protobuf:"bytes,24,rep,name=validity_hides,json=validityHides,proto3" json:"validity_hides,omitempty"`
+	ValidityProofs []*RangeProof      `protobuf:"bytes,25,rep,name=validity_proofs,json=validityProofs,proto3" json:"validity_proofs,omitempty"`
+	// key_id - the identifier of the issuer key the signature is made under
+	KeyId string `protobuf:"bytes,26,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	// version - the format of the signature, a signature without version has format 1.
+	// In format 1 every hidden attribute and validity value has its own proof in hides and validity_hides.
+	// In format 2 sigma_2 is blinded by sigma_1^{t} and one proof covers all of them:
+	// proof_s_hidden holds the responses of the hidden attributes followed by those of the validity window,
+	// proof_s_blind the response of t, and commitments the commitments to the hidden attributes and
+	// validity values that range, set and equality proofs refer to, all under the challenge proof_c.
+	// Formats 3 and 4 have the fields of formats 2 and 1 and derive the challenges of all their proofs from
+	// transcripts. Formats 1 and 2 are only accepted by verifiers that allow legacy proofs.
+	Version              uint32                 `protobuf:"varint,27,opt,name=version,proto3" json:"version,omitempty"`
+	ProofSHidden         [][]byte               `protobuf:"bytes,28,rep,name=proof_s_hidden,json=proofSHidden,proto3" json:"proof_s_hidden,omitempty"`
+	ProofSBlind          []byte                 `protobuf:"bytes,29,opt,name=proof_s_blind,json=proofSBlind,proto3" json:"proof_s_blind,omitempty"`
+	Commitments          []*AttributeCommitment `protobuf:"bytes,30,rep,name=commitments,proto3" json:"commitments,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
 }
 
 func (m *NymSignature) Reset()         { *m = NymSignature{} }
 func (m *NymSignature) String() string { return proto.CompactTextString(m) }
 func (*NymSignature) ProtoMessage()    {}
 func (*NymSignature) Descriptor() ([]byte, []int) {
-	return fileDescriptor_28d23908e9a304c6, []int{13}
+	return fileDescriptor_28d23908e9a304c6, []int{12}
 }
 
 func (m *NymSignature) XXX_Unmarshal(b []byte) error {
@@ -937,135 +1078,848 @@ func (m *NymSignature) GetRevocationEpochPk() *ECP2 {
 	return nil
 }
 
-// CredRequest specifies a credential request object that consists of
-// nym - a pseudonym, which is a commitment to the user secret
-// issuer_nonce - a random nonce provided by the issuer
-// proof_c, proof_s - a zero-knowledge proof of knowledge of the
-// user secret inside Nym
-type CredRequest struct {
-	Nym                  *ECP     `protobuf:"bytes,1,opt,name=nym,proto3" json:"nym,omitempty"`
-	IssuerNonce          []byte   `protobuf:"bytes,2,opt,name=issuer_nonce,json=issuerNonce,proto3" json:"issuer_nonce,omitempty"`
-	ProofC               []byte   `protobuf:"bytes,3,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
-	ProofS1              []byte   `protobuf:"bytes,4,opt,name=proof_s1,json=proofS1,proto3" json:"proof_s1,omitempty"`
-	ProofS2              []byte   `protobuf:"bytes,5,opt,name=proof_s2,json=proofS2,proto3" json:"proof_s2,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *NymSignature) GetSigma_1() *ECP {
+	if m != nil {
+		return m.Sigma_1
+	}
+	return nil
 }
 
-func (m *CredRequest) Reset()         { *m = CredRequest{} }
-func (m *CredRequest) String() string { return proto.CompactTextString(m) }
-func (*CredRequest) ProtoMessage()    {}
-func (*CredRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_28d23908e9a304c6, []int{14}
+func (m *NymSignature) GetSigma_2() *ECP {
+	if m != nil {
+		return m.Sigma_2
+	}
+	return nil
 }
 
-func (m *CredRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_CredRequest.Unmarshal(m, b)
-}
-func (m *CredRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_CredRequest.Marshal(b, m, deterministic)
-}
-func (m *CredRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_CredRequest.Merge(m, src)
-}
-func (m *CredRequest) XXX_Size() int {
-	return xxx_messageInfo_CredRequest.Size(m)
-}
-func (m *CredRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_CredRequest.DiscardUnknown(m)
+func (m *NymSignature) GetSigma_3() *ECP {
+	if m != nil {
+		return m.Sigma_3
+	}
+	return nil
 }
 
-var xxx_messageInfo_CredRequest proto.InternalMessageInfo
-
-func (m *CredRequest) GetNym() *ECP {
+func (m *NymSignature) GetProofC() []byte {
 	if m != nil {
-		return m.Nym
+		return m.ProofC
 	}
 	return nil
 }
 
-func (m *CredRequest) GetIssuerNonce() []byte {
+func (m *NymSignature) GetProofS() []byte {
 	if m != nil {
-		return m.IssuerNonce
+		return m.ProofS
 	}
 	return nil
 }
 
-func (m *CredRequest) GetProofC() []byte {
+func (m *NymSignature) GetDisclosure() []byte {
 	if m != nil {
-		return m.ProofC
+		return m.Disclosure
 	}
 	return nil
 }
 
-func (m *CredRequest) GetProofS1() []byte {
+func (m *NymSignature) GetTraceC1() *ECP {
 	if m != nil {
-		return m.ProofS1
+		return m.TraceC1
 	}
 	return nil
 }
 
-func (m *CredRequest) GetProofS2() []byte {
+func (m *NymSignature) GetTraceC2() *ECP {
 	if m != nil {
-		return m.ProofS2
+		return m.TraceC2
 	}
 	return nil
 }
 
-// NonRevocationProof contains proof that the credential is not revoked
-type NonRevocationProof struct {
-	RevocationAlg        int32    `protobuf:"varint,1,opt,name=revocation_alg,json=revocationAlg,proto3" json:"revocation_alg,omitempty"`
-	NonRevocationProof   []byte   `protobuf:"bytes,2,opt,name=non_revocation_proof,json=nonRevocationProof,proto3" json:"non_revocation_proof,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *NymSignature) GetProofSTrace() []byte {
+	if m != nil {
+		return m.ProofSTrace
+	}
+	return nil
 }
 
-func (m *NonRevocationProof) Reset()         { *m = NonRevocationProof{} }
-func (m *NonRevocationProof) String() string { return proto.CompactTextString(m) }
-func (*NonRevocationProof) ProtoMessage()    {}
-func (*NonRevocationProof) Descriptor() ([]byte, []int) {
-	return fileDescriptor_28d23908e9a304c6, []int{15}
+func (m *NymSignature) GetScope() []byte {
+	if m != nil {
+		return m.Scope
+	}
+	return nil
 }
 
-func (m *NonRevocationProof) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_NonRevocationProof.Unmarshal(m, b)
-}
-func (m *NonRevocationProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_NonRevocationProof.Marshal(b, m, deterministic)
-}
-func (m *NonRevocationProof) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_NonRevocationProof.Merge(m, src)
-}
-func (m *NonRevocationProof) XXX_Size() int {
-	return xxx_messageInfo_NonRevocationProof.Size(m)
+func (m *NymSignature) GetScopeNym() *ECP {
+	if m != nil {
+		return m.ScopeNym
+	}
+	return nil
 }
-func (m *NonRevocationProof) XXX_DiscardUnknown() {
-	xxx_messageInfo_NonRevocationProof.DiscardUnknown(m)
+
+func (m *NymSignature) GetRangeProofs() []*RangeProof {
+	if m != nil {
+		return m.RangeProofs
+	}
+	return nil
 }
 
-var xxx_messageInfo_NonRevocationProof proto.InternalMessageInfo
+func (m *NymSignature) GetSetProofs() []*SetMembershipProof {
+	if m != nil {
+		return m.SetProofs
+	}
+	return nil
+}
 
-func (m *NonRevocationProof) GetRevocationAlg() int32 {
+func (m *NymSignature) GetValidAt() int64 {
 	if m != nil {
-		return m.RevocationAlg
+		return m.ValidAt
 	}
 	return 0
 }
 
-func (m *NonRevocationProof) GetNonRevocationProof() []byte {
+func (m *NymSignature) GetValidityHides() []*HiddenAttribute {
 	if m != nil {
-		return m.NonRevocationProof
+		return m.ValidityHides
 	}
 	return nil
 }
 
-type CredentialRevocationInformation struct {
-	// epoch contains the epoch (time window) in which this CRI is valid
-	Epoch int64 `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
-	// epoch_pk is the public key that is used by the revocation authority in this
-	// epoch
+func (m *NymSignature) GetValidityProofs() []*RangeProof {
+	if m != nil {
+		return m.ValidityProofs
+	}
+	return nil
+}
+
+func (m *NymSignature) GetKeyId() string {
+	if m != nil {
+		return m.KeyId
+	}
+	return ""
+}
+
+func (m *NymSignature) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *NymSignature) GetProofSHidden() [][]byte {
+	if m != nil {
+		return m.ProofSHidden
+	}
+	return nil
+}
+
+func (m *NymSignature) GetProofSBlind() []byte {
+	if m != nil {
+		return m.ProofSBlind
+	}
+	return nil
+}
+
+func (m *NymSignature) GetCommitments() []*AttributeCommitment {
+	if m != nil {
+		return m.Commitments
+	}
+	return nil
+}
+
+// AttributeCommitment is a commitment com = sigma_1^{attr} * g_1^{rho} to the hidden attribute at index attribute,
+// the validity window follows the attributes of the credential.
+// proof_s_rand is the response of rho in the proof of the NymSignature.
+type AttributeCommitment struct {
+	Attribute            int64    `protobuf:"varint,1,opt,name=attribute,proto3" json:"attribute,omitempty"`
+	Com                  *ECP     `protobuf:"bytes,2,opt,name=com,proto3" json:"com,omitempty"`
+	ProofSRand           []byte   `protobuf:"bytes,3,opt,name=proof_s_rand,json=proofSRand,proto3" json:"proof_s_rand,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AttributeCommitment) Reset()         { *m = AttributeCommitment{} }
+func (m *AttributeCommitment) String() string { return proto.CompactTextString(m) }
+func (*AttributeCommitment) ProtoMessage()    {}
+func (*AttributeCommitment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{13}
+}
+
+func (m *AttributeCommitment) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AttributeCommitment.Unmarshal(m, b)
+}
+func (m *AttributeCommitment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AttributeCommitment.Marshal(b, m, deterministic)
+}
+func (m *AttributeCommitment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AttributeCommitment.Merge(m, src)
+}
+func (m *AttributeCommitment) XXX_Size() int {
+	return xxx_messageInfo_AttributeCommitment.Size(m)
+}
+func (m *AttributeCommitment) XXX_DiscardUnknown() {
+	xxx_messageInfo_AttributeCommitment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AttributeCommitment proto.InternalMessageInfo
+
+func (m *AttributeCommitment) GetAttribute() int64 {
+	if m != nil {
+		return m.Attribute
+	}
+	return 0
+}
+
+func (m *AttributeCommitment) GetCom() *ECP {
+	if m != nil {
+		return m.Com
+	}
+	return nil
+}
+
+func (m *AttributeCommitment) GetProofSRand() []byte {
+	if m != nil {
+		return m.ProofSRand
+	}
+	return nil
+}
+
+// SetMembershipProof proves that the hidden attribute at index attribute is one of the values in set
+// proof_c, proof_s - for every value of the set the challenge and response of an OR proof,
+// the challenges add up to the challenge of the proof
+type SetMembershipProof struct {
+	Attribute            int64    `protobuf:"varint,1,opt,name=attribute,proto3" json:"attribute,omitempty"`
+	Set                  [][]byte `protobuf:"bytes,2,rep,name=set,proto3" json:"set,omitempty"`
+	ProofC               [][]byte `protobuf:"bytes,3,rep,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofS               [][]byte `protobuf:"bytes,4,rep,name=proof_s,json=proofS,proto3" json:"proof_s,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetMembershipProof) Reset()         { *m = SetMembershipProof{} }
+func (m *SetMembershipProof) String() string { return proto.CompactTextString(m) }
+func (*SetMembershipProof) ProtoMessage()    {}
+func (*SetMembershipProof) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{14}
+}
+
+func (m *SetMembershipProof) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetMembershipProof.Unmarshal(m, b)
+}
+func (m *SetMembershipProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetMembershipProof.Marshal(b, m, deterministic)
+}
+func (m *SetMembershipProof) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetMembershipProof.Merge(m, src)
+}
+func (m *SetMembershipProof) XXX_Size() int {
+	return xxx_messageInfo_SetMembershipProof.Size(m)
+}
+func (m *SetMembershipProof) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetMembershipProof.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetMembershipProof proto.InternalMessageInfo
+
+func (m *SetMembershipProof) GetAttribute() int64 {
+	if m != nil {
+		return m.Attribute
+	}
+	return 0
+}
+
+func (m *SetMembershipProof) GetSet() [][]byte {
+	if m != nil {
+		return m.Set
+	}
+	return nil
+}
+
+func (m *SetMembershipProof) GetProofC() [][]byte {
+	if m != nil {
+		return m.ProofC
+	}
+	return nil
+}
+
+func (m *SetMembershipProof) GetProofS() [][]byte {
+	if m != nil {
+		return m.ProofS
+	}
+	return nil
+}
+
+// LinkedNymSignature is a signature with several credentials of the same user
+// signatures - a NymSignature with each of the credentials
+// equalities - proofs that hidden attributes of two of the credentials are equal
+// proof_c, proof_s_sk - a proof that all signatures are made with the same user secret
+type LinkedNymSignature struct {
+	Signatures           []*NymSignature           `protobuf:"bytes,1,rep,name=signatures,proto3" json:"signatures,omitempty"`
+	Equalities           []*AttributeEqualityProof `protobuf:"bytes,2,rep,name=equalities,proto3" json:"equalities,omitempty"`
+	ProofC               []byte                    `protobuf:"bytes,3,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofSSk             []byte                    `protobuf:"bytes,4,opt,name=proof_s_sk,json=proofSSk,proto3" json:"proof_s_sk,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
+	XXX_unrecognized     []byte                    `json:"-"`
+	XXX_sizecache        int32                     `json:"-"`
+}
+
+func (m *LinkedNymSignature) Reset()         { *m = LinkedNymSignature{} }
+func (m *LinkedNymSignature) String() string { return proto.CompactTextString(m) }
+func (*LinkedNymSignature) ProtoMessage()    {}
+func (*LinkedNymSignature) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{15}
+}
+
+func (m *LinkedNymSignature) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LinkedNymSignature.Unmarshal(m, b)
+}
+func (m *LinkedNymSignature) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LinkedNymSignature.Marshal(b, m, deterministic)
+}
+func (m *LinkedNymSignature) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LinkedNymSignature.Merge(m, src)
+}
+func (m *LinkedNymSignature) XXX_Size() int {
+	return xxx_messageInfo_LinkedNymSignature.Size(m)
+}
+func (m *LinkedNymSignature) XXX_DiscardUnknown() {
+	xxx_messageInfo_LinkedNymSignature.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LinkedNymSignature proto.InternalMessageInfo
+
+func (m *LinkedNymSignature) GetSignatures() []*NymSignature {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
+func (m *LinkedNymSignature) GetEqualities() []*AttributeEqualityProof {
+	if m != nil {
+		return m.Equalities
+	}
+	return nil
+}
+
+func (m *LinkedNymSignature) GetProofC() []byte {
+	if m != nil {
+		return m.ProofC
+	}
+	return nil
+}
+
+func (m *LinkedNymSignature) GetProofSSk() []byte {
+	if m != nil {
+		return m.ProofSSk
+	}
+	return nil
+}
+
+// AttributeEqualityProof proves that the hidden attribute attribute_1 of the signature credential_1
+// equals the hidden attribute attribute_2 of the signature credential_2 of a LinkedNymSignature
+// proof_s_attr, proof_s_rand_1, proof_s_rand_2 - the responses for the attribute and the randomness of both commitments
+type AttributeEqualityProof struct {
+	Credential_1         int64    `protobuf:"varint,1,opt,name=credential_1,json=credential1,proto3" json:"credential_1,omitempty"`
+	Attribute_1          int64    `protobuf:"varint,2,opt,name=attribute_1,json=attribute1,proto3" json:"attribute_1,omitempty"`
+	Credential_2         int64    `protobuf:"varint,3,opt,name=credential_2,json=credential2,proto3" json:"credential_2,omitempty"`
+	Attribute_2          int64    `protobuf:"varint,4,opt,name=attribute_2,json=attribute2,proto3" json:"attribute_2,omitempty"`
+	ProofSAttr           []byte   `protobuf:"bytes,5,opt,name=proof_s_attr,json=proofSAttr,proto3" json:"proof_s_attr,omitempty"`
+	ProofSRand_1         []byte   `protobuf:"bytes,6,opt,name=proof_s_rand_1,json=proofSRand1,proto3" json:"proof_s_rand_1,omitempty"`
+	ProofSRand_2         []byte   `protobuf:"bytes,7,opt,name=proof_s_rand_2,json=proofSRand2,proto3" json:"proof_s_rand_2,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AttributeEqualityProof) Reset()         { *m = AttributeEqualityProof{} }
+func (m *AttributeEqualityProof) String() string { return proto.CompactTextString(m) }
+func (*AttributeEqualityProof) ProtoMessage()    {}
+func (*AttributeEqualityProof) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{16}
+}
+
+func (m *AttributeEqualityProof) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AttributeEqualityProof.Unmarshal(m, b)
+}
+func (m *AttributeEqualityProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AttributeEqualityProof.Marshal(b, m, deterministic)
+}
+func (m *AttributeEqualityProof) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AttributeEqualityProof.Merge(m, src)
+}
+func (m *AttributeEqualityProof) XXX_Size() int {
+	return xxx_messageInfo_AttributeEqualityProof.Size(m)
+}
+func (m *AttributeEqualityProof) XXX_DiscardUnknown() {
+	xxx_messageInfo_AttributeEqualityProof.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AttributeEqualityProof proto.InternalMessageInfo
+
+func (m *AttributeEqualityProof) GetCredential_1() int64 {
+	if m != nil {
+		return m.Credential_1
+	}
+	return 0
+}
+
+func (m *AttributeEqualityProof) GetAttribute_1() int64 {
+	if m != nil {
+		return m.Attribute_1
+	}
+	return 0
+}
+
+func (m *AttributeEqualityProof) GetCredential_2() int64 {
+	if m != nil {
+		return m.Credential_2
+	}
+	return 0
+}
+
+func (m *AttributeEqualityProof) GetAttribute_2() int64 {
+	if m != nil {
+		return m.Attribute_2
+	}
+	return 0
+}
+
+func (m *AttributeEqualityProof) GetProofSAttr() []byte {
+	if m != nil {
+		return m.ProofSAttr
+	}
+	return nil
+}
+
+func (m *AttributeEqualityProof) GetProofSRand_1() []byte {
+	if m != nil {
+		return m.ProofSRand_1
+	}
+	return nil
+}
+
+func (m *AttributeEqualityProof) GetProofSRand_2() []byte {
+	if m != nil {
+		return m.ProofSRand_2
+	}
+	return nil
+}
+
+// RangeProof proves that the hidden attribute at index attribute is at least bound,
+// or at most bound if upper is set, where the difference is less than 2^RangeBits.
+// bits - commitments g_1^{b_j} h^{r_j} to the bits of the difference
+// bit_c0, bit_s0, bit_s1 - for every bit a proof that it commits to 0 or 1
+// proof_c, proof_s_attr, proof_s_rand, proof_s_bits - a proof that the bits add up to
+// the difference of bound and the attribute committed in the NymSignature
+type RangeProof struct {
+	Attribute            int64    `protobuf:"varint,1,opt,name=attribute,proto3" json:"attribute,omitempty"`
+	Bound                []byte   `protobuf:"bytes,2,opt,name=bound,proto3" json:"bound,omitempty"`
+	Upper                bool     `protobuf:"varint,3,opt,name=upper,proto3" json:"upper,omitempty"`
+	Bits                 []*ECP   `protobuf:"bytes,4,rep,name=bits,proto3" json:"bits,omitempty"`
+	BitC0                [][]byte `protobuf:"bytes,5,rep,name=bit_c0,json=bitC0,proto3" json:"bit_c0,omitempty"`
+	BitS0                [][]byte `protobuf:"bytes,6,rep,name=bit_s0,json=bitS0,proto3" json:"bit_s0,omitempty"`
+	BitS1                [][]byte `protobuf:"bytes,7,rep,name=bit_s1,json=bitS1,proto3" json:"bit_s1,omitempty"`
+	ProofC               []byte   `protobuf:"bytes,8,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofSAttr           []byte   `protobuf:"bytes,9,opt,name=proof_s_attr,json=proofSAttr,proto3" json:"proof_s_attr,omitempty"`
+	ProofSRand           []byte   `protobuf:"bytes,10,opt,name=proof_s_rand,json=proofSRand,proto3" json:"proof_s_rand,omitempty"`
+	ProofSBits           []byte   `protobuf:"bytes,11,opt,name=proof_s_bits,json=proofSBits,proto3" json:"proof_s_bits,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RangeProof) Reset()         { *m = RangeProof{} }
+func (m *RangeProof) String() string { return proto.CompactTextString(m) }
+func (*RangeProof) ProtoMessage()    {}
+func (*RangeProof) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{17}
+}
+
+func (m *RangeProof) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RangeProof.Unmarshal(m, b)
+}
+func (m *RangeProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RangeProof.Marshal(b, m, deterministic)
+}
+func (m *RangeProof) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RangeProof.Merge(m, src)
+}
+func (m *RangeProof) XXX_Size() int {
+	return xxx_messageInfo_RangeProof.Size(m)
+}
+func (m *RangeProof) XXX_DiscardUnknown() {
+	xxx_messageInfo_RangeProof.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RangeProof proto.InternalMessageInfo
+
+func (m *RangeProof) GetAttribute() int64 {
+	if m != nil {
+		return m.Attribute
+	}
+	return 0
+}
+
+func (m *RangeProof) GetBound() []byte {
+	if m != nil {
+		return m.Bound
+	}
+	return nil
+}
+
+func (m *RangeProof) GetUpper() bool {
+	if m != nil {
+		return m.Upper
+	}
+	return false
+}
+
+func (m *RangeProof) GetBits() []*ECP {
+	if m != nil {
+		return m.Bits
+	}
+	return nil
+}
+
+func (m *RangeProof) GetBitC0() [][]byte {
+	if m != nil {
+		return m.BitC0
+	}
+	return nil
+}
+
+func (m *RangeProof) GetBitS0() [][]byte {
+	if m != nil {
+		return m.BitS0
+	}
+	return nil
+}
+
+func (m *RangeProof) GetBitS1() [][]byte {
+	if m != nil {
+		return m.BitS1
+	}
+	return nil
+}
+
+func (m *RangeProof) GetProofC() []byte {
+	if m != nil {
+		return m.ProofC
+	}
+	return nil
+}
+
+func (m *RangeProof) GetProofSAttr() []byte {
+	if m != nil {
+		return m.ProofSAttr
+	}
+	return nil
+}
+
+func (m *RangeProof) GetProofSRand() []byte {
+	if m != nil {
+		return m.ProofSRand
+	}
+	return nil
+}
+
+func (m *RangeProof) GetProofSBits() []byte {
+	if m != nil {
+		return m.ProofSBits
+	}
+	return nil
+}
+
+// CredRequest specifies a credential request object that consists of
+// nym - a pseudonym, which is a commitment to the user secret
+// issuer_nonce - a random nonce provided by the issuer
+// proof_c, proof_s - a zero-knowledge proof of knowledge of the
+// user secret inside Nym
+type CredRequest struct {
+	Nym         *ECP   `protobuf:"bytes,1,opt,name=nym,proto3" json:"nym,omitempty"`
+	IssuerNonce []byte `protobuf:"bytes,2,opt,name=issuer_nonce,json=issuerNonce,proto3" json:"issuer_nonce,omitempty"`
+	ProofC      []byte `protobuf:"bytes,3,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofS1     []byte `protobuf:"bytes,4,opt,name=proof_s1,json=proofS1,proto3" json:"proof_s1,omitempty"`
+	ProofS2     []byte `protobuf:"bytes,5,opt,name=proof_s2,json=proofS2,proto3" json:"proof_s2,omitempty"`
+	// proof_version - 1 if the proof is made with a transcript, requests made before are not accepted
+	ProofVersion         uint32   `protobuf:"varint,6,opt,name=proof_version,json=proofVersion,proto3" json:"proof_version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CredRequest) Reset()         { *m = CredRequest{} }
+func (m *CredRequest) String() string { return proto.CompactTextString(m) }
+func (*CredRequest) ProtoMessage()    {}
+func (*CredRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{18}
+}
+
+func (m *CredRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CredRequest.Unmarshal(m, b)
+}
+func (m *CredRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CredRequest.Marshal(b, m, deterministic)
+}
+func (m *CredRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CredRequest.Merge(m, src)
+}
+func (m *CredRequest) XXX_Size() int {
+	return xxx_messageInfo_CredRequest.Size(m)
+}
+func (m *CredRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CredRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CredRequest proto.InternalMessageInfo
+
+func (m *CredRequest) GetNym() *ECP {
+	if m != nil {
+		return m.Nym
+	}
+	return nil
+}
+
+func (m *CredRequest) GetIssuerNonce() []byte {
+	if m != nil {
+		return m.IssuerNonce
+	}
+	return nil
+}
+
+func (m *CredRequest) GetProofC() []byte {
+	if m != nil {
+		return m.ProofC
+	}
+	return nil
+}
+
+func (m *CredRequest) GetProofS1() []byte {
+	if m != nil {
+		return m.ProofS1
+	}
+	return nil
+}
+
+func (m *CredRequest) GetProofS2() []byte {
+	if m != nil {
+		return m.ProofS2
+	}
+	return nil
+}
+
+func (m *CredRequest) GetProofVersion() uint32 {
+	if m != nil {
+		return m.ProofVersion
+	}
+	return 0
+}
+
+// NonRevocationProof contains proof that the credential is not revoked
+type NonRevocationProof struct {
+	RevocationAlg        int32    `protobuf:"varint,1,opt,name=revocation_alg,json=revocationAlg,proto3" json:"revocation_alg,omitempty"`
+	NonRevocationProof   []byte   `protobuf:"bytes,2,opt,name=non_revocation_proof,json=nonRevocationProof,proto3" json:"non_revocation_proof,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NonRevocationProof) Reset()         { *m = NonRevocationProof{} }
+func (m *NonRevocationProof) String() string { return proto.CompactTextString(m) }
+func (*NonRevocationProof) ProtoMessage()    {}
+func (*NonRevocationProof) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{19}
+}
+
+func (m *NonRevocationProof) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NonRevocationProof.Unmarshal(m, b)
+}
+func (m *NonRevocationProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NonRevocationProof.Marshal(b, m, deterministic)
+}
+func (m *NonRevocationProof) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NonRevocationProof.Merge(m, src)
+}
+func (m *NonRevocationProof) XXX_Size() int {
+	return xxx_messageInfo_NonRevocationProof.Size(m)
+}
+func (m *NonRevocationProof) XXX_DiscardUnknown() {
+	xxx_messageInfo_NonRevocationProof.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NonRevocationProof proto.InternalMessageInfo
+
+func (m *NonRevocationProof) GetRevocationAlg() int32 {
+	if m != nil {
+		return m.RevocationAlg
+	}
+	return 0
+}
+
+func (m *NonRevocationProof) GetNonRevocationProof() []byte {
+	if m != nil {
+		return m.NonRevocationProof
+	}
+	return nil
+}
+
+// PlainSigNonRevokedProof proves knowledge of a weak Boneh-Boyen signature
+// of the epoch key on the hidden revocation handle, where
+// sigma_prime = sigma^r, sigma_bar = sigma_prime^{-rh} * g1^r and
+// proof_s_r is the response for r (the response for rh is shared with the
+// hidden attribute holding the revocation handle)
+type PlainSigNonRevokedProof struct {
+	SigmaPrime           *ECP     `protobuf:"bytes,1,opt,name=sigma_prime,json=sigmaPrime,proto3" json:"sigma_prime,omitempty"`
+	SigmaBar             *ECP     `protobuf:"bytes,2,opt,name=sigma_bar,json=sigmaBar,proto3" json:"sigma_bar,omitempty"`
+	ProofSR              []byte   `protobuf:"bytes,3,opt,name=proof_s_r,json=proofSR,proto3" json:"proof_s_r,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PlainSigNonRevokedProof) Reset()         { *m = PlainSigNonRevokedProof{} }
+func (m *PlainSigNonRevokedProof) String() string { return proto.CompactTextString(m) }
+func (*PlainSigNonRevokedProof) ProtoMessage()    {}
+func (*PlainSigNonRevokedProof) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{20}
+}
+
+func (m *PlainSigNonRevokedProof) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PlainSigNonRevokedProof.Unmarshal(m, b)
+}
+func (m *PlainSigNonRevokedProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PlainSigNonRevokedProof.Marshal(b, m, deterministic)
+}
+func (m *PlainSigNonRevokedProof) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PlainSigNonRevokedProof.Merge(m, src)
+}
+func (m *PlainSigNonRevokedProof) XXX_Size() int {
+	return xxx_messageInfo_PlainSigNonRevokedProof.Size(m)
+}
+func (m *PlainSigNonRevokedProof) XXX_DiscardUnknown() {
+	xxx_messageInfo_PlainSigNonRevokedProof.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PlainSigNonRevokedProof proto.InternalMessageInfo
+
+func (m *PlainSigNonRevokedProof) GetSigmaPrime() *ECP {
+	if m != nil {
+		return m.SigmaPrime
+	}
+	return nil
+}
+
+func (m *PlainSigNonRevokedProof) GetSigmaBar() *ECP {
+	if m != nil {
+		return m.SigmaBar
+	}
+	return nil
+}
+
+func (m *PlainSigNonRevokedProof) GetProofSR() []byte {
+	if m != nil {
+		return m.ProofSR
+	}
+	return nil
+}
+
+// MessageSignature is a weak Boneh-Boyen signature rh_signature on a
+// revocation handle
+type MessageSignature struct {
+	RevocationHandle     []byte   `protobuf:"bytes,1,opt,name=revocation_handle,json=revocationHandle,proto3" json:"revocation_handle,omitempty"`
+	RhSignature          *ECP     `protobuf:"bytes,2,opt,name=rh_signature,json=rhSignature,proto3" json:"rh_signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MessageSignature) Reset()         { *m = MessageSignature{} }
+func (m *MessageSignature) String() string { return proto.CompactTextString(m) }
+func (*MessageSignature) ProtoMessage()    {}
+func (*MessageSignature) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{21}
+}
+
+func (m *MessageSignature) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MessageSignature.Unmarshal(m, b)
+}
+func (m *MessageSignature) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MessageSignature.Marshal(b, m, deterministic)
+}
+func (m *MessageSignature) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MessageSignature.Merge(m, src)
+}
+func (m *MessageSignature) XXX_Size() int {
+	return xxx_messageInfo_MessageSignature.Size(m)
+}
+func (m *MessageSignature) XXX_DiscardUnknown() {
+	xxx_messageInfo_MessageSignature.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MessageSignature proto.InternalMessageInfo
+
+func (m *MessageSignature) GetRevocationHandle() []byte {
+	if m != nil {
+		return m.RevocationHandle
+	}
+	return nil
+}
+
+func (m *MessageSignature) GetRhSignature() *ECP {
+	if m != nil {
+		return m.RhSignature
+	}
+	return nil
+}
+
+// PlainSigRevocationData is the revocation_data of a CRI for the plain
+// signature revocation algorithm, it holds a signature for every unrevoked
+// handle of the epoch
+type PlainSigRevocationData struct {
+	Signatures           []*MessageSignature `protobuf:"bytes,1,rep,name=signatures,proto3" json:"signatures,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *PlainSigRevocationData) Reset()         { *m = PlainSigRevocationData{} }
+func (m *PlainSigRevocationData) String() string { return proto.CompactTextString(m) }
+func (*PlainSigRevocationData) ProtoMessage()    {}
+func (*PlainSigRevocationData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{22}
+}
+
+func (m *PlainSigRevocationData) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PlainSigRevocationData.Unmarshal(m, b)
+}
+func (m *PlainSigRevocationData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PlainSigRevocationData.Marshal(b, m, deterministic)
+}
+func (m *PlainSigRevocationData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PlainSigRevocationData.Merge(m, src)
+}
+func (m *PlainSigRevocationData) XXX_Size() int {
+	return xxx_messageInfo_PlainSigRevocationData.Size(m)
+}
+func (m *PlainSigRevocationData) XXX_DiscardUnknown() {
+	xxx_messageInfo_PlainSigRevocationData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PlainSigRevocationData proto.InternalMessageInfo
+
+func (m *PlainSigRevocationData) GetSignatures() []*MessageSignature {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
+type CredentialRevocationInformation struct {
+	// epoch contains the epoch (time window) in which this CRI is valid
+	Epoch int64 `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// epoch_pk is the public key that is used by the revocation authority in this
+	// epoch
 	EpochPk *ECP2 `protobuf:"bytes,2,opt,name=epoch_pk,json=epochPk,proto3" json:"epoch_pk,omitempty"`
 	// epoch_pk_sig is a signature on the EpochPK valid under the revocation
 	// authority's long term key
@@ -1079,62 +1933,1131 @@ type CredentialRevocationInformation struct {
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *CredentialRevocationInformation) Reset()         { *m = CredentialRevocationInformation{} }
-func (m *CredentialRevocationInformation) String() string { return proto.CompactTextString(m) }
-func (*CredentialRevocationInformation) ProtoMessage()    {}
-func (*CredentialRevocationInformation) Descriptor() ([]byte, []int) {
-	return fileDescriptor_28d23908e9a304c6, []int{16}
+func (m *CredentialRevocationInformation) Reset()         { *m = CredentialRevocationInformation{} }
+func (m *CredentialRevocationInformation) String() string { return proto.CompactTextString(m) }
+func (*CredentialRevocationInformation) ProtoMessage()    {}
+func (*CredentialRevocationInformation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{23}
+}
+
+func (m *CredentialRevocationInformation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CredentialRevocationInformation.Unmarshal(m, b)
+}
+func (m *CredentialRevocationInformation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CredentialRevocationInformation.Marshal(b, m, deterministic)
+}
+func (m *CredentialRevocationInformation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CredentialRevocationInformation.Merge(m, src)
+}
+func (m *CredentialRevocationInformation) XXX_Size() int {
+	return xxx_messageInfo_CredentialRevocationInformation.Size(m)
+}
+func (m *CredentialRevocationInformation) XXX_DiscardUnknown() {
+	xxx_messageInfo_CredentialRevocationInformation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CredentialRevocationInformation proto.InternalMessageInfo
+
+func (m *CredentialRevocationInformation) GetEpoch() int64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *CredentialRevocationInformation) GetEpochPk() *ECP2 {
+	if m != nil {
+		return m.EpochPk
+	}
+	return nil
+}
+
+func (m *CredentialRevocationInformation) GetEpochPkSig() []byte {
+	if m != nil {
+		return m.EpochPkSig
+	}
+	return nil
+}
+
+func (m *CredentialRevocationInformation) GetRevocationAlg() int32 {
+	if m != nil {
+		return m.RevocationAlg
+	}
+	return 0
+}
+
+func (m *CredentialRevocationInformation) GetRevocationData() []byte {
+	if m != nil {
+		return m.RevocationData
+	}
+	return nil
+}
+
+// ArbitratorKey is the share z_j = f(j) of the tracing secret z = f(0) held by
+// the arbitrator with index j
+type ArbitratorKey struct {
+	Index                int64    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Share                []byte   `protobuf:"bytes,2,opt,name=share,proto3" json:"share,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ArbitratorKey) Reset()         { *m = ArbitratorKey{} }
+func (m *ArbitratorKey) String() string { return proto.CompactTextString(m) }
+func (*ArbitratorKey) ProtoMessage()    {}
+func (*ArbitratorKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{24}
+}
+
+func (m *ArbitratorKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ArbitratorKey.Unmarshal(m, b)
+}
+func (m *ArbitratorKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ArbitratorKey.Marshal(b, m, deterministic)
+}
+func (m *ArbitratorKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ArbitratorKey.Merge(m, src)
+}
+func (m *ArbitratorKey) XXX_Size() int {
+	return xxx_messageInfo_ArbitratorKey.Size(m)
+}
+func (m *ArbitratorKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_ArbitratorKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ArbitratorKey proto.InternalMessageInfo
+
+func (m *ArbitratorKey) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *ArbitratorKey) GetShare() []byte {
+	if m != nil {
+		return m.Share
+	}
+	return nil
+}
+
+// ArbitrationPublicKey specifies a k-of-n sharing of the tracing secret that
+// consists of
+// threshold - the number k of arbitrators needed to trace a signature
+// share_pks - g1^{z_j} for the arbitrators j = 1, ..., n
+type ArbitrationPublicKey struct {
+	Threshold            int64    `protobuf:"varint,1,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	SharePks             []*ECP   `protobuf:"bytes,2,rep,name=share_pks,json=sharePks,proto3" json:"share_pks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ArbitrationPublicKey) Reset()         { *m = ArbitrationPublicKey{} }
+func (m *ArbitrationPublicKey) String() string { return proto.CompactTextString(m) }
+func (*ArbitrationPublicKey) ProtoMessage()    {}
+func (*ArbitrationPublicKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{25}
+}
+
+func (m *ArbitrationPublicKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ArbitrationPublicKey.Unmarshal(m, b)
+}
+func (m *ArbitrationPublicKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ArbitrationPublicKey.Marshal(b, m, deterministic)
+}
+func (m *ArbitrationPublicKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ArbitrationPublicKey.Merge(m, src)
+}
+func (m *ArbitrationPublicKey) XXX_Size() int {
+	return xxx_messageInfo_ArbitrationPublicKey.Size(m)
+}
+func (m *ArbitrationPublicKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_ArbitrationPublicKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ArbitrationPublicKey proto.InternalMessageInfo
+
+func (m *ArbitrationPublicKey) GetThreshold() int64 {
+	if m != nil {
+		return m.Threshold
+	}
+	return 0
+}
+
+func (m *ArbitrationPublicKey) GetSharePks() []*ECP {
+	if m != nil {
+		return m.SharePks
+	}
+	return nil
+}
+
+// OpeningShare specifies a partial opening of the tracing tag of a
+// NymSignature by the arbitrator with index j that consists of
+// d - trace_c1^{z_j}
+// proof_c, proof_s - a zero-knowledge proof that d and share_pks[j-1] share z_j
+type OpeningShare struct {
+	Index                int64    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	D                    *ECP     `protobuf:"bytes,2,opt,name=d,proto3" json:"d,omitempty"`
+	ProofC               []byte   `protobuf:"bytes,3,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofS               []byte   `protobuf:"bytes,4,opt,name=proof_s,json=proofS,proto3" json:"proof_s,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OpeningShare) Reset()         { *m = OpeningShare{} }
+func (m *OpeningShare) String() string { return proto.CompactTextString(m) }
+func (*OpeningShare) ProtoMessage()    {}
+func (*OpeningShare) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{26}
+}
+
+func (m *OpeningShare) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpeningShare.Unmarshal(m, b)
+}
+func (m *OpeningShare) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpeningShare.Marshal(b, m, deterministic)
+}
+func (m *OpeningShare) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpeningShare.Merge(m, src)
+}
+func (m *OpeningShare) XXX_Size() int {
+	return xxx_messageInfo_OpeningShare.Size(m)
+}
+func (m *OpeningShare) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpeningShare.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpeningShare proto.InternalMessageInfo
+
+func (m *OpeningShare) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *OpeningShare) GetD() *ECP {
+	if m != nil {
+		return m.D
+	}
+	return nil
+}
+
+func (m *OpeningShare) GetProofC() []byte {
+	if m != nil {
+		return m.ProofC
+	}
+	return nil
+}
+
+func (m *OpeningShare) GetProofS() []byte {
+	if m != nil {
+		return m.ProofS
+	}
+	return nil
+}
+
+// ThresholdOpening specifies the opening of the tracing tag of a NymSignature
+// by threshold arbitrators that consists of their opening shares
+type ThresholdOpening struct {
+	Shares               []*OpeningShare `protobuf:"bytes,1,rep,name=shares,proto3" json:"shares,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ThresholdOpening) Reset()         { *m = ThresholdOpening{} }
+func (m *ThresholdOpening) String() string { return proto.CompactTextString(m) }
+func (*ThresholdOpening) ProtoMessage()    {}
+func (*ThresholdOpening) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{27}
+}
+
+func (m *ThresholdOpening) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ThresholdOpening.Unmarshal(m, b)
+}
+func (m *ThresholdOpening) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ThresholdOpening.Marshal(b, m, deterministic)
+}
+func (m *ThresholdOpening) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ThresholdOpening.Merge(m, src)
+}
+func (m *ThresholdOpening) XXX_Size() int {
+	return xxx_messageInfo_ThresholdOpening.Size(m)
+}
+func (m *ThresholdOpening) XXX_DiscardUnknown() {
+	xxx_messageInfo_ThresholdOpening.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ThresholdOpening proto.InternalMessageInfo
+
+func (m *ThresholdOpening) GetShares() []*OpeningShare {
+	if m != nil {
+		return m.Shares
+	}
+	return nil
+}
+
+// OpeningProof specifies a proof that the tracing tag of a NymSignature opens
+// to a user that consists of
+// t - the trace g2^{usk} of the user, for which e(g2, eta) = e(t, xi)
+// proof_c, proof_s - a zero-knowledge proof that the tag decrypts to g1^{usk}
+// under the tracing key, bound to the signature and its message
+type OpeningProof struct {
+	T      *ECP2  `protobuf:"bytes,1,opt,name=t,proto3" json:"t,omitempty"`
+	ProofC []byte `protobuf:"bytes,2,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofS []byte `protobuf:"bytes,3,opt,name=proof_s,json=proofS,proto3" json:"proof_s,omitempty"`
+	// proof_version - 1 if the proof is made with a transcript, 0 for proofs made before,
+	// which are only accepted by verifiers that allow legacy proofs
+	ProofVersion         uint32   `protobuf:"varint,4,opt,name=proof_version,json=proofVersion,proto3" json:"proof_version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OpeningProof) Reset()         { *m = OpeningProof{} }
+func (m *OpeningProof) String() string { return proto.CompactTextString(m) }
+func (*OpeningProof) ProtoMessage()    {}
+func (*OpeningProof) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{28}
+}
+
+func (m *OpeningProof) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpeningProof.Unmarshal(m, b)
+}
+func (m *OpeningProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpeningProof.Marshal(b, m, deterministic)
+}
+func (m *OpeningProof) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpeningProof.Merge(m, src)
+}
+func (m *OpeningProof) XXX_Size() int {
+	return xxx_messageInfo_OpeningProof.Size(m)
+}
+func (m *OpeningProof) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpeningProof.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpeningProof proto.InternalMessageInfo
+
+func (m *OpeningProof) GetT() *ECP2 {
+	if m != nil {
+		return m.T
+	}
+	return nil
+}
+
+func (m *OpeningProof) GetProofC() []byte {
+	if m != nil {
+		return m.ProofC
+	}
+	return nil
+}
+
+func (m *OpeningProof) GetProofS() []byte {
+	if m != nil {
+		return m.ProofS
+	}
+	return nil
+}
+
+func (m *OpeningProof) GetProofVersion() uint32 {
+	if m != nil {
+		return m.ProofVersion
+	}
+	return 0
+}
+
+// DKGCommitment holds the commitments g2^{a_k} to the coefficients a_k of a
+// polynomial that shares one secret. The tracing secret has no part in G2, it
+// is committed to by g1^{a_k} instead, g1 is empty for the other secrets.
+type DKGCommitment struct {
+	G1                   []*ECP   `protobuf:"bytes,1,rep,name=g1,proto3" json:"g1,omitempty"`
+	G2                   []*ECP2  `protobuf:"bytes,2,rep,name=g2,proto3" json:"g2,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DKGCommitment) Reset()         { *m = DKGCommitment{} }
+func (m *DKGCommitment) String() string { return proto.CompactTextString(m) }
+func (*DKGCommitment) ProtoMessage()    {}
+func (*DKGCommitment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{29}
+}
+
+func (m *DKGCommitment) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DKGCommitment.Unmarshal(m, b)
+}
+func (m *DKGCommitment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DKGCommitment.Marshal(b, m, deterministic)
+}
+func (m *DKGCommitment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DKGCommitment.Merge(m, src)
+}
+func (m *DKGCommitment) XXX_Size() int {
+	return xxx_messageInfo_DKGCommitment.Size(m)
+}
+func (m *DKGCommitment) XXX_DiscardUnknown() {
+	xxx_messageInfo_DKGCommitment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DKGCommitment proto.InternalMessageInfo
+
+func (m *DKGCommitment) GetG1() []*ECP {
+	if m != nil {
+		return m.G1
+	}
+	return nil
+}
+
+func (m *DKGCommitment) GetG2() []*ECP2 {
+	if m != nil {
+		return m.G2
+	}
+	return nil
+}
+
+// DKGShare holds the shares f(j) of all secrets for the issuer with index
+// recipient, it must only be sent to that issuer
+type DKGShare struct {
+	Recipient            int64    `protobuf:"varint,1,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	Secrets              [][]byte `protobuf:"bytes,2,rep,name=secrets,proto3" json:"secrets,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DKGShare) Reset()         { *m = DKGShare{} }
+func (m *DKGShare) String() string { return proto.CompactTextString(m) }
+func (*DKGShare) ProtoMessage()    {}
+func (*DKGShare) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{30}
+}
+
+func (m *DKGShare) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DKGShare.Unmarshal(m, b)
+}
+func (m *DKGShare) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DKGShare.Marshal(b, m, deterministic)
+}
+func (m *DKGShare) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DKGShare.Merge(m, src)
+}
+func (m *DKGShare) XXX_Size() int {
+	return xxx_messageInfo_DKGShare.Size(m)
+}
+func (m *DKGShare) XXX_DiscardUnknown() {
+	xxx_messageInfo_DKGShare.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DKGShare proto.InternalMessageInfo
+
+func (m *DKGShare) GetRecipient() int64 {
+	if m != nil {
+		return m.Recipient
+	}
+	return 0
+}
+
+func (m *DKGShare) GetSecrets() [][]byte {
+	if m != nil {
+		return m.Secrets
+	}
+	return nil
+}
+
+// DKGDeal is the contribution of the issuer with index dealer to the
+// distributed generation of a threshold issuer key
+type DKGDeal struct {
+	Dealer               int64            `protobuf:"varint,1,opt,name=dealer,proto3" json:"dealer,omitempty"`
+	Threshold            int64            `protobuf:"varint,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	AttributeNames       []string         `protobuf:"bytes,3,rep,name=attribute_names,json=attributeNames,proto3" json:"attribute_names,omitempty"`
+	Commitments          []*DKGCommitment `protobuf:"bytes,4,rep,name=commitments,proto3" json:"commitments,omitempty"`
+	Shares               []*DKGShare      `protobuf:"bytes,5,rep,name=shares,proto3" json:"shares,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *DKGDeal) Reset()         { *m = DKGDeal{} }
+func (m *DKGDeal) String() string { return proto.CompactTextString(m) }
+func (*DKGDeal) ProtoMessage()    {}
+func (*DKGDeal) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{31}
+}
+
+func (m *DKGDeal) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DKGDeal.Unmarshal(m, b)
+}
+func (m *DKGDeal) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DKGDeal.Marshal(b, m, deterministic)
+}
+func (m *DKGDeal) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DKGDeal.Merge(m, src)
+}
+func (m *DKGDeal) XXX_Size() int {
+	return xxx_messageInfo_DKGDeal.Size(m)
+}
+func (m *DKGDeal) XXX_DiscardUnknown() {
+	xxx_messageInfo_DKGDeal.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DKGDeal proto.InternalMessageInfo
+
+func (m *DKGDeal) GetDealer() int64 {
+	if m != nil {
+		return m.Dealer
+	}
+	return 0
+}
+
+func (m *DKGDeal) GetThreshold() int64 {
+	if m != nil {
+		return m.Threshold
+	}
+	return 0
+}
+
+func (m *DKGDeal) GetAttributeNames() []string {
+	if m != nil {
+		return m.AttributeNames
+	}
+	return nil
+}
+
+func (m *DKGDeal) GetCommitments() []*DKGCommitment {
+	if m != nil {
+		return m.Commitments
+	}
+	return nil
+}
+
+func (m *DKGDeal) GetShares() []*DKGShare {
+	if m != nil {
+		return m.Shares
+	}
+	return nil
+}
+
+// ThresholdIssuerPublicKey specifies a threshold issuer that consists of
+// threshold - the number of issuers needed to issue a credential
+// issuers - the number n of issuers
+// ipk - the combined issuer public key credentials verify under
+// commitments - the commitments to the shared secrets, from which the
+// verification key of every issuer is derived
+type ThresholdIssuerPublicKey struct {
+	Threshold            int64            `protobuf:"varint,1,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Issuers              int64            `protobuf:"varint,2,opt,name=issuers,proto3" json:"issuers,omitempty"`
+	Ipk                  *IssuerPublicKey `protobuf:"bytes,3,opt,name=ipk,proto3" json:"ipk,omitempty"`
+	Commitments          []*DKGCommitment `protobuf:"bytes,4,rep,name=commitments,proto3" json:"commitments,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *ThresholdIssuerPublicKey) Reset()         { *m = ThresholdIssuerPublicKey{} }
+func (m *ThresholdIssuerPublicKey) String() string { return proto.CompactTextString(m) }
+func (*ThresholdIssuerPublicKey) ProtoMessage()    {}
+func (*ThresholdIssuerPublicKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{32}
+}
+
+func (m *ThresholdIssuerPublicKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ThresholdIssuerPublicKey.Unmarshal(m, b)
+}
+func (m *ThresholdIssuerPublicKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ThresholdIssuerPublicKey.Marshal(b, m, deterministic)
+}
+func (m *ThresholdIssuerPublicKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ThresholdIssuerPublicKey.Merge(m, src)
+}
+func (m *ThresholdIssuerPublicKey) XXX_Size() int {
+	return xxx_messageInfo_ThresholdIssuerPublicKey.Size(m)
+}
+func (m *ThresholdIssuerPublicKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_ThresholdIssuerPublicKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ThresholdIssuerPublicKey proto.InternalMessageInfo
+
+func (m *ThresholdIssuerPublicKey) GetThreshold() int64 {
+	if m != nil {
+		return m.Threshold
+	}
+	return 0
+}
+
+func (m *ThresholdIssuerPublicKey) GetIssuers() int64 {
+	if m != nil {
+		return m.Issuers
+	}
+	return 0
+}
+
+func (m *ThresholdIssuerPublicKey) GetIpk() *IssuerPublicKey {
+	if m != nil {
+		return m.Ipk
+	}
+	return nil
+}
+
+func (m *ThresholdIssuerPublicKey) GetCommitments() []*DKGCommitment {
+	if m != nil {
+		return m.Commitments
+	}
+	return nil
+}
+
+// PartialIssuerPublicKey holds the share of the issuer with index index of the
+// parts in G1 of the combined issuer public key, for its shares x_j, y_j, y_{i,j}
+// of the secrets and r_{x,j}, r_{y,j} of the randomness of the proofs of knowledge
+// bar_g2, bar_g3 - BarG1^{x_j} and BarG1^{y_j}
+// t_x, t_y - BarG1^{r_{x,j}} and BarG1^{r_{y,j}}
+// h_attrs, h_validity - g1^{y_{i,j}} for the attributes and the validity window
+type PartialIssuerPublicKey struct {
+	Index                int64    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	BarG2                *ECP     `protobuf:"bytes,2,opt,name=bar_g2,json=barG2,proto3" json:"bar_g2,omitempty"`
+	BarG3                *ECP     `protobuf:"bytes,3,opt,name=bar_g3,json=barG3,proto3" json:"bar_g3,omitempty"`
+	TX                   *ECP     `protobuf:"bytes,4,opt,name=t_x,json=tX,proto3" json:"t_x,omitempty"`
+	TY                   *ECP     `protobuf:"bytes,5,opt,name=t_y,json=tY,proto3" json:"t_y,omitempty"`
+	HAttrs               []*ECP   `protobuf:"bytes,6,rep,name=h_attrs,json=hAttrs,proto3" json:"h_attrs,omitempty"`
+	HValidity            []*ECP   `protobuf:"bytes,7,rep,name=h_validity,json=hValidity,proto3" json:"h_validity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PartialIssuerPublicKey) Reset()         { *m = PartialIssuerPublicKey{} }
+func (m *PartialIssuerPublicKey) String() string { return proto.CompactTextString(m) }
+func (*PartialIssuerPublicKey) ProtoMessage()    {}
+func (*PartialIssuerPublicKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{33}
+}
+
+func (m *PartialIssuerPublicKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PartialIssuerPublicKey.Unmarshal(m, b)
+}
+func (m *PartialIssuerPublicKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PartialIssuerPublicKey.Marshal(b, m, deterministic)
+}
+func (m *PartialIssuerPublicKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PartialIssuerPublicKey.Merge(m, src)
+}
+func (m *PartialIssuerPublicKey) XXX_Size() int {
+	return xxx_messageInfo_PartialIssuerPublicKey.Size(m)
+}
+func (m *PartialIssuerPublicKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_PartialIssuerPublicKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PartialIssuerPublicKey proto.InternalMessageInfo
+
+func (m *PartialIssuerPublicKey) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *PartialIssuerPublicKey) GetBarG2() *ECP {
+	if m != nil {
+		return m.BarG2
+	}
+	return nil
+}
+
+func (m *PartialIssuerPublicKey) GetBarG3() *ECP {
+	if m != nil {
+		return m.BarG3
+	}
+	return nil
+}
+
+func (m *PartialIssuerPublicKey) GetTX() *ECP {
+	if m != nil {
+		return m.TX
+	}
+	return nil
+}
+
+func (m *PartialIssuerPublicKey) GetTY() *ECP {
+	if m != nil {
+		return m.TY
+	}
+	return nil
+}
+
+func (m *PartialIssuerPublicKey) GetHAttrs() []*ECP {
+	if m != nil {
+		return m.HAttrs
+	}
+	return nil
+}
+
+func (m *PartialIssuerPublicKey) GetHValidity() []*ECP {
+	if m != nil {
+		return m.HValidity
+	}
+	return nil
+}
+
+// PartialKeyProof holds the share of an issuer of the responses proof_s_x,
+// proof_s_y of the proofs of knowledge in the combined issuer public key
+type PartialKeyProof struct {
+	Index                int64    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	ProofSX              []byte   `protobuf:"bytes,2,opt,name=proof_s_x,json=proofSX,proto3" json:"proof_s_x,omitempty"`
+	ProofSY              []byte   `protobuf:"bytes,3,opt,name=proof_s_y,json=proofSY,proto3" json:"proof_s_y,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PartialKeyProof) Reset()         { *m = PartialKeyProof{} }
+func (m *PartialKeyProof) String() string { return proto.CompactTextString(m) }
+func (*PartialKeyProof) ProtoMessage()    {}
+func (*PartialKeyProof) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{34}
 }
 
-func (m *CredentialRevocationInformation) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_CredentialRevocationInformation.Unmarshal(m, b)
+func (m *PartialKeyProof) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PartialKeyProof.Unmarshal(m, b)
 }
-func (m *CredentialRevocationInformation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_CredentialRevocationInformation.Marshal(b, m, deterministic)
+func (m *PartialKeyProof) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PartialKeyProof.Marshal(b, m, deterministic)
 }
-func (m *CredentialRevocationInformation) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_CredentialRevocationInformation.Merge(m, src)
+func (m *PartialKeyProof) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PartialKeyProof.Merge(m, src)
 }
-func (m *CredentialRevocationInformation) XXX_Size() int {
-	return xxx_messageInfo_CredentialRevocationInformation.Size(m)
+func (m *PartialKeyProof) XXX_Size() int {
+	return xxx_messageInfo_PartialKeyProof.Size(m)
 }
-func (m *CredentialRevocationInformation) XXX_DiscardUnknown() {
-	xxx_messageInfo_CredentialRevocationInformation.DiscardUnknown(m)
+func (m *PartialKeyProof) XXX_DiscardUnknown() {
+	xxx_messageInfo_PartialKeyProof.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_CredentialRevocationInformation proto.InternalMessageInfo
+var xxx_messageInfo_PartialKeyProof proto.InternalMessageInfo
 
-func (m *CredentialRevocationInformation) GetEpoch() int64 {
+func (m *PartialKeyProof) GetIndex() int64 {
 	if m != nil {
-		return m.Epoch
+		return m.Index
 	}
 	return 0
 }
 
-func (m *CredentialRevocationInformation) GetEpochPk() *ECP2 {
+func (m *PartialKeyProof) GetProofSX() []byte {
 	if m != nil {
-		return m.EpochPk
+		return m.ProofSX
 	}
 	return nil
 }
 
-func (m *CredentialRevocationInformation) GetEpochPkSig() []byte {
+func (m *PartialKeyProof) GetProofSY() []byte {
 	if m != nil {
-		return m.EpochPkSig
+		return m.ProofSY
 	}
 	return nil
 }
 
-func (m *CredentialRevocationInformation) GetRevocationAlg() int32 {
+// IssuerKeyShare specifies the key of the issuer with index index in a
+// threshold issuer, with its shares proof_r_x, proof_r_y of the randomness of
+// the proofs of knowledge in the combined issuer public key
+type IssuerKeyShare struct {
+	Index                int64                     `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Isk                  *SecretKey                `protobuf:"bytes,2,opt,name=isk,proto3" json:"isk,omitempty"`
+	Tpk                  *ThresholdIssuerPublicKey `protobuf:"bytes,3,opt,name=tpk,proto3" json:"tpk,omitempty"`
+	Partial              *PartialIssuerPublicKey   `protobuf:"bytes,5,opt,name=partial,proto3" json:"partial,omitempty"`
+	ProofRX              []byte                    `protobuf:"bytes,6,opt,name=proof_r_x,json=proofRX,proto3" json:"proof_r_x,omitempty"`
+	ProofRY              []byte                    `protobuf:"bytes,7,opt,name=proof_r_y,json=proofRY,proto3" json:"proof_r_y,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
+	XXX_unrecognized     []byte                    `json:"-"`
+	XXX_sizecache        int32                     `json:"-"`
+}
+
+func (m *IssuerKeyShare) Reset()         { *m = IssuerKeyShare{} }
+func (m *IssuerKeyShare) String() string { return proto.CompactTextString(m) }
+func (*IssuerKeyShare) ProtoMessage()    {}
+func (*IssuerKeyShare) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{35}
+}
+
+func (m *IssuerKeyShare) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IssuerKeyShare.Unmarshal(m, b)
+}
+func (m *IssuerKeyShare) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IssuerKeyShare.Marshal(b, m, deterministic)
+}
+func (m *IssuerKeyShare) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IssuerKeyShare.Merge(m, src)
+}
+func (m *IssuerKeyShare) XXX_Size() int {
+	return xxx_messageInfo_IssuerKeyShare.Size(m)
+}
+func (m *IssuerKeyShare) XXX_DiscardUnknown() {
+	xxx_messageInfo_IssuerKeyShare.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IssuerKeyShare proto.InternalMessageInfo
+
+func (m *IssuerKeyShare) GetIndex() int64 {
 	if m != nil {
-		return m.RevocationAlg
+		return m.Index
 	}
 	return 0
 }
 
-func (m *CredentialRevocationInformation) GetRevocationData() []byte {
+func (m *IssuerKeyShare) GetIsk() *SecretKey {
 	if m != nil {
-		return m.RevocationData
+		return m.Isk
+	}
+	return nil
+}
+
+func (m *IssuerKeyShare) GetTpk() *ThresholdIssuerPublicKey {
+	if m != nil {
+		return m.Tpk
+	}
+	return nil
+}
+
+func (m *IssuerKeyShare) GetPartial() *PartialIssuerPublicKey {
+	if m != nil {
+		return m.Partial
+	}
+	return nil
+}
+
+func (m *IssuerKeyShare) GetProofRX() []byte {
+	if m != nil {
+		return m.ProofRX
+	}
+	return nil
+}
+
+func (m *IssuerKeyShare) GetProofRY() []byte {
+	if m != nil {
+		return m.ProofRY
+	}
+	return nil
+}
+
+// ThresholdCredRequest specifies a credential request to a threshold issuer
+// that consists of
+// a_sk - A^{usk} for the credential base A
+// proof_c, proof_s - a zero-knowledge proof that a_sk and the user public key
+// share usk
+type ThresholdCredRequest struct {
+	IssuerNonce          []byte   `protobuf:"bytes,1,opt,name=issuer_nonce,json=issuerNonce,proto3" json:"issuer_nonce,omitempty"`
+	ASk                  *ECP     `protobuf:"bytes,2,opt,name=a_sk,json=aSk,proto3" json:"a_sk,omitempty"`
+	ProofC               []byte   `protobuf:"bytes,3,opt,name=proof_c,json=proofC,proto3" json:"proof_c,omitempty"`
+	ProofS               []byte   `protobuf:"bytes,4,opt,name=proof_s,json=proofS,proto3" json:"proof_s,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ThresholdCredRequest) Reset()         { *m = ThresholdCredRequest{} }
+func (m *ThresholdCredRequest) String() string { return proto.CompactTextString(m) }
+func (*ThresholdCredRequest) ProtoMessage()    {}
+func (*ThresholdCredRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{36}
+}
+
+func (m *ThresholdCredRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ThresholdCredRequest.Unmarshal(m, b)
+}
+func (m *ThresholdCredRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ThresholdCredRequest.Marshal(b, m, deterministic)
+}
+func (m *ThresholdCredRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ThresholdCredRequest.Merge(m, src)
+}
+func (m *ThresholdCredRequest) XXX_Size() int {
+	return xxx_messageInfo_ThresholdCredRequest.Size(m)
+}
+func (m *ThresholdCredRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ThresholdCredRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ThresholdCredRequest proto.InternalMessageInfo
+
+func (m *ThresholdCredRequest) GetIssuerNonce() []byte {
+	if m != nil {
+		return m.IssuerNonce
+	}
+	return nil
+}
+
+func (m *ThresholdCredRequest) GetASk() *ECP {
+	if m != nil {
+		return m.ASk
+	}
+	return nil
+}
+
+func (m *ThresholdCredRequest) GetProofC() []byte {
+	if m != nil {
+		return m.ProofC
+	}
+	return nil
+}
+
+func (m *ThresholdCredRequest) GetProofS() []byte {
+	if m != nil {
+		return m.ProofS
+	}
+	return nil
+}
+
+// PartialCredential specifies the share B_j of the credential signature
+// issued by the issuer with index index
+type PartialCredential struct {
+	Index                int64    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	B                    *ECP     `protobuf:"bytes,2,opt,name=b,proto3" json:"b,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PartialCredential) Reset()         { *m = PartialCredential{} }
+func (m *PartialCredential) String() string { return proto.CompactTextString(m) }
+func (*PartialCredential) ProtoMessage()    {}
+func (*PartialCredential) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{37}
+}
+
+func (m *PartialCredential) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PartialCredential.Unmarshal(m, b)
+}
+func (m *PartialCredential) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PartialCredential.Marshal(b, m, deterministic)
+}
+func (m *PartialCredential) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PartialCredential.Merge(m, src)
+}
+func (m *PartialCredential) XXX_Size() int {
+	return xxx_messageInfo_PartialCredential.Size(m)
+}
+func (m *PartialCredential) XXX_DiscardUnknown() {
+	xxx_messageInfo_PartialCredential.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PartialCredential proto.InternalMessageInfo
+
+func (m *PartialCredential) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *PartialCredential) GetB() *ECP {
+	if m != nil {
+		return m.B
+	}
+	return nil
+}
+
+// WalletIdentity specifies an identity of a user wallet that consists of
+// name - the name of the identity in the wallet
+// key - the user key
+// trace - the trace the user registered with the issuer
+// ipk, credential - the issuer public key and the credential issued under it, unset before issuance
+type WalletIdentity struct {
+	Name                 string           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Key                  *UserKey         `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Trace                *Trace           `protobuf:"bytes,3,opt,name=trace,proto3" json:"trace,omitempty"`
+	Ipk                  *IssuerPublicKey `protobuf:"bytes,4,opt,name=ipk,proto3" json:"ipk,omitempty"`
+	Credential           *Credential      `protobuf:"bytes,5,opt,name=credential,proto3" json:"credential,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *WalletIdentity) Reset()         { *m = WalletIdentity{} }
+func (m *WalletIdentity) String() string { return proto.CompactTextString(m) }
+func (*WalletIdentity) ProtoMessage()    {}
+func (*WalletIdentity) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{38}
+}
+
+func (m *WalletIdentity) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WalletIdentity.Unmarshal(m, b)
+}
+func (m *WalletIdentity) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WalletIdentity.Marshal(b, m, deterministic)
+}
+func (m *WalletIdentity) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WalletIdentity.Merge(m, src)
+}
+func (m *WalletIdentity) XXX_Size() int {
+	return xxx_messageInfo_WalletIdentity.Size(m)
+}
+func (m *WalletIdentity) XXX_DiscardUnknown() {
+	xxx_messageInfo_WalletIdentity.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WalletIdentity proto.InternalMessageInfo
+
+func (m *WalletIdentity) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *WalletIdentity) GetKey() *UserKey {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *WalletIdentity) GetTrace() *Trace {
+	if m != nil {
+		return m.Trace
+	}
+	return nil
+}
+
+func (m *WalletIdentity) GetIpk() *IssuerPublicKey {
+	if m != nil {
+		return m.Ipk
+	}
+	return nil
+}
+
+func (m *WalletIdentity) GetCredential() *Credential {
+	if m != nil {
+		return m.Credential
+	}
+	return nil
+}
+
+// WalletContents specifies the identities of a wallet, which are encrypted in a WalletFile
+type WalletContents struct {
+	Identities           []*WalletIdentity `protobuf:"bytes,1,rep,name=identities,proto3" json:"identities,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *WalletContents) Reset()         { *m = WalletContents{} }
+func (m *WalletContents) String() string { return proto.CompactTextString(m) }
+func (*WalletContents) ProtoMessage()    {}
+func (*WalletContents) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{39}
+}
+
+func (m *WalletContents) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WalletContents.Unmarshal(m, b)
+}
+func (m *WalletContents) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WalletContents.Marshal(b, m, deterministic)
+}
+func (m *WalletContents) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WalletContents.Merge(m, src)
+}
+func (m *WalletContents) XXX_Size() int {
+	return xxx_messageInfo_WalletContents.Size(m)
+}
+func (m *WalletContents) XXX_DiscardUnknown() {
+	xxx_messageInfo_WalletContents.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WalletContents proto.InternalMessageInfo
+
+func (m *WalletContents) GetIdentities() []*WalletIdentity {
+	if m != nil {
+		return m.Identities
+	}
+	return nil
+}
+
+// WalletFile specifies an encrypted wallet that consists of
+// kdf_time, kdf_memory, kdf_threads - the argon2id parameters the key is derived from the password with,
+// the memory in KiB
+// salt - the argon2id salt
+// nonce, ciphertext - the AES-256-GCM encryption of the WalletContents, authenticated together
+// with the other fields
+type WalletFile struct {
+	KdfTime              uint32   `protobuf:"varint,1,opt,name=kdf_time,json=kdfTime,proto3" json:"kdf_time,omitempty"`
+	KdfMemory            uint32   `protobuf:"varint,2,opt,name=kdf_memory,json=kdfMemory,proto3" json:"kdf_memory,omitempty"`
+	KdfThreads           uint32   `protobuf:"varint,3,opt,name=kdf_threads,json=kdfThreads,proto3" json:"kdf_threads,omitempty"`
+	Salt                 []byte   `protobuf:"bytes,4,opt,name=salt,proto3" json:"salt,omitempty"`
+	Nonce                []byte   `protobuf:"bytes,5,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Ciphertext           []byte   `protobuf:"bytes,6,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WalletFile) Reset()         { *m = WalletFile{} }
+func (m *WalletFile) String() string { return proto.CompactTextString(m) }
+func (*WalletFile) ProtoMessage()    {}
+func (*WalletFile) Descriptor() ([]byte, []int) {
+	return fileDescriptor_28d23908e9a304c6, []int{40}
+}
+
+func (m *WalletFile) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WalletFile.Unmarshal(m, b)
+}
+func (m *WalletFile) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WalletFile.Marshal(b, m, deterministic)
+}
+func (m *WalletFile) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WalletFile.Merge(m, src)
+}
+func (m *WalletFile) XXX_Size() int {
+	return xxx_messageInfo_WalletFile.Size(m)
+}
+func (m *WalletFile) XXX_DiscardUnknown() {
+	xxx_messageInfo_WalletFile.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WalletFile proto.InternalMessageInfo
+
+func (m *WalletFile) GetKdfTime() uint32 {
+	if m != nil {
+		return m.KdfTime
+	}
+	return 0
+}
+
+func (m *WalletFile) GetKdfMemory() uint32 {
+	if m != nil {
+		return m.KdfMemory
+	}
+	return 0
+}
+
+func (m *WalletFile) GetKdfThreads() uint32 {
+	if m != nil {
+		return m.KdfThreads
+	}
+	return 0
+}
+
+func (m *WalletFile) GetSalt() []byte {
+	if m != nil {
+		return m.Salt
+	}
+	return nil
+}
+
+func (m *WalletFile) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+func (m *WalletFile) GetCiphertext() []byte {
+	if m != nil {
+		return m.Ciphertext
 	}
 	return nil
 }
@@ -1155,7 +3078,7 @@ func (m *Certificate) Reset()         { *m = Certificate{} }
 func (m *Certificate) String() string { return proto.CompactTextString(m) }
 func (*Certificate) ProtoMessage()    {}
 func (*Certificate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_28d23908e9a304c6, []int{17}
+	return fileDescriptor_28d23908e9a304c6, []int{41}
 }
 
 func (m *Certificate) XXX_Unmarshal(b []byte) error {
@@ -1222,84 +3145,205 @@ func init() {
 	proto.RegisterType((*Traces)(nil), "Traces")
 	proto.RegisterType((*UserSecretKey)(nil), "UserSecretKey")
 	proto.RegisterType((*UserKey)(nil), "UserKey")
-	proto.RegisterType((*SignedAttribute)(nil), "SignedAttribute")
 	proto.RegisterType((*HiddenAttribute)(nil), "HiddenAttribute")
 	proto.RegisterType((*Credential)(nil), "Credential")
 	proto.RegisterType((*NymSignature)(nil), "NymSignature")
+	proto.RegisterType((*AttributeCommitment)(nil), "AttributeCommitment")
+	proto.RegisterType((*SetMembershipProof)(nil), "SetMembershipProof")
+	proto.RegisterType((*LinkedNymSignature)(nil), "LinkedNymSignature")
+	proto.RegisterType((*AttributeEqualityProof)(nil), "AttributeEqualityProof")
+	proto.RegisterType((*RangeProof)(nil), "RangeProof")
 	proto.RegisterType((*CredRequest)(nil), "CredRequest")
 	proto.RegisterType((*NonRevocationProof)(nil), "NonRevocationProof")
+	proto.RegisterType((*PlainSigNonRevokedProof)(nil), "PlainSigNonRevokedProof")
+	proto.RegisterType((*MessageSignature)(nil), "MessageSignature")
+	proto.RegisterType((*PlainSigRevocationData)(nil), "PlainSigRevocationData")
 	proto.RegisterType((*CredentialRevocationInformation)(nil), "CredentialRevocationInformation")
+	proto.RegisterType((*ArbitratorKey)(nil), "ArbitratorKey")
+	proto.RegisterType((*ArbitrationPublicKey)(nil), "ArbitrationPublicKey")
+	proto.RegisterType((*OpeningShare)(nil), "OpeningShare")
+	proto.RegisterType((*ThresholdOpening)(nil), "ThresholdOpening")
+	proto.RegisterType((*OpeningProof)(nil), "OpeningProof")
+	proto.RegisterType((*DKGCommitment)(nil), "DKGCommitment")
+	proto.RegisterType((*DKGShare)(nil), "DKGShare")
+	proto.RegisterType((*DKGDeal)(nil), "DKGDeal")
+	proto.RegisterType((*ThresholdIssuerPublicKey)(nil), "ThresholdIssuerPublicKey")
+	proto.RegisterType((*PartialIssuerPublicKey)(nil), "PartialIssuerPublicKey")
+	proto.RegisterType((*PartialKeyProof)(nil), "PartialKeyProof")
+	proto.RegisterType((*IssuerKeyShare)(nil), "IssuerKeyShare")
+	proto.RegisterType((*ThresholdCredRequest)(nil), "ThresholdCredRequest")
+	proto.RegisterType((*PartialCredential)(nil), "PartialCredential")
+	proto.RegisterType((*WalletIdentity)(nil), "WalletIdentity")
+	proto.RegisterType((*WalletContents)(nil), "WalletContents")
+	proto.RegisterType((*WalletFile)(nil), "WalletFile")
 	proto.RegisterType((*Certificate)(nil), "Certificate")
 }
 
 func init() { proto.RegisterFile("idemix.proto", fileDescriptor_28d23908e9a304c6) }
 
 var fileDescriptor_28d23908e9a304c6 = []byte{
-	// 1043 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x56, 0x4d, 0x6f, 0xe3, 0x36,
-	0x10, 0x85, 0x24, 0x7f, 0x44, 0x63, 0x25, 0xd9, 0x32, 0x41, 0x57, 0xdd, 0x76, 0x51, 0xaf, 0xfa,
-	0x91, 0x60, 0x0f, 0x4e, 0xad, 0xa0, 0x87, 0x5e, 0x0a, 0x6c, 0xd3, 0xb4, 0x5d, 0xa4, 0x1b, 0x18,
-	0x74, 0x02, 0x24, 0xbd, 0x08, 0x94, 0xc4, 0x58, 0x84, 0x6d, 0xc9, 0x15, 0xe5, 0xae, 0x74, 0x2e,
-	0xfa, 0x1b, 0x7a, 0xe8, 0xa5, 0xff, 0xa7, 0x97, 0xfe, 0xa4, 0x82, 0xa4, 0x6c, 0xd1, 0x4e, 0xb2,
-	0xe8, 0x69, 0x6f, 0x9a, 0xf7, 0xc8, 0xe1, 0xf0, 0xbd, 0x19, 0x42, 0xe0, 0xb0, 0x98, 0xce, 0x59,
-	0x39, 0x58, 0xe4, 0x59, 0x91, 0x79, 0x2f, 0xc0, 0x3a, 0x3f, 0x1b, 0x21, 0x07, 0x8c, 0xd2, 0x35,
-	0xfa, 0xc6, 0xb1, 0x83, 0x8d, 0x52, 0x44, 0x95, 0x6b, 0xaa, 0xa8, 0xf2, 0x7e, 0x80, 0xd6, 0xf9,
-	0xd9, 0xc8, 0x47, 0x7b, 0x60, 0x96, 0xa4, 0x5e, 0x64, 0x96, 0x44, 0xc6, 0x61, 0xbd, 0xcc, 0x2c,
-	0x43, 0x11, 0x57, 0xc4, 0xb5, 0x54, 0x5c, 0x49, 0xbe, 0x0a, 0xdd, 0x56, 0x1d, 0x87, 0xde, 0xef,
-	0x16, 0xec, 0xbf, 0xe6, 0x7c, 0x49, 0xf3, 0xd1, 0x32, 0x9c, 0xb1, 0xe8, 0x82, 0x56, 0xe8, 0x08,
-	0xf6, 0x49, 0x51, 0xe4, 0x2c, 0x5c, 0x16, 0x34, 0x48, 0xc9, 0x9c, 0x72, 0xd7, 0xe8, 0x5b, 0xc7,
-	0x36, 0xde, 0x5b, 0xc3, 0x97, 0x02, 0x45, 0x4f, 0xa1, 0x95, 0x04, 0x7c, 0x2a, 0x8f, 0xeb, 0xf9,
-	0xad, 0xc1, 0xf9, 0xd9, 0x08, 0x5b, 0xc9, 0x78, 0x8a, 0x3e, 0x86, 0x4e, 0x12, 0xe4, 0x24, 0x8d,
-	0xe5, 0xc9, 0x2b, 0xaa, 0x9d, 0x60, 0x92, 0xc6, 0xe8, 0x19, 0xb4, 0x43, 0x92, 0x07, 0xa5, 0xac,
-	0xa2, 0xe7, 0xb7, 0x05, 0xe7, 0xe3, 0x56, 0x48, 0xf2, 0x9b, 0x15, 0x57, 0xb9, 0xed, 0x6d, 0xee,
-	0x56, 0x24, 0x15, 0xdc, 0x64, 0xe8, 0x76, 0xf4, 0xa4, 0x21, 0xc9, 0x7f, 0x1c, 0xae, 0x49, 0xdf,
-	0xed, 0x6e, 0x93, 0xfe, 0x9a, 0x3c, 0x75, 0x77, 0xb6, 0xc9, 0x53, 0xf4, 0x0c, 0xec, 0x45, 0x9e,
-	0x65, 0x77, 0x41, 0x14, 0x94, 0xae, 0x2d, 0x85, 0xe9, 0x4a, 0xe0, 0xec, 0xa6, 0xe1, 0x78, 0x50,
-	0xba, 0xa0, 0x71, 0xe3, 0x1b, 0x7d, 0x5f, 0xe5, 0xf6, 0xf4, 0x7d, 0xb7, 0xfa, 0xbe, 0xca, 0x75,
-	0xf4, 0x7d, 0xb7, 0x08, 0x41, 0x2b, 0x21, 0x3c, 0x71, 0x77, 0x25, 0x2c, 0xbf, 0xbd, 0x23, 0xb0,
-	0xc7, 0x34, 0xca, 0x69, 0x21, 0xe4, 0x7f, 0x97, 0xed, 0x6f, 0xc0, 0x56, 0x6e, 0x89, 0x85, 0x9f,
-	0x80, 0xc5, 0xf8, 0x54, 0x2e, 0xed, 0xf9, 0x30, 0x58, 0x67, 0xc0, 0x02, 0x46, 0x1e, 0x58, 0x6c,
-	0xb1, 0xf2, 0xe6, 0xc9, 0x60, 0xcb, 0x64, 0x2c, 0x48, 0xef, 0x2f, 0x13, 0x76, 0xaf, 0xf9, 0x7b,
-	0xf4, 0xfe, 0x00, 0x8c, 0xb7, 0x9b, 0xbe, 0x1b, 0x6f, 0x35, 0x63, 0xdb, 0xef, 0x32, 0xb6, 0x73,
-	0xdf, 0xd8, 0xa7, 0xd0, 0xad, 0x3d, 0x90, 0xb6, 0x3b, 0xb8, 0xa3, 0x1c, 0x68, 0x08, 0x2e, 0x2d,
-	0x5f, 0x11, 0xe3, 0xb5, 0xfa, 0x76, 0xa3, 0x3e, 0xfa, 0x10, 0xac, 0xeb, 0xd1, 0x85, 0xf4, 0x77,
-	0x7d, 0x93, 0xeb, 0xd1, 0x85, 0xf7, 0x2d, 0xb4, 0xaf, 0x72, 0x12, 0x51, 0x51, 0xf5, 0x55, 0x2d,
-	0xf3, 0xaa, 0xea, 0x2b, 0xd4, 0x07, 0x6b, 0xb9, 0xd6, 0x77, 0x6f, 0xb0, 0x21, 0x23, 0x16, 0x94,
-	0x37, 0x80, 0x8e, 0xdc, 0xcf, 0xd1, 0xe7, 0x60, 0x17, 0xe2, 0xeb, 0x67, 0xc6, 0x0b, 0xa9, 0x67,
-	0xcf, 0xef, 0x0c, 0x24, 0x87, 0x1b, 0xc2, 0x7b, 0xae, 0xcc, 0x78, 0xa4, 0x13, 0xbc, 0x37, 0xd0,
-	0x15, 0xb4, 0x20, 0xc4, 0xd9, 0x6b, 0xe7, 0xd5, 0xd9, 0x9a, 0xfb, 0x4b, 0x3e, 0xfd, 0x1f, 0xd5,
-	0x7d, 0x03, 0xfb, 0x63, 0x36, 0x49, 0x69, 0xfc, 0x6a, 0x65, 0x2c, 0x42, 0x60, 0x90, 0x3a, 0xa9,
-	0x92, 0xc1, 0x20, 0x02, 0x0b, 0x37, 0x4c, 0x36, 0x42, 0xef, 0x6f, 0x03, 0xf6, 0x7f, 0x62, 0x71,
-	0x4c, 0xd3, 0x66, 0xef, 0x73, 0xe8, 0x72, 0x36, 0x99, 0x93, 0x60, 0xb8, 0x91, 0xa1, 0x23, 0xc1,
-	0x61, 0x43, 0xfb, 0x1b, 0xc9, 0x14, 0xed, 0x37, 0xf4, 0xe9, 0x46, 0xd7, 0x28, 0xfa, 0x54, 0xf7,
-	0xb9, 0xf5, 0x98, 0xcf, 0x6d, 0xdd, 0x67, 0x8f, 0x03, 0x9c, 0xe5, 0x34, 0xa6, 0x69, 0xc1, 0xc8,
-	0x0c, 0x7d, 0x09, 0xed, 0x28, 0xa7, 0x31, 0xaf, 0xb5, 0x7f, 0x32, 0xd8, 0xba, 0x39, 0x56, 0xf4,
-	0x43, 0xdd, 0x6f, 0x3e, 0xd8, 0xfd, 0x87, 0xd0, 0x16, 0x08, 0x77, 0xad, 0xbe, 0x75, 0xec, 0x60,
-	0x15, 0x78, 0xff, 0x9a, 0xe0, 0x5c, 0x56, 0x73, 0x91, 0x9c, 0x14, 0xcb, 0x9c, 0x8a, 0xce, 0xa2,
-	0xc5, 0xa6, 0xa4, 0x02, 0x40, 0x87, 0x60, 0x96, 0x6c, 0x43, 0x08, 0xb3, 0x64, 0xa2, 0xca, 0x84,
-	0xc5, 0x54, 0x25, 0x15, 0x55, 0x6e, 0x69, 0x8c, 0x15, 0xdd, 0x1c, 0xde, 0xd2, 0x0e, 0x17, 0x68,
-	0x9a, 0xa5, 0x11, 0xad, 0x85, 0x50, 0x01, 0x7a, 0x09, 0x1f, 0xe4, 0xf4, 0xb7, 0x2c, 0x22, 0x05,
-	0xcb, 0xd2, 0x60, 0x31, 0x0d, 0x38, 0x9b, 0xc8, 0x49, 0x72, 0xf0, 0x7e, 0x43, 0x8c, 0xa6, 0x63,
-	0x36, 0x11, 0x19, 0xe8, 0x22, 0x8b, 0x12, 0x39, 0x4b, 0x16, 0x56, 0x01, 0x3a, 0x87, 0xc3, 0x34,
-	0x4b, 0x03, 0x3d, 0x8b, 0x90, 0xb8, 0x7e, 0x4a, 0x0f, 0x06, 0x97, 0x59, 0x8a, 0x9b, 0x44, 0x82,
-	0xc2, 0x28, 0xbd, 0x87, 0xa1, 0xaf, 0xe1, 0x40, 0x4b, 0x21, 0x53, 0x07, 0x8b, 0xa9, 0x9c, 0xc3,
-	0xf5, 0x54, 0x69, 0xa5, 0x9e, 0x8b, 0x05, 0xa3, 0xa9, 0xf7, 0xa7, 0x01, 0x3d, 0x61, 0x24, 0xa6,
-	0xbf, 0x2e, 0x29, 0x2f, 0x84, 0xa2, 0x69, 0x35, 0xdf, 0x54, 0x34, 0xad, 0xe6, 0xe8, 0x05, 0x38,
-	0x4c, 0xbe, 0x70, 0x81, 0x12, 0x41, 0xbd, 0x98, 0x3d, 0x85, 0x5d, 0x4a, 0x29, 0xb4, 0x26, 0xb2,
-	0x36, 0x9a, 0xe8, 0x23, 0xd8, 0xa9, 0x9b, 0x68, 0x58, 0xb7, 0x57, 0xfd, 0x58, 0x0f, 0x35, 0xca,
-	0xaf, 0x75, 0xad, 0x29, 0xdf, 0x9b, 0x03, 0xba, 0x7f, 0x75, 0xf4, 0x05, 0xec, 0x69, 0xd7, 0x24,
-	0xb3, 0x89, 0x2c, 0xb5, 0x8d, 0x77, 0x1b, 0xf4, 0xd5, 0x6c, 0x82, 0xbe, 0x7a, 0x44, 0x54, 0x55,
-	0xf6, 0x03, 0xfa, 0x79, 0xff, 0x18, 0xf0, 0x69, 0xd3, 0xd1, 0x0d, 0xfb, 0x3a, 0xbd, 0xcb, 0xf2,
-	0xb9, 0xfc, 0x6c, 0x0c, 0x34, 0x74, 0x03, 0xfb, 0xb0, 0xb3, 0x96, 0xdb, 0xd4, 0xe5, 0xee, 0x52,
-	0x25, 0x32, 0xea, 0x83, 0xb3, 0x5a, 0x21, 0xfb, 0x43, 0xc9, 0x03, 0x35, 0x2d, 0x5a, 0xe3, 0xfe,
-	0xb5, 0x5a, 0x0f, 0x5d, 0xeb, 0x08, 0xb4, 0xa6, 0x0a, 0x62, 0x52, 0x90, 0x5a, 0x35, 0x6d, 0xf7,
-	0xf7, 0xa4, 0x20, 0xde, 0x1f, 0xc2, 0x56, 0x9a, 0x17, 0xec, 0x8e, 0x45, 0xa4, 0xa0, 0xe2, 0xb7,
-	0x24, 0x4a, 0x65, 0xd9, 0x36, 0x36, 0xa3, 0x54, 0x3c, 0xd3, 0x62, 0xfc, 0x64, 0xbd, 0x36, 0x96,
-	0xdf, 0xc2, 0xbf, 0x88, 0xc8, 0xa9, 0x94, 0x05, 0xda, 0xb8, 0x13, 0x11, 0x31, 0x8d, 0xe8, 0x33,
-	0xd8, 0xe5, 0x34, 0x67, 0x64, 0x16, 0xa4, 0xcb, 0x79, 0x48, 0x73, 0x59, 0x9b, 0x8d, 0x1d, 0x05,
-	0x5e, 0x4a, 0x4c, 0x68, 0x93, 0x64, 0xbc, 0x10, 0xef, 0x84, 0x18, 0x68, 0x15, 0x7c, 0xf7, 0xf2,
-	0x97, 0xe3, 0x09, 0x2b, 0x92, 0x65, 0x38, 0x88, 0xb2, 0xf9, 0x49, 0x52, 0x2d, 0x68, 0x3e, 0xa3,
-	0xf1, 0x84, 0xe6, 0x27, 0x77, 0x24, 0xcc, 0x59, 0x74, 0xa2, 0xfe, 0xcb, 0x16, 0xb3, 0x25, 0x0f,
-	0x3b, 0xf2, 0xe7, 0xec, 0xf4, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x03, 0xd2, 0x5f, 0xab, 0xac,
-	0x09, 0x00, 0x00,
+	// 2596 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x59, 0x4b, 0x93, 0xdb, 0xc6,
+	0xf1, 0x2f, 0x10, 0x7c, 0x36, 0x1f, 0xbb, 0x9a, 0xa5, 0xb4, 0x90, 0xbc, 0xb2, 0xd7, 0xb0, 0xfd,
+	0xf7, 0x96, 0x5d, 0x7f, 0x4a, 0xa4, 0x92, 0xb8, 0x52, 0x79, 0x54, 0xad, 0x56, 0x8a, 0x2c, 0xaf,
+	0xa5, 0x6c, 0x81, 0xb2, 0x2d, 0xe5, 0x82, 0x1a, 0x00, 0xb3, 0xc4, 0x84, 0x24, 0x40, 0x03, 0x43,
+	0x99, 0xcc, 0x25, 0x97, 0x24, 0xa7, 0x7c, 0x90, 0xdc, 0x52, 0xa9, 0xe4, 0x98, 0x54, 0x72, 0x4f,
+	0x3e, 0x41, 0x8e, 0xf9, 0x10, 0x39, 0xa7, 0xe6, 0x01, 0x60, 0x40, 0x72, 0x57, 0x49, 0x0e, 0xb9,
+	0x71, 0x7e, 0xdd, 0x33, 0xe8, 0x9e, 0x7e, 0x4e, 0x13, 0x3a, 0x34, 0x20, 0x73, 0xba, 0x1a, 0x2c,
+	0x92, 0x98, 0xc5, 0xf6, 0x29, 0x98, 0x8f, 0xcf, 0x2e, 0x50, 0x07, 0x8c, 0x95, 0x65, 0x1c, 0x1b,
+	0x27, 0x1d, 0xc7, 0x58, 0xf1, 0xd5, 0xda, 0xaa, 0xc8, 0xd5, 0x1a, 0xbd, 0x0d, 0xe0, 0xc7, 0xf3,
+	0x45, 0x42, 0xd2, 0x94, 0x04, 0x96, 0x29, 0x60, 0x0d, 0xb1, 0x2f, 0xa1, 0xfa, 0xf8, 0xec, 0x62,
+	0x84, 0x7a, 0x50, 0x59, 0x61, 0x75, 0x48, 0x65, 0x85, 0xc5, 0xda, 0x53, 0xc7, 0x54, 0x56, 0x1e,
+	0x5f, 0xaf, 0xb1, 0xda, 0x5f, 0x59, 0x0b, 0xfa, 0xda, 0xb3, 0xaa, 0x6a, 0xed, 0x6d, 0x7c, 0xa7,
+	0xb6, 0xf5, 0x9d, 0x7f, 0x56, 0x61, 0xef, 0x69, 0x9a, 0x2e, 0x49, 0x72, 0xb1, 0xf4, 0x66, 0xd4,
+	0x3f, 0x27, 0x6b, 0xf4, 0x21, 0xec, 0x61, 0xc6, 0x12, 0xea, 0x2d, 0x19, 0x71, 0x23, 0x3c, 0x27,
+	0xa9, 0x65, 0x1c, 0x9b, 0x27, 0x2d, 0xa7, 0x97, 0xc3, 0xcf, 0x39, 0x8a, 0x0e, 0xa1, 0x1a, 0xba,
+	0xe9, 0x54, 0x88, 0xd3, 0x1e, 0x55, 0x07, 0x8f, 0xcf, 0x2e, 0x1c, 0x33, 0x1c, 0x4f, 0xd1, 0x5b,
+	0x50, 0x0f, 0xdd, 0x04, 0x47, 0x52, 0xb3, 0x8c, 0x54, 0x0b, 0x1d, 0x1c, 0x05, 0xe8, 0x0e, 0xd4,
+	0x3c, 0x9c, 0xb8, 0x2b, 0x21, 0x65, 0x7b, 0x54, 0xe3, 0xb4, 0x91, 0x53, 0xf5, 0x70, 0xf2, 0x32,
+	0xa3, 0xad, 0xad, 0xda, 0x26, 0xed, 0x15, 0x3f, 0x94, 0xd3, 0x26, 0x43, 0xab, 0xae, 0x1f, 0xea,
+	0xe1, 0xe4, 0xc9, 0x30, 0x27, 0x8e, 0xac, 0xc6, 0x26, 0x71, 0x94, 0x13, 0x1f, 0x58, 0xcd, 0x4d,
+	0xe2, 0x03, 0x74, 0x07, 0x5a, 0x8b, 0x24, 0x8e, 0x2f, 0x5d, 0xdf, 0x5d, 0x59, 0x2d, 0x71, 0x41,
+	0x0d, 0x01, 0x9c, 0xbd, 0x2c, 0x68, 0xa9, 0xbb, 0xb2, 0x40, 0xa3, 0x8d, 0x5f, 0xea, 0xfb, 0xd6,
+	0x56, 0x5b, 0xdf, 0xf7, 0x4a, 0xdf, 0xb7, 0xb6, 0x3a, 0xfa, 0xbe, 0x57, 0x08, 0x41, 0x35, 0xc4,
+	0x69, 0x68, 0x75, 0x05, 0x2c, 0x7e, 0xa3, 0xbb, 0xd0, 0x08, 0x5d, 0x7e, 0xb9, 0xa9, 0xd5, 0x3b,
+	0x36, 0x73, 0x09, 0xeb, 0xe1, 0x29, 0xc7, 0x90, 0x0d, 0x2d, 0x2e, 0xbf, 0x64, 0xd8, 0x3b, 0x36,
+	0x8b, 0x9b, 0x69, 0x7a, 0x38, 0x91, 0x3c, 0xef, 0x01, 0xb0, 0x04, 0xfb, 0x34, 0x9a, 0xb8, 0x8b,
+	0xa9, 0xb5, 0xaf, 0xe9, 0xd9, 0x52, 0xf8, 0xc5, 0x94, 0x33, 0x85, 0xee, 0x6b, 0x3c, 0xa3, 0x01,
+	0x65, 0x6b, 0xeb, 0x86, 0xf6, 0xa9, 0x56, 0xf8, 0xa5, 0x82, 0xd1, 0x09, 0x74, 0xf8, 0xd7, 0x72,
+	0x36, 0xa4, 0x7f, 0xb0, 0xed, 0xe1, 0x24, 0xe7, 0xbc, 0x09, 0xf5, 0x29, 0x59, 0xbb, 0x34, 0xb0,
+	0x0e, 0x8e, 0x8d, 0x93, 0x96, 0x53, 0x9b, 0x92, 0xf5, 0xd3, 0x00, 0xbd, 0x07, 0x5d, 0xa9, 0xfd,
+	0x6b, 0x92, 0xa4, 0x34, 0x8e, 0xac, 0xfe, 0xb1, 0x71, 0xd2, 0x75, 0x3a, 0x02, 0xfc, 0x52, 0x62,
+	0xf6, 0x0a, 0x5a, 0x63, 0xe2, 0x27, 0x84, 0x71, 0x8f, 0xbb, 0x2e, 0x52, 0xfa, 0x50, 0x93, 0x8a,
+	0x9b, 0xc7, 0xe6, 0x49, 0xc7, 0x91, 0x0b, 0x74, 0xb7, 0x50, 0x37, 0x9d, 0x2a, 0x7f, 0xcf, 0x14,
+	0x1d, 0x4f, 0xd1, 0x1d, 0x68, 0xe6, 0xf2, 0xd7, 0xc4, 0xbe, 0x7c, 0x6d, 0x3f, 0x83, 0x96, 0xf4,
+	0x78, 0xfe, 0xe5, 0x23, 0x30, 0x69, 0x3a, 0x15, 0xdf, 0x6e, 0x8f, 0x60, 0x90, 0x8b, 0xe4, 0x70,
+	0x18, 0xd9, 0x60, 0xd2, 0x45, 0xe6, 0xdf, 0xfb, 0x83, 0x8d, 0x40, 0x71, 0x38, 0xd1, 0xfe, 0x4b,
+	0x05, 0xba, 0x5f, 0xa4, 0xff, 0xc3, 0xf8, 0x39, 0x00, 0xe3, 0x9b, 0x72, 0xec, 0x18, 0xdf, 0x68,
+	0xc1, 0x51, 0xbb, 0x2e, 0x38, 0xea, 0xdb, 0xc1, 0x71, 0x08, 0x0d, 0xe5, 0xc7, 0x22, 0x74, 0x3a,
+	0x4e, 0x5d, 0x7a, 0x71, 0x41, 0x48, 0xad, 0xa6, 0x46, 0x18, 0xe7, 0x1e, 0xdc, 0xd2, 0x3c, 0xf8,
+	0x16, 0x98, 0x5f, 0x5c, 0x9c, 0x5b, 0xa0, 0x9d, 0xcf, 0x81, 0x6d, 0x5f, 0x68, 0xef, 0xf0, 0x85,
+	0x1f, 0x42, 0xed, 0x45, 0x82, 0x7d, 0xc2, 0x55, 0x7b, 0x61, 0x19, 0x25, 0xd5, 0x5e, 0xa0, 0x63,
+	0x30, 0x97, 0xb9, 0x11, 0x7a, 0x83, 0xd2, 0x5d, 0x3b, 0x9c, 0x64, 0x0f, 0xa0, 0x2e, 0xf6, 0xa7,
+	0xe8, 0x7d, 0x10, 0x4e, 0x40, 0x3e, 0xa7, 0x29, 0x13, 0x97, 0xde, 0x1e, 0xd5, 0x07, 0x82, 0xe6,
+	0x14, 0x04, 0xfb, 0xae, 0xb4, 0xd8, 0x15, 0xfe, 0x67, 0x3f, 0x83, 0x06, 0x27, 0x73, 0x02, 0xff,
+	0x76, 0xee, 0x1e, 0xbd, 0x41, 0x69, 0x97, 0xc3, 0x49, 0xff, 0x86, 0x74, 0xbf, 0x32, 0x60, 0xef,
+	0x53, 0x1a, 0x04, 0x24, 0x3a, 0xcd, 0xcc, 0xcf, 0xaf, 0xcb, 0x8f, 0xe7, 0x96, 0xa1, 0x5f, 0x97,
+	0x1f, 0xcf, 0x75, 0x63, 0x54, 0x4a, 0xc6, 0x38, 0x86, 0x4e, 0x96, 0x51, 0xb8, 0x13, 0x65, 0x15,
+	0x43, 0x5a, 0x84, 0x9f, 0xab, 0x73, 0x08, 0xcf, 0xa9, 0xea, 0x1c, 0xdc, 0x71, 0xec, 0xbf, 0x19,
+	0x00, 0x67, 0x09, 0x09, 0x48, 0xc4, 0x28, 0x9e, 0xed, 0x72, 0xd3, 0xca, 0x4e, 0x37, 0xdd, 0x1d,
+	0x81, 0x08, 0x0c, 0x6c, 0x55, 0x35, 0x05, 0x0c, 0xcc, 0x31, 0xaf, 0xe4, 0x80, 0x86, 0xc7, 0x23,
+	0x35, 0x8a, 0x99, 0xeb, 0x91, 0xcb, 0x38, 0x21, 0xc2, 0x01, 0x4d, 0xa7, 0x15, 0xc5, 0xec, 0xa1,
+	0x00, 0xd0, 0x5b, 0xc0, 0x17, 0x2e, 0xbe, 0x64, 0x24, 0x11, 0x0e, 0x68, 0x3a, 0xcd, 0x28, 0x66,
+	0xa7, 0x7c, 0xad, 0x25, 0x98, 0xa6, 0x96, 0x60, 0x3e, 0xab, 0x36, 0x8d, 0xfd, 0x8a, 0xfd, 0xe7,
+	0x26, 0x74, 0x9e, 0xaf, 0xe7, 0x63, 0x3a, 0x89, 0x30, 0x5b, 0x26, 0xe2, 0x52, 0x09, 0xc3, 0xe5,
+	0x4b, 0x25, 0x0c, 0xa3, 0x3e, 0x54, 0x56, 0xb4, 0x14, 0x64, 0x95, 0x15, 0x45, 0xff, 0x07, 0xb5,
+	0x90, 0x06, 0x44, 0x6a, 0xc5, 0xa3, 0x7b, 0xc3, 0x46, 0x8e, 0x24, 0x17, 0xda, 0x57, 0x75, 0xed,
+	0xfb, 0x50, 0x8b, 0xe2, 0xc8, 0x27, 0xaa, 0xa4, 0xca, 0x05, 0xfa, 0x08, 0x6e, 0x24, 0xe4, 0x75,
+	0xec, 0x63, 0x46, 0xe3, 0xc8, 0x5d, 0x4c, 0xdd, 0x94, 0x4e, 0x84, 0xca, 0x1d, 0x67, 0xaf, 0x20,
+	0x5c, 0x4c, 0xc7, 0x74, 0xc2, 0x4f, 0x20, 0x8b, 0xd8, 0x0f, 0x95, 0xd2, 0x72, 0x81, 0x1e, 0x43,
+	0x3f, 0x8a, 0x23, 0x57, 0x3f, 0x85, 0x1b, 0x50, 0x15, 0xae, 0x83, 0xc1, 0xf3, 0x38, 0x72, 0x8a,
+	0x83, 0x38, 0xc9, 0x41, 0xd1, 0x16, 0x86, 0xbe, 0x0d, 0x07, 0xda, 0x11, 0xe2, 0x68, 0x5e, 0x16,
+	0x5a, 0x7a, 0x68, 0x69, 0xa2, 0x3e, 0xe6, 0x0c, 0x17, 0x53, 0x5e, 0x87, 0x52, 0x3a, 0x99, 0x63,
+	0x77, 0x58, 0x8a, 0xe4, 0xba, 0x00, 0x87, 0x05, 0x79, 0x64, 0xb5, 0xb7, 0xc8, 0xa3, 0x82, 0xfc,
+	0xc0, 0xea, 0x6c, 0x91, 0x1f, 0xe8, 0xbe, 0xdd, 0xbd, 0x2a, 0xd1, 0xf4, 0x4a, 0x89, 0xe6, 0x6d,
+	0x80, 0x80, 0xa6, 0xfe, 0x2c, 0x4e, 0x97, 0x09, 0xb1, 0xf6, 0x04, 0x4d, 0x43, 0xd0, 0x3b, 0xd0,
+	0x14, 0x41, 0xed, 0xfa, 0xc3, 0x52, 0xc5, 0x6b, 0x08, 0xf4, 0x6c, 0xa8, 0x31, 0x8c, 0xac, 0x1b,
+	0xdb, 0x0c, 0x23, 0x64, 0x67, 0xe9, 0x29, 0x75, 0x05, 0x64, 0x21, 0xf1, 0x91, 0xb6, 0x14, 0x40,
+	0x26, 0xa5, 0x3e, 0xd4, 0x52, 0x3f, 0x5e, 0x10, 0x51, 0xe4, 0x3a, 0x8e, 0x5c, 0xa0, 0x77, 0xa1,
+	0x25, 0x7e, 0xb8, 0xd1, 0x7a, 0x6e, 0xf5, 0xb5, 0xb3, 0x9b, 0x02, 0x7e, 0xbe, 0x9e, 0xa3, 0x01,
+	0x74, 0x12, 0x1c, 0x4d, 0x88, 0x34, 0x61, 0x6a, 0xdd, 0x14, 0x8e, 0xd6, 0x1e, 0x38, 0x1c, 0x94,
+	0xb6, 0x6b, 0x27, 0xf9, 0xef, 0x14, 0x8d, 0x00, 0x52, 0xc2, 0x32, 0xee, 0x5b, 0x82, 0xfb, 0x60,
+	0x30, 0x26, 0xec, 0x19, 0x99, 0x7b, 0x24, 0x49, 0x43, 0xba, 0x90, 0xbb, 0x5a, 0x29, 0x61, 0x6a,
+	0xcf, 0x6d, 0x55, 0xe8, 0x5c, 0xcc, 0xac, 0x43, 0xe1, 0x48, 0x0d, 0xb1, 0x3e, 0x65, 0xe8, 0x13,
+	0xe8, 0x65, 0x35, 0xcf, 0x95, 0x9e, 0x6e, 0x5d, 0xe1, 0xe9, 0xdd, 0x8c, 0xef, 0x53, 0xe1, 0xf1,
+	0xdf, 0x82, 0xbd, 0x7c, 0xa3, 0x12, 0xe6, 0xf6, 0xb6, 0xe8, 0xf9, 0xe1, 0x4a, 0x92, 0x22, 0x56,
+	0xef, 0xe8, 0xcd, 0x80, 0x05, 0x8d, 0x2c, 0xf5, 0xbf, 0x25, 0x52, 0x7f, 0xb6, 0x44, 0xef, 0x43,
+	0x2f, 0xbb, 0xfb, 0x50, 0x08, 0x64, 0x1d, 0x89, 0x08, 0x93, 0x69, 0x6c, 0x2c, 0x85, 0xd4, 0x2d,
+	0xe4, 0xcd, 0x68, 0x14, 0x58, 0x77, 0x75, 0x0b, 0x3d, 0xe4, 0x10, 0xfa, 0x0e, 0xb4, 0xfd, 0x78,
+	0x3e, 0xa7, 0x6c, 0x4e, 0x22, 0x96, 0x5a, 0x6f, 0x0b, 0x61, 0xfb, 0x83, 0x5c, 0xc1, 0xb3, 0x9c,
+	0xe8, 0xe8, 0x8c, 0xf6, 0x1c, 0x0e, 0x76, 0xf0, 0xa0, 0x23, 0x68, 0xe5, 0x19, 0x50, 0x64, 0x13,
+	0xd3, 0x29, 0x80, 0x2c, 0x75, 0x57, 0x36, 0x53, 0xf7, 0x66, 0xfe, 0x35, 0xb7, 0xf2, 0xef, 0x6b,
+	0x40, 0xdb, 0xc6, 0x7c, 0xc3, 0xd7, 0xf6, 0xc1, 0x4c, 0x09, 0x13, 0x89, 0xb9, 0xe3, 0xf0, 0x9f,
+	0x7a, 0x18, 0xc9, 0x7c, 0xbc, 0x23, 0x8c, 0xaa, 0x1a, 0x61, 0x6c, 0xff, 0xde, 0x00, 0xf4, 0x39,
+	0x8d, 0xa6, 0x24, 0x28, 0xa5, 0xcb, 0xff, 0x07, 0x48, 0xb3, 0x45, 0xaa, 0x8a, 0x65, 0x77, 0xa0,
+	0xb3, 0x38, 0x1a, 0x03, 0xfa, 0x04, 0x80, 0x7c, 0xbd, 0xc4, 0x33, 0xca, 0xa8, 0xaa, 0x14, 0xed,
+	0xd1, 0x61, 0x71, 0xc7, 0x8f, 0x25, 0x4d, 0x7a, 0x83, 0xa3, 0xb1, 0x96, 0x05, 0xd6, 0xe3, 0xfe,
+	0x08, 0x20, 0xbb, 0xb1, 0xbc, 0x87, 0x6b, 0x4a, 0x99, 0xc7, 0x53, 0xfb, 0xd7, 0x15, 0xb8, 0xb5,
+	0xfb, 0x74, 0xf4, 0x2e, 0x74, 0xfc, 0xbc, 0x8e, 0xb9, 0x43, 0x75, 0x6b, 0xed, 0x02, 0xe3, 0x91,
+	0xdf, 0x2e, 0x8a, 0xdb, 0x50, 0x58, 0xcb, 0x74, 0x20, 0x87, 0x86, 0x1b, 0x67, 0x8c, 0x2c, 0x73,
+	0xf3, 0x8c, 0x51, 0xf9, 0x8c, 0x91, 0x55, 0xdd, 0x38, 0x63, 0xb4, 0x55, 0x94, 0x6b, 0x5b, 0x45,
+	0xf9, 0x3d, 0xe8, 0xe9, 0x4e, 0xe1, 0x0e, 0xad, 0xba, 0xee, 0xbe, 0xdc, 0x2d, 0x86, 0x5b, 0x4c,
+	0x23, 0xab, 0xb1, 0xc9, 0x34, 0xb2, 0xff, 0x50, 0x01, 0x28, 0xa2, 0xef, 0x0d, 0x5e, 0xd3, 0x87,
+	0x9a, 0x17, 0x2f, 0xa3, 0x40, 0x35, 0x11, 0x72, 0xc1, 0xd1, 0xe5, 0x62, 0x41, 0x64, 0xf3, 0xd0,
+	0x74, 0xe4, 0x02, 0x59, 0x50, 0xf5, 0x28, 0x93, 0x3e, 0x93, 0x39, 0xb4, 0x40, 0x78, 0x44, 0x7b,
+	0x94, 0xb9, 0xfe, 0x7d, 0xd5, 0x42, 0xd7, 0x3c, 0xca, 0xce, 0xee, 0x67, 0x70, 0x7a, 0xdf, 0xaa,
+	0xe7, 0xf0, 0xb8, 0x80, 0x87, 0x56, 0xa3, 0x80, 0x87, 0xba, 0xf5, 0x9b, 0xd7, 0x76, 0x34, 0xad,
+	0x37, 0x76, 0x34, 0xb0, 0x19, 0x51, 0x3a, 0x87, 0xd0, 0xa1, 0xad, 0x73, 0x3c, 0xa4, 0x2c, 0xb5,
+	0xff, 0x68, 0x40, 0x9b, 0xf7, 0x3c, 0x0e, 0xf9, 0x7a, 0x49, 0x52, 0xc6, 0xa3, 0x97, 0x27, 0xec,
+	0x52, 0x8f, 0x10, 0xad, 0xe7, 0xdc, 0x1d, 0xa8, 0xe8, 0xee, 0x5d, 0x59, 0xd6, 0xe5, 0xc5, 0xb5,
+	0x25, 0xf6, 0x9c, 0x43, 0x57, 0xfb, 0xf1, 0x6d, 0x68, 0x2a, 0x29, 0x86, 0xca, 0x8b, 0xd5, 0x63,
+	0x6f, 0xa8, 0x91, 0x46, 0x56, 0x4d, 0x27, 0x8d, 0xb6, 0x3b, 0xe3, 0xfa, 0x8e, 0xce, 0x78, 0x0e,
+	0x68, 0xbb, 0xe2, 0xa3, 0x0f, 0xa0, 0xa7, 0x55, 0x77, 0x3c, 0x9b, 0x08, 0x7d, 0x6a, 0x4e, 0xb7,
+	0x40, 0x4f, 0x67, 0x13, 0x74, 0xff, 0x8a, 0x5e, 0x42, 0xea, 0xb6, 0xa3, 0x6d, 0xb0, 0x7f, 0x0e,
+	0x87, 0x17, 0x33, 0x4c, 0xa3, 0x31, 0x9d, 0xa8, 0xcf, 0x4e, 0x49, 0x90, 0x7d, 0xb3, 0x2d, 0x8b,
+	0xfb, 0x22, 0xa1, 0x73, 0x52, 0xba, 0x40, 0x10, 0x84, 0x0b, 0x8e, 0x8b, 0xb2, 0x28, 0xd8, 0x3c,
+	0x9c, 0x94, 0x72, 0x64, 0x53, 0xc0, 0x0f, 0x71, 0xa2, 0x3f, 0x8e, 0xb3, 0x3e, 0x56, 0x5d, 0x8a,
+	0x63, 0x87, 0xb0, 0xff, 0x8c, 0xa4, 0x29, 0x9e, 0x90, 0x22, 0x4f, 0x7d, 0x5c, 0x6a, 0xaa, 0x42,
+	0x1c, 0x05, 0x33, 0xa2, 0x9a, 0xf5, 0xfd, 0x82, 0xf0, 0xa9, 0xc0, 0xd1, 0x87, 0xd0, 0x49, 0x42,
+	0x37, 0x4f, 0x5b, 0x25, 0x11, 0xda, 0x49, 0x98, 0x9f, 0x6a, 0x9f, 0xc3, 0xad, 0x4c, 0xd5, 0xe2,
+	0x16, 0x1e, 0x61, 0x86, 0xd1, 0x70, 0x47, 0x5e, 0xbc, 0x31, 0xd8, 0x14, 0x4b, 0xcf, 0x8d, 0xf6,
+	0x5f, 0x0d, 0x78, 0xa7, 0xe8, 0xac, 0x8b, 0xf3, 0x9e, 0x46, 0x97, 0x71, 0x32, 0x17, 0x3f, 0x8b,
+	0x7e, 0xcf, 0xd0, 0xfb, 0xbd, 0x63, 0x68, 0xe6, 0xdd, 0x59, 0x45, 0xef, 0xce, 0x1a, 0x44, 0xf5,
+	0x64, 0xc7, 0xd0, 0xc9, 0x38, 0x44, 0x3b, 0xa9, 0xea, 0x8a, 0x22, 0xf3, 0x4e, 0x72, 0xdb, 0x1d,
+	0xaa, 0xbb, 0xdc, 0xe1, 0x43, 0xd0, 0x7a, 0x50, 0x37, 0xc0, 0x0c, 0x2b, 0x97, 0xec, 0x25, 0xa5,
+	0x0b, 0xb0, 0xbf, 0x07, 0xdd, 0xd3, 0xc4, 0xa3, 0x2c, 0xc1, 0x2c, 0x16, 0xaf, 0xa0, 0x3e, 0xd4,
+	0x68, 0x14, 0x90, 0x55, 0x26, 0xba, 0x58, 0x70, 0x34, 0x0d, 0x71, 0x92, 0xc5, 0x8a, 0x5c, 0xd8,
+	0x5f, 0x41, 0x3f, 0xdb, 0xcc, 0xdd, 0x2a, 0x7f, 0x14, 0x1f, 0x41, 0x8b, 0x85, 0x09, 0x49, 0xc3,
+	0x78, 0x16, 0x64, 0x09, 0x2b, 0x07, 0x84, 0xdb, 0xf0, 0xed, 0xee, 0x62, 0x9a, 0xd5, 0x96, 0xcc,
+	0x6d, 0x38, 0x7c, 0x31, 0x4d, 0xed, 0x9f, 0x42, 0xe7, 0xc7, 0x0b, 0x12, 0xf1, 0xf7, 0x3d, 0x87,
+	0xae, 0x10, 0x0a, 0x81, 0x11, 0x94, 0x8c, 0x6e, 0x04, 0x57, 0x07, 0x6e, 0xa9, 0x62, 0x1a, 0x5a,
+	0xc5, 0xfc, 0x2e, 0xec, 0xbf, 0xc8, 0x64, 0x53, 0x1f, 0x45, 0x1f, 0x40, 0x5d, 0xc8, 0x52, 0x94,
+	0x4a, 0x5d, 0x1c, 0x47, 0x11, 0xed, 0x9f, 0xe5, 0x62, 0xca, 0xb8, 0x39, 0x00, 0x83, 0x6d, 0x3c,
+	0x69, 0xd9, 0xd5, 0xcf, 0x3c, 0x4d, 0x22, 0xb3, 0xd4, 0x0a, 0x6f, 0x65, 0x8b, 0xea, 0x8e, 0x6c,
+	0xf1, 0x7d, 0xe8, 0x3e, 0x3a, 0x7f, 0xa2, 0x75, 0x32, 0x7d, 0xa8, 0x4c, 0x86, 0x4a, 0x5e, 0xf5,
+	0xf2, 0x99, 0x0c, 0xd1, 0x4d, 0xa8, 0x4c, 0x46, 0xea, 0x96, 0x95, 0x4c, 0x95, 0xc9, 0xc8, 0x7e,
+	0x08, 0xcd, 0x47, 0xe7, 0x4f, 0xe4, 0xe5, 0x1e, 0x41, 0x2b, 0x21, 0x3e, 0x5d, 0x50, 0x12, 0xb1,
+	0xcc, 0x5a, 0x39, 0xc0, 0x7b, 0xba, 0x54, 0xbc, 0x82, 0x53, 0xd5, 0x98, 0x64, 0x4b, 0xfb, 0x4f,
+	0x06, 0x34, 0x1e, 0x9d, 0x3f, 0x79, 0x44, 0xf0, 0x0c, 0xdd, 0x82, 0x7a, 0x40, 0xf0, 0x8c, 0x24,
+	0xea, 0x00, 0xb5, 0x2a, 0x7b, 0x42, 0x65, 0xd3, 0x13, 0x76, 0xbc, 0x4a, 0xcd, 0x9d, 0xaf, 0xd2,
+	0xfb, 0xe5, 0xa6, 0x4f, 0x96, 0xaf, 0xde, 0xa0, 0x74, 0x01, 0xa5, 0x76, 0x0f, 0xbd, 0x9b, 0x5b,
+	0xb0, 0x26, 0x98, 0x5b, 0x83, 0x4c, 0xdf, 0xdc, 0x7a, 0xbf, 0x31, 0xc0, 0xca, 0x2d, 0xbf, 0x39,
+	0x17, 0xbd, 0xde, 0x85, 0x2d, 0x68, 0xc8, 0x6a, 0x91, 0x2a, 0xa5, 0xb2, 0x65, 0x36, 0x45, 0x32,
+	0xaf, 0x99, 0x22, 0xfd, 0xe7, 0xda, 0xd8, 0x7f, 0x37, 0xe0, 0xd6, 0x05, 0x4e, 0x78, 0xc2, 0xd9,
+	0x14, 0x74, 0x77, 0x68, 0x14, 0x53, 0xa0, 0xca, 0x75, 0x23, 0x52, 0x73, 0x7b, 0x44, 0x7a, 0x13,
+	0x4c, 0x96, 0xcf, 0x6b, 0x95, 0x1f, 0xb1, 0x97, 0x12, 0x5e, 0x97, 0xde, 0xfb, 0x15, 0xf6, 0x4a,
+	0x1f, 0x66, 0xd6, 0x77, 0x0c, 0x33, 0xcb, 0x33, 0xc8, 0xc6, 0xce, 0x19, 0xa4, 0xed, 0xc2, 0x9e,
+	0xd2, 0xed, 0x9c, 0xa8, 0xa6, 0x6f, 0xb7, 0x52, 0xa5, 0x09, 0x6d, 0xe5, 0x8a, 0x09, 0x2d, 0x9f,
+	0xc2, 0x96, 0x0a, 0xcd, 0x2b, 0xfb, 0x1f, 0x06, 0xf4, 0xf2, 0x29, 0xe0, 0x75, 0x09, 0x45, 0x0d,
+	0x08, 0x2b, 0xbb, 0x07, 0x84, 0x1f, 0x83, 0xc9, 0x72, 0xd3, 0xde, 0x1e, 0x5c, 0xe5, 0x3a, 0x0e,
+	0xe7, 0x42, 0x43, 0x68, 0x2c, 0xa4, 0x52, 0xea, 0xce, 0x0e, 0x07, 0xbb, 0x0d, 0xe8, 0x64, 0x7c,
+	0x85, 0x0a, 0x7c, 0x5e, 0x5e, 0xd7, 0x54, 0x70, 0x5e, 0xea, 0xb4, 0xb5, 0xd5, 0xd0, 0x69, 0xaf,
+	0x3e, 0xab, 0x36, 0xab, 0xfb, 0x35, 0xfb, 0x17, 0x06, 0xf4, 0x73, 0x91, 0xf4, 0x2e, 0x68, 0xb3,
+	0xdb, 0x31, 0x76, 0x75, 0x3b, 0x55, 0xbc, 0x35, 0x9b, 0xc4, 0xe3, 0xe9, 0x7f, 0x91, 0x4d, 0x7f,
+	0x00, 0x37, 0x94, 0x9e, 0xda, 0xf4, 0xe9, 0xca, 0xf4, 0xed, 0x95, 0xd3, 0xb7, 0x67, 0xff, 0xd6,
+	0x80, 0xde, 0x57, 0x78, 0x36, 0x23, 0xec, 0xa9, 0xd8, 0xcc, 0xd6, 0x7c, 0x02, 0xc9, 0x53, 0x83,
+	0xd8, 0xdb, 0x72, 0xc4, 0x6f, 0x74, 0x07, 0xcc, 0x29, 0x59, 0xab, 0xcd, 0xcd, 0x81, 0x9a, 0xe0,
+	0x39, 0x1c, 0x44, 0x47, 0x50, 0x93, 0xcf, 0x7b, 0x69, 0xa8, 0x6c, 0x24, 0x28, 0xc1, 0x2c, 0x3e,
+	0xab, 0xd7, 0xc5, 0xe7, 0xc7, 0x00, 0xc5, 0xd3, 0x40, 0x99, 0xaf, 0x3d, 0xd0, 0x6a, 0xbe, 0x46,
+	0xb6, 0x4f, 0x33, 0x81, 0xcf, 0xe2, 0x88, 0x89, 0xd4, 0x73, 0x0f, 0x80, 0x4a, 0xe1, 0x69, 0x5e,
+	0x40, 0xf6, 0x06, 0x65, 0xad, 0x1c, 0x8d, 0xc5, 0xfe, 0x9d, 0x01, 0x20, 0xc9, 0x3f, 0xa2, 0x33,
+	0xc2, 0xfb, 0xc8, 0x69, 0x70, 0xe9, 0xb2, 0xac, 0xf5, 0xea, 0x3a, 0x8d, 0x69, 0x70, 0xf9, 0x82,
+	0x77, 0x5c, 0x77, 0x01, 0x38, 0x69, 0x4e, 0xe6, 0x71, 0x22, 0xd5, 0xef, 0x3a, 0xad, 0x69, 0x70,
+	0xf9, 0x4c, 0x00, 0xfc, 0x11, 0x23, 0x76, 0x86, 0x09, 0xc1, 0x81, 0xac, 0x2a, 0x5d, 0x87, 0xef,
+	0x78, 0x21, 0x11, 0x7e, 0x97, 0x29, 0x9e, 0x31, 0x65, 0x33, 0xf1, 0xfb, 0x8a, 0xe9, 0x16, 0xff,
+	0x2f, 0x89, 0x2e, 0x42, 0x92, 0x30, 0xb2, 0x62, 0xca, 0x1b, 0x35, 0xc4, 0xfe, 0x25, 0xef, 0xb5,
+	0x49, 0xc2, 0xe8, 0x25, 0xf5, 0x31, 0x23, 0xfc, 0xbf, 0x28, 0x3f, 0x52, 0x36, 0xaa, 0xf8, 0x51,
+	0x6e, 0xb5, 0x8a, 0x66, 0xb5, 0x43, 0x68, 0xf8, 0x58, 0xe4, 0x79, 0x21, 0x5a, 0xcb, 0xa9, 0xfb,
+	0x98, 0xe7, 0x77, 0x5e, 0xf0, 0x52, 0x92, 0xf0, 0xb7, 0x59, 0xb4, 0xe4, 0x0f, 0x66, 0x21, 0x5f,
+	0xcb, 0xe9, 0x48, 0xf0, 0xb9, 0xc0, 0xb8, 0x9c, 0x61, 0x9c, 0x32, 0x99, 0xd0, 0x5b, 0x8e, 0x5c,
+	0x3c, 0xfc, 0xe8, 0x27, 0x27, 0x13, 0xca, 0xc2, 0xa5, 0x37, 0xf0, 0xe3, 0xf9, 0xbd, 0x70, 0xbd,
+	0x20, 0xc9, 0x8c, 0x04, 0x13, 0x92, 0xdc, 0xbb, 0xc4, 0x5e, 0x42, 0xfd, 0x7b, 0xf2, 0xcf, 0xba,
+	0xc5, 0x6c, 0x99, 0x7a, 0x75, 0xf1, 0x8f, 0xdd, 0x83, 0x7f, 0x0d, 0x00, 0xd0, 0xd5, 0x02, 0x29,
+	0xc1, 0x1b, 0x00, 0x00,
 }
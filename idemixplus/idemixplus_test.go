@@ -1,21 +1,63 @@
 package idemixplus
 
 import (
+	"crypto/ecdsa"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/hyperledger/fabric-amcl/amcl"
 	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
 	"github.com/stretchr/testify/assert"
 )
 
+// testNow is the time signatures in the tests prove validity at, within the validity window of the test credentials
+var (
+	testNow       = time.Now().Unix()
+	testNotBefore = testNow - 3600
+	testNotAfter  = testNow + 3600
+)
+
+// testUser is a user holding a credential, with the request it was issued on
+type testUser struct {
+	ukey  *UserKey
+	trace *Trace
+	usk   *FP256BN.BIG
+	m     *CredRequest
+	cred  *Credential
+}
+
+// newTestUser creates an issuer key on the attribute names and issues a credential on attrs to a new user
+func newTestUser(tb testing.TB, AttributeNames []string, attrs []*FP256BN.BIG, rng *amcl.RAND) (*IssuerKey, *testUser) {
+	key, err := NewIssuerKey(AttributeNames, rng)
+	assert.NoError(tb, err)
+	return key, issueTestUser(tb, key, AttributeNames, attrs, rng)
+}
+
+// issueTestUser issues a credential on attrs under the issuer key to a new user
+func issueTestUser(tb testing.TB, key *IssuerKey, AttributeNames []string, attrs []*FP256BN.BIG, rng *amcl.RAND) *testUser {
+	ukey, trace, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(tb, err)
+	usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
+	nonces := NewIssuerNonceStore(time.Minute)
+	m := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+	cred, err := NewCredential(key, nonces, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.NoError(tb, err)
+	return &testUser{ukey: ukey, trace: trace, usk: usk, m: m, cred: cred}
+}
+
 func TestIdemixplus(t *testing.T) {
 	//// Test KeyGen
 	rng := GetRand(32)
 	// Test idemixplus functionality
 	AttributeNames := []string{"Attr1", "Attr2", "Attr3", "Attr4", "Attr5"}
+	rhIndex := 3
 	attrs := make([]*FP256BN.BIG, len(AttributeNames))
 	for i := range AttributeNames {
 		attrs[i] = FP256BN.NewBIGint(i)
@@ -80,30 +122,76 @@ func TestIdemixplus(t *testing.T) {
 		userTime := time.Now().UnixNano()
 		// Test create credential request
 		usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
-		ni := RandModOrder(rng)
-		m := NewCredRequest(usk, BigToBytes(ni), key.Ipk, rng)
+		nonces := NewIssuerNonceStore(time.Minute)
+		m := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
 
 		// the Issuer chech the request from user
-		traces := new(Traces)
-		traces.TraceList = append(traces.TraceList, trace)
-		cred, err := NewCredential(key, m, ukey.Upk, attrs, rng)
+		traces := NewTraceIndex()
+		assert.NoError(t, traces.Add(trace))
+		cred, err := NewCredential(key, nonces, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
 		assert.NoError(t, err, "Failed to issue a credentoal: \"%s\"", err)
 		assert.NoError(t, cred.Ver(usk, key.Ipk), "credential should be valid")
 
+		// attribute values are bound to the credential signature
+		tampered := proto.Clone(cred).(*Credential)
+		tampered.Attrs[2] = BigToBytes(FP256BN.NewBIGint(42))
+		assert.Error(t, tampered.Ver(usk, key.Ipk), "credential with a modified attribute should be invalid")
+		offCurve := &ECP{X: BigToBytes(FP256BN.NewBIGint(1)), Y: BigToBytes(FP256BN.NewBIGint(1))}
+		for _, P := range []*ECP{offCurve, EcpToProto(FP256BN.NewECP())} {
+			tampered = proto.Clone(cred).(*Credential)
+			tampered.B = P
+			assert.Error(t, tampered.Ver(usk, key.Ipk), "credential with a malformed B should be invalid")
+			tampered.A = P
+			assert.Error(t, tampered.Ver(usk, key.Ipk), "credential with a malformed A and B should be invalid")
+		}
+		tamperedSig, err := NewNymSignature(usk, tampered, key.Ipk, []byte("tampered"), &SignOpts{ValidAt: testNow, Disclosure: []byte{1, 1, 1, 1, 1}, RhIndex: rhIndex}, rng)
+		assert.NoError(t, err)
+		tamperedAttrs := append([]*FP256BN.BIG{}, attrs...)
+		tamperedAttrs[2] = FP256BN.NewBIGint(42)
+		assert.Error(t, tamperedSig.Ver(key.GetIpk(), []byte("tampered"), &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 1, 1, 1, 1}, AttributeValues: tamperedAttrs, RhIndex: rhIndex}), "signature on a modified credential should be invalid")
+
 		creTime := time.Now().UnixNano()
 		// Generate a nymCredential
 		nymattrs := []byte{1, 1, 1, 0, 1}
 		msg := []byte("hello world")
 		msg1 := []byte("hello world1")
-		nymcred, err := NewNymSignature(usk, cred, key.Ipk, msg, nymattrs, nil, rng)
+		nymcred, err := NewNymSignature(usk, cred, key.Ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: nymattrs, RhIndex: rhIndex}, rng)
 		assert.NoError(t, err)
 		sigTime := time.Now().UnixNano()
-		assert.NoError(t, nymcred.Ver(key.GetIpk(), msg1, nil, 0))
+		assert.NoError(t, nymcred.Ver(key.GetIpk(), msg, &VerifyOpts{ValidAt: testNow, Disclosure: nymattrs, AttributeValues: attrs, RhIndex: rhIndex}))
+		assert.Error(t, nymcred.Ver(key.GetIpk(), msg1, &VerifyOpts{ValidAt: testNow, Disclosure: nymattrs, AttributeValues: attrs, RhIndex: rhIndex}), "signature should not verify for another message")
+
+		// disclosed attributes are checked against the expected disclosure and values
+		wrongAttrs := append([]*FP256BN.BIG{}, attrs...)
+		wrongAttrs[1] = FP256BN.NewBIGint(42)
+		assert.Error(t, nymcred.Ver(key.GetIpk(), msg, &VerifyOpts{ValidAt: testNow, Disclosure: nymattrs, AttributeValues: wrongAttrs, RhIndex: rhIndex}), "signature should not verify for another attribute value")
+		assert.Error(t, nymcred.Ver(key.GetIpk(), msg, &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 1, 0, 0, 1}, AttributeValues: attrs, RhIndex: rhIndex}), "signature should not verify for another disclosure")
+		forged := proto.Clone(nymcred).(*NymSignature)
+		forged.Attrs[1] = BigToBytes(FP256BN.NewBIGint(42))
+		assert.Error(t, forged.Ver(key.GetIpk(), msg, &VerifyOpts{ValidAt: testNow, Disclosure: nymattrs, AttributeValues: wrongAttrs, RhIndex: rhIndex}), "signature with a modified disclosed attribute should be invalid")
 		verTime := time.Now().UnixNano()
 		// Test arbitration
-		upk, err := Arbitration(traces, nymcred)
+		opts := &VerifyOpts{ValidAt: testNow, Disclosure: nymattrs, AttributeValues: attrs, RhIndex: rhIndex}
+		upk, opening, err := Arbitration(key, traces, nymcred, msg, opts, rng)
 		assert.NoError(t, err)
 		assert.Equal(t, upk, ukey.GetUpk(), "Not the same")
+		assert.NoError(t, VerifyOpening(key.GetIpk(), upk, nymcred, msg, opts, opening))
+
+		// the opening proof does not frame another user or hold for another message
+		otherKey, _, err := NewUserKey(AttributeNames1, rng)
+		assert.NoError(t, err)
+		assert.Error(t, VerifyOpening(key.GetIpk(), otherKey.GetUpk(), nymcred, msg, opts, opening), "opening should not hold for another user")
+		assert.Error(t, VerifyOpening(key.GetIpk(), upk, nymcred, msg1, opts, opening), "opening should be bound to the message")
+		framing := proto.Clone(opening).(*OpeningProof)
+		framing.T = otherKey.GetUpk().GetW()
+		assert.Error(t, VerifyOpening(key.GetIpk(), otherKey.GetUpk(), nymcred, msg, opts, framing), "opening should not hold for another trace")
+
+		// the tracing tag is bound to the signature
+		forged = proto.Clone(nymcred).(*NymSignature)
+		forged.TraceC2 = EcpToProto(EcpFromProto(forged.TraceC2).Mul(FP256BN.NewBIGint(2)))
+		assert.Error(t, forged.Ver(key.GetIpk(), msg, &VerifyOpts{ValidAt: testNow, Disclosure: nymattrs, AttributeValues: attrs, RhIndex: rhIndex}), "signature with a modified tracing tag should be invalid")
+		_, _, err = Arbitration(key, NewTraceIndex(), nymcred, msg, opts, rng)
+		assert.Error(t, err, "unregistered user should not be traced")
 
 		traceTime := time.Now().UnixNano()
 
@@ -125,3 +213,1489 @@ func TestIdemixplus(t *testing.T) {
 	fmt.Println("*****************E N D*******************")
 	fmt.Println("-----------------------------------------")
 }
+
+func TestNonRevocation(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2", "RevocationHandle"}
+	rhIndex := 2
+	rh := RandModOrder(rng)
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2), rh}
+	key, user := newTestUser(t, AttributeNames, attrs, rng)
+	usk, cred := user.usk, user.cred
+
+	revocationKey, err := GenerateLongTermRevocationKey()
+	assert.NoError(t, err)
+	epoch := 7
+	cri, err := CreateCRI(revocationKey, []*FP256BN.BIG{RandModOrder(rng), rh}, epoch, ALG_PLAIN_SIGNATURE, rng)
+	assert.NoError(t, err)
+
+	disclosure := []byte{1, 0, 0}
+	msg := []byte("non-revoked")
+	sig, err := NewNymSignature(usk, cred, key.Ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: rhIndex, Cri: cri}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, sig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch}))
+	err = sig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch + 1})
+	assert.True(t, IsExpired(err), "signature from a stale epoch should be rejected as expired")
+	err = sig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch - 1})
+	assert.Equal(t, ErrKindInvalid, VerificationErrorKindOf(err), "signature from a future epoch should be rejected")
+	assert.Error(t, sig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, Epoch: epoch}), "non-revocation proof needs the revocation public key")
+	err = sig.Ver(key.Ipk, []byte("forged"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch})
+	assert.Error(t, err)
+	assert.False(t, IsRevoked(err) || IsExpired(err), "forged signature should not be reported as revoked or expired")
+
+	otherKey, err := GenerateLongTermRevocationKey()
+	assert.NoError(t, err)
+	assert.Error(t, sig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &otherKey.PublicKey, Epoch: epoch}), "epoch key signed by another authority should be rejected")
+
+	// the revocation handle must stay hidden
+	_, err = NewNymSignature(usk, cred, key.Ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: []byte{0, 0, 1}, RhIndex: rhIndex, Cri: cri}, rng)
+	assert.Error(t, err)
+
+	// a user whose handle is not in the CRI cannot prove non-revocation
+	revokedCri, err := CreateCRI(revocationKey, []*FP256BN.BIG{RandModOrder(rng)}, epoch, ALG_PLAIN_SIGNATURE, rng)
+	assert.NoError(t, err)
+	_, err = NewNymSignature(usk, cred, key.Ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: rhIndex, Cri: revokedCri}, rng)
+	assert.Error(t, err)
+
+	// a proof made for another epoch key does not verify
+	forged := proto.Clone(sig).(*NymSignature)
+	forged.RevocationEpochPk = revokedCri.EpochPk
+	forged.RevocationPkSig = revokedCri.EpochPkSig
+	assert.Error(t, forged.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch}))
+
+	// the epoch is bound to the proof
+	forged = proto.Clone(sig).(*NymSignature)
+	forged.Epoch = int64(epoch + 1)
+	assert.Error(t, forged.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch + 1}))
+
+	// a broken non-revocation proof is reported as revoked
+	forged = proto.Clone(sig).(*NymSignature)
+	forged.NonRevocationProof.NonRevocationProof = nil
+	assert.True(t, IsRevoked(forged.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch})))
+
+	// without a revocation algorithm the epoch is still enforced when a revocation public key is given
+	noRevCri, err := CreateCRI(revocationKey, nil, epoch, ALG_NO_REVOCATION, rng)
+	assert.NoError(t, err)
+	noRevSig, err := NewNymSignature(usk, cred, key.Ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: rhIndex, Cri: noRevCri}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, noRevSig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch}))
+	assert.NoError(t, noRevSig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex}))
+	assert.True(t, IsExpired(noRevSig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch + 1})))
+	assert.Error(t, noRevSig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &otherKey.PublicKey, Epoch: epoch}))
+	plainSig, err := NewNymSignature(usk, cred, key.Ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: rhIndex}, rng)
+	assert.NoError(t, err)
+	assert.True(t, IsRevoked(plainSig.Ver(key.Ipk, msg, &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch})), "signature without revocation information should be rejected")
+}
+
+// benchmarkTraces holds 100k synthetic traces, built once for all runs of BenchmarkArbitration
+var benchmarkTraces struct {
+	sync.Once
+	list []*Trace
+}
+
+// arbitrationLookupBudget is the time the lookup of the signer of a signature among 100k registered users may take
+const arbitrationLookupBudget = time.Millisecond
+
+// BenchmarkArbitration traces a signature among 100k registered users. Lookup looks the signer up by the
+// opened tracing tag, the only step whose cost could grow with the number of users, and fails when it takes
+// longer than arbitrationLookupBudget. Arbitration adds the opening of the tag, the verification of
+// the signature and the opening proof, which take the same time for any number of users.
+func BenchmarkArbitration(b *testing.B) {
+	rng := GetRand(32)
+	key, user := newTestUser(b, []string{"Attr1"}, []*FP256BN.BIG{FP256BN.NewBIGint(1)}, rng)
+	sig, err := NewNymSignature(user.usk, user.cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: []byte{0}, RhIndex: -1}, rng)
+	assert.NoError(b, err)
+	opts := &VerifyOpts{ValidAt: testNow, Disclosure: []byte{0}, AttributeValues: []*FP256BN.BIG{FP256BN.NewBIGint(1)}, RhIndex: -1}
+
+	benchmarkTraces.Do(func() {
+		P := genG1().Mul(RandModOrder(rng))
+		for i := 0; i < 100000; i++ {
+			P.Add(genG1())
+			benchmarkTraces.list = append(benchmarkTraces.list, &Trace{Upk: &UserPublicKey{UPK: EcpToProto(P)}})
+		}
+	})
+	index := NewTraceIndex()
+	assert.NoError(b, index.AddTraces(&Traces{TraceList: benchmarkTraces.list}))
+	assert.NoError(b, index.Add(user.trace))
+	z := FP256BN.FromBytes(key.GetIsk().GetTracingSk())
+	upk := EcpFromProto(sig.GetTraceC1()).Mul(FP256BN.Modneg(z, GroupOrder))
+	upk.Add(EcpFromProto(sig.GetTraceC2()))
+
+	b.Run("Lookup", func(b *testing.B) {
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			trace, err := index.lookup(upk)
+			if err != nil || trace != user.trace {
+				b.Fatalf("lookup should find the signer")
+			}
+		}
+		perLookup := time.Since(start) / time.Duration(b.N)
+		b.ReportMetric(float64(perLookup)/float64(time.Millisecond), "ms/lookup")
+		if perLookup > arbitrationLookupBudget {
+			b.Fatalf("lookup among %d users takes %v, the budget is %v", index.Len(), perLookup, arbitrationLookupBudget)
+		}
+	})
+	b.Run("Arbitration", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			upk, _, err := Arbitration(key, index, sig, []byte("msg"), opts, rng)
+			if err != nil || upk != user.ukey.Upk {
+				b.Fatalf("Arbitration should find the signer")
+			}
+		}
+	})
+}
+
+func TestThresholdArbitration(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	key, user := newTestUser(t, AttributeNames, attrs, rng)
+	ukey, trace, usk, cred := user.ukey, user.trace, user.usk, user.cred
+	sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+
+	index := NewTraceIndex()
+	assert.NoError(t, index.Add(trace))
+
+	_, _, err = SplitTracingKey(key, 4, 3, rng)
+	assert.Error(t, err, "threshold above the number of arbitrators should be rejected")
+	arbitratorKeys, apk, err := SplitTracingKey(key, 3, 5, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, apk.Check(key.Ipk))
+	opts := &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}
+	_, _, err = Arbitration(key, index, sig, []byte("msg"), opts, rng)
+	assert.Error(t, err, "the issuer alone should not trace after the split")
+
+	shares := make([]*OpeningShare, len(arbitratorKeys))
+	for j, ak := range arbitratorKeys {
+		shares[j], err = NewOpeningShare(ak, sig, rng)
+		assert.NoError(t, err)
+		assert.NoError(t, apk.VerifyOpeningShare(shares[j], sig))
+	}
+
+	// any 3 of the 5 arbitrators can trace
+	upk, err := CombineOpeningShares(apk, key.Ipk, index, sig, []byte("msg"), opts, []*OpeningShare{shares[4], shares[1], shares[2]})
+	assert.NoError(t, err)
+	assert.Equal(t, ukey.GetUpk(), upk)
+
+	// 2 shares, a repeated share or a wrong share are not enough
+	_, err = CombineOpeningShares(apk, key.Ipk, index, sig, []byte("msg"), opts, shares[:2])
+	assert.Error(t, err)
+	_, err = CombineOpeningShares(apk, key.Ipk, index, sig, []byte("msg"), opts, []*OpeningShare{shares[0], shares[0], shares[1]})
+	assert.Error(t, err)
+	wrong := proto.Clone(shares[3]).(*OpeningShare)
+	wrong.D = EcpToProto(genG1().Mul(RandModOrder(rng)))
+	assert.Error(t, apk.VerifyOpeningShare(wrong, sig))
+	_, err = CombineOpeningShares(apk, key.Ipk, index, sig, []byte("msg"), opts, []*OpeningShare{shares[0], wrong, shares[1]})
+	assert.Error(t, err)
+
+	// an invalid share is skipped when enough valid ones are given
+	upk, err = CombineOpeningShares(apk, key.Ipk, index, sig, []byte("msg"), opts, []*OpeningShare{wrong, shares[0], shares[3], shares[1]})
+	assert.NoError(t, err)
+	assert.Equal(t, ukey.GetUpk(), upk)
+
+	// anyone can check the opening with the public keys, it opens to the signer only
+	opening := &ThresholdOpening{Shares: []*OpeningShare{shares[4], shares[1], shares[2]}}
+	assert.NoError(t, VerifyThresholdOpening(apk, key.Ipk, ukey.GetUpk(), sig, []byte("msg"), opts, opening))
+	other, _, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	assert.Error(t, VerifyThresholdOpening(apk, key.Ipk, other.GetUpk(), sig, []byte("msg"), opts, opening))
+	assert.Error(t, VerifyThresholdOpening(apk, key.Ipk, ukey.GetUpk(), sig, []byte("other"), opts, opening))
+	assert.Error(t, VerifyThresholdOpening(apk, key.Ipk, ukey.GetUpk(), sig, []byte("msg"), opts, &ThresholdOpening{Shares: shares[:2]}))
+
+	// a share of an arbitrator is bound to its index
+	misplaced := proto.Clone(shares[0]).(*OpeningShare)
+	misplaced.Index = 2
+	assert.Error(t, apk.VerifyOpeningShare(misplaced, sig))
+
+	// shares that do not match the tracing key are detected
+	_, otherApk, err := SplitTracingKey(&IssuerKey{Isk: &SecretKey{TracingSk: BigToBytes(RandModOrder(rng))}}, 3, 5, rng)
+	assert.NoError(t, err)
+	assert.Error(t, otherApk.Check(key.Ipk))
+}
+
+// TestForgedTracingTag checks that a tracing tag encrypting the public key of a victim,
+// which anyone can build from public values, is not opened to the victim
+func TestForgedTracingTag(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	key, err := NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	victim, trace, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	index := NewTraceIndex()
+	assert.NoError(t, index.Add(trace))
+
+	// the forger signs with a credential of its own
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	forger := issueTestUser(t, key, AttributeNames, attrs, rng)
+	usk, cred := forger.usk, forger.cred
+	msg := []byte("msg")
+	sig, err := NewNymSignature(usk, cred, key.Ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+
+	// Xi = g_1^u, Eta = UPK^u, C_1 = g_1^k, C_2 = UPK \cdot Z^k for the public key UPK of the victim
+	UPK := EcpFromProto(victim.GetUpk().GetUPK())
+	u, k := RandModOrder(rng), RandModOrder(rng)
+	forged := proto.Clone(sig).(*NymSignature)
+	forged.Xi = EcpToProto(genG1().Mul(u))
+	forged.Eta = EcpToProto(UPK.Mul(u))
+	forged.TraceC1 = EcpToProto(genG1().Mul(k))
+	TraceC2 := EcpFromProto(key.GetIpk().GetTracingPk()).Mul(k)
+	TraceC2.Add(UPK)
+	forged.TraceC2 = EcpToProto(TraceC2)
+
+	opts := &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}
+	assert.Error(t, forged.Ver(key.Ipk, msg, opts))
+	_, _, err = Arbitration(key, index, forged, msg, opts, rng)
+	assert.Error(t, err, "a forged signature should not be opened")
+
+	// an opening of the forged tag is correct for the victim, yet it is not accepted
+	z := FP256BN.FromBytes(key.GetIsk().GetTracingSk())
+	opening := newOpeningProof(key, z, trace, UPK, EcpFromProto(forged.GetTraceC1()), forged, msg, rng)
+	assert.Error(t, VerifyOpening(key.Ipk, victim.Upk, forged, msg, opts, opening), "the opening of a forged signature should be rejected")
+
+	arbitratorKeys, apk, err := SplitTracingKey(key, 2, 3, rng)
+	assert.NoError(t, err)
+	var shares []*OpeningShare
+	for _, ak := range arbitratorKeys {
+		share, err := NewOpeningShare(ak, forged, rng)
+		assert.NoError(t, err)
+		shares = append(shares, share)
+	}
+	_, err = CombineOpeningShares(apk, key.Ipk, index, forged, msg, opts, shares)
+	assert.Error(t, err, "arbitrators should not open a forged signature")
+}
+
+func TestThresholdIssuer(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	threshold, n := 2, 3
+
+	deals := make([]*DKGDeal, n)
+	var err error
+	for j := range deals {
+		deals[j], err = NewDKGDeal(j+1, threshold, n, AttributeNames, rng)
+		assert.NoError(t, err)
+	}
+	shares := make([]*IssuerKeyShare, n)
+	var partialKeys []*PartialIssuerPublicKey
+	for j := range shares {
+		shares[j], err = CompleteDKG(j+1, deals)
+		assert.NoError(t, err)
+		partialKeys = append(partialKeys, shares[j].Partial)
+	}
+
+	// a dealer that deals a share not matching its commitments is detected
+	cheat := proto.Clone(deals[1]).(*DKGDeal)
+	cheat.Shares[0].Secrets[dkgX] = BigToBytes(RandModOrder(rng))
+	_, err = CompleteDKG(1, []*DKGDeal{deals[0], cheat, deals[2]})
+	assert.Error(t, err)
+	// and so is a dealer that commits to x in G1
+	cheat = proto.Clone(deals[1]).(*DKGDeal)
+	cheat.Commitments[dkgX].G1 = []*ECP{EcpToProto(genG1()), EcpToProto(genG1())}
+	_, err = CompleteDKG(1, []*DKGDeal{deals[0], cheat, deals[2]})
+	assert.Error(t, err)
+
+	// every issuer combines threshold partial keys and proves its share of the key
+	_, err = shares[0].ProveKey()
+	assert.Error(t, err, "the key is proven once the partial keys are combined")
+	assert.Error(t, shares[0].Tpk.CombinePartialKeys(partialKeys[:1]))
+	wrongKey := proto.Clone(partialKeys[1]).(*PartialIssuerPublicKey)
+	wrongKey.BarG2 = partialKeys[2].BarG2
+	var proofs []*PartialKeyProof
+	for j, share := range shares {
+		assert.NoError(t, share.Tpk.CombinePartialKeys([]*PartialIssuerPublicKey{wrongKey, partialKeys[(j+1)%n], partialKeys[j]}))
+		proof, err := share.ProveKey()
+		assert.NoError(t, err)
+		proofs = append(proofs, proof)
+	}
+
+	// the issuer public key is complete with threshold partial proofs
+	assert.Error(t, shares[0].Tpk.Combine(proofs[:1]))
+	for _, share := range shares {
+		assert.NoError(t, share.Tpk.Combine([]*PartialKeyProof{proofs[2], proofs[0]}))
+	}
+	ipk := shares[0].Tpk.Ipk
+	assert.NoError(t, ipk.Check())
+	assert.Equal(t, ipk.Hash, shares[1].Tpk.Ipk.Hash, "all issuers should agree on the issuer public key")
+	assert.Equal(t, ipk.Hash, shares[2].Tpk.Ipk.Hash, "all issuers should agree on the issuer public key")
+
+	// the key publishes neither g1^x nor g1^y: a credential forged as if BarG2 = g1^x and BarG3 = g1^y
+	// does not verify, A = g1^s and B = BarG2^s \cdot BarG3^{s \cdot usk} \cdot \prod_i HAttrs_i^{s \cdot attr_i} \cdot \prod_v HValidity_v^{s \cdot validity_v}
+	forgedAttrs := []*FP256BN.BIG{FP256BN.NewBIGint(7), FP256BN.NewBIGint(8)}
+	forgedUsk, s := RandModOrder(rng), RandModOrder(rng)
+	B := EcpFromProto(ipk.BarG2).Mul(s)
+	B.Add(EcpFromProto(ipk.BarG3).Mul(FP256BN.Modmul(s, forgedUsk, GroupOrder)))
+	for i, attr := range forgedAttrs {
+		B.Add(EcpFromProto(ipk.HAttrs[i]).Mul(FP256BN.Modmul(s, attr, GroupOrder)))
+	}
+	for v, value := range validityValues(testNotBefore, testNotAfter) {
+		B.Add(EcpFromProto(ipk.HValidity[v]).Mul(FP256BN.Modmul(s, value, GroupOrder)))
+	}
+	forged := &Credential{A: EcpToProto(genG1().Mul(s)), B: EcpToProto(B), AttributeNames: AttributeNames,
+		Attrs: [][]byte{BigToBytes(forgedAttrs[0]), BigToBytes(forgedAttrs[1])}, NotBefore: testNotBefore, NotAfter: testNotAfter, KeyId: ipk.KeyId}
+	assert.Error(t, forged.Ver(forgedUsk, ipk))
+	for s, commitment := range shares[0].Tpk.Commitments {
+		if s != dkgZ {
+			assert.Empty(t, commitment.G1, "only the tracing secret is committed to in G1")
+		}
+	}
+
+	ukey, trace, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	m := NewThresholdCredRequest(usk, BigToBytes(RandModOrder(rng)), ipk, attrs, testNotBefore, testNotAfter, rng)
+	assert.NoError(t, m.Check(ipk, ukey.Upk, attrs, testNotBefore, testNotAfter))
+	otherKey, _, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	assert.Error(t, m.Check(ipk, otherKey.Upk, attrs, testNotBefore, testNotAfter), "request should be bound to the user public key")
+	_, err = NewPartialCredential(shares[0], m, ukey.Upk, []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(3)}, testNotBefore, testNotAfter)
+	assert.Error(t, err, "request should be bound to the attribute values")
+
+	partials := make([]*PartialCredential, n)
+	for j, share := range shares {
+		partials[j], err = NewPartialCredential(share, m, ukey.Upk, attrs, testNotBefore, testNotAfter)
+		assert.NoError(t, err)
+		assert.NoError(t, shares[0].Tpk.VerifyPartialCredential(usk, m, attrs, testNotBefore, testNotAfter, partials[j]))
+	}
+	misplaced := proto.Clone(partials[0]).(*PartialCredential)
+	misplaced.Index = 2
+	assert.Error(t, shares[0].Tpk.VerifyPartialCredential(usk, m, attrs, testNotBefore, testNotAfter, misplaced))
+
+	_, err = AggregateCredential(shares[0].Tpk, usk, m, attrs, testNotBefore, testNotAfter, partials[:1])
+	assert.Error(t, err, "one partial credential should not be enough")
+	_, err = AggregateCredential(shares[0].Tpk, usk, m, attrs, testNotBefore, testNotAfter, []*PartialCredential{partials[1], misplaced})
+	assert.Error(t, err)
+	cred, err := AggregateCredential(shares[0].Tpk, usk, m, attrs, testNotBefore, testNotAfter, []*PartialCredential{misplaced, partials[2], partials[1]})
+	assert.NoError(t, err)
+	assert.NoError(t, cred.Ver(usk, ipk))
+
+	// the credential is used like one of a single issuer and traced by threshold issuers
+	msg := []byte("threshold")
+	sig, err := NewNymSignature(usk, cred, ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	opts := &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}
+	assert.NoError(t, sig.Ver(ipk, msg, opts))
+
+	index := NewTraceIndex()
+	assert.NoError(t, index.Add(trace))
+	apk := shares[0].Tpk.ArbitrationPublicKey()
+	assert.NoError(t, apk.Check(ipk))
+	var openings []*OpeningShare
+	for _, share := range shares[1:] {
+		opening, err := NewOpeningShare(share.ArbitratorKey(), sig, rng)
+		assert.NoError(t, err)
+		openings = append(openings, opening)
+	}
+	upk, err := CombineOpeningShares(apk, ipk, index, sig, msg, opts, openings)
+	assert.NoError(t, err)
+	assert.Equal(t, ukey.GetUpk(), upk)
+}
+
+func TestEncoding(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	key, user := newTestUser(t, AttributeNames, attrs, rng)
+	ukey, trace, usk, cred := user.ukey, user.trace, user.usk, user.cred
+	sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+
+	// every type survives a round trip through both encodings
+	raw, err := key.Bytes()
+	assert.NoError(t, err)
+	decodedKey, err := IssuerKeyFromBytes(raw)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(key, decodedKey))
+	text, err := key.Ipk.Text()
+	assert.NoError(t, err)
+	decodedIpk, err := IssuerPublicKeyFromText(text)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(key.Ipk, decodedIpk))
+
+	raw, err = ukey.Bytes()
+	assert.NoError(t, err)
+	decodedUkey, err := UserKeyFromBytes(raw)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(ukey, decodedUkey))
+	raw, err = ukey.Upk.Bytes()
+	assert.NoError(t, err)
+	decodedUpk, err := UserPublicKeyFromBytes(raw)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(ukey.Upk, decodedUpk))
+	text, err = ukey.Usk.Text()
+	assert.NoError(t, err)
+	decodedUsk, err := UserSecretKeyFromText(text)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(ukey.Usk, decodedUsk))
+
+	text, err = trace.Text()
+	assert.NoError(t, err)
+	decodedTrace, err := TraceFromText(text)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(trace, decodedTrace))
+	raw, err = user.m.Bytes()
+	assert.NoError(t, err)
+	decodedRequest, err := CredRequestFromBytes(raw)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(user.m, decodedRequest))
+	raw, err = cred.Bytes()
+	assert.NoError(t, err)
+	decodedCred, err := CredentialFromBytes(raw)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(cred, decodedCred))
+
+	raw, err = sig.Bytes()
+	assert.NoError(t, err)
+	again, err := sig.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, raw, again, "encoding should be stable")
+	decodedSig, err := NymSignatureFromBytes(raw)
+	assert.NoError(t, err)
+	assert.NoError(t, decodedSig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}))
+	text, err = sig.Text()
+	assert.NoError(t, err)
+	_, err = NymSignatureFromText(text)
+	assert.NoError(t, err)
+
+	// encodings of another type, version or with a broken checksum are rejected
+	_, err = CredentialFromBytes(raw)
+	assert.Error(t, err)
+	_, err = CredentialFromText(text)
+	assert.Error(t, err)
+	corrupted := append([]byte{}, raw...)
+	corrupted[len(corrupted)/2] ^= 1
+	_, err = NymSignatureFromBytes(corrupted)
+	assert.Error(t, err)
+	_, err = NymSignatureFromBytes(raw[:len(raw)-1])
+	assert.Error(t, err)
+	future, err := encodeBytesVersion(TypeNymSignature, EncodingVersion+1, sig)
+	assert.NoError(t, err)
+	_, err = NymSignatureFromBytes(future)
+	assert.Error(t, err)
+
+	// well-formed encodings of incomplete objects are rejected
+	raw, err = (&UserPublicKey{}).Bytes()
+	assert.NoError(t, err)
+	_, err = UserPublicKeyFromBytes(raw)
+	assert.Error(t, err)
+
+	// objects made before point compression are still read and keep their key IDs and hashes
+	legacyIpk := proto.Clone(key.Ipk).(*IssuerPublicKey)
+	uncompressPoints(legacyIpk)
+	legacyRaw, err := encodeBytesVersion(TypeIssuerPublicKey, EncodingVersionUncompressed, legacyIpk)
+	assert.NoError(t, err)
+	decodedIpk, err = IssuerPublicKeyFromBytes(legacyRaw)
+	assert.NoError(t, err)
+	assert.NoError(t, decodedIpk.Check())
+	assert.Equal(t, key.Ipk.KeyId, decodedIpk.KeyId)
+	assert.Equal(t, key.Ipk.Hash, decodedIpk.Hash)
+	raw, err = decodedIpk.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, EncodingVersion, raw[5])
+	assert.True(t, len(raw) < len(legacyRaw), "compressed keys are smaller")
+
+	legacySig := proto.Clone(sig).(*NymSignature)
+	uncompressPoints(legacySig)
+	compressedCount, uncompressedCount := pointForms(legacySig)
+	assert.Zero(t, compressedCount)
+	assert.NotZero(t, uncompressedCount)
+	legacyRaw, err = encodeBytesVersion(TypeNymSignature, EncodingVersionUncompressed, legacySig)
+	assert.NoError(t, err)
+	decodedSig, err = NymSignatureFromBytes(legacyRaw)
+	assert.NoError(t, err)
+	assert.NoError(t, decodedSig.Ver(decodedIpk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}))
+	raw, err = decodedSig.Bytes()
+	assert.NoError(t, err)
+	assert.True(t, len(raw) < len(legacyRaw), "compressed signatures are smaller")
+	// records stored as plain protobuf are read whichever way their points are encoded
+	record, err := proto.Marshal(legacySig)
+	assert.NoError(t, err)
+	fromRecord := &NymSignature{}
+	assert.NoError(t, proto.Unmarshal(record, fromRecord))
+	assert.NoError(t, fromRecord.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}))
+
+	// the points of a payload must be encoded as its version requires
+	mixed, err := encodeBytesVersion(TypeNymSignature, EncodingVersionUncompressed, sig)
+	assert.NoError(t, err)
+	_, err = NymSignatureFromBytes(mixed)
+	assert.Error(t, err)
+	mixed, err = encodeBytesVersion(TypeNymSignature, EncodingVersion, legacySig)
+	assert.NoError(t, err)
+	_, err = NymSignatureFromBytes(mixed)
+	assert.Error(t, err)
+}
+
+func TestPointValidation(t *testing.T) {
+	rng := GetRand(32)
+
+	// points that are not on the curve or have unreduced coordinates are rejected
+	R := genG1().Mul(RandModOrder(rng))
+	valid := &ECP{X: BigToBytes(R.GetX()), Y: BigToBytes(R.GetY())}
+	P, err := EcpFromProtoChecked(valid)
+	assert.NoError(t, err)
+	assert.True(t, P.Equals(EcpFromProto(valid)))
+	y := Modadd(FP256BN.FromBytes(valid.Y), FP256BN.NewBIGint(1), fieldModulus)
+	offCurve := &ECP{X: valid.X, Y: BigToBytes(y)}
+	_, err = EcpFromProtoChecked(offCurve)
+	assert.Error(t, err)
+	_, err = EcpFromProtoChecked(&ECP{X: BigToBytes(fieldModulus), Y: valid.Y})
+	assert.Error(t, err)
+	_, err = EcpFromProtoChecked(&ECP{X: valid.X[1:], Y: valid.Y})
+	assert.Error(t, err)
+	_, err = EcpFromProtoChecked(EcpToProto(FP256BN.NewECP()))
+	assert.Error(t, err)
+	_, err = EcpFromProtoChecked(nil)
+	assert.Error(t, err)
+
+	// compressed points decompress to the point they encode, malformed ones are rejected
+	compressed := EcpToProto(P)
+	assert.Len(t, compressed.Compressed, FieldBytes+1)
+	Q, err := EcpFromProtoChecked(compressed)
+	assert.NoError(t, err)
+	assert.True(t, Q.Equals(P))
+	Q, err = EcpFromProtoChecked(&ECP{Compressed: append([]byte{compressed.Compressed[0] ^ 1}, compressed.Compressed[1:]...)})
+	assert.NoError(t, err)
+	Q.Add(P)
+	assert.True(t, Q.Is_infinity(), "the other prefix encodes the negated point")
+	_, err = EcpFromProtoChecked(&ECP{Compressed: append([]byte{0x04}, compressed.Compressed[1:]...)})
+	assert.Error(t, err)
+	_, err = EcpFromProtoChecked(&ECP{X: valid.X, Y: valid.Y, Compressed: compressed.Compressed})
+	assert.Error(t, err)
+	_, err = EcpFromProtoChecked(&ECP{Compressed: compressed.Compressed[1:]})
+	assert.Error(t, err)
+	for i := 1; ; i++ {
+		if FP256BN.NewECPbig(FP256BN.NewBIGint(i)).Is_infinity() {
+			_, err = EcpFromProtoChecked(&ECP{Compressed: append([]byte{0x02}, BigToBytes(FP256BN.NewBIGint(i))...)})
+			assert.Error(t, err, "x without a point on the curve")
+			break
+		}
+	}
+	for _, P2 := range []*FP256BN.ECP2{genG2().Mul(RandModOrder(rng)), genG2().Mul(RandModOrder(rng))} {
+		negated := FP256BN.NewECP2()
+		negated.Sub(P2)
+		for _, Q2 := range []*FP256BN.ECP2{P2, negated} {
+			decoded, err := Ecp2FromProtoChecked(Ecp2ToProto(Q2))
+			assert.NoError(t, err)
+			assert.True(t, decoded.Equals(Q2))
+		}
+	}
+
+	// points on the twist outside the subgroup of order q are rejected
+	_, err = Ecp2FromProtoChecked(Ecp2ToProto(genG2().Mul(RandModOrder(rng))))
+	assert.NoError(t, err)
+	var outside *FP256BN.ECP2
+	for i := 1; outside == nil; i++ {
+		Q := FP256BN.NewECP2fp2(FP256BN.NewFP2bigs(FP256BN.NewBIGint(i), FP256BN.NewBIGint(1)))
+		if !Q.Is_infinity() && !Q.Mul(GroupOrder).Is_infinity() {
+			outside = Q
+		}
+	}
+	_, err = Ecp2FromProtoChecked(Ecp2ToProto(outside))
+	assert.Error(t, err)
+
+	// scalars must be FieldBytes long and reduced modulo the group order
+	_, err = BigFromBytesChecked(BigToBytes(RandModOrder(rng)))
+	assert.NoError(t, err)
+	_, err = BigFromBytesChecked(BigToBytes(GroupOrder))
+	assert.Error(t, err)
+	_, err = BigFromBytesChecked([]byte{1, 2, 3})
+	assert.Error(t, err)
+
+	// the protocol checks reject malformed inputs instead of computing with them
+	AttributeNames := []string{"Attr1", "Attr2"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	key, err := NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	ukey, trace, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
+
+	nonces := NewIssuerNonceStore(time.Minute)
+	m := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+	assert.NoError(t, m.checkProof(key.Ipk))
+	badRequest := proto.Clone(m).(*CredRequest)
+	badRequest.Nym = offCurve
+	assert.Error(t, badRequest.checkProof(key.Ipk))
+	badRequest = proto.Clone(m).(*CredRequest)
+	badRequest.ProofS1 = BigToBytes(GroupOrder)
+	assert.Error(t, badRequest.checkProof(key.Ipk))
+
+	badIpk := proto.Clone(key.Ipk).(*IssuerPublicKey)
+	badIpk.BarAttrs[1] = Ecp2ToProto(outside)
+	assert.Error(t, badIpk.Check())
+	badUpk := proto.Clone(ukey.Upk).(*UserPublicKey)
+	badUpk.HSk = offCurve
+	assert.Error(t, badUpk.Check())
+
+	cred, err := NewCredential(key, nonces, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.NoError(t, err)
+	sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}))
+	badSig := proto.Clone(sig).(*NymSignature)
+	badSig.Xi = offCurve
+	err = badSig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1})
+	assert.Error(t, err)
+	assert.Equal(t, ErrKindInvalid, VerificationErrorKindOf(err))
+	badSig = proto.Clone(sig).(*NymSignature)
+	badSig.ProofSHidden[0] = BigToBytes(GroupOrder)
+	assert.Error(t, badSig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}))
+
+	index := NewTraceIndex()
+	assert.NoError(t, index.Add(trace))
+	badSig = proto.Clone(sig).(*NymSignature)
+	badSig.TraceC1 = offCurve
+	opts := &VerifyOpts{ValidAt: testNow, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}
+	_, _, err = Arbitration(key, index, badSig, []byte("msg"), opts, rng)
+	assert.Error(t, err)
+	upk, opening, err := Arbitration(key, index, sig, []byte("msg"), opts, rng)
+	assert.NoError(t, err)
+	opening.T = Ecp2ToProto(outside)
+	assert.Error(t, VerifyOpening(key.Ipk, upk, sig, []byte("msg"), opts, opening))
+}
+
+func TestScopePseudonym(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	disclosure := []byte{1, 0}
+	key, err := NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+
+	newUser := func() (*FP256BN.BIG, *Credential) {
+		user := issueTestUser(t, key, AttributeNames, attrs, rng)
+		return user.usk, user.cred
+	}
+	usk, cred := newUser()
+	otherUsk, otherCred := newUser()
+
+	vote := []byte("election-2020")
+	sig1, err := NewNymSignature(usk, cred, key.Ipk, []byte("yes"), &SignOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	sig2, err := NewNymSignature(usk, cred, key.Ipk, []byte("no"), &SignOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, sig1.Ver(key.Ipk, []byte("yes"), &VerifyOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	assert.NoError(t, sig2.Ver(key.Ipk, []byte("no"), &VerifyOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+
+	// the same user is linked within a scope, but not across scopes or with other users
+	assert.True(t, LinkedInScope(sig1, sig2))
+	other, err := NewNymSignature(otherUsk, otherCred, key.Ipk, []byte("yes"), &SignOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, other.Ver(key.Ipk, []byte("yes"), &VerifyOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	assert.False(t, LinkedInScope(sig1, other))
+	sig3, err := NewNymSignature(usk, cred, key.Ipk, []byte("yes"), &SignOpts{Scope: []byte("election-2024"), ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.False(t, LinkedInScope(sig1, sig3))
+	assert.False(t, EcpFromProto(sig1.ScopeNym).Equals(EcpFromProto(sig3.ScopeNym)))
+	unscoped, err := NewNymSignature(usk, cred, key.Ipk, []byte("yes"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.Nil(t, unscoped.ScopeNym)
+	assert.False(t, LinkedInScope(unscoped, unscoped))
+
+	// a signature verifies only for its own scope
+	assert.Error(t, sig1.Ver(key.Ipk, []byte("yes"), &VerifyOpts{Scope: []byte("election-2024"), ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	assert.Error(t, sig1.Ver(key.Ipk, []byte("yes"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	assert.Error(t, unscoped.Ver(key.Ipk, []byte("yes"), &VerifyOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+
+	// the pseudonym cannot be replaced with the one of another user or moved to another scope
+	forged := proto.Clone(sig1).(*NymSignature)
+	forged.ScopeNym = other.ScopeNym
+	assert.Error(t, forged.Ver(key.Ipk, []byte("yes"), &VerifyOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	forged = proto.Clone(sig1).(*NymSignature)
+	forged.Scope = sig3.Scope
+	forged.ScopeNym = sig3.ScopeNym
+	assert.Error(t, forged.Ver(key.Ipk, []byte("yes"), &VerifyOpts{Scope: sig3.Scope, ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+
+	// the scope survives the encoding
+	raw, err := sig1.Bytes()
+	assert.NoError(t, err)
+	decoded, err := NymSignatureFromBytes(raw)
+	assert.NoError(t, err)
+	assert.NoError(t, decoded.Ver(key.Ipk, []byte("yes"), &VerifyOpts{Scope: vote, ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	assert.True(t, LinkedInScope(decoded, sig2))
+}
+
+func TestRangeProof(t *testing.T) {
+	rng := GetRand(32)
+	// Attr2 is a birth year, Attr3 an expiry date
+	AttributeNames := []string{"Attr1", "Attr2", "Attr3"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(1990), FP256BN.NewBIGint(20301231)}
+	disclosure := []byte{1, 0, 0}
+	key, user := newTestUser(t, AttributeNames, attrs, rng)
+	usk, cred := user.usk, user.cred
+
+	// born in 1950, ..., 2002 and not expired on 2020-03-12
+	predicates := append(InRange(1, FP256BN.NewBIGint(1950), FP256BN.NewBIGint(2002)), AtLeast(2, FP256BN.NewBIGint(20200312)))
+	sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.Len(t, sig.RangeProofs, 3)
+	assert.NoError(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+
+	// the bounds themselves satisfy the predicates
+	tight := InRange(1, FP256BN.NewBIGint(1990), FP256BN.NewBIGint(1990))
+	sig2, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: tight, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, sig2.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: tight, AttributeValues: attrs, RhIndex: -1}))
+
+	// attributes outside the range cannot be proven
+	_, err = NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: []*RangePredicate{AtLeast(1, FP256BN.NewBIGint(1991))}, RhIndex: -1}, rng)
+	assert.Error(t, err)
+	_, err = NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: []*RangePredicate{AtMost(2, FP256BN.NewBIGint(20200312))}, RhIndex: -1}, rng)
+	assert.Error(t, err)
+	// predicates are only allowed over hidden attributes
+	_, err = NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: []*RangePredicate{AtLeast(0, FP256BN.NewBIGint(0))}, RhIndex: -1}, rng)
+	assert.Error(t, err)
+
+	// the verifier must get proofs of exactly the predicates it expects
+	assert.Error(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	assert.Error(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates[:2], AttributeValues: attrs, RhIndex: -1}))
+	stricter := append(InRange(1, FP256BN.NewBIGint(1950), FP256BN.NewBIGint(2002)), AtLeast(2, FP256BN.NewBIGint(20400101)))
+	assert.Error(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: stricter, AttributeValues: attrs, RhIndex: -1}))
+
+	// a proof does not transfer to another bound, another message or another signature
+	forged := proto.Clone(sig).(*NymSignature)
+	forged.RangeProofs[2].Bound = BigToBytes(FP256BN.NewBIGint(20400101))
+	err = forged.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: stricter, AttributeValues: attrs, RhIndex: -1})
+	assert.Error(t, err)
+	assert.Equal(t, ErrKindInvalid, VerificationErrorKindOf(err))
+	forged = proto.Clone(sig).(*NymSignature)
+	forged.RangeProofs[0] = sig2.RangeProofs[0]
+	assert.Error(t, forged.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+	forged = proto.Clone(sig).(*NymSignature)
+	forged.RangeProofs[1].BitC0[5] = forged.RangeProofs[1].BitS0[5]
+	assert.Error(t, forged.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+
+	raw, err := sig.Bytes()
+	assert.NoError(t, err)
+	decoded, err := NymSignatureFromBytes(raw)
+	assert.NoError(t, err)
+	assert.NoError(t, decoded.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+}
+
+func TestSetMembership(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(12)}
+	disclosure := []byte{1, 0}
+	key, user := newTestUser(t, AttributeNames, attrs, rng)
+	usk, cred := user.usk, user.cred
+
+	regions := []*FP256BN.BIG{FP256BN.NewBIGint(11), FP256BN.NewBIGint(12), FP256BN.NewBIGint(13)}
+	sets := []*SetPredicate{InSet(1, regions)}
+	sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Sets: sets, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Sets: sets, AttributeValues: attrs, RhIndex: -1}))
+	single := []*SetPredicate{InSet(1, regions[1:2])}
+	sig2, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Sets: single, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, sig2.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Sets: single, AttributeValues: attrs, RhIndex: -1}))
+
+	// values outside the set cannot be proven and the verifier must expect the same set
+	_, err = NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Sets: []*SetPredicate{InSet(1, []*FP256BN.BIG{regions[0], regions[2]})}, RhIndex: -1}, rng)
+	assert.Error(t, err)
+	_, err = NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Sets: []*SetPredicate{InSet(0, regions)}, RhIndex: -1}, rng)
+	assert.Error(t, err)
+	assert.Error(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	assert.Error(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Sets: []*SetPredicate{InSet(1, regions[:2])}, AttributeValues: attrs, RhIndex: -1}))
+
+	// shrinking the set of a proof breaks it
+	forged := proto.Clone(sig).(*NymSignature)
+	forged.SetProofs[0].Set[0] = BigToBytes(regions[1])
+	assert.Error(t, forged.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Sets: []*SetPredicate{InSet(1, []*FP256BN.BIG{regions[1], regions[1], regions[2]})}, AttributeValues: attrs, RhIndex: -1}))
+	forged = proto.Clone(sig).(*NymSignature)
+	forged.SetProofs[0].ProofC[0], forged.SetProofs[0].ProofC[1] = forged.SetProofs[0].ProofC[1], forged.SetProofs[0].ProofC[0]
+	assert.Error(t, forged.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Sets: sets, AttributeValues: attrs, RhIndex: -1}))
+}
+
+func TestLinkedNymSignature(t *testing.T) {
+	rng := GetRand(32)
+	// a producer credential and an inspector credential of the same user share the batch number in Attr2
+	producerKey, err := NewIssuerKey([]string{"Attr1", "Attr2", "Attr3"}, rng)
+	assert.NoError(t, err)
+	inspectorKey, err := NewIssuerKey([]string{"Attr1", "Attr2", "Attr3"}, rng)
+	assert.NoError(t, err)
+	producerAttrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(4711), FP256BN.NewBIGint(6)}
+	inspectorAttrs := []*FP256BN.BIG{FP256BN.NewBIGint(2), FP256BN.NewBIGint(4711), FP256BN.NewBIGint(3)}
+
+	newCred := func(key *IssuerKey, usk *FP256BN.BIG, upk *UserPublicKey, attrs []*FP256BN.BIG) *Credential {
+		nonces := NewIssuerNonceStore(time.Minute)
+		m := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+		cred, err := NewCredential(key, nonces, m, upk, attrs, testNotBefore, testNotAfter, rng)
+		assert.NoError(t, err)
+		return cred
+	}
+	ukey, _, err := NewUserKey([]string{"Attr1", "Attr2", "Attr3"}, rng)
+	assert.NoError(t, err)
+	usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
+	producerCred := newCred(producerKey, usk, ukey.Upk, producerAttrs)
+	inspectorCred := newCred(inspectorKey, usk, ukey.Upk, inspectorAttrs)
+
+	presentations := []*Presentation{
+		{Cred: producerCred, Ipk: producerKey.Ipk, Disclosure: []byte{1, 0, 1}, RhIndex: -1},
+		{Cred: inspectorCred, Ipk: inspectorKey.Ipk, Disclosure: []byte{1, 0, 0}, RhIndex: -1,
+			Sets: []*SetPredicate{InSet(2, []*FP256BN.BIG{FP256BN.NewBIGint(3), FP256BN.NewBIGint(5)})}},
+	}
+	policies := []*PresentationPolicy{
+		{Ipk: producerKey.Ipk, Disclosure: []byte{1, 0, 1}, AttributeValues: producerAttrs, RhIndex: -1},
+		{Ipk: inspectorKey.Ipk, Disclosure: []byte{1, 0, 0}, AttributeValues: inspectorAttrs, RhIndex: -1,
+			Sets: presentations[1].Sets},
+	}
+	equalities := []*AttributeEquality{{Credential1: 0, Attribute1: 1, Credential2: 1, Attribute2: 1}}
+
+	linked, err := NewLinkedNymSignature(usk, presentations, equalities, []byte("msg"), nil, testNow, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, linked.Ver(policies, equalities, []byte("msg"), nil, testNow))
+	assert.Error(t, linked.Ver(policies, equalities, []byte("another msg"), nil, testNow))
+	assert.Error(t, linked.Ver(policies, nil, []byte("msg"), nil, testNow))
+	assert.Error(t, linked.Ver(policies, []*AttributeEquality{{Credential1: 0, Attribute1: 1, Credential2: 1, Attribute2: 2}}, []byte("msg"), nil, testNow))
+
+	// unequal attributes cannot be linked
+	_, err = NewLinkedNymSignature(usk, presentations, []*AttributeEquality{{Credential1: 0, Attribute1: 1, Credential2: 1, Attribute2: 2}}, []byte("msg"), nil, testNow, rng)
+	assert.Error(t, err)
+
+	// credentials of different users cannot be linked
+	otherKey, _, err := NewUserKey([]string{"Attr1", "Attr2", "Attr3"}, rng)
+	assert.NoError(t, err)
+	otherUsk := FP256BN.FromBytes(otherKey.GetUsk().GetX())
+	otherLinked, err := NewLinkedNymSignature(otherUsk, []*Presentation{
+		{Cred: newCred(producerKey, otherUsk, otherKey.Upk, producerAttrs), Ipk: producerKey.Ipk, Disclosure: []byte{1, 0, 1}, RhIndex: -1},
+		presentations[1],
+	}, nil, []byte("msg"), nil, testNow, rng)
+	assert.NoError(t, err)
+	forged := proto.Clone(linked).(*LinkedNymSignature)
+	forged.Signatures[0] = otherLinked.Signatures[0]
+	assert.Error(t, forged.Ver(policies, equalities, []byte("msg"), nil, testNow))
+	forged = proto.Clone(linked).(*LinkedNymSignature)
+	forged.Equalities[0].ProofSRand_2 = forged.Equalities[0].ProofSRand_1
+	assert.Error(t, forged.Ver(policies, equalities, []byte("msg"), nil, testNow))
+}
+
+func TestValidityWindow(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	disclosure := []byte{1, 0}
+	key, err := NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, key.Ipk.Check())
+	ukey, _, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
+	nonces := NewIssuerNonceStore(time.Minute)
+	m := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+
+	// the validity window must not be empty
+	_, err = NewCredential(key, nonces, m, ukey.Upk, attrs, testNotAfter, testNotBefore, rng)
+	assert.Error(t, err)
+
+	cred, err := NewCredential(key, nonces, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, cred.Ver(usk, key.Ipk))
+	extended := proto.Clone(cred).(*Credential)
+	extended.NotAfter += 3600
+	assert.Error(t, extended.Ver(usk, key.Ipk), "the validity window is signed by the issuer")
+
+	sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	raw, err := sig.Bytes()
+	assert.NoError(t, err)
+	decoded, err := NymSignatureFromBytes(raw)
+	assert.NoError(t, err)
+	assert.NoError(t, decoded.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+
+	// the signature proves validity at the time of the verifier only
+	assert.Error(t, sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow + 1, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	moved := proto.Clone(sig).(*NymSignature)
+	moved.ValidAt = testNotAfter + 1
+	assert.Error(t, moved.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNotAfter + 1, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+
+	// an expired or not yet valid credential cannot sign
+	_, err = NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNotAfter + 1, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.Error(t, err)
+	_, err = NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNotBefore - 1, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.Error(t, err)
+
+	// extending the validity window breaks the issuer signature
+	forged, err := NewNymSignature(usk, extended, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNotAfter + 1, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.Error(t, forged.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNotAfter + 1, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+
+	// a validity proof that does not verify is reported as expired
+	other, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	other.ValidityProofs[1] = sig.ValidityProofs[1]
+	err = other.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1})
+	assert.Error(t, err)
+	assert.True(t, IsExpired(err))
+
+	// the issuer public key must carry the validity key
+	noValidity := proto.Clone(key.Ipk).(*IssuerPublicKey)
+	noValidity.HValidity = nil
+	noValidity.BarValidity = nil
+	assert.Error(t, noValidity.Check())
+	assert.Error(t, sig.Ver(noValidity, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+}
+
+func TestIssuerNonce(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1)}
+	key, err := NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	ukey, _, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
+	nonces := NewIssuerNonceStore(time.Minute)
+	clock := time.Now()
+	nonces.now = func() time.Time { return clock }
+
+	m := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+	_, err = NewCredential(key, nil, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.Error(t, err, "the issuer must check the nonce")
+	bad := proto.Clone(m).(*CredRequest)
+	bad.ProofC = BigToBytes(FP256BN.NewBIGint(1))
+	_, err = NewCredential(key, nonces, bad, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.Error(t, err)
+	assert.Equal(t, 1, nonces.Len(), "an invalid request must not consume the nonce")
+	cred, err := NewCredential(key, nonces, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, cred.Ver(usk, key.Ipk))
+	assert.Equal(t, 0, nonces.Len())
+	_, err = NewCredential(key, nonces, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.Error(t, err, "a request must not be replayed")
+
+	unknown := NewCredRequest(usk, BigToBytes(RandModOrder(rng)), key.Ipk, rng)
+	_, err = NewCredential(key, nonces, unknown, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.Error(t, err, "the nonce must have been handed out by the issuer")
+
+	expired := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+	clock = clock.Add(30 * time.Second)
+	fresh := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+	clock = clock.Add(30 * time.Second)
+	assert.Equal(t, 1, nonces.Len())
+	_, err = NewCredential(key, nonces, expired, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.Error(t, err, "an expired nonce must be rejected")
+	_, err = NewCredential(key, nonces, fresh, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+	assert.NoError(t, err)
+}
+
+func TestWalletFile(t *testing.T) {
+	rng := GetRand(32)
+	ukey, trace, err := NewUserKey([]string{"Attr1"}, rng)
+	assert.NoError(t, err)
+	contents := &WalletContents{Identities: []*WalletIdentity{{Name: "user", Key: ukey, Trace: trace}}}
+	password := []byte("password")
+
+	file, err := SealWallet(contents, password)
+	assert.NoError(t, err)
+	text, err := file.Text()
+	assert.NoError(t, err)
+	decoded, err := WalletFileFromText(text)
+	assert.NoError(t, err)
+	opened, err := OpenWallet(decoded, password)
+	assert.NoError(t, err)
+	assert.Equal(t, ukey.Usk.X, opened.Identities[0].Key.Usk.X)
+	_, err = OpenWallet(decoded, []byte("wrong"))
+	assert.Error(t, err)
+
+	// the key derivation parameters and the salt are authenticated
+	tampered := proto.Clone(file).(*WalletFile)
+	tampered.KdfTime = 1
+	_, err = OpenWallet(tampered, password)
+	assert.Error(t, err)
+	tampered = proto.Clone(file).(*WalletFile)
+	tampered.Ciphertext[0] ^= 1
+	_, err = OpenWallet(tampered, password)
+	assert.Error(t, err)
+	tampered = proto.Clone(file).(*WalletFile)
+	tampered.KdfMemory = 1 << 30
+	_, err = OpenWallet(tampered, password)
+	assert.Error(t, err, "a wallet file must not make the key derivation exhaust memory")
+
+	duplicated := &WalletContents{Identities: []*WalletIdentity{{Name: "user", Key: ukey, Trace: trace}, {Name: "user", Key: ukey, Trace: trace}}}
+	_, err = SealWallet(duplicated, password)
+	assert.Error(t, err)
+}
+
+func TestIssuerKeyRotation(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	disclosure := []byte{1, 0}
+	oldKey, err := NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	newKey, err := NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, oldKey.Ipk.KeyId)
+	assert.NotEqual(t, oldKey.Ipk.KeyId, newKey.Ipk.KeyId)
+	id, err := IssuerKeyID(oldKey.Ipk)
+	assert.NoError(t, err)
+	assert.Equal(t, oldKey.Ipk.KeyId, id)
+
+	// the key ID is bound to the key material
+	renamed := proto.Clone(oldKey.Ipk).(*IssuerPublicKey)
+	renamed.KeyId = newKey.Ipk.KeyId
+	assert.Error(t, renamed.Check())
+
+	keyring := NewIssuerKeyring()
+	assert.Nil(t, keyring.Current())
+	assert.NoError(t, keyring.Rotate(oldKey.Ipk))
+	assert.Error(t, keyring.Add(oldKey.Ipk), "a key is added once")
+	assert.Error(t, keyring.Add(renamed))
+
+	ukey, _, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
+	issue := func(key *IssuerKey) *Credential {
+		nonces := NewIssuerNonceStore(time.Minute)
+		m := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+		cred, err := NewCredential(key, nonces, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+		assert.NoError(t, err)
+		return cred
+	}
+	oldCred := issue(oldKey)
+	oldSig, err := NewNymSignature(usk, oldCred, oldKey.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	assert.Equal(t, oldKey.Ipk.KeyId, oldSig.KeyId)
+
+	// after the rotation new credentials are issued under the new key, the old ones still verify
+	assert.NoError(t, keyring.Rotate(newKey.Ipk))
+	assert.Equal(t, newKey.Ipk.KeyId, keyring.Current().KeyId)
+	assert.Equal(t, []string{oldKey.Ipk.KeyId, newKey.Ipk.KeyId}, keyring.IDs())
+	newCred := issue(newKey)
+	assert.Equal(t, newKey.Ipk.KeyId, newCred.KeyId)
+	newSig, err := NewNymSignature(usk, newCred, newKey.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	for _, sig := range []*NymSignature{oldSig, newSig} {
+		assert.NoError(t, keyring.Verify(sig, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	}
+
+	// credentials and signatures are checked against the key they name only
+	assert.Error(t, oldCred.Ver(usk, newKey.Ipk))
+	_, err = NewNymSignature(usk, oldCred, newKey.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+	assert.Error(t, err)
+	assert.Error(t, oldSig.Ver(newKey.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	moved := proto.Clone(oldSig).(*NymSignature)
+	moved.KeyId = newKey.Ipk.KeyId
+	assert.Error(t, keyring.Verify(moved, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+	moved.KeyId = "unknown"
+	_, err = keyring.Get("unknown")
+	assert.Error(t, err)
+	assert.Error(t, keyring.Verify(moved, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}))
+}
+
+func TestBatchVerify(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2", "Attr3"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2), FP256BN.NewBIGint(3)}
+	ukey, _, err := NewUserKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	usk := FP256BN.FromBytes(ukey.GetUsk().GetX())
+
+	// signatures under two issuer keys with different disclosures
+	var items []*BatchItem
+	for _, disclosure := range [][]byte{{1, 0, 1}, {0, 0, 0}} {
+		key, err := NewIssuerKey(AttributeNames, rng)
+		assert.NoError(t, err)
+		nonces := NewIssuerNonceStore(time.Minute)
+		m := NewCredRequest(usk, nonces.NewNonce(rng), key.Ipk, rng)
+		cred, err := NewCredential(key, nonces, m, ukey.Upk, attrs, testNotBefore, testNotAfter, rng)
+		assert.NoError(t, err)
+		for k := 0; k < 2; k++ {
+			msg := []byte(fmt.Sprintf("msg%d", k))
+			sig, err := NewNymSignature(usk, cred, key.Ipk, msg, &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, rng)
+			assert.NoError(t, err)
+			items = append(items, &BatchItem{Signature: sig, Ipk: key.Ipk, Msg: msg,
+				Opts: &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1}})
+		}
+	}
+	assert.NoError(t, BatchVerify(items, rng))
+	assert.NoError(t, BatchVerify(nil, rng))
+
+	// a signature whose pairing equation fails is found by checking the signatures one by one
+	forged := proto.Clone(items[2].Signature).(*NymSignature)
+	Sigma2 := EcpFromProto(forged.Sigma_2)
+	Sigma2.Add(genG1())
+	forged.Sigma_2 = EcpToProto(Sigma2)
+	assert.Error(t, forged.Ver(items[2].Ipk, items[2].Msg, items[2].Opts))
+	bad := append([]*BatchItem{}, items...)
+	bad[2] = &BatchItem{Signature: forged, Ipk: items[2].Ipk, Msg: items[2].Msg, Opts: items[2].Opts}
+	// and a signature whose proofs fail is reported without entering the merged equation
+	bad[1] = &BatchItem{Signature: items[1].Signature, Ipk: items[1].Ipk, Msg: []byte("other"), Opts: items[1].Opts}
+	err = BatchVerify(bad, rng)
+	assert.Error(t, err)
+	batchErr, ok := err.(*BatchVerificationError)
+	assert.True(t, ok)
+	assert.Len(t, batchErr.Failed, 2)
+	assert.Error(t, batchErr.Failed[1])
+	assert.Error(t, batchErr.Failed[2])
+}
+
+// preparedVerifierFixture is a signature with every one of 8 attributes hidden and bounded by a range predicate
+func preparedVerifierFixture(tb testing.TB) (*IssuerKey, *NymSignature, []byte, []*RangePredicate, []*FP256BN.BIG) {
+	rng := GetRand(32)
+	var AttributeNames []string
+	var attrs []*FP256BN.BIG
+	var predicates []*RangePredicate
+	for i := 0; i < 8; i++ {
+		AttributeNames = append(AttributeNames, fmt.Sprintf("Attr%d", i))
+		attrs = append(attrs, FP256BN.NewBIGint(i+10))
+		predicates = append(predicates, AtLeast(i, FP256BN.NewBIGint(i)))
+	}
+	disclosure := make([]byte, len(attrs))
+	key, user := newTestUser(tb, AttributeNames, attrs, rng)
+	usk, cred := user.usk, user.cred
+	sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, RhIndex: -1}, rng)
+	assert.NoError(tb, err)
+	return key, sig, disclosure, predicates, attrs
+}
+
+func TestPreparedVerifier(t *testing.T) {
+	key, sig, disclosure, predicates, attrs := preparedVerifierFixture(t)
+	for _, workers := range []int{0, 1, 3} {
+		verifier, err := NewPreparedVerifier(key.Ipk, workers)
+		assert.NoError(t, err)
+		assert.NoError(t, verifier.Ver(sig, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+		assert.Error(t, verifier.Ver(sig, []byte("other"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+
+		// a bad response for the last hidden value is found whatever worker checks it
+		bad := proto.Clone(sig).(*NymSignature)
+		bad.ProofSHidden[len(bad.ProofSHidden)-1] = BigToBytes(FP256BN.NewBIGint(1))
+		assert.Error(t, verifier.Ver(bad, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+
+		// a signature whose pairing equation fails
+		forged := proto.Clone(sig).(*NymSignature)
+		Sigma2 := EcpFromProto(forged.Sigma_2)
+		Sigma2.Add(genG1())
+		forged.Sigma_2 = EcpToProto(Sigma2)
+		assert.Error(t, verifier.Ver(forged, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+	}
+
+	// a signature under another key does not verify
+	other, _, _, _, _ := preparedVerifierFixture(t)
+	verifier, err := NewPreparedVerifier(other.Ipk, 2)
+	assert.NoError(t, err)
+	assert.Error(t, verifier.Ver(sig, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+
+	_, err = NewPreparedVerifier(nil, 1)
+	assert.Error(t, err)
+
+	// a key that does not pass its check is rejected when it is prepared
+	badIpk := proto.Clone(key.Ipk).(*IssuerPublicKey)
+	badIpk.BarX = Ecp2ToProto(genG2())
+	_, err = NewPreparedVerifier(badIpk, 1)
+	assert.Error(t, err)
+	assert.Error(t, sig.Ver(badIpk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}))
+	assert.Error(t, NewIssuerKeyring().Add(badIpk))
+
+	// the key of the caller is only read
+	unhashed := proto.Clone(key.Ipk).(*IssuerPublicKey)
+	unhashed.Hash = nil
+	_, err = NewPreparedVerifier(unhashed, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, unhashed.Hash)
+}
+
+// BenchmarkNymSignatureVer compares NymSignature.Ver with a PreparedVerifier on 1, 2 and 4 workers,
+// the speedup of more workers is bounded by the number of CPUs
+func BenchmarkNymSignatureVer(b *testing.B) {
+	key, sig, disclosure, predicates, attrs := preparedVerifierFixture(b)
+
+	b.Run("Ver", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := sig.Ver(key.Ipk, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	for _, workers := range []int{1, 2, 4} {
+		verifier, err := NewPreparedVerifier(key.Ipk, workers)
+		assert.NoError(b, err)
+		b.Run(fmt.Sprintf("Prepared/workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := verifier.Ver(sig, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, AttributeValues: attrs, RhIndex: -1}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestNymSignatureFormats(t *testing.T) {
+	rng := GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2", "Attr3", "RevocationHandle"}
+	rhIndex := 3
+	rh := RandModOrder(rng)
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(20), FP256BN.NewBIGint(12), rh}
+	key, user := newTestUser(t, AttributeNames, attrs, rng)
+	usk, cred := user.usk, user.cred
+	revocationKey, err := GenerateLongTermRevocationKey()
+	assert.NoError(t, err)
+	epoch := 3
+	cri, err := CreateCRI(revocationKey, []*FP256BN.BIG{rh}, epoch, ALG_PLAIN_SIGNATURE, rng)
+	assert.NoError(t, err)
+
+	disclosure := []byte{1, 0, 0, 0}
+	predicates := []*RangePredicate{AtLeast(1, FP256BN.NewBIGint(18))}
+	sets := []*SetPredicate{InSet(2, []*FP256BN.BIG{FP256BN.NewBIGint(11), FP256BN.NewBIGint(12)})}
+	opts := &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, Sets: sets, AttributeValues: attrs,
+		RhIndex: rhIndex, RevPk: &revocationKey.PublicKey, Epoch: epoch}
+	ver := func(sig *NymSignature) error {
+		return sig.Ver(key.Ipk, []byte("msg"), opts)
+	}
+	verifier, err := NewPreparedVerifier(key.Ipk, 2)
+	assert.NoError(t, err)
+
+	signOpts := func(version uint32) *SignOpts {
+		return &SignOpts{ValidAt: testNow, Disclosure: disclosure, Predicates: predicates, Sets: sets, RhIndex: rhIndex, Cri: cri, Version: version}
+	}
+	legacyOpts := *opts
+	legacyOpts.AllowLegacy = true
+
+	// signatures are made in format 3 by default and in format 4 on request, both decode and verify
+	var items []*BatchItem
+	for _, version := range []uint32{0, NymSignatureV3, NymSignatureV4, NymSignatureV4} {
+		sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), signOpts(version), rng)
+		assert.NoError(t, err)
+		if version == 0 {
+			version = NymSignatureV3
+		}
+		assert.Equal(t, version, sig.GetVersion())
+		assert.NoError(t, ver(sig))
+		assert.NoError(t, verifier.Ver(sig, []byte("msg"), opts))
+		raw, err := sig.Bytes()
+		assert.NoError(t, err)
+		decoded, err := NymSignatureFromBytes(raw)
+		assert.NoError(t, err)
+		assert.NoError(t, ver(decoded))
+		items = append(items, &BatchItem{Signature: sig, Ipk: key.Ipk, Msg: []byte("msg"), Opts: opts})
+	}
+	for _, version := range []uint32{NymSignatureV1, NymSignatureV2, 5} {
+		_, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), signOpts(version), rng)
+		assert.Error(t, err, "format %d is not made", version)
+	}
+	v3, v4 := items[1].Signature, items[2].Signature
+	assert.Empty(t, v3.Hides)
+	assert.Empty(t, v3.ValidityHides)
+	assert.Len(t, v3.ProofSHidden, 3+numValidityKeys)
+	assert.Len(t, v3.Commitments, 2+numValidityKeys)
+	assert.Len(t, v4.Hides, 3)
+	assert.Len(t, v4.ValidityHides, numValidityKeys)
+
+	// the legacy formats verify only for a verifier that allows them
+	for _, version := range []uint32{NymSignatureV1, NymSignatureV2} {
+		sig, _, err := newNymSignature(usk, cred, key.Ipk, []byte("msg"), signOpts(0), nil, version, rng)
+		assert.NoError(t, err)
+		assert.Error(t, ver(sig), "format %d", version)
+		assert.NoError(t, sig.Ver(key.Ipk, []byte("msg"), &legacyOpts), "format %d", version)
+		items = append(items, &BatchItem{Signature: sig, Ipk: key.Ipk, Msg: []byte("msg"), Opts: &legacyOpts})
+	}
+
+	// the pairing equations of the signatures in formats 4 and 1 are merged
+	assert.NoError(t, BatchVerify(items, rng))
+	items[2].Msg = []byte("other")
+	err = BatchVerify(items, rng)
+	assert.Error(t, err)
+	assert.Len(t, err.(*BatchVerificationError).Failed, 1)
+	assert.Contains(t, err.(*BatchVerificationError).Failed, 2)
+
+	// a signature cannot be passed off in another format, not even in a legacy format the verifier accepts
+	for _, version := range []uint32{0, NymSignatureV1, NymSignatureV2, NymSignatureV3, 5} {
+		forged := proto.Clone(v4).(*NymSignature)
+		forged.Version = version
+		assert.Error(t, ver(forged), "format %d", version)
+		assert.Error(t, forged.Ver(key.Ipk, []byte("msg"), &legacyOpts), "format %d", version)
+	}
+	for _, version := range []uint32{0, NymSignatureV1, NymSignatureV2, NymSignatureV4, 5} {
+		forged := proto.Clone(v3).(*NymSignature)
+		forged.Version = version
+		assert.Error(t, ver(forged), "format %d", version)
+		assert.Error(t, forged.Ver(key.Ipk, []byte("msg"), &legacyOpts), "format %d", version)
+	}
+	forged := proto.Clone(v3).(*NymSignature)
+
+	// every part of the aggregated proof is bound to the challenge
+	forged.ProofSBlind = BigToBytes(FP256BN.NewBIGint(1))
+	assert.Error(t, ver(forged))
+	forged = proto.Clone(v3).(*NymSignature)
+	forged.ProofSHidden[0], forged.ProofSHidden[1] = forged.ProofSHidden[1], forged.ProofSHidden[0]
+	assert.Error(t, ver(forged))
+	forged = proto.Clone(v3).(*NymSignature)
+	forged.Commitments[0].ProofSRand = BigToBytes(FP256BN.NewBIGint(1))
+	assert.Error(t, ver(forged))
+	forged = proto.Clone(v3).(*NymSignature)
+	forged.Commitments = forged.Commitments[1:]
+	assert.Error(t, ver(forged))
+	raw, err := forged.Bytes()
+	assert.NoError(t, err)
+	_, err = NymSignatureFromBytes(raw)
+	assert.NoError(t, err, "a signature without commitments is well formed")
+	forged = proto.Clone(v3).(*NymSignature)
+	forged.ProofSHidden = forged.ProofSHidden[1:]
+	raw, err = forged.Bytes()
+	assert.NoError(t, err)
+	_, err = NymSignatureFromBytes(raw)
+	assert.Error(t, err)
+}
+
+// BenchmarkNymSignatureFormats compares the size and the verification time of the formats of a signature
+// that hides 8 attributes: format 1, in which signatures were made before one proof covered all hidden attributes,
+// format 3, in which they are made now, and format 4, which has the proofs of format 1 with transcripts
+func BenchmarkNymSignatureFormats(b *testing.B) {
+	rng := GetRand(32)
+	var AttributeNames []string
+	var attrs []*FP256BN.BIG
+	for i := 0; i < 8; i++ {
+		AttributeNames = append(AttributeNames, fmt.Sprintf("Attr%d", i))
+		attrs = append(attrs, FP256BN.NewBIGint(i))
+	}
+	disclosure := make([]byte, len(attrs))
+	key, user := newTestUser(b, AttributeNames, attrs, rng)
+	usk, cred := user.usk, user.cred
+
+	for _, version := range []uint32{NymSignatureV1, NymSignatureV3, NymSignatureV4} {
+		sig, _, err := newNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: disclosure, RhIndex: -1}, nil, version, rng)
+		assert.NoError(b, err)
+		raw, err := sig.Bytes()
+		assert.NoError(b, err)
+		// the proofs of the hidden values, without the range proofs of the validity window they share
+		hidden := &NymSignature{Hides: sig.Hides, ValidityHides: sig.ValidityHides, ProofSHidden: sig.ProofSHidden,
+			ProofSBlind: sig.ProofSBlind, Commitments: sig.Commitments}
+		// the key is prepared once, so that only the signature is verified in the loop
+		verifier, err := NewPreparedVerifier(key.Ipk, 1)
+		assert.NoError(b, err)
+		b.Run(fmt.Sprintf("V%d", version), func(b *testing.B) {
+			b.ReportMetric(float64(len(raw)), "bytes")
+			b.ReportMetric(float64(proto.Size(hidden)), "hidden-bytes")
+			for i := 0; i < b.N; i++ {
+				if err := verifier.Ver(sig, []byte("msg"), &VerifyOpts{ValidAt: testNow, Disclosure: disclosure, AttributeValues: attrs, RhIndex: -1, AllowLegacy: true}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestLegacyObjects checks that objects made by earlier versions of the package, which are kept in testdata,
+// are rejected, but for keys, signatures and opening proofs whose verifier asks for them to be accepted
+func TestLegacyObjects(t *testing.T) {
+	const validAt = 1700000000
+	read := func(name string, m proto.Message) {
+		raw, err := ioutil.ReadFile(filepath.Join("testdata", name))
+		assert.NoError(t, err)
+		assert.NoError(t, proto.Unmarshal(raw, m))
+	}
+	ipk, upk, m := &IssuerPublicKey{}, &UserPublicKey{}, &CredRequest{}
+	read("issuer-public-key.pb", ipk)
+	read("user-public-key.pb", upk)
+	read("cred-request.pb", m)
+	assert.Error(t, ipk.Check())
+	assert.Error(t, upk.Check())
+	assert.NoError(t, ipk.CheckLegacy())
+	assert.NoError(t, upk.CheckLegacy())
+	assert.Error(t, m.checkProof(ipk))
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", "revocation-public-key.der"))
+	assert.NoError(t, err)
+	revPk, err := x509.ParsePKIXPublicKey(raw)
+	assert.NoError(t, err)
+
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(20), FP256BN.NewBIGint(12), FP256BN.NewBIGint(0)}
+	disclosure := []byte{1, 0, 0, 0}
+	predicates := []*RangePredicate{AtLeast(1, FP256BN.NewBIGint(18))}
+	sets := []*SetPredicate{InSet(2, []*FP256BN.BIG{FP256BN.NewBIGint(11), FP256BN.NewBIGint(12)})}
+	opts := &VerifyOpts{Scope: []byte("scope"), ValidAt: validAt, Disclosure: disclosure, Predicates: predicates, Sets: sets, AttributeValues: attrs, RhIndex: 3, RevPk: revPk.(*ecdsa.PublicKey), Epoch: 5}
+	legacyOpts := *opts
+	legacyOpts.AllowLegacy = true
+	for k, name := range []string{"nym-signature-v1.pb", "nym-signature-v2.pb"} {
+		sig := &NymSignature{}
+		read(name, sig)
+		assert.Equal(t, []uint32{NymSignatureV1, NymSignatureV2}[k], sig.formatVersion(), name)
+		assert.Error(t, sig.Ver(ipk, []byte("legacy"), opts), name)
+		assert.NoError(t, sig.Ver(ipk, []byte("legacy"), &legacyOpts), name)
+		assert.Error(t, sig.Ver(ipk, []byte("other"), &legacyOpts), name)
+		if name == "nym-signature-v2.pb" {
+			opening := &OpeningProof{}
+			read("opening-proof.pb", opening)
+			assert.Error(t, VerifyOpening(ipk, upk, sig, []byte("legacy"), opts, opening))
+			assert.NoError(t, VerifyOpening(ipk, upk, sig, []byte("legacy"), &legacyOpts, opening))
+			assert.Error(t, VerifyOpening(ipk, upk, sig, []byte("other"), &legacyOpts, opening))
+		}
+	}
+
+	linked := &LinkedNymSignature{}
+	read("linked-nym-signature.pb", linked)
+	policies := []*PresentationPolicy{
+		{Ipk: ipk, Disclosure: []byte{1, 0, 1, 0}, AttributeValues: attrs, RhIndex: -1},
+		{Ipk: ipk, Disclosure: []byte{0, 0, 1, 0}, AttributeValues: attrs, RhIndex: -1, Predicates: predicates},
+	}
+	equalities := []*AttributeEquality{{Credential1: 0, Attribute1: 1, Credential2: 1, Attribute2: 1}}
+	assert.Error(t, linked.Ver(policies, equalities, []byte("legacy"), nil, validAt))
+	for _, policy := range policies {
+		policy.AllowLegacy = true
+	}
+	assert.NoError(t, linked.Ver(policies, equalities, []byte("legacy"), nil, validAt))
+	assert.Error(t, linked.Ver(policies, equalities, []byte("other"), nil, validAt))
+}
+
+// TestTranscript checks that new objects derive their challenges from labelled transcripts
+// and cannot be passed off as objects with legacy proof data
+func TestTranscript(t *testing.T) {
+	// a transcript frames every append, legacy proof data does not
+	challenge := func(tr *transcript, first string, second string) *FP256BN.BIG {
+		tr.appendString("first", first)
+		tr.appendString("second", second)
+		return tr.challenge()
+	}
+	assert.NotEqual(t, *challenge(newTranscript("test"), "ab", "c"), *challenge(newTranscript("test"), "a", "bc"))
+	assert.NotEqual(t, *challenge(newTranscript("test"), "a", "b"), *challenge(newTranscript("other"), "a", "b"))
+	assert.Equal(t, *challenge(newLegacyTranscript(), "ab", "c"), *challenge(newLegacyTranscript(), "a", "bc"))
+
+	rng := GetRand(32)
+	key, user := newTestUser(t, []string{"Attr1"}, []*FP256BN.BIG{FP256BN.NewBIGint(1)}, rng)
+	ukey, trace, usk, cred, m := user.ukey, user.trace, user.usk, user.cred, user.m
+	assert.NoError(t, m.checkProof(key.Ipk))
+	sig, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: []byte{0}, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	index := NewTraceIndex()
+	assert.NoError(t, index.Add(trace))
+	opts := &VerifyOpts{ValidAt: testNow, Disclosure: []byte{0}, AttributeValues: []*FP256BN.BIG{FP256BN.NewBIGint(1)}, RhIndex: -1}
+	_, opening, err := Arbitration(key, index, sig, []byte("msg"), opts, rng)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ProofVersionTranscript, key.Ipk.GetProofVersion())
+	assert.Equal(t, ProofVersionTranscript, ukey.Upk.GetProofVersion())
+	assert.Equal(t, ProofVersionTranscript, m.GetProofVersion())
+	assert.Equal(t, ProofVersionTranscript, opening.GetProofVersion())
+	assert.Equal(t, NymSignatureV3, sig.GetVersion())
+	assert.NoError(t, VerifyOpening(key.Ipk, ukey.Upk, sig, []byte("msg"), opts, opening))
+
+	// the proofs do not verify as legacy proofs
+	ipk := proto.Clone(key.Ipk).(*IssuerPublicKey)
+	ipk.ProofVersion = 0
+	assert.Error(t, ipk.Check())
+	assert.Error(t, ipk.CheckLegacy())
+	upk := proto.Clone(ukey.Upk).(*UserPublicKey)
+	upk.ProofVersion = 0
+	assert.Error(t, upk.Check())
+	assert.Error(t, upk.CheckLegacy())
+	legacyRequest := proto.Clone(m).(*CredRequest)
+	legacyRequest.ProofVersion = 0
+	assert.Error(t, legacyRequest.checkProof(key.Ipk))
+	legacyOpening := proto.Clone(opening).(*OpeningProof)
+	legacyOpening.ProofVersion = 0
+	assert.Error(t, VerifyOpening(key.Ipk, ukey.Upk, sig, []byte("msg"), opts, legacyOpening))
+	legacyOpts := *opts
+	legacyOpts.AllowLegacy = true
+	assert.Error(t, VerifyOpening(key.Ipk, ukey.Upk, sig, []byte("msg"), &legacyOpts, legacyOpening))
+
+	// the opening proof is bound to the signature it opens
+	other, err := NewNymSignature(usk, cred, key.Ipk, []byte("msg"), &SignOpts{ValidAt: testNow, Disclosure: []byte{0}, RhIndex: -1}, rng)
+	assert.NoError(t, err)
+	other.Nonce, other.Eta, other.Xi = sig.Nonce, sig.Eta, sig.Xi
+	other.TraceC1, other.TraceC2 = sig.TraceC1, sig.TraceC2
+	assert.Error(t, VerifyOpening(key.Ipk, ukey.Upk, other, []byte("msg"), opts, opening))
+}
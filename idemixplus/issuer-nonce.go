@@ -0,0 +1,80 @@
+package idemixplus
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/pkg/errors"
+)
+
+// IssuerNonceStore holds the nonces an issuer handed out for credential requests until they are consumed or expire.
+// A nonce is accepted once and only before it expires, so that a credential request cannot be replayed.
+// It is safe for concurrent use.
+type IssuerNonceStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	expiry  map[string]time.Time // the expiry of every outstanding nonce, by its hex encoding
+	pending []pendingNonce       // the nonces in the order they were handed out, to drop them when they expire
+	now     func() time.Time
+}
+
+type pendingNonce struct {
+	id     string
+	expiry time.Time
+}
+
+// NewIssuerNonceStore creates an IssuerNonceStore whose nonces expire ttl after they are handed out
+func NewIssuerNonceStore(ttl time.Duration) *IssuerNonceStore {
+	return &IssuerNonceStore{ttl: ttl, expiry: make(map[string]time.Time), now: time.Now}
+}
+
+// NewNonce hands out a fresh nonce for a credential request
+func (store *IssuerNonceStore) NewNonce(rng *amcl.RAND) []byte {
+	nonce := BigToBytes(RandModOrder(rng))
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := store.now()
+	store.dropExpired(now)
+	id := hex.EncodeToString(nonce)
+	store.expiry[id] = now.Add(store.ttl)
+	store.pending = append(store.pending, pendingNonce{id: id, expiry: now.Add(store.ttl)})
+	return nonce
+}
+
+// Consume accepts a nonce the store handed out and has not expired, and removes it so that it is not accepted again
+func (store *IssuerNonceStore) Consume(nonce []byte) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := store.now()
+	store.dropExpired(now)
+	id := hex.EncodeToString(nonce)
+	if _, exists := store.expiry[id]; !exists {
+		return errors.Errorf("issuer nonce is unknown, expired or already used")
+	}
+	delete(store.expiry, id)
+	return nil
+}
+
+// Len returns the number of outstanding nonces
+func (store *IssuerNonceStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.dropExpired(store.now())
+	return len(store.expiry)
+}
+
+// dropExpired removes the nonces that expired at now, all nonces share the ttl so they expire in the order they were handed out
+func (store *IssuerNonceStore) dropExpired(now time.Time) {
+	dropped := 0
+	for _, p := range store.pending {
+		if now.Before(p.expiry) {
+			break
+		}
+		delete(store.expiry, p.id)
+		dropped++
+	}
+	store.pending = store.pending[dropped:]
+}
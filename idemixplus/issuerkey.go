@@ -8,7 +8,6 @@ package idemixplus
 
 import (
 	"fmt"
-	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-amcl/amcl"
 	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
 	"github.com/pkg/errors"
@@ -54,21 +53,21 @@ func NewIssuerKey(AttributeNames []string, rng *amcl.RAND) (*IssuerKey, error) {
 	key.Ipk = new(IssuerPublicKey)
 	key.Ipk.AttributeNames = AttributeNames
 
-	BarX := GenG2.Mul(x)
+	BarX := genG2().Mul(x)
 	key.Ipk.BarX = Ecp2ToProto(BarX)
 
-	BarY := GenG2.Mul(y)
+	BarY := genG2().Mul(y)
 	key.Ipk.BarY = Ecp2ToProto(BarY)
 
 	// generate base for the secret key
-	HSk := GenG1.Mul(RandModOrder(rng))
+	HSk := genG1().Mul(RandModOrder(rng))
 	key.Ipk.HSk = EcpToProto(HSk)
 
 	// generate base for the randomness
-	HRand := GenG1.Mul(RandModOrder(rng))
+	HRand := genG1().Mul(RandModOrder(rng))
 	key.Ipk.HRand = EcpToProto(HRand)
 
-	BarG1 := GenG1.Mul(RandModOrder(rng))
+	BarG1 := genG1().Mul(RandModOrder(rng))
 	key.Ipk.BarG1 = EcpToProto(BarG1)
 
 	BarG2 := BarG1.Mul(x)
@@ -77,6 +76,23 @@ func NewIssuerKey(AttributeNames []string, rng *amcl.RAND) (*IssuerKey, error) {
 	BarG3 := BarG1.Mul(y)
 	key.Ipk.BarG3 = EcpToProto(BarG3)
 
+	// generate one key component y_i per attribute, so that attribute values
+	// are signed together with the user secret
+	for range AttributeNames {
+		yAttr := RandModOrder(rng)
+		isk.Attrs = append(isk.Attrs, BigToBytes(yAttr))
+		key.Ipk.HAttrs = append(key.Ipk.HAttrs, EcpToProto(genG1().Mul(yAttr)))
+		key.Ipk.BarAttrs = append(key.Ipk.BarAttrs, Ecp2ToProto(genG2().Mul(yAttr)))
+	}
+
+	// generate the key components y_{nb}, y_{na} that sign the validity window of credentials
+	newValidityKeys(isk, key.Ipk, rng)
+
+	// generate the tracing key, signatures carry an encryption of the user public key under it
+	z := RandModOrder(rng)
+	isk.TracingSk = BigToBytes(z)
+	key.Ipk.TracingPk = EcpToProto(genG1().Mul(z))
+
 	// generate a zero-knowledge proof of knowledge (ZK PoK) of the secret key which
 	// is in W and BarG2.
 
@@ -85,46 +101,34 @@ func NewIssuerKey(AttributeNames []string, rng *amcl.RAND) (*IssuerKey, error) {
 	r2 := RandModOrder(rng)
 
 	// Step 1: First message (t-values)
-	t11 := GenG2.Mul(r1) // t1 = g_2^r, cover W
-	t12 := BarG1.Mul(r1) // t2 = (\bar g_1)^r, cover BarG2
+	t11 := genG2().Mul(r1) // t1 = g_2^r, cover W
+	t12 := BarG1.Mul(r1)   // t2 = (\bar g_1)^r, cover BarG2
 
-	t21 := GenG2.Mul(r2)
+	t21 := genG2().Mul(r2)
 	t22 := BarG1.Mul(r2)
 
 	// Step 2: Compute the Fiat-Shamir hash, forming the challenge of the ZKP.
-	proofData := make([]byte, 18*FieldBytes+3)
-	index := 0
-	index = appendBytesG2(proofData, index, t11)
-	index = appendBytesG1(proofData, index, t12)
-	index = appendBytesG2(proofData, index, GenG2)
-	index = appendBytesG1(proofData, index, BarG1)
-	index = appendBytesG2(proofData, index, BarX)
-	index = appendBytesG1(proofData, index, BarG2)
-
-	proofCX := HashModOrder(proofData)
+	key.Ipk.ProofVersion = ProofVersionTranscript
+	proofCX := keyProofChallenge(issuerKeyXLabel, false, t11, t12, BarG1, BarX, BarG2)
 	key.Ipk.ProofCX = BigToBytes(proofCX)
 
 	// Step 3: reply to the challenge message (s-values)
 	proofSX := Modadd(FP256BN.Modmul(proofCX, x, GroupOrder), r1, GroupOrder) // // s = r + C \cdot ISk
 	key.Ipk.ProofSX = BigToBytes(proofSX)
 
-	proofDataY := make([]byte, 18*FieldBytes+3)
-	index = 0
-	index = appendBytesG2(proofDataY, index, t21)
-	index = appendBytesG1(proofDataY, index, t22)
-	index = appendBytesG2(proofDataY, index, GenG2)
-	index = appendBytesG1(proofDataY, index, BarG1)
-	index = appendBytesG2(proofDataY, index, BarY)
-	index = appendBytesG1(proofDataY, index, BarG3)
-
-	proofCY := HashModOrder(proofDataY)
+	proofCY := keyProofChallenge(issuerKeyYLabel, false, t21, t22, BarG1, BarY, BarG3)
 	key.Ipk.ProofCY = BigToBytes(proofCY)
 
 	proofSY := Modadd(FP256BN.Modmul(proofCY, y, GroupOrder), r2, GroupOrder)
 	key.Ipk.ProofSY = BigToBytes(proofSY)
 
+	// Name the key by its key ID, so that the key is told apart from the other keys of the issuer
+	if err := key.Ipk.setKeyID(); err != nil {
+		return nil, err
+	}
+
 	// Hash the public key
-	serializedIPk, err := proto.Marshal(key.Ipk)
+	serializedIPk, err := marshalUncompressed(key.Ipk)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to marshal issuer public key")
 	}
@@ -136,7 +140,47 @@ func NewIssuerKey(AttributeNames []string, rng *amcl.RAND) (*IssuerKey, error) {
 // Check checks that this issuer public key is valid, i.e.
 // that all components are present and a ZK proofs verifies
 func (IPk *IssuerPublicKey) Check() error {
+	return IPk.check(false)
+}
+
+// CheckLegacy checks an issuer public key as Check does, but also accepts a key whose proofs were made
+// before transcripts. It is meant for reading keys that the caller stored itself, never for keys received from others.
+func (IPk *IssuerPublicKey) CheckLegacy() error {
+	return IPk.check(true)
+}
+
+// check checks an issuer public key, and accepts proofs made before transcripts if allowLegacy is set
+func (IPk *IssuerPublicKey) check(allowLegacy bool) error {
 	fmt.Println("NewIssuerKey Check")
+	// Check that every group element is on the curve and in the right subgroup
+	// and that the proofs are reduced scalars, before using any of them
+	if len(IPk.GetHAttrs()) != len(IPk.GetAttributeNames()) || len(IPk.GetBarAttrs()) != len(IPk.GetAttributeNames()) ||
+		len(IPk.GetHValidity()) != numValidityKeys || len(IPk.GetBarValidity()) != numValidityKeys {
+		return errors.Errorf("some part of the public key is undefined")
+	}
+	g1 := []namedEcp{{"HSk", IPk.GetHSk()}, {"HRand", IPk.GetHRand()}, {"BarG1", IPk.GetBarG1()},
+		{"BarG2", IPk.GetBarG2()}, {"BarG3", IPk.GetBarG3()}, {"TracingPk", IPk.GetTracingPk()}}
+	g2 := []namedEcp2{{"BarX", IPk.GetBarX()}, {"BarY", IPk.GetBarY()}}
+	for i, name := range IPk.GetAttributeNames() {
+		g1 = append(g1, namedEcp{"HAttrs[" + name + "]", IPk.HAttrs[i]})
+		g2 = append(g2, namedEcp2{"BarAttrs[" + name + "]", IPk.BarAttrs[i]})
+	}
+	for v := 0; v < numValidityKeys; v++ {
+		g1 = append(g1, namedEcp{fmt.Sprintf("HValidity[%d]", v), IPk.HValidity[v]})
+		g2 = append(g2, namedEcp2{fmt.Sprintf("BarValidity[%d]", v), IPk.BarValidity[v]})
+	}
+	scalars := []namedBig{{"ProofCX", IPk.GetProofCX()}, {"ProofSX", IPk.GetProofSX()},
+		{"ProofCY", IPk.GetProofCY()}, {"ProofSY", IPk.GetProofSY()}}
+	if err := checkPublicKeyElements(g1, g2, scalars); err != nil {
+		return errors.WithMessage(err, "issuer public key invalid")
+	}
+	legacy := allowLegacy && IPk.GetProofVersion() == 0
+	if !legacy {
+		if err := checkProofVersion(IPk.GetProofVersion()); err != nil {
+			return errors.WithMessage(err, "issuer public key invalid")
+		}
+	}
+
 	// Unmarshall the public key
 	NumAttrs := len(IPk.GetAttributeNames())
 	HSk := EcpFromProto(IPk.GetHSk())
@@ -158,59 +202,63 @@ func (IPk *IssuerPublicKey) Check() error {
 		BarG1 == nil ||
 		BarG1.Is_infinity() ||
 		BarG2 == nil ||
-		BarG3 == nil {
+		BarG3 == nil ||
+		len(IPk.GetHAttrs()) != NumAttrs ||
+		len(IPk.GetBarAttrs()) != NumAttrs ||
+		IPk.GetTracingPk() == nil ||
+		EcpFromProto(IPk.GetTracingPk()).Is_infinity() {
 		return errors.Errorf("some part of the public key is undefined")
 	}
 
-	// Verify Proof
+	// Check that the attribute key components in G1 and G2 share the same exponent
+	for i := 0; i < NumAttrs; i++ {
+		left := FP256BN.Fexp(FP256BN.Ate(genG2(), EcpFromProto(IPk.HAttrs[i])))
+		right := FP256BN.Fexp(FP256BN.Ate(Ecp2FromProto(IPk.BarAttrs[i]), genG1()))
+		if !left.Equals(right) {
+			return errors.Errorf("attribute key of %s in public key is malformed", IPk.AttributeNames[i])
+		}
+	}
+	for v := 0; v < numValidityKeys; v++ {
+		left := FP256BN.Fexp(FP256BN.Ate(genG2(), EcpFromProto(IPk.HValidity[v])))
+		right := FP256BN.Fexp(FP256BN.Ate(Ecp2FromProto(IPk.BarValidity[v]), genG1()))
+		if !left.Equals(right) {
+			return errors.Errorf("validity key %d in public key is malformed", v)
+		}
+	}
 
-	// Recompute challenge
-	proofData := make([]byte, 18*FieldBytes+3)
-	index := 0
+	// Verify Proof
 
 	// Recompute t-values using s-values
-	t11 := GenG2.Mul(ProofSX)
+	t11 := genG2().Mul(ProofSX)
 	t11.Add(BarX.Mul(FP256BN.Modneg(ProofCX, GroupOrder))) // t1 = g_2^s \cdot W^{-C}
 
 	t12 := BarG1.Mul(ProofSX)
 	t12.Add(BarG2.Mul(FP256BN.Modneg(ProofCX, GroupOrder))) // t2 = {\bar g_1}^s \cdot {\bar g_2}^C
 
-	index = appendBytesG2(proofData, index, t11)
-	index = appendBytesG1(proofData, index, t12)
-	index = appendBytesG2(proofData, index, GenG2)
-	index = appendBytesG1(proofData, index, BarG1)
-	index = appendBytesG2(proofData, index, BarX)
-	index = appendBytesG1(proofData, index, BarG2)
-
-	proofDataY := make([]byte, 18*FieldBytes+3)
-	index = 0
-
 	// Recompute t-values using s-values
-	t21 := GenG2.Mul(ProofSY)
+	t21 := genG2().Mul(ProofSY)
 	t21.Add(BarY.Mul(FP256BN.Modneg(ProofCY, GroupOrder))) // t1 = g_2^s \cdot W^{-C}
 
 	t22 := BarG1.Mul(ProofSY)
 	t22.Add(BarG3.Mul(FP256BN.Modneg(ProofCY, GroupOrder))) // t2 = {\bar g_1}^s \cdot {\bar g_2}^C
 
-	index = appendBytesG2(proofDataY, index, t21)
-	index = appendBytesG1(proofDataY, index, t22)
-	index = appendBytesG2(proofDataY, index, GenG2)
-	index = appendBytesG1(proofDataY, index, BarG1)
-	index = appendBytesG2(proofDataY, index, BarY)
-	index = appendBytesG1(proofDataY, index, BarG3)
-
-	// Verify that the challenge is the same
-	if *ProofCX != *HashModOrder(proofData) || *ProofCY != *HashModOrder(proofDataY) {
+	// Verify that the challenges are the same
+	if *ProofCX != *keyProofChallenge(issuerKeyXLabel, legacy, t11, t12, BarG1, BarX, BarG2) ||
+		*ProofCY != *keyProofChallenge(issuerKeyYLabel, legacy, t21, t22, BarG1, BarY, BarG3) {
 		return errors.Errorf("zero knowledge proof in public key invalid")
 	}
 
+	if err := IPk.checkKeyID(); err != nil {
+		return errors.WithMessage(err, "issuer public key invalid")
+	}
+
 	return IPk.SetHash()
 }
 
 // SetHash appends a hash of a serialized public key
 func (IPk *IssuerPublicKey) SetHash() error {
 	IPk.Hash = nil
-	serializedIPk, err := proto.Marshal(IPk)
+	serializedIPk, err := marshalUncompressed(IPk)
 	if err != nil {
 		return errors.Wrap(err, "Failed to marshal issuer public key")
 	}
@@ -243,20 +291,20 @@ func NewUserKey(AttributeNames []string, rng *amcl.RAND) (*UserKey, *Trace, erro
 	// generate the corresponding public key
 	key.Upk = new(UserPublicKey)
 	key.Upk.AttributeNames = AttributeNames
-	key.Upk.UPK = EcpToProto(GenG1.Mul(USk))
+	key.Upk.UPK = EcpToProto(genG1().Mul(USk))
 
-	W := GenG2.Mul(USk)
+	W := genG2().Mul(USk)
 	key.Upk.W = Ecp2ToProto(W)
 
 	// generate base for the secret key
-	HSk := GenG1.Mul(RandModOrder(rng))
+	HSk := genG1().Mul(RandModOrder(rng))
 	key.Upk.HSk = EcpToProto(HSk)
 
 	// generate base for the randomness
-	HRand := GenG1.Mul(RandModOrder(rng))
+	HRand := genG1().Mul(RandModOrder(rng))
 	key.Upk.HRand = EcpToProto(HRand)
 
-	BarG1 := GenG1.Mul(RandModOrder(rng))
+	BarG1 := genG1().Mul(RandModOrder(rng))
 	key.Upk.BarG1 = EcpToProto(BarG1)
 
 	BarG2 := BarG1.Mul(USk)
@@ -269,20 +317,12 @@ func NewUserKey(AttributeNames []string, rng *amcl.RAND) (*UserKey, *Trace, erro
 	r := RandModOrder(rng)
 
 	// Step 1: First message (t-values)
-	t1 := GenG2.Mul(r) // t1 = g_2^r, cover W
-	t2 := BarG1.Mul(r) // t2 = (\bar g_1)^r, cover BarG2
+	t1 := genG2().Mul(r) // t1 = g_2^r, cover W
+	t2 := BarG1.Mul(r)   // t2 = (\bar g_1)^r, cover BarG2
 
 	// Step 2: Compute the Fiat-Shamir hash, forming the challenge of the ZKP.
-	proofData := make([]byte, 18*FieldBytes+3)
-	index := 0
-	index = appendBytesG2(proofData, index, t1)
-	index = appendBytesG1(proofData, index, t2)
-	index = appendBytesG2(proofData, index, GenG2)
-	index = appendBytesG1(proofData, index, BarG1)
-	index = appendBytesG2(proofData, index, W)
-	index = appendBytesG1(proofData, index, BarG2)
-
-	proofC := HashModOrder(proofData)
+	key.Upk.ProofVersion = ProofVersionTranscript
+	proofC := keyProofChallenge(userKeyLabel, false, t1, t2, BarG1, W, BarG2)
 	key.Upk.ProofC = BigToBytes(proofC)
 
 	// Step 3: reply to the challenge message (s-values)
@@ -290,14 +330,14 @@ func NewUserKey(AttributeNames []string, rng *amcl.RAND) (*UserKey, *Trace, erro
 	key.Upk.ProofS = BigToBytes(proofS)
 
 	// Hash the public key
-	serializedUPk, err := proto.Marshal(key.Upk)
+	serializedUPk, err := marshalUncompressed(key.Upk)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to marshal issuer public key")
 	}
 	key.Upk.Hash = BigToBytes(HashModOrder(serializedUPk))
 
 	// Generate a Trace
-	trace.T = Ecp2ToProto(GenG2.Mul(USk))
+	trace.T = Ecp2ToProto(genG2().Mul(USk))
 	trace.Upk = key.Upk
 	// We are done
 	return key, trace, nil
@@ -306,7 +346,34 @@ func NewUserKey(AttributeNames []string, rng *amcl.RAND) (*UserKey, *Trace, erro
 // Check checks that this user public key is valid, i.e.
 // that all components are present and a ZK proofs verifies
 func (UPk *UserPublicKey) Check() error {
+	return UPk.check(false)
+}
+
+// CheckLegacy checks a user public key as Check does, but also accepts a key whose proof was made
+// before transcripts. It is meant for reading keys that the caller stored itself, never for keys received from others.
+func (UPk *UserPublicKey) CheckLegacy() error {
+	return UPk.check(true)
+}
+
+// check checks a user public key, and accepts a proof made before transcripts if allowLegacy is set
+func (UPk *UserPublicKey) check(allowLegacy bool) error {
 	fmt.Println("NewUserKey Check")
+	// Check that every group element is on the curve and in the right subgroup
+	// and that the proof is made of reduced scalars, before using any of them
+	g1 := []namedEcp{{"UPK", UPk.GetUPK()}, {"HSk", UPk.GetHSk()}, {"HRand", UPk.GetHRand()},
+		{"BarG1", UPk.GetBarG1()}, {"BarG2", UPk.GetBarG2()}}
+	g2 := []namedEcp2{{"W", UPk.GetW()}}
+	scalars := []namedBig{{"ProofC", UPk.GetProofC()}, {"ProofS", UPk.GetProofS()}}
+	if err := checkPublicKeyElements(g1, g2, scalars); err != nil {
+		return errors.WithMessage(err, "user public key invalid")
+	}
+	legacy := allowLegacy && UPk.GetProofVersion() == 0
+	if !legacy {
+		if err := checkProofVersion(UPk.GetProofVersion()); err != nil {
+			return errors.WithMessage(err, "user public key invalid")
+		}
+	}
+
 	// Unmarshall the public key
 	NumAttrs := len(UPk.GetAttributeNames())
 	HSk := EcpFromProto(UPk.GetHSk())
@@ -329,26 +396,15 @@ func (UPk *UserPublicKey) Check() error {
 
 	// Verify Proof
 
-	// Recompute challenge
-	proofData := make([]byte, 18*FieldBytes+3)
-	index := 0
-
 	// Recompute t-values using s-values
-	t1 := GenG2.Mul(ProofS)
+	t1 := genG2().Mul(ProofS)
 	t1.Add(W.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t1 = g_2^s \cdot W^{-C}
 
 	t2 := BarG1.Mul(ProofS)
 	t2.Add(BarG2.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t2 = {\bar g_1}^s \cdot {\bar g_2}^C
 
-	index = appendBytesG2(proofData, index, t1)
-	index = appendBytesG1(proofData, index, t2)
-	index = appendBytesG2(proofData, index, GenG2)
-	index = appendBytesG1(proofData, index, BarG1)
-	index = appendBytesG2(proofData, index, W)
-	index = appendBytesG1(proofData, index, BarG2)
-
 	// Verify that the challenge is the same
-	if *ProofC != *HashModOrder(proofData) {
+	if *ProofC != *keyProofChallenge(userKeyLabel, legacy, t1, t2, BarG1, W, BarG2) {
 		return errors.Errorf("zero knowledge proof in public key invalid")
 	}
 
@@ -358,7 +414,7 @@ func (UPk *UserPublicKey) Check() error {
 // SetHash appends a hash of a serialized public key
 func (UPk *UserPublicKey) SetHash() error {
 	UPk.Hash = nil
-	serializedUPk, err := proto.Marshal(UPk)
+	serializedUPk, err := marshalUncompressed(UPk)
 	if err != nil {
 		return errors.Wrap(err, "Failed to marshal issuer public key")
 	}
@@ -366,12 +422,61 @@ func (UPk *UserPublicKey) SetHash() error {
 	return nil
 }
 
-func (UPK UserPublicKey) Bytes() ([]byte, error) {
-	// TODO
-	return nil, nil
+// issuerKeyXLabel, issuerKeyYLabel and userKeyLabel separate the proofs of knowledge of the secret keys
+const (
+	issuerKeyXLabel = "issuerKeyX"
+	issuerKeyYLabel = "issuerKeyY"
+	userKeyLabel    = "userKey"
+)
+
+// keyProofChallenge derives the challenge of a proof of knowledge of the secret w with W = g_2^w and BarW = {\bar g_1}^w
+// from the t-values t1 and t2, from the proof data of keys made before transcripts if legacy is set
+func keyProofChallenge(label string, legacy bool, t1 *FP256BN.ECP2, t2 *FP256BN.ECP, BarG1 *FP256BN.ECP, W *FP256BN.ECP2, BarW *FP256BN.ECP) *FP256BN.BIG {
+	t := newTranscript(label)
+	if legacy {
+		t = newLegacyTranscript()
+	}
+	t.appendG2("t1", t1)
+	t.appendG1("t2", t2)
+	t.appendG2("g2", genG2())
+	t.appendG1("BarG1", BarG1)
+	t.appendG2("W", W)
+	t.appendG1("BarW", BarW)
+	return t.challenge()
+}
+
+// namedEcp, namedEcp2 and namedBig name an element of a public key in validation errors
+type namedEcp struct {
+	name  string
+	point *ECP
+}
+
+type namedEcp2 struct {
+	name  string
+	point *ECP2
+}
+
+type namedBig struct {
+	name   string
+	scalar []byte
 }
 
-func (UPK UserSecretKey) Bytes() ([]byte, error) {
-	// TODO
-	return nil, nil
+// checkPublicKeyElements validates the group elements and scalars of a public key with the checked decoders
+func checkPublicKeyElements(g1 []namedEcp, g2 []namedEcp2, scalars []namedBig) error {
+	for _, e := range g1 {
+		if _, err := EcpFromProtoChecked(e.point); err != nil {
+			return errors.WithMessage(err, e.name)
+		}
+	}
+	for _, e := range g2 {
+		if _, err := Ecp2FromProtoChecked(e.point); err != nil {
+			return errors.WithMessage(err, e.name)
+		}
+	}
+	for _, e := range scalars {
+		if _, err := BigFromBytesChecked(e.scalar); err != nil {
+			return errors.WithMessage(err, e.name)
+		}
+	}
+	return nil
 }
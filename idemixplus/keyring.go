@@ -0,0 +1,149 @@
+package idemixplus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// An issuer rotates its key by generating a new one and adding it to the IssuerKeyring of every verifier.
+// The keyring keeps the old public keys, so that credentials and signatures made under them still verify,
+// while new credentials are issued under the current key. Every key is named by its key ID, which
+// credentials and signatures carry to tell the verifier which key to use.
+
+// keyIDBytes is the number of bytes of the hash of an issuer public key that form its key ID
+const keyIDBytes = 8
+
+// IssuerKeyID derives the key ID of an issuer public key from its key material
+func IssuerKeyID(ipk *IssuerPublicKey) (string, error) {
+	material := proto.Clone(ipk).(*IssuerPublicKey)
+	material.Hash = nil
+	material.KeyId = ""
+	serialized, err := marshalUncompressed(material)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal issuer public key")
+	}
+	digest := sha256.Sum256(serialized)
+	return hex.EncodeToString(digest[:keyIDBytes]), nil
+}
+
+// setKeyID names the issuer public key by its key ID
+func (IPk *IssuerPublicKey) setKeyID() error {
+	id, err := IssuerKeyID(IPk)
+	if err != nil {
+		return err
+	}
+	IPk.KeyId = id
+	return nil
+}
+
+// checkKeyID checks that the key ID of the issuer public key is derived from its key material.
+// Keys made before key IDs were introduced have no key ID.
+func (IPk *IssuerPublicKey) checkKeyID() error {
+	if IPk.GetKeyId() == "" {
+		return nil
+	}
+	id, err := IssuerKeyID(IPk)
+	if err != nil {
+		return err
+	}
+	if id != IPk.GetKeyId() {
+		return errors.Errorf("key ID %s does not match the issuer public key", IPk.GetKeyId())
+	}
+	return nil
+}
+
+// IssuerKeyring holds the current and the retired public keys of an issuer by their key ID,
+// every key is checked and prepared for verification once, when it is added.
+// It is safe for concurrent use, signatures are verified outside of its lock while the issuer rotates its key.
+type IssuerKeyring struct {
+	mu       sync.RWMutex
+	keys     map[string]*IssuerPublicKey
+	prepared map[string]*preparedKey
+	ids      []string
+	current  string
+}
+
+// NewIssuerKeyring creates an empty IssuerKeyring
+func NewIssuerKeyring() *IssuerKeyring {
+	return &IssuerKeyring{keys: make(map[string]*IssuerPublicKey), prepared: make(map[string]*preparedKey)}
+}
+
+// Add checks an issuer public key and adds it to the keyring without making it the current key
+func (keyring *IssuerKeyring) Add(ipk *IssuerPublicKey) error {
+	return keyring.add(ipk, false)
+}
+
+// Rotate adds an issuer public key to the keyring and makes it the current key,
+// the previous keys are kept to verify what was made under them
+func (keyring *IssuerKeyring) Rotate(ipk *IssuerPublicKey) error {
+	return keyring.add(ipk, true)
+}
+
+// add checks and prepares an issuer public key before it takes the lock to add it
+func (keyring *IssuerKeyring) add(ipk *IssuerPublicKey, current bool) error {
+	if ipk == nil || ipk.GetKeyId() == "" {
+		return errors.Errorf("cannot add issuer public key: key has no key ID")
+	}
+	id := ipk.GetKeyId()
+	key, err := prepareKey(ipk, false)
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("cannot add issuer public key %s", id))
+	}
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	if _, exists := keyring.keys[id]; exists {
+		return errors.Errorf("issuer public key %s is already in the keyring", id)
+	}
+	keyring.keys[id] = ipk
+	keyring.prepared[id] = key
+	keyring.ids = append(keyring.ids, id)
+	if current {
+		keyring.current = id
+	}
+	return nil
+}
+
+// Current returns the current issuer public key, or nil if the keyring is empty
+func (keyring *IssuerKeyring) Current() *IssuerPublicKey {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	return keyring.keys[keyring.current]
+}
+
+// Get returns the issuer public key with the given key ID
+func (keyring *IssuerKeyring) Get(id string) (*IssuerPublicKey, error) {
+	keyring.mu.RLock()
+	ipk, exists := keyring.keys[id]
+	keyring.mu.RUnlock()
+	if !exists {
+		return nil, errors.Errorf("issuer public key %s is not in the keyring", id)
+	}
+	return ipk, nil
+}
+
+// IDs returns the key IDs in the keyring in the order they were added
+func (keyring *IssuerKeyring) IDs() []string {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	return append([]string{}, keyring.ids...)
+}
+
+// Verify verifies a NymSignature under the issuer public key it names, see NymSignature.Ver
+func (keyring *IssuerKeyring) Verify(nym *NymSignature, msg []byte, opts *VerifyOpts) error {
+	keyring.mu.RLock()
+	key, exists := keyring.prepared[nym.GetKeyId()]
+	keyring.mu.RUnlock()
+	if !exists {
+		return verificationErrorf(ErrKindInvalid, "NymSignature is made under an unknown issuer key %s", nym.GetKeyId())
+	}
+	check, err := nym.verifyProofs(key, 1, msg, opts)
+	if err != nil || check == nil {
+		return err
+	}
+	return check.verify(1)
+}
@@ -0,0 +1,286 @@
+package idemixplus
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// A LinkedNymSignature signs a message with several credentials of the same user, possibly from different issuers.
+// Next to a NymSignature with every credential it proves that
+// Eta_i = Xi_i^{sk} for the same sk in all signatures, and for every equality of hidden attributes that
+// Com_1 = Sigma1_1^{attr} \cdot g_1^{rho_1} and Com_2 = Sigma1_2^{attr} \cdot g_1^{rho_2} share the same attr.
+
+// linkedSignatureLabel is the label used in ZKP to identify that this ZKP links NymSignatures
+const linkedSignatureLabel = "linkedNymSignature"
+
+// Presentation is a credential of a LinkedNymSignature, with what the signature discloses and proves about it
+type Presentation struct {
+	Cred       *Credential
+	Ipk        *IssuerPublicKey
+	Disclosure []byte
+	Predicates []*RangePredicate
+	Sets       []*SetPredicate
+	RhIndex    int
+	Cri        *CredentialRevocationInformation
+}
+
+// PresentationPolicy is what the verifier of a LinkedNymSignature expects of one of the credentials,
+// the fields have the meaning of the fields of VerifyOpts
+type PresentationPolicy struct {
+	Ipk             *IssuerPublicKey
+	Disclosure      []byte
+	Predicates      []*RangePredicate
+	Sets            []*SetPredicate
+	AttributeValues []*FP256BN.BIG
+	RhIndex         int
+	RevPk           *ecdsa.PublicKey
+	Epoch           int
+	AllowLegacy     bool
+}
+
+// AttributeEquality states that the hidden attribute Attribute1 of the credential Credential1 equals
+// the hidden attribute Attribute2 of the credential Credential2, credentials are counted from 0
+type AttributeEquality struct {
+	Credential1 int
+	Attribute1  int
+	Credential2 int
+	Attribute2  int
+}
+
+// checkAttributeEqualities checks that every equality is between hidden attributes of two of the credentials
+func checkAttributeEqualities(equalities []*AttributeEquality, disclosures [][]byte) error {
+	for _, eq := range equalities {
+		if eq == nil {
+			return errors.Errorf("attribute equality is undefined")
+		}
+		for _, ref := range [][2]int{{eq.Credential1, eq.Attribute1}, {eq.Credential2, eq.Attribute2}} {
+			if ref[0] < 0 || ref[0] >= len(disclosures) {
+				return errors.Errorf("attribute equality refers to unknown credential %d", ref[0])
+			}
+			if !isIn(hiddenIndices(disclosures[ref[0]]), ref[1]) {
+				return errors.Errorf("attribute %d of credential %d is used in an attribute equality and must be hidden", ref[1], ref[0])
+			}
+		}
+	}
+	return nil
+}
+
+// NewLinkedNymSignature signs msg with every credential of the presentations and proves
+// that the credentials belong to the same user and that the attributes in equalities are equal.
+// Every credential is shown to be valid at the time validAt.
+func NewLinkedNymSignature(sk *FP256BN.BIG, presentations []*Presentation, equalities []*AttributeEquality, msg []byte, scope []byte, validAt int64, rng *amcl.RAND) (*LinkedNymSignature, error) {
+	if sk == nil || rng == nil || len(presentations) == 0 {
+		return nil, errors.Errorf("cannot create LinkedNymSignature: received nil input")
+	}
+	disclosures := make([][]byte, len(presentations))
+	for i, p := range presentations {
+		if p == nil || p.Cred == nil {
+			return nil, errors.Errorf("cannot create LinkedNymSignature: received nil input")
+		}
+		disclosures[i] = p.Disclosure
+	}
+	if err := checkAttributeEqualities(equalities, disclosures); err != nil {
+		return nil, err
+	}
+	// the signatures commit to the attributes of the equalities
+	commit := make([][]int, len(presentations))
+	for _, eq := range equalities {
+		commit[eq.Credential1] = append(commit[eq.Credential1], eq.Attribute1)
+		commit[eq.Credential2] = append(commit[eq.Credential2], eq.Attribute2)
+	}
+	linked := new(LinkedNymSignature)
+	rhos := make([]map[int]*FP256BN.BIG, len(presentations))
+	for i, p := range presentations {
+		opts := &SignOpts{Scope: scope, ValidAt: validAt, Disclosure: p.Disclosure, Predicates: p.Predicates, Sets: p.Sets, RhIndex: p.RhIndex, Cri: p.Cri}
+		nymSign, rho, err := newNymSignature(sk, p.Cred, p.Ipk, msg, opts, commit[i], NymSignatureV3, rng)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot create NymSignature with credential %d", i)
+		}
+		linked.Signatures = append(linked.Signatures, nymSign)
+		rhos[i] = rho
+	}
+	for _, eq := range equalities {
+		if !bytes.Equal(presentations[eq.Credential1].Cred.Attrs[eq.Attribute1], presentations[eq.Credential2].Cred.Attrs[eq.Attribute2]) {
+			return nil, errors.Errorf("attribute %d of credential %d does not equal attribute %d of credential %d",
+				eq.Attribute1, eq.Credential1, eq.Attribute2, eq.Credential2)
+		}
+	}
+
+	// Prove that all Eta_i = Xi_i^{sk} share the same sk
+	rSk := RandModOrder(rng)
+	tSk := make([]*FP256BN.ECP, len(linked.Signatures))
+	for i, nymSign := range linked.Signatures {
+		tSk[i] = EcpFromProto(nymSign.Xi).Mul(rSk)
+	}
+
+	// Prove that the commitments of every equality share the same attribute
+	rAttr := make([]*FP256BN.BIG, len(equalities))
+	rRand1 := make([]*FP256BN.BIG, len(equalities))
+	rRand2 := make([]*FP256BN.BIG, len(equalities))
+	tEq := make([][2]*FP256BN.ECP, len(equalities))
+	for e, eq := range equalities {
+		rAttr[e] = RandModOrder(rng)
+		rRand1[e] = RandModOrder(rng)
+		rRand2[e] = RandModOrder(rng)
+		tEq[e][0] = EcpFromProto(linked.Signatures[eq.Credential1].Sigma_1).Mul2(rAttr[e], genG1(), rRand1[e])
+		tEq[e][1] = EcpFromProto(linked.Signatures[eq.Credential2].Sigma_1).Mul2(rAttr[e], genG1(), rRand2[e])
+	}
+
+	c, err := linkedChallenge(linked.Signatures, equalities, tSk, tEq, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	linked.ProofC = BigToBytes(c)
+	linked.ProofSSk = BigToBytes(Modadd(rSk, FP256BN.Modmul(c, sk, GroupOrder), GroupOrder))
+	for e, eq := range equalities {
+		attr := FP256BN.FromBytes(presentations[eq.Credential1].Cred.Attrs[eq.Attribute1])
+		linked.Equalities = append(linked.Equalities, &AttributeEqualityProof{
+			Credential_1: int64(eq.Credential1),
+			Attribute_1:  int64(eq.Attribute1),
+			Credential_2: int64(eq.Credential2),
+			Attribute_2:  int64(eq.Attribute2),
+			ProofSAttr:   BigToBytes(Modadd(rAttr[e], FP256BN.Modmul(c, attr, GroupOrder), GroupOrder)),
+			ProofSRand_1: BigToBytes(Modadd(rRand1[e], FP256BN.Modmul(c, rhos[eq.Credential1][eq.Attribute1], GroupOrder), GroupOrder)),
+			ProofSRand_2: BigToBytes(Modadd(rRand2[e], FP256BN.Modmul(c, rhos[eq.Credential2][eq.Attribute2], GroupOrder), GroupOrder)),
+		})
+	}
+	return linked, nil
+}
+
+// Ver verifies every NymSignature of the LinkedNymSignature against the policy of its credential,
+// and that the signatures are made by the same user with the expected attributes being equal
+func (linked *LinkedNymSignature) Ver(policies []*PresentationPolicy, equalities []*AttributeEquality, msg []byte, scope []byte, validAt int64) error {
+	if len(policies) == 0 || len(linked.GetSignatures()) != len(policies) {
+		return verificationErrorf(ErrKindInvalid, "LinkedNymSignature has %d signatures, expected %d", len(linked.GetSignatures()), len(policies))
+	}
+	disclosures := make([][]byte, len(policies))
+	for i, policy := range policies {
+		if policy == nil || linked.Signatures[i] == nil {
+			return verificationErrorf(ErrKindInvalid, "LinkedNymSignature is not fit with the LinkedNymSignature format")
+		}
+		err := linked.Signatures[i].Ver(policy.Ipk, msg, &VerifyOpts{Scope: scope, ValidAt: validAt, Disclosure: policy.Disclosure, Predicates: policy.Predicates,
+			Sets: policy.Sets, AttributeValues: policy.AttributeValues, RhIndex: policy.RhIndex, RevPk: policy.RevPk, Epoch: policy.Epoch, AllowLegacy: policy.AllowLegacy})
+		if err != nil {
+			return wrapVerificationError(VerificationErrorKindOf(err), err, fmt.Sprintf("NymSignature with credential %d is invalid", i))
+		}
+		disclosures[i] = policy.Disclosure
+	}
+	if err := checkAttributeEqualities(equalities, disclosures); err != nil {
+		return wrapVerificationError(ErrKindInvalid, err, "expected attribute equalities are invalid")
+	}
+	if len(linked.GetEqualities()) != len(equalities) {
+		return verificationErrorf(ErrKindInvalid, "LinkedNymSignature has %d attribute equalities, expected %d", len(linked.GetEqualities()), len(equalities))
+	}
+	for _, eq := range equalities {
+		for _, ref := range [][2]int{{eq.Credential1, eq.Attribute1}, {eq.Credential2, eq.Attribute2}} {
+			if hiddenCommitment(linked.Signatures[ref[0]], ref[1]) == nil {
+				return verificationErrorf(ErrKindInvalid, "NymSignature with credential %d has no commitment to attribute %d", ref[0], ref[1])
+			}
+		}
+	}
+
+	ProofC, err := BigFromBytesChecked(linked.GetProofC())
+	if err != nil {
+		return wrapVerificationError(ErrKindInvalid, err, "LinkedNymSignature is malformed")
+	}
+	ProofSSk, err := BigFromBytesChecked(linked.GetProofSSk())
+	if err != nil {
+		return wrapVerificationError(ErrKindInvalid, err, "LinkedNymSignature is malformed")
+	}
+
+	// Recompute t-values using s-values, the signatures are verified so their group elements are well-formed
+	tSk := make([]*FP256BN.ECP, len(linked.Signatures))
+	for i, nymSign := range linked.Signatures {
+		tSk[i] = EcpFromProto(nymSign.Xi).Mul(ProofSSk)
+		tSk[i].Add(EcpFromProto(nymSign.Eta).Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t_i = Xi_i^{s_sk} \cdot Eta_i^{-c}
+	}
+
+	tEq := make([][2]*FP256BN.ECP, len(equalities))
+	for e, eq := range equalities {
+		proof := linked.Equalities[e]
+		if proof == nil || proof.GetCredential_1() != int64(eq.Credential1) || proof.GetAttribute_1() != int64(eq.Attribute1) ||
+			proof.GetCredential_2() != int64(eq.Credential2) || proof.GetAttribute_2() != int64(eq.Attribute2) {
+			return verificationErrorf(ErrKindInvalid, "attribute equality proof %d does not prove the expected equality", e)
+		}
+		scalars := make([]*FP256BN.BIG, 3)
+		for k, b := range [][]byte{proof.GetProofSAttr(), proof.GetProofSRand_1(), proof.GetProofSRand_2()} {
+			scalars[k], err = BigFromBytesChecked(b)
+			if err != nil {
+				return wrapVerificationError(ErrKindInvalid, err, fmt.Sprintf("attribute equality proof %d is malformed", e))
+			}
+		}
+		for side, ref := range [][2]int{{eq.Credential1, eq.Attribute1}, {eq.Credential2, eq.Attribute2}} {
+			nymSign := linked.Signatures[ref[0]]
+			Com := hiddenCommitment(nymSign, ref[1])
+			tEq[e][side] = EcpFromProto(nymSign.Sigma_1).Mul2(scalars[0], genG1(), scalars[1+side])
+			tEq[e][side].Add(Com.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t = Sigma1^{s_attr} \cdot g_1^{s_rho} \cdot Com^{-c}
+		}
+	}
+
+	c, err := linkedChallenge(linked.Signatures, equalities, tSk, tEq, msg)
+	if err != nil {
+		return wrapVerificationError(ErrKindInvalid, err, "LinkedNymSignature is malformed")
+	}
+	if *ProofC != *c {
+		return verificationErrorf(ErrKindInvalid, "LinkedNymSignature does not link the signatures")
+	}
+	return nil
+}
+
+// hiddenCommitment returns the commitment to the hidden attribute at index attribute of a verified NymSignature,
+// or nil if the signature does not commit to it
+func hiddenCommitment(nym *NymSignature, attribute int) *FP256BN.ECP {
+	if nym.aggregated() {
+		for _, com := range nym.GetCommitments() {
+			if com.GetAttribute() == int64(attribute) {
+				return EcpFromProto(com.Com)
+			}
+		}
+		return nil
+	}
+	for j, index := range hiddenIndices(nym.GetDisclosure()) {
+		if index == attribute {
+			return EcpFromProto(nym.Hides[j].Com)
+		}
+	}
+	return nil
+}
+
+// linkedChallenge derives the challenge of a LinkedNymSignature, whose transcript follows the format of its signatures
+func linkedChallenge(signatures []*NymSignature, equalities []*AttributeEquality, tSk []*FP256BN.ECP, tEq [][2]*FP256BN.ECP, msg []byte) (*FP256BN.BIG, error) {
+	for k, nymSign := range signatures {
+		if nymSign.formatVersion() != signatures[0].formatVersion() {
+			return nil, errors.Errorf("NymSignature %d is not in the format of the other signatures", k)
+		}
+	}
+	t := signatures[0].newTranscript(linkedSignatureLabel)
+	t.appendLegacyLabel(linkedSignatureLabel)
+	for k, nymSign := range signatures {
+		if len(nymSign.GetNonce()) != FieldBytes {
+			return nil, errors.Errorf("NymSignature %d has no nonce", k)
+		}
+		t.appendG1("Xi", EcpFromProto(nymSign.Xi))
+		t.appendG1("Eta", EcpFromProto(nymSign.Eta))
+		t.appendG1("tSk", tSk[k])
+		t.appendBytes("nonce", nymSign.Nonce)
+	}
+	for e, eq := range equalities {
+		t.appendInt64("credential", int64(eq.Credential1))
+		t.appendInt64("attribute", int64(eq.Attribute1))
+		t.appendInt64("credential", int64(eq.Credential2))
+		t.appendInt64("attribute", int64(eq.Attribute2))
+		for side, ref := range [][2]int{{eq.Credential1, eq.Attribute1}, {eq.Credential2, eq.Attribute2}} {
+			t.appendG1("Sigma1", EcpFromProto(signatures[ref[0]].Sigma_1))
+			t.appendG1("Com", hiddenCommitment(signatures[ref[0]], ref[1]))
+			t.appendG1("tEq", tEq[e][side])
+		}
+	}
+	t.appendBytes("msg", msg)
+	return t.challenge(), nil
+}
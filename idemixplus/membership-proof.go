@@ -0,0 +1,159 @@
+package idemixplus
+
+import (
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// A set membership proof shows that the hidden attribute committed in Com = Sigma1^{attr} \cdot g_1^{rho}
+// is one of the values v_1, ..., v_n of a public set. It is an OR proof that one of
+// Y_k = Com \cdot Sigma1^{-v_k} is g_1^{rho}, the branches of the other values are simulated.
+
+// setMembershipLabel is the label used in ZKP to identify that this ZKP is a set membership proof
+const setMembershipLabel = "setMembership"
+
+// SetPredicate states that the hidden attribute at index Attribute is one of the values in Set
+type SetPredicate struct {
+	Attribute int
+	Set       []*FP256BN.BIG
+}
+
+// InSet returns the predicate attr \in set on the attribute at index attribute
+func InSet(attribute int, set []*FP256BN.BIG) *SetPredicate {
+	return &SetPredicate{Attribute: attribute, Set: set}
+}
+
+// checkSetPredicates checks that every predicate is over a hidden attribute and has a non-empty set
+func checkSetPredicates(predicates []*SetPredicate, HiddenIndices []int) error {
+	for _, predicate := range predicates {
+		if predicate == nil || len(predicate.Set) == 0 {
+			return errors.Errorf("set predicate is undefined")
+		}
+		for _, value := range predicate.Set {
+			if value == nil {
+				return errors.Errorf("set predicate is undefined")
+			}
+		}
+		if !isIn(HiddenIndices, predicate.Attribute) {
+			return errors.Errorf("attribute %d is used in a set predicate and must be hidden", predicate.Attribute)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the set membership proof is a proof of the predicate
+func (predicate *SetPredicate) matches(proof *SetMembershipProof) bool {
+	if proof == nil || proof.GetAttribute() != int64(predicate.Attribute) || len(proof.GetSet()) != len(predicate.Set) {
+		return false
+	}
+	for k, value := range predicate.Set {
+		if len(proof.Set[k]) != FieldBytes || FP256BN.Comp(FP256BN.FromBytes(proof.Set[k]), reducedBound(value)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// newSetMembershipProof proves the predicate over the attribute attr committed in Com = Sigma1^{attr} \cdot g_1^{rho}
+// as part of the NymSignature nymSign
+func newSetMembershipProof(nymSign *NymSignature, predicate *SetPredicate, attr *FP256BN.BIG, rho *FP256BN.BIG, Sigma1 *FP256BN.ECP, Com *FP256BN.ECP, msg []byte, rng *amcl.RAND) (*SetMembershipProof, error) {
+	proof := &SetMembershipProof{Attribute: int64(predicate.Attribute)}
+	member := -1
+	for k, value := range predicate.Set {
+		proof.Set = append(proof.Set, BigToBytes(reducedBound(value)))
+		if member < 0 && FP256BN.Comp(reducedBound(value), attr) == 0 {
+			member = k
+		}
+	}
+	if member < 0 {
+		return nil, errors.Errorf("attribute %d does not satisfy the set predicate", predicate.Attribute)
+	}
+
+	n := len(predicate.Set)
+	challenges := make([]*FP256BN.BIG, n)
+	responses := make([]*FP256BN.BIG, n)
+	t := make([]*FP256BN.ECP, n)
+	w := RandModOrder(rng)
+	for k := range predicate.Set {
+		if k == member {
+			t[k] = genG1().Mul(w)
+			continue
+		}
+		// t_k = g_1^{s_k} \cdot Y_k^{-c_k}
+		challenges[k] = RandModOrder(rng)
+		responses[k] = RandModOrder(rng)
+		t[k] = genG1().Mul(responses[k])
+		t[k].Add(setMembershipBranch(Com, Sigma1, proof.Set[k]).Mul(FP256BN.Modneg(challenges[k], GroupOrder)))
+	}
+
+	// the challenge of the real branch is c minus the challenges of the simulated ones
+	c := setMembershipChallenge(nymSign, proof, Sigma1, Com, t, msg)
+	challenges[member] = c
+	for k := range predicate.Set {
+		if k != member {
+			challenges[member] = Modsub(challenges[member], challenges[k], GroupOrder)
+		}
+	}
+	responses[member] = Modadd(w, FP256BN.Modmul(challenges[member], rho, GroupOrder), GroupOrder)
+
+	for k := range predicate.Set {
+		proof.ProofC = append(proof.ProofC, BigToBytes(challenges[k]))
+		proof.ProofS = append(proof.ProofS, BigToBytes(responses[k]))
+	}
+	return proof, nil
+}
+
+// verifySetMembershipProof checks the set membership proof of the NymSignature nym over the attribute committed in Com
+func verifySetMembershipProof(nym *NymSignature, proof *SetMembershipProof, Sigma1 *FP256BN.ECP, Com *FP256BN.ECP, msg []byte) error {
+	n := len(proof.GetSet())
+	if n == 0 || len(proof.GetProofC()) != n || len(proof.GetProofS()) != n {
+		return errors.Errorf("set membership proof of attribute %d is malformed", proof.GetAttribute())
+	}
+
+	g1, Sigma1Copy := ecpCopy(genG1()), ecpCopy(Sigma1)
+	t := make([]*FP256BN.ECP, n)
+	sum := FP256BN.NewBIGint(0)
+	for k := 0; k < n; k++ {
+		scalars := make([]*FP256BN.BIG, 3)
+		for i, b := range [][]byte{proof.Set[k], proof.ProofC[k], proof.ProofS[k]} {
+			big, err := BigFromBytesChecked(b)
+			if err != nil {
+				return errors.Wrapf(err, "set membership proof of attribute %d is malformed", proof.GetAttribute())
+			}
+			scalars[i] = big
+		}
+		ProofC, ProofS := scalars[1], scalars[2]
+
+		t[k] = g1.Mul(ProofS)
+		t[k].Add(setMembershipBranch(Com, Sigma1Copy, proof.Set[k]).Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t_k = g_1^{s_k} \cdot Y_k^{-c_k}
+		sum = Modadd(sum, ProofC, GroupOrder)
+	}
+
+	if FP256BN.Comp(sum, setMembershipChallenge(nym, proof, Sigma1, Com, t, msg)) != 0 {
+		return errors.Errorf("set membership proof of attribute %d is invalid", proof.GetAttribute())
+	}
+	return nil
+}
+
+// setMembershipBranch returns Y = Com \cdot Sigma1^{-v} for the value v of a set
+func setMembershipBranch(Com *FP256BN.ECP, Sigma1 *FP256BN.ECP, value []byte) *FP256BN.ECP {
+	Y := Sigma1.Mul(FP256BN.Modneg(FP256BN.FromBytes(value), GroupOrder))
+	Y.Add(Com)
+	return Y
+}
+
+// setMembershipChallenge derives the challenge of a set membership proof of the NymSignature nym
+func setMembershipChallenge(nym *NymSignature, proof *SetMembershipProof, Sigma1, Com *FP256BN.ECP, tValues []*FP256BN.ECP, msg []byte) *FP256BN.BIG {
+	t := nym.newTranscript(setMembershipLabel)
+	t.appendLegacyLabel(setMembershipLabel)
+	t.appendInt64("attribute", proof.GetAttribute())
+	t.appendG1("Sigma1", Sigma1)
+	t.appendG1("Com", Com)
+	for k := range tValues {
+		t.appendBytes("value", proof.Set[k])
+		t.appendG1("t", tValues[k])
+	}
+	t.appendBytes("msg", msg)
+	return t.challengeWithNonce(nym.GetNonce())
+}
@@ -7,6 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package idemixplus
 
 import (
+	"bytes"
+
+	"github.com/golang/protobuf/proto"
 	amcl "github.com/hyperledger/fabric-amcl/amcl"
 	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
 	"github.com/pkg/errors"
@@ -14,8 +17,8 @@ import (
 
 // nonRevokedProver is the Prover of the ZK proof system that handles revocation.
 type nonRevokedProver interface {
-	// getFSContribution returns the non-revocation contribution to the Fiat-Shamir hash, forming the challenge of the ZKP,
-	getFSContribution(rh *FP256BN.BIG, rRh *FP256BN.BIG, cri *CredentialRevocationInformation, rng *amcl.RAND) ([]byte, error)
+	// getFSContribution returns the points the non-revocation proof contributes to the Fiat-Shamir hash, forming the challenge of the ZKP,
+	getFSContribution(rh *FP256BN.BIG, rRh *FP256BN.BIG, cri *CredentialRevocationInformation, rng *amcl.RAND) ([]*FP256BN.ECP, error)
 
 	// getNonRevokedProof returns a proof of non-revocation with the respect to passed challenge
 	getNonRevokedProof(chal *FP256BN.BIG) (*NonRevocationProof, error)
@@ -24,7 +27,7 @@ type nonRevokedProver interface {
 // nopNonRevokedProver is an empty nonRevokedProver
 type nopNonRevokedProver struct{}
 
-func (prover *nopNonRevokedProver) getFSContribution(rh *FP256BN.BIG, rRh *FP256BN.BIG, cri *CredentialRevocationInformation, rng *amcl.RAND) ([]byte, error) {
+func (prover *nopNonRevokedProver) getFSContribution(rh *FP256BN.BIG, rRh *FP256BN.BIG, cri *CredentialRevocationInformation, rng *amcl.RAND) ([]*FP256BN.ECP, error) {
 	return nil, nil
 }
 
@@ -34,11 +37,69 @@ func (prover *nopNonRevokedProver) getNonRevokedProof(chal *FP256BN.BIG) (*NonRe
 	return ret, nil
 }
 
+// plainSigNonRevokedProver proves knowledge of the epoch signature on the revocation handle
+type plainSigNonRevokedProver struct {
+	r          *FP256BN.BIG
+	rR         *FP256BN.BIG
+	sigmaPrime *FP256BN.ECP
+	sigmaBar   *FP256BN.ECP
+}
+
+func (prover *plainSigNonRevokedProver) getFSContribution(rh *FP256BN.BIG, rRh *FP256BN.BIG, cri *CredentialRevocationInformation, rng *amcl.RAND) ([]*FP256BN.ECP, error) {
+	revocationData := &PlainSigRevocationData{}
+	err := proto.Unmarshal(cri.GetRevocationData(), revocationData)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal revocation data")
+	}
+
+	// find the epoch signature on the revocation handle
+	var sigma *FP256BN.ECP
+	rhBytes := BigToBytes(rh)
+	for _, signature := range revocationData.GetSignatures() {
+		if bytes.Equal(signature.GetRevocationHandle(), rhBytes) {
+			sigma = EcpFromProto(signature.GetRhSignature())
+			break
+		}
+	}
+	if sigma == nil {
+		return nil, errors.Errorf("revocation handle is revoked in epoch %d", cri.GetEpoch())
+	}
+
+	// randomize the signature: sigma' = sigma^r, sigmaBar = sigma'^{-rh} \cdot g_1^r
+	prover.r = RandModOrder(rng)
+	prover.rR = RandModOrder(rng)
+	prover.sigmaPrime = sigma.Mul(prover.r)
+	prover.sigmaBar = prover.sigmaPrime.Mul2(FP256BN.Modneg(rh, GroupOrder), genG1(), prover.r)
+
+	// t = sigma'^{-r_{rh}} \cdot g_1^{r_r}
+	t := prover.sigmaPrime.Mul2(FP256BN.Modneg(rRh, GroupOrder), genG1(), prover.rR)
+
+	return []*FP256BN.ECP{t, prover.sigmaPrime, prover.sigmaBar}, nil
+}
+
+func (prover *plainSigNonRevokedProver) getNonRevokedProof(chal *FP256BN.BIG) (*NonRevocationProof, error) {
+	proof := &PlainSigNonRevokedProof{
+		SigmaPrime: EcpToProto(prover.sigmaPrime),
+		SigmaBar:   EcpToProto(prover.sigmaBar),
+		ProofSR:    BigToBytes(Modadd(prover.rR, FP256BN.Modmul(chal, prover.r, GroupOrder), GroupOrder)),
+	}
+	proofBytes, err := proto.Marshal(proof)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal non-revocation proof")
+	}
+	ret := &NonRevocationProof{}
+	ret.RevocationAlg = int32(ALG_PLAIN_SIGNATURE)
+	ret.NonRevocationProof = proofBytes
+	return ret, nil
+}
+
 // getNonRevocationProver returns the nonRevokedProver bound to the passed revocation algorithm
 func getNonRevocationProver(algorithm RevocationAlgorithm) (nonRevokedProver, error) {
 	switch algorithm {
 	case ALG_NO_REVOCATION:
 		return &nopNonRevokedProver{}, nil
+	case ALG_PLAIN_SIGNATURE:
+		return &plainSigNonRevokedProver{}, nil
 	default:
 		// unknown revocation algorithm
 		return nil, errors.Errorf("unknown revocation algorithm %d", algorithm)
@@ -7,28 +7,73 @@ SPDX-License-Identifier: Apache-2.0
 package idemixplus
 
 import (
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
 	"github.com/pkg/errors"
 )
 
 // nonRevokedProver is the Verifier of the ZK proof system that handles revocation.
 type nonRevocationVerifier interface {
-	// recomputeFSContribution recomputes the contribution of the non-revocation proof to the ZKP challenge
-	recomputeFSContribution(proof *NonRevocationProof, chal *FP256BN.BIG, epochPK *FP256BN.ECP2, proofSRh *FP256BN.BIG) ([]byte, error)
+	// recomputeFSContribution recomputes the points the non-revocation proof contributes to the ZKP challenge
+	recomputeFSContribution(proof *NonRevocationProof, chal *FP256BN.BIG, epochPK *FP256BN.ECP2, proofSRh *FP256BN.BIG) ([]*FP256BN.ECP, error)
 }
 
 // nopNonRevocationVerifier is an empty nonRevocationVerifier that produces an empty contribution
 type nopNonRevocationVerifier struct{}
 
-func (verifier *nopNonRevocationVerifier) recomputeFSContribution(proof *NonRevocationProof, chal *FP256BN.BIG, epochPK *FP256BN.ECP2, proofSRh *FP256BN.BIG) ([]byte, error) {
+func (verifier *nopNonRevocationVerifier) recomputeFSContribution(proof *NonRevocationProof, chal *FP256BN.BIG, epochPK *FP256BN.ECP2, proofSRh *FP256BN.BIG) ([]*FP256BN.ECP, error) {
 	return nil, nil
 }
 
+// plainSigNonRevocationVerifier checks the proof of knowledge of the epoch signature on the revocation handle
+type plainSigNonRevocationVerifier struct{}
+
+func (verifier *plainSigNonRevocationVerifier) recomputeFSContribution(proof *NonRevocationProof, chal *FP256BN.BIG, epochPK *FP256BN.ECP2, proofSRh *FP256BN.BIG) ([]*FP256BN.ECP, error) {
+	if proof == nil || epochPK == nil || proofSRh == nil {
+		return nil, errors.Errorf("non-revocation proof invalid: received nil input")
+	}
+	plainSigProof := &PlainSigNonRevokedProof{}
+	err := proto.Unmarshal(proof.GetNonRevocationProof(), plainSigProof)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal non-revocation proof")
+	}
+	if plainSigProof.GetSigmaPrime() == nil || plainSigProof.GetSigmaBar() == nil || plainSigProof.GetProofSR() == nil {
+		return nil, errors.Errorf("non-revocation proof invalid: missing proof elements")
+	}
+	sigmaPrime, err := EcpFromProtoChecked(plainSigProof.GetSigmaPrime())
+	if err != nil {
+		return nil, errors.Wrap(err, "non-revocation proof invalid: sigma' is malformed")
+	}
+	sigmaBar, err := EcpFromProtoChecked(plainSigProof.GetSigmaBar())
+	if err != nil {
+		return nil, errors.Wrap(err, "non-revocation proof invalid: sigmaBar is malformed")
+	}
+	ProofSR, err := BigFromBytesChecked(plainSigProof.GetProofSR())
+	if err != nil {
+		return nil, errors.Wrap(err, "non-revocation proof invalid: s_r is malformed")
+	}
+
+	// check e(sigma', epochPK) = e(sigmaBar, g_2)
+	left := FP256BN.Fexp(FP256BN.Ate(epochPK, sigmaPrime))
+	right := FP256BN.Fexp(FP256BN.Ate(genG2(), sigmaBar))
+	if !left.Equals(right) {
+		return nil, errors.Errorf("non-revocation proof invalid: signature on revocation handle does not verify")
+	}
+
+	// recompute t = sigma'^{-s_{rh}} \cdot g_1^{s_r} \cdot sigmaBar^{-c}
+	t := sigmaPrime.Mul2(FP256BN.Modneg(proofSRh, GroupOrder), genG1(), ProofSR)
+	t.Add(sigmaBar.Mul(FP256BN.Modneg(chal, GroupOrder)))
+
+	return []*FP256BN.ECP{t, sigmaPrime, sigmaBar}, nil
+}
+
 // getNonRevocationVerifier returns the nonRevocationVerifier bound to the passed revocation algorithm
 func getNonRevocationVerifier(algorithm RevocationAlgorithm) (nonRevocationVerifier, error) {
 	switch algorithm {
 	case ALG_NO_REVOCATION:
 		return &nopNonRevocationVerifier{}, nil
+	case ALG_PLAIN_SIGNATURE:
+		return &plainSigNonRevocationVerifier{}, nil
 	default:
 		// unknown revocation algorithm
 		return nil, errors.Errorf("unknown revocation algorithm %d", algorithm)
@@ -0,0 +1,193 @@
+package idemixplus
+
+import (
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// Points are serialized compressed: a G1 element as 0x02 or 0x03 | x, where the prefix is the parity of y,
+// and a G2 element as 0x02 or 0x03 | xa | xb, where the prefix is the sign of y. The sign of y = ya + yb*i
+// is the parity of ya, or the parity of yb if ya is zero.
+// The point at infinity is encoded with zero bytes, which does not decompress.
+// Objects made before compression hold the coordinates of their points, they are still read as they are.
+
+// compressG1 returns the compressed encoding of a G1 element
+func compressG1(P *FP256BN.ECP) []byte {
+	res := make([]byte, FieldBytes+1)
+	if !P.Is_infinity() {
+		P.ToBytes(res, true)
+	}
+	return res
+}
+
+// compressG2 returns the compressed encoding of a G2 element
+func compressG2(P *FP256BN.ECP2) []byte {
+	res := make([]byte, 2*FieldBytes+1)
+	if P.Is_infinity() {
+		return res
+	}
+	res[0] = 0x02 | byte(fp2Sign(P.GetY()))
+	P.GetX().GetA().ToBytes(res[1 : FieldBytes+1])
+	P.GetX().GetB().ToBytes(res[FieldBytes+1:])
+	return res
+}
+
+// decompressG1 parses the compressed encoding of a G1 element
+func decompressG1(b []byte) (*FP256BN.ECP, error) {
+	if len(b) != FieldBytes+1 || (b[0] != 0x02 && b[0] != 0x03) {
+		return nil, errors.Errorf("compressed G1 element is malformed")
+	}
+	x, err := coordinateFromBytes(b[1:])
+	if err != nil {
+		return nil, errors.Wrap(err, "compressed G1 element is malformed")
+	}
+	P := FP256BN.NewECPbigint(x, int(b[0]&1))
+	if P.Is_infinity() {
+		return nil, errors.Errorf("compressed G1 element is not on the curve")
+	}
+	return P, nil
+}
+
+// decompressG2 parses the compressed encoding of a G2 element, it does not check the subgroup
+func decompressG2(b []byte) (*FP256BN.ECP2, error) {
+	if len(b) != 2*FieldBytes+1 || (b[0] != 0x02 && b[0] != 0x03) {
+		return nil, errors.Errorf("compressed G2 element is malformed")
+	}
+	xa, err := coordinateFromBytes(b[1 : FieldBytes+1])
+	if err != nil {
+		return nil, errors.Wrap(err, "compressed G2 element is malformed")
+	}
+	xb, err := coordinateFromBytes(b[FieldBytes+1:])
+	if err != nil {
+		return nil, errors.Wrap(err, "compressed G2 element is malformed")
+	}
+	x := FP256BN.NewFP2bigs(xa, xb)
+	P := FP256BN.NewECP2fp2(x)
+	if P.Is_infinity() {
+		return nil, errors.Errorf("compressed G2 element is not on the curve")
+	}
+	if y := P.GetY(); fp2Sign(y) != int(b[0]&1) {
+		ya, yb := FP256BN.Modneg(y.GetA(), fieldModulus), FP256BN.Modneg(y.GetB(), fieldModulus)
+		ya.Mod(fieldModulus)
+		yb.Mod(fieldModulus)
+		P = FP256BN.NewECP2fp2s(x, FP256BN.NewFP2bigs(ya, yb))
+	}
+	return P, nil
+}
+
+// fp2Sign returns the sign of an element of FP2, which tells it apart from its negation
+func fp2Sign(y *FP256BN.FP2) int {
+	a := y.GetA()
+	if FP256BN.Comp(a, FP256BN.NewBIGint(0)) == 0 {
+		a = y.GetB()
+	}
+	b := make([]byte, FieldBytes)
+	a.ToBytes(b)
+	return int(b[FieldBytes-1] & 1)
+}
+
+// forEachPoint calls g1 and g2 with every G1 and G2 element held by a proto message
+func forEachPoint(msg proto.Message, g1 func(*ECP), g2 func(*ECP2)) {
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() {
+				return
+			}
+			switch p := v.Interface().(type) {
+			case *ECP:
+				g1(p)
+				return
+			case *ECP2:
+				g2(p)
+				return
+			}
+			walk(v.Elem())
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).PkgPath == "" {
+					walk(v.Field(i))
+				}
+			}
+		case reflect.Slice, reflect.Array:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return
+			}
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Map:
+			for _, key := range v.MapKeys() {
+				walk(v.MapIndex(key))
+			}
+		}
+	}
+	walk(reflect.ValueOf(msg))
+}
+
+// pointForms counts the compressed and the uncompressed points held by a proto message
+func pointForms(msg proto.Message) (compressed int, uncompressed int) {
+	forEachPoint(msg, func(p *ECP) {
+		if len(p.GetCompressed()) > 0 {
+			compressed++
+		} else {
+			uncompressed++
+		}
+	}, func(p *ECP2) {
+		if len(p.GetCompressed()) > 0 {
+			compressed++
+		} else {
+			uncompressed++
+		}
+	})
+	return compressed, uncompressed
+}
+
+// uncompressPoints replaces the compressed points of a proto message by their coordinates,
+// points that do not decompress are left as they are
+func uncompressPoints(msg proto.Message) {
+	forEachPoint(msg, func(p *ECP) {
+		if P, err := decompressG1(p.GetCompressed()); err == nil {
+			p.X, p.Y, p.Compressed = BigToBytes(P.GetX()), BigToBytes(P.GetY()), nil
+		}
+	}, func(p *ECP2) {
+		if P, err := decompressG2(p.GetCompressed()); err == nil {
+			p.Xa, p.Xb = BigToBytes(P.GetX().GetA()), BigToBytes(P.GetX().GetB())
+			p.Ya, p.Yb = BigToBytes(P.GetY().GetA()), BigToBytes(P.GetY().GetB())
+			p.Compressed = nil
+		}
+	})
+}
+
+// compressPoints replaces the coordinates of the points of a proto message by their compressed encoding,
+// points that are not valid are left as they are
+func compressPoints(msg proto.Message) {
+	forEachPoint(msg, func(p *ECP) {
+		if len(p.GetCompressed()) > 0 {
+			return
+		}
+		if P, err := EcpFromProtoChecked(p); err == nil {
+			p.X, p.Y, p.Compressed = nil, nil, compressG1(P)
+		}
+	}, func(p *ECP2) {
+		if len(p.GetCompressed()) > 0 {
+			return
+		}
+		if P, err := Ecp2FromProtoChecked(p); err == nil {
+			p.Xa, p.Xb, p.Ya, p.Yb, p.Compressed = nil, nil, nil, nil, compressG2(P)
+		}
+	})
+}
+
+// marshalUncompressed returns the protobuf encoding of a message with the coordinates of all its points.
+// Hashes, key IDs and signatures of objects are taken over it, so they do not depend on whether
+// the points of an object are compressed and match the ones of objects made before compression.
+func marshalUncompressed(msg proto.Message) ([]byte, error) {
+	msg = proto.Clone(msg)
+	uncompressPoints(msg)
+	return proto.Marshal(msg)
+}
@@ -0,0 +1,195 @@
+package idemixplus
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// A PreparedVerifier verifies NymSignatures and credentials of one issuer public key. It decodes and checks
+// the key once instead of on every verification, and spreads the proofs of the hidden attributes and the
+// validity window, and the Miller loops of the pairing equation, over a pool of workers.
+// fabric-amcl exports no precomputation of the lines of a G2 argument, the prepared verifier caches
+// the decoded points of the key in G2 instead.
+// fabric-amcl normalizes the coordinates of the receiver of Mul and of the G1 arguments of Ate in place,
+// so the workers only read the points they share and multiply copies of them. This holds for the
+// points of the key as well, a PreparedVerifier and the keys of an IssuerKeyring are used by several goroutines at once.
+
+// preparedKey is an issuer public key with its points decoded, TracingPk is nil for a key without tracing key
+type preparedKey struct {
+	ipk         *IssuerPublicKey
+	BarX        *FP256BN.ECP2
+	BarY        *FP256BN.ECP2
+	BarAttrs    []*FP256BN.ECP2
+	BarValidity []*FP256BN.ECP2
+	TracingPk   *FP256BN.ECP
+}
+
+// prepareKey checks an issuer public key and decodes the points of it that verifiers use.
+// The key is checked on a copy, as Check sets its hash, so that keys shared between goroutines are only read.
+// A key whose proofs were made before transcripts is accepted if allowLegacy is set, see CheckLegacy.
+func prepareKey(ipk *IssuerPublicKey, allowLegacy bool) (*preparedKey, error) {
+	if ipk == nil || ipk.GetBarX() == nil || ipk.GetBarY() == nil {
+		return nil, errors.Errorf("issuer public key is undefined")
+	}
+	if len(ipk.GetBarValidity()) != numValidityKeys {
+		return nil, errors.Errorf("issuer public key has no validity key")
+	}
+	checked := proto.Clone(ipk).(*IssuerPublicKey)
+	if err := checked.check(allowLegacy); err != nil {
+		return nil, err
+	}
+
+	key := &preparedKey{ipk: checked}
+	var err error
+	if key.BarX, err = Ecp2FromProtoChecked(checked.GetBarX()); err != nil {
+		return nil, errors.WithMessage(err, "issuer public key invalid: BarX")
+	}
+	if key.BarY, err = Ecp2FromProtoChecked(checked.GetBarY()); err != nil {
+		return nil, errors.WithMessage(err, "issuer public key invalid: BarY")
+	}
+	if checked.GetTracingPk() != nil {
+		if key.TracingPk, err = EcpFromProtoChecked(checked.GetTracingPk()); err != nil {
+			return nil, errors.WithMessage(err, "issuer public key invalid: TracingPk")
+		}
+	}
+	for i, BarAttr := range checked.GetBarAttrs() {
+		P, err := Ecp2FromProtoChecked(BarAttr)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("issuer public key invalid: BarAttrs[%d]", i))
+		}
+		key.BarAttrs = append(key.BarAttrs, P)
+	}
+	for v, BarValidity := range checked.GetBarValidity() {
+		P, err := Ecp2FromProtoChecked(BarValidity)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("issuer public key invalid: BarValidity[%d]", v))
+		}
+		key.BarValidity = append(key.BarValidity, P)
+	}
+	return key, nil
+}
+
+// PreparedVerifier verifies NymSignatures and credentials of one issuer public key with a pool of workers
+type PreparedVerifier struct {
+	key     *preparedKey
+	workers int
+}
+
+// NewPreparedVerifier checks the issuer public key and prepares a verifier for it that runs on up to
+// workers goroutines per verification, or on one per CPU if workers is not positive
+func NewPreparedVerifier(ipk *IssuerPublicKey, workers int) (*PreparedVerifier, error) {
+	if ipk == nil {
+		return nil, errors.Errorf("cannot create PreparedVerifier: received nil input")
+	}
+	key, err := prepareKey(ipk, false)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &PreparedVerifier{key: key, workers: workers}, nil
+}
+
+// Ver verifies a NymSignature like NymSignature.Ver does under the issuer public key of the verifier
+func (verifier *PreparedVerifier) Ver(nym *NymSignature, msg []byte, opts *VerifyOpts) error {
+	check, err := nym.verifyProofs(verifier.key, verifier.workers, msg, opts)
+	if err != nil || check == nil {
+		return err
+	}
+	return check.verify(verifier.workers)
+}
+
+// VerifyCredential checks a credential of the user with secret sk like Credential.Ver does
+// under the issuer public key of the verifier
+func (verifier *PreparedVerifier) VerifyCredential(cred *Credential, sk *FP256BN.BIG) error {
+	return cred.verify(sk, verifier.key)
+}
+
+// ecpCopy returns a copy of P that a goroutine can multiply while other goroutines read P
+func ecpCopy(P *FP256BN.ECP) *FP256BN.ECP {
+	res := FP256BN.NewECP()
+	res.Copy(P)
+	return res
+}
+
+// ecp2Copy returns a copy of P that a goroutine can multiply while other goroutines read P
+func ecp2Copy(P *FP256BN.ECP2) *FP256BN.ECP2 {
+	res := FP256BN.NewECP2()
+	res.Copy(P)
+	return res
+}
+
+// parallelFor runs job(0), ..., job(n-1) on up to workers goroutines. It returns the error of the
+// job with the lowest index that fails, jobs after a failed one may be skipped.
+func parallelFor(n int, workers int, job func(k int) error) error {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for k := 0; k < n; k++ {
+			if err := job(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, n)
+	jobs := make(chan int)
+	var failed sync.Once
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				if errs[k] = job(k); errs[k] != nil {
+					failed.Do(func() { close(done) })
+				}
+			}
+		}()
+	}
+feed:
+	for k := 0; k < n; k++ {
+		select {
+		case jobs <- k:
+		case <-done:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multiPairing returns the product of the Miller loops e(G2_k, G1_k) before the final exponentiation,
+// pairs of them are computed together on up to workers goroutines
+func multiPairing(G2 []*FP256BN.ECP2, G1 []*FP256BN.ECP, workers int) *FP256BN.FP12 {
+	loops := make([]*FP256BN.FP12, (len(G2)+1)/2)
+	_ = parallelFor(len(loops), workers, func(k int) error {
+		if 2*k+1 < len(G2) {
+			loops[k] = FP256BN.Ate2(G2[2*k], G1[2*k], G2[2*k+1], G1[2*k+1])
+		} else {
+			loops[k] = FP256BN.Ate(G2[2*k], G1[2*k])
+		}
+		return nil
+	})
+	res := FP256BN.NewFP12int(1)
+	for _, loop := range loops {
+		res.Mul(loop)
+	}
+	return res
+}
@@ -0,0 +1,293 @@
+package idemixplus
+
+import (
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// A range proof shows that a hidden attribute attr satisfies attr >= bound (or attr <= bound)
+// without disclosing it. The difference delta = attr - bound (or bound - attr) is decomposed into
+// RangeBits bits b_j, every bit is committed as C_j = g_1^{b_j} h^{r_j} and shown to be 0 or 1
+// with an OR proof, and a Schnorr proof shows that
+// Com = Sigma1^{attr} \cdot g_1^{rho} and D = g_1^{attr} \cdot h^{R}
+// share the same attr, where D = g_1^{bound} \prod C_j^{2^j} (or g_1^{bound} \prod C_j^{-2^j}).
+
+// RangeBits is the number of bits of the difference between a hidden attribute and the bound of a predicate
+const RangeBits = 64
+
+// rangeProofLabel is the label used in ZKP to identify that this ZKP is a range proof
+const rangeProofLabel = "rangeProof"
+
+// rangeBaseG1 is the base h of the bit commitments, no one knows its discrete log to g_1
+var rangeBaseG1 = hashToG1([]byte(rangeProofLabel + "Base"))
+
+// rangeBase returns a copy of the base h, as the generators are handed out
+func rangeBase() *FP256BN.ECP {
+	return ecpCopy(rangeBaseG1)
+}
+
+// RangePredicate states that the hidden attribute at index Attribute is at least Bound,
+// or at most Bound if Upper is set
+type RangePredicate struct {
+	Attribute int
+	Bound     *FP256BN.BIG
+	Upper     bool
+
+	// bits is the number of bits of the difference, RangeBits if not set
+	bits int
+}
+
+// AtLeast returns the predicate attr >= bound on the attribute at index attribute
+func AtLeast(attribute int, bound *FP256BN.BIG) *RangePredicate {
+	return &RangePredicate{Attribute: attribute, Bound: bound}
+}
+
+// AtMost returns the predicate attr <= bound on the attribute at index attribute
+func AtMost(attribute int, bound *FP256BN.BIG) *RangePredicate {
+	return &RangePredicate{Attribute: attribute, Bound: bound, Upper: true}
+}
+
+// InRange returns the predicates lower <= attr <= upper on the attribute at index attribute
+func InRange(attribute int, lower *FP256BN.BIG, upper *FP256BN.BIG) []*RangePredicate {
+	return []*RangePredicate{AtLeast(attribute, lower), AtMost(attribute, upper)}
+}
+
+// checkRangePredicates checks that every predicate is over a hidden attribute
+func checkRangePredicates(predicates []*RangePredicate, HiddenIndices []int) error {
+	for _, predicate := range predicates {
+		if predicate == nil || predicate.Bound == nil {
+			return errors.Errorf("range predicate is undefined")
+		}
+		if !isIn(HiddenIndices, predicate.Attribute) {
+			return errors.Errorf("attribute %d is used in a range predicate and must be hidden", predicate.Attribute)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the range proof is a proof of the predicate
+func (predicate *RangePredicate) matches(proof *RangeProof) bool {
+	return proof != nil &&
+		proof.GetAttribute() == int64(predicate.Attribute) &&
+		proof.GetUpper() == predicate.Upper &&
+		len(proof.GetBound()) == FieldBytes &&
+		FP256BN.Comp(FP256BN.FromBytes(proof.GetBound()), reducedBound(predicate.Bound)) == 0
+}
+
+// rangeBits returns the number of bits of the difference between the attribute and the bound
+func (predicate *RangePredicate) rangeBits() int {
+	if predicate.bits == 0 {
+		return RangeBits
+	}
+	return predicate.bits
+}
+
+// reducedBound returns the bound of a predicate modulo the group order
+func reducedBound(bound *FP256BN.BIG) *FP256BN.BIG {
+	reduced := FP256BN.NewBIGcopy(bound)
+	reduced.Mod(GroupOrder)
+	return reduced
+}
+
+// newRangeProof proves the predicate over the attribute attr committed in Com = Sigma1^{attr} \cdot g_1^{rho}
+// as part of the NymSignature nymSign
+func newRangeProof(nymSign *NymSignature, predicate *RangePredicate, attr *FP256BN.BIG, rho *FP256BN.BIG, Sigma1 *FP256BN.ECP, Com *FP256BN.ECP, msg []byte, rng *amcl.RAND) (*RangeProof, error) {
+	bound := reducedBound(predicate.Bound)
+	numBits := predicate.rangeBits()
+
+	var delta *FP256BN.BIG
+	if predicate.Upper {
+		delta = Modsub(bound, attr, GroupOrder)
+	} else {
+		delta = Modsub(attr, bound, GroupOrder)
+	}
+	deltaBytes := BigToBytes(delta)
+	for _, b := range deltaBytes[:FieldBytes-numBits/8] {
+		if b != 0 {
+			return nil, errors.Errorf("attribute %d does not satisfy the range predicate", predicate.Attribute)
+		}
+	}
+
+	proof := &RangeProof{
+		Attribute: int64(predicate.Attribute),
+		Bound:     BigToBytes(bound),
+		Upper:     predicate.Upper,
+	}
+
+	// Commit to the bits of delta and prepare the OR proofs, the branch of the other bit value is simulated
+	bits := make([]int, numBits)
+	rands := make([]*FP256BN.BIG, numBits)
+	witnesses := make([]*FP256BN.BIG, numBits)
+	simC := make([]*FP256BN.BIG, numBits)
+	simS := make([]*FP256BN.BIG, numBits)
+	commitments := make([]*FP256BN.ECP, numBits)
+	t0 := make([]*FP256BN.ECP, numBits)
+	t1 := make([]*FP256BN.ECP, numBits)
+	R := FP256BN.NewBIGint(0)
+	pow := FP256BN.NewBIGint(1)
+	for j := 0; j < numBits; j++ {
+		bits[j] = int(deltaBytes[FieldBytes-1-j/8]>>uint(j%8)) & 1
+		rands[j] = RandModOrder(rng)
+		commitments[j] = rangeBase().Mul(rands[j])
+		if bits[j] == 1 {
+			commitments[j].Add(genG1())
+		}
+		R = Modadd(R, FP256BN.Modmul(pow, rands[j], GroupOrder), GroupOrder)
+		pow = Modadd(pow, pow, GroupOrder)
+
+		witnesses[j] = RandModOrder(rng)
+		simC[j] = RandModOrder(rng)
+		simS[j] = RandModOrder(rng)
+		if bits[j] == 0 {
+			// C_j = h^{r_j}, simulate C_j / g_1 = h^{r_j}
+			t0[j] = rangeBase().Mul(witnesses[j])
+			t1[j] = rangeBase().Mul(simS[j])
+			t1[j].Add(rangeBitMinusOne(commitments[j]).Mul(FP256BN.Modneg(simC[j], GroupOrder)))
+		} else {
+			// C_j / g_1 = h^{r_j}, simulate C_j = h^{r_j}
+			t0[j] = rangeBase().Mul(simS[j])
+			t0[j].Add(commitments[j].Mul(FP256BN.Modneg(simC[j], GroupOrder)))
+			t1[j] = rangeBase().Mul(witnesses[j])
+		}
+	}
+	// D = g_1^{attr} \cdot h^{R} with R = \sum 2^j r_j, or R = -\sum 2^j r_j for an upper bound
+	if predicate.Upper {
+		R = FP256BN.Modneg(R, GroupOrder)
+	}
+
+	// Prove that Com and D share the same attr
+	rAttr := RandModOrder(rng)
+	rRho := RandModOrder(rng)
+	rR := RandModOrder(rng)
+	tCom := Sigma1.Mul2(rAttr, genG1(), rRho)
+	tD := genG1().Mul2(rAttr, rangeBase(), rR)
+
+	c := rangeProofChallenge(nymSign, proof, Sigma1, Com, commitments, t0, t1, tCom, tD, msg)
+
+	for j := 0; j < numBits; j++ {
+		// the challenge of the real branch is c minus the challenge of the simulated one
+		realC := Modsub(c, simC[j], GroupOrder)
+		realS := Modadd(witnesses[j], FP256BN.Modmul(realC, rands[j], GroupOrder), GroupOrder)
+		proof.Bits = append(proof.Bits, EcpToProto(commitments[j]))
+		if bits[j] == 0 {
+			proof.BitC0 = append(proof.BitC0, BigToBytes(realC))
+			proof.BitS0 = append(proof.BitS0, BigToBytes(realS))
+			proof.BitS1 = append(proof.BitS1, BigToBytes(simS[j]))
+		} else {
+			proof.BitC0 = append(proof.BitC0, BigToBytes(simC[j]))
+			proof.BitS0 = append(proof.BitS0, BigToBytes(simS[j]))
+			proof.BitS1 = append(proof.BitS1, BigToBytes(realS))
+		}
+	}
+	proof.ProofC = BigToBytes(c)
+	proof.ProofSAttr = BigToBytes(Modadd(rAttr, FP256BN.Modmul(c, attr, GroupOrder), GroupOrder))
+	proof.ProofSRand = BigToBytes(Modadd(rRho, FP256BN.Modmul(c, rho, GroupOrder), GroupOrder))
+	proof.ProofSBits = BigToBytes(Modadd(rR, FP256BN.Modmul(c, R, GroupOrder), GroupOrder))
+	return proof, nil
+}
+
+// verifyRangeProof checks the range proof of the NymSignature nym with numBits bits over the attribute committed in Com
+func verifyRangeProof(nym *NymSignature, proof *RangeProof, numBits int, Sigma1 *FP256BN.ECP, Com *FP256BN.ECP, msg []byte) error {
+	if len(proof.GetBits()) != numBits || len(proof.GetBitC0()) != numBits ||
+		len(proof.GetBitS0()) != numBits || len(proof.GetBitS1()) != numBits {
+		return errors.Errorf("range proof of attribute %d is malformed", proof.GetAttribute())
+	}
+	scalars := make([]*FP256BN.BIG, 5)
+	for k, b := range [][]byte{proof.GetBound(), proof.GetProofC(), proof.GetProofSAttr(), proof.GetProofSRand(), proof.GetProofSBits()} {
+		big, err := BigFromBytesChecked(b)
+		if err != nil {
+			return errors.Wrapf(err, "range proof of attribute %d is malformed", proof.GetAttribute())
+		}
+		scalars[k] = big
+	}
+	bound, ProofC, ProofSAttr, ProofSRand, ProofSBits := scalars[0], scalars[1], scalars[2], scalars[3], scalars[4]
+	g1, h := genG1(), rangeBase()
+
+	// Recompute the t-values of the OR proofs and D from the bit commitments
+	commitments := make([]*FP256BN.ECP, numBits)
+	t0 := make([]*FP256BN.ECP, numBits)
+	t1 := make([]*FP256BN.ECP, numBits)
+	D := FP256BN.NewECP()
+	for j := numBits - 1; j >= 0; j-- {
+		C, err := EcpFromProtoChecked(proof.Bits[j])
+		if err != nil {
+			return errors.Wrapf(err, "range proof of attribute %d is malformed", proof.GetAttribute())
+		}
+		C0, err := BigFromBytesChecked(proof.BitC0[j])
+		if err != nil {
+			return errors.Wrapf(err, "range proof of attribute %d is malformed", proof.GetAttribute())
+		}
+		S0, err := BigFromBytesChecked(proof.BitS0[j])
+		if err != nil {
+			return errors.Wrapf(err, "range proof of attribute %d is malformed", proof.GetAttribute())
+		}
+		S1, err := BigFromBytesChecked(proof.BitS1[j])
+		if err != nil {
+			return errors.Wrapf(err, "range proof of attribute %d is malformed", proof.GetAttribute())
+		}
+		C1 := Modsub(ProofC, C0, GroupOrder)
+		commitments[j] = C
+
+		t0[j] = h.Mul(S0)
+		t0[j].Add(C.Mul(FP256BN.Modneg(C0, GroupOrder))) // t0 = h^{s_0} \cdot C_j^{-c_0}
+		t1[j] = h.Mul(S1)
+		t1[j].Add(rangeBitMinusOne(C).Mul(FP256BN.Modneg(C1, GroupOrder))) // t1 = h^{s_1} \cdot (C_j / g_1)^{-c_1}
+
+		// D = D^2 \cdot C_j
+		double := FP256BN.NewECP()
+		double.Copy(D)
+		D.Add(double)
+		D.Add(C)
+	}
+	if proof.GetUpper() {
+		upper := g1.Mul(bound)
+		upper.Sub(D)
+		D = upper
+	} else {
+		D.Add(g1.Mul(bound))
+	}
+
+	tCom := Sigma1.Mul2(ProofSAttr, genG1(), ProofSRand)
+	tCom.Add(Com.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // tCom = Sigma1^{s_attr} \cdot g_1^{s_rho} \cdot Com^{-c}
+	tD := genG1().Mul2(ProofSAttr, rangeBase(), ProofSBits)
+	tD.Add(D.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // tD = g_1^{s_attr} \cdot h^{s_R} \cdot D^{-c}
+
+	if *ProofC != *rangeProofChallenge(nym, proof, Sigma1, Com, commitments, t0, t1, tCom, tD, msg) {
+		return errors.Errorf("range proof of attribute %d is invalid", proof.GetAttribute())
+	}
+	return nil
+}
+
+// rangeBitMinusOne returns C / g_1 for a bit commitment C
+func rangeBitMinusOne(C *FP256BN.ECP) *FP256BN.ECP {
+	res := FP256BN.NewECP()
+	res.Copy(C)
+	res.Sub(genG1())
+	return res
+}
+
+// rangeProofChallenge derives the challenge of a range proof of the NymSignature nym
+func rangeProofChallenge(nym *NymSignature, proof *RangeProof, Sigma1, Com *FP256BN.ECP, commitments, t0, t1 []*FP256BN.ECP, tCom, tD *FP256BN.ECP, msg []byte) *FP256BN.BIG {
+	upper := []byte{0}
+	if proof.GetUpper() {
+		upper[0] = 1
+	}
+	t := nym.newTranscript(rangeProofLabel)
+	t.appendLegacyLabel(rangeProofLabel)
+	t.appendInt64("attribute", proof.GetAttribute())
+	t.appendBytes("bound", proof.GetBound())
+	t.appendBytes("upper", upper)
+	t.appendG1("Sigma1", Sigma1)
+	t.appendG1("Com", Com)
+	t.appendG1("h", rangeBase())
+	for j := range commitments {
+		t.appendG1("bit", commitments[j])
+		t.appendG1("t0", t0[j])
+		t.appendG1("t1", t1[j])
+	}
+	t.appendG1("tCom", tCom)
+	t.appendG1("tD", tD)
+	t.appendBytes("msg", msg)
+	return t.challengeWithNonce(nym.GetNonce())
+}
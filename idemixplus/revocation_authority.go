@@ -24,12 +24,9 @@ type RevocationAlgorithm int32
 
 const (
 	ALG_NO_REVOCATION RevocationAlgorithm = iota
+	ALG_PLAIN_SIGNATURE
 )
 
-var ProofBytes = map[RevocationAlgorithm]int{
-	ALG_NO_REVOCATION: 0,
-}
-
 // GenerateLongTermRevocationKey generates a long term signing key that will be used for revocation
 func GenerateLongTermRevocationKey() (*ecdsa.PrivateKey, error) {
 	return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
@@ -39,25 +36,32 @@ func GenerateLongTermRevocationKey() (*ecdsa.PrivateKey, error) {
 // Users can use the CRI to prove that they are not revoked.
 // Note that when not using revocation (i.e., alg = ALG_NO_REVOCATION), the entered unrevokedHandles are not used,
 // and the resulting CRI can be used by any signer.
+// With ALG_PLAIN_SIGNATURE a fresh epoch key signs every unrevoked handle with a weak Boneh-Boyen signature,
+// only users holding a signature on their revocation handle can prove non-revocation in this epoch.
 func CreateCRI(key *ecdsa.PrivateKey, unrevokedHandles []*FP256BN.BIG, epoch int, alg RevocationAlgorithm, rng *amcl.RAND) (*CredentialRevocationInformation, error) {
 	if key == nil || rng == nil {
 		return nil, errors.Errorf("CreateCRI received nil input")
 	}
+	if alg != ALG_NO_REVOCATION && alg != ALG_PLAIN_SIGNATURE {
+		return nil, errors.Errorf("the specified revocation algorithm is not supported.")
+	}
 	cri := &CredentialRevocationInformation{}
 	cri.RevocationAlg = int32(alg)
 	cri.Epoch = int64(epoch)
 
+	var epochSk *FP256BN.BIG
 	if alg == ALG_NO_REVOCATION {
 		// put a dummy PK in the proto
-		cri.EpochPk = Ecp2ToProto(GenG2)
+		cri.EpochPk = Ecp2ToProto(genG2())
 	} else {
 		// create epoch key
-		_, epochPk := WBBKeyGen(rng)
+		var epochPk *FP256BN.ECP2
+		epochSk, epochPk = WBBKeyGen(rng)
 		cri.EpochPk = Ecp2ToProto(epochPk)
 	}
 
 	// sign epoch + epoch key with long term key
-	bytesToSign, err := proto.Marshal(cri)
+	bytesToSign, err := marshalUncompressed(cri)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to marshal CRI")
 	}
@@ -71,9 +75,21 @@ func CreateCRI(key *ecdsa.PrivateKey, unrevokedHandles []*FP256BN.BIG, epoch int
 
 	if alg == ALG_NO_REVOCATION {
 		return cri, nil
-	} else {
-		return nil, errors.Errorf("the specified revocation algorithm is not supported.")
 	}
+
+	// sign every unrevoked handle with the epoch key
+	revocationData := &PlainSigRevocationData{}
+	for _, rh := range unrevokedHandles {
+		revocationData.Signatures = append(revocationData.Signatures, &MessageSignature{
+			RevocationHandle: BigToBytes(rh),
+			RhSignature:      EcpToProto(WBBSign(epochSk, rh)),
+		})
+	}
+	cri.RevocationData, err = proto.Marshal(revocationData)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal revocation data")
+	}
+	return cri, nil
 }
 
 // VerifyEpochPK verifies that the revocation PK for a certain epoch is valid,
@@ -89,7 +105,7 @@ func VerifyEpochPK(pk *ecdsa.PublicKey, epochPK *ECP2, epochPkSig []byte, epoch
 	cri.RevocationAlg = int32(alg)
 	cri.EpochPk = epochPK
 	cri.Epoch = int64(epoch)
-	bytesToSign, err := proto.Marshal(cri)
+	bytesToSign, err := marshalUncompressed(cri)
 	if err != nil {
 		return err
 	}
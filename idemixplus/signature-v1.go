@@ -0,0 +1,151 @@
+package idemixplus
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// In formats 1 and 4 of a NymSignature every hidden attribute value is committed as Com_i = Sigma1^{attr_i} \cdot g_1^{rho_i}
+// with a proof of knowledge of its opening under a challenge of its own, the randomness of the commitments
+// is folded into Sigma2, so that
+// e(Sigma2, g_2) = e(Sigma1, BarX \prod_{disclosed} BarAttr_i^{attr_i}) e(Sigma3, BarY) \prod_{hidden} e(Com_i, BarAttr_i)
+// The validity window is committed and proven the same way. Signatures are made in format 3 by default,
+// format 4 is kept for BatchVerify, which merges the pairing equations of many signatures in format 4 into one.
+
+// newHiddenProofsV1 commits to the hidden attributes and the validity window of the credential in format 4
+// and proves the predicates over them. It returns the randomness rho_i of the commitment to every hidden
+// attribute by the index of the attribute.
+func newHiddenProofsV1(nymSign *NymSignature, cred *Credential, ipk *IssuerPublicKey, Sigma1 *FP256BN.ECP, Sigma2 *FP256BN.ECP, validAt int64, predicates []*RangePredicate, sets []*SetPredicate, rhIndex int, revocationAlg RevocationAlgorithm, prover nonRevokedProver, cri *CredentialRevocationInformation, msg []byte, rng *amcl.RAND) (map[int]*FP256BN.BIG, error) {
+	var err error
+	rhos := make(map[int]*FP256BN.BIG)
+	for _, index := range hiddenIndices(nymSign.Disclosure) {
+		attr := FP256BN.FromBytes(cred.Attrs[index])
+		rho := RandModOrder(rng)
+		Com := Sigma1.Mul2(attr, genG1(), rho)
+		Sigma2.Add(EcpFromProto(ipk.HAttrs[index]).Mul(rho))
+
+		// Prove knowledge of the opening of Com
+		rAttr := RandModOrder(rng)
+		rRand := RandModOrder(rng)
+		t := Sigma1.Mul2(rAttr, genG1(), rRand)
+
+		// the revocation handle shares its randomness with the non-revocation proof
+		var nonRevokedPoints []*FP256BN.ECP
+		isRh := revocationAlg != ALG_NO_REVOCATION && index == rhIndex
+		if isRh {
+			nonRevokedPoints, err = prover.getFSContribution(attr, rAttr, cri, rng)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to compute non-revoked proof")
+			}
+		}
+
+		c := hiddenAttributeChallengeV1(nymSign, t, Sigma1, Com, nonRevokedPoints, msg)
+		if isRh {
+			nymSign.NonRevocationProof, err = prover.getNonRevokedProof(c)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to compute non-revoked proof")
+			}
+		}
+
+		// Prove the range and set predicates over the attribute
+		if err := newPredicateProofs(nymSign, index, predicates, sets, attr, rho, Sigma1, Com, msg, rng); err != nil {
+			return nil, err
+		}
+		rhos[index] = rho
+
+		hide := new(HiddenAttribute)
+		hide.Com = EcpToProto(Com)
+		hide.ProofC = BigToBytes(c)
+		hide.ProofSAttr = BigToBytes(Modadd(rAttr, FP256BN.Modmul(c, attr, GroupOrder), GroupOrder))
+		hide.ProofSRand = BigToBytes(Modadd(rRand, FP256BN.Modmul(c, rho, GroupOrder), GroupOrder))
+		nymSign.Hides = append(nymSign.Hides, hide)
+	}
+
+	// Commit to the validity window and prove that it contains validAt
+	if err := newValidityProofs(nymSign, cred, ipk, Sigma1, Sigma2, validAt, msg, rng); err != nil {
+		return nil, err
+	}
+	return rhos, nil
+}
+
+// verifyHiddenProofsV1 checks the proofs of the hidden attributes and of the validity window of a NymSignature
+// in format 1 or 4 on up to workers goroutines and enters their commitments into the pairing check
+func verifyHiddenProofsV1(nym *NymSignature, check *nymPairingCheck, workers int, Sigma1 *FP256BN.ECP, validAt int64, predicates []*RangePredicate, sets []*SetPredicate, rhIndex int, revocationAlg RevocationAlgorithm, verifier nonRevocationVerifier, epochPK *FP256BN.ECP2, msg []byte) error {
+	Hides := nym.GetHides()
+	HiddenIndices := hiddenIndices(nym.GetDisclosure())
+	NumAttrs := len(check.key.BarAttrs)
+	if len(Hides) != len(HiddenIndices) {
+		return verificationErrorf(ErrKindInvalid, "NymSignature is not fit with the NymSignature format")
+	}
+	if err := checkValidityProofCount(nym); err != nil {
+		return err
+	}
+
+	// Check the proofs of the hidden attributes, followed by those of the validity window, every job
+	// writes only its own entry of the pairing check
+	return parallelFor(len(Hides)+numValidityKeys, workers, func(job int) error {
+		if job >= len(Hides) {
+			Com, err := verifyValidityProof(nym, job-len(Hides), NumAttrs, Sigma1, validAt, msg)
+			if err != nil {
+				return err
+			}
+			check.validity[job-len(Hides)] = Com
+			return nil
+		}
+
+		j, hide := job, Hides[job]
+		Com, err := EcpFromProtoChecked(hide.GetCom())
+		if err != nil {
+			return wrapVerificationError(ErrKindInvalid, err, fmt.Sprintf("commitment to hidden attribute %d is malformed", HiddenIndices[j]))
+		}
+		scalars := make([]*FP256BN.BIG, 3)
+		for k, b := range [][]byte{hide.GetProofC(), hide.GetProofSAttr(), hide.GetProofSRand()} {
+			scalars[k], err = BigFromBytesChecked(b)
+			if err != nil {
+				return wrapVerificationError(ErrKindInvalid, err, fmt.Sprintf("proof of hidden attribute %d is malformed", HiddenIndices[j]))
+			}
+		}
+		ProofC, ProofSAttr, ProofSRand := scalars[0], scalars[1], scalars[2]
+
+		t := Sigma1.Mul2(ProofSAttr, genG1(), ProofSRand)
+		t.Add(Com.Mul(FP256BN.Modneg(ProofC, GroupOrder)))
+
+		var nonRevokedPoints []*FP256BN.ECP
+		if revocationAlg != ALG_NO_REVOCATION && HiddenIndices[j] == rhIndex {
+			nonRevokedPoints, err = verifier.recomputeFSContribution(nym.GetNonRevocationProof(), ProofC, epochPK, ProofSAttr)
+			if err != nil {
+				return wrapVerificationError(ErrKindRevoked, err, "non-revocation proof is invalid")
+			}
+		}
+
+		if *ProofC != *hiddenAttributeChallengeV1(nym, t, Sigma1, Com, nonRevokedPoints, msg) {
+			return verificationErrorf(ErrKindInvalid, "NymSignature is not fit with the Issuer PublicKey")
+		}
+
+		if err := verifyPredicateProofs(nym, HiddenIndices[j], predicates, sets, Sigma1, Com, msg); err != nil {
+			return err
+		}
+
+		check.hidden[HiddenIndices[j]] = Com
+		return nil
+	})
+}
+
+// hiddenAttributeLabel is the label of the transcript of the proof of a hidden attribute in format 4
+const hiddenAttributeLabel = "nymSignatureHiddenAttribute"
+
+// hiddenAttributeChallengeV1 derives the challenge of the proof of a hidden attribute of the NymSignature nym in format 1 or 4
+func hiddenAttributeChallengeV1(nym *NymSignature, tValue, Sigma1, Com *FP256BN.ECP, nonRevokedPoints []*FP256BN.ECP, msg []byte) *FP256BN.BIG {
+	t := nym.newTranscript(hiddenAttributeLabel)
+	t.appendG1("t", tValue)
+	t.appendG1("Sigma1", Sigma1)
+	t.appendG1("Com", Com)
+	for _, P := range nonRevokedPoints {
+		t.appendG1("nonRevocation", P)
+	}
+	t.appendBytes("msg", msg)
+	return t.challengeWithNonce(nym.GetNonce())
+}
@@ -1,6 +1,7 @@
 package idemixplus
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"fmt"
 	"github.com/hyperledger/fabric-amcl/amcl"
@@ -18,6 +19,17 @@ func hiddenIndices(Disclosure []byte) []int {
 	return HiddenIndices
 }
 
+// disclosureBits normalizes a disclosure to one byte per attribute that is either 0 or 1
+func disclosureBits(Disclosure []byte) []byte {
+	bits := make([]byte, len(Disclosure))
+	for index, disclose := range Disclosure {
+		if disclose != 0 {
+			bits[index] = 1
+		}
+	}
+	return bits
+}
+
 func isIn(arr []int, value int) bool {
 	for _, v := range arr {
 		if v == value {
@@ -27,12 +39,177 @@ func isIn(arr []int, value int) bool {
 	return false
 }
 
+// nymChallenge turns the hash of the proof data and the signature nonce into a challenge
+func nymChallenge(proofData []byte, nonce []byte) *FP256BN.BIG {
+	Ca := HashModOrder(proofData)
+
+	C := make([]byte, len(BigToBytes(Ca))+len(nonce))
+	i := 0
+	i = appendBytes(C, i, BigToBytes(Ca))
+	i = appendBytes(C, i, nonce)
+	return HashModOrder(C)
+}
+
+// nymSignatureLabel is the label of the transcript of a NymSignature
+const nymSignatureLabel = "nymSignature"
+
+// nymLegacyPadding is the number of zero bytes the legacy proof data of a NymSignature ends with,
+// its buffer had room for 18*FieldBytes+3+5*(2*FieldBytes+1) bytes of points but holds 11 points
+var nymLegacyPadding = 18*FieldBytes + 3 + 5*(2*FieldBytes+1) - 11*(2*FieldBytes+1)
+
+// scopeLabel separates the hash of a scope from the other hashes to G1
+const scopeLabel = "nymScope"
+
+// scopeBase returns the base H(scope) of the pseudonyms in a scope
+func scopeBase(scope []byte) *FP256BN.ECP {
+	data := make([]byte, len([]byte(scopeLabel))+len(scope))
+	i := appendBytesString(data, 0, scopeLabel)
+	appendBytes(data, i, scope)
+	return hashToG1(data)
+}
+
+// LinkedInScope reports whether two NymSignatures are made in the same scope by the same user.
+// Both signatures must have been verified for the scope before.
+func LinkedInScope(nym1, nym2 *NymSignature) bool {
+	if len(nym1.GetScope()) == 0 || !bytes.Equal(nym1.GetScope(), nym2.GetScope()) ||
+		nym1.GetScopeNym() == nil || nym2.GetScopeNym() == nil {
+		return false
+	}
+	return EcpFromProto(nym1.GetScopeNym()).Equals(EcpFromProto(nym2.GetScopeNym()))
+}
+
+// SignOpts is what a NymSignature discloses and proves about the credential it is made with
+type SignOpts struct {
+	// Scope is the scope of the pseudonym of the signature, an empty scope makes a signature without scope
+	Scope []byte
+	// ValidAt is the time the credential is shown to be valid at
+	ValidAt int64
+	// Disclosure has an entry for every attribute of the credential, non-zero for the disclosed ones
+	Disclosure []byte
+	// Predicates and Sets are the range and set predicates proven over hidden attributes
+	Predicates []*RangePredicate
+	Sets       []*SetPredicate
+	// RhIndex is the index of the attribute that holds the revocation handle, it is used when Cri is set
+	RhIndex int
+	// Cri is the credential revocation information of the epoch, nil for a signature without revocation
+	Cri *CredentialRevocationInformation
+	// Version is the format of the signature, NymSignatureV3 if it is 0. A signature in format NymSignatureV4
+	// is larger and proves every hidden attribute on its own, but BatchVerify merges its pairing equation
+	// with those of the other signatures in format 4 of a batch.
+	Version uint32
+}
+
+// VerifyOpts is what the verifier of a NymSignature expects of it
+type VerifyOpts struct {
+	// Scope is the scope the signature must be made for, an empty scope accepts signatures without a scope
+	Scope []byte
+	// ValidAt is the time the credential must be valid at
+	ValidAt int64
+	// Disclosure is the disclosure the signature must make
+	Disclosure []byte
+	// Predicates and Sets are the range and set predicates the signature must prove, in the same order
+	Predicates []*RangePredicate
+	Sets       []*SetPredicate
+	// AttributeValues holds the expected value of every disclosed attribute at its index,
+	// entries of hidden attributes are ignored
+	AttributeValues []*FP256BN.BIG
+	// RhIndex is the index of the attribute that holds the revocation handle
+	RhIndex int
+	// RevPk is the long term key of the revocation authority, nil for a verifier that does not check revocation
+	RevPk *ecdsa.PublicKey
+	// Epoch is the current revocation epoch, it is checked when RevPk is set
+	Epoch int
+	// AllowLegacy accepts signatures in formats 1 and 2, and issuer keys and opening proofs, made before
+	// transcripts, whose proofs do not cover the whole statement. It is meant for verifying signatures
+	// that the caller stored itself, never for signatures received from others.
+	AllowLegacy bool
+}
+
+// allowLegacy reports whether the verifier accepts proofs made before transcripts
+func (opts *VerifyOpts) allowLegacy() bool {
+	return opts != nil && opts.AllowLegacy
+}
+
 // NewNymSignature creates signature
-func NewNymSignature(sk *FP256BN.BIG, cred *Credential, ipk *IssuerPublicKey, msg []byte, disclosure []byte, cri *CredentialRevocationInformation, rng *amcl.RAND) (*NymSignature, error) {
+// The credential (A, B) is randomized into (Sigma1, Sigma2) and one proof of knowledge covers all
+// hidden attribute values and the validity window, see format 3 in aggregate-proof.go,
+// or every hidden value has a proof of its own if opts.Version is NymSignatureV4.
+// When opts.Cri uses a revocation algorithm, the hidden attribute at opts.RhIndex is the revocation handle and
+// the signature proves that it is not revoked in the epoch of opts.Cri.
+// When opts.Scope is not empty, the signature carries the pseudonym H(scope)^{sk} of the user in the scope,
+// so that signatures of the same user in one scope can be linked while they stay unlinkable across scopes.
+// For every range predicate over a hidden attribute the signature carries a RangeProof,
+// for every set predicate a SetMembershipProof.
+// The validity window of the credential is always hidden, the signature proves that it contains
+// the time opts.ValidAt supplied by the verifier.
+func NewNymSignature(sk *FP256BN.BIG, cred *Credential, ipk *IssuerPublicKey, msg []byte, opts *SignOpts, rng *amcl.RAND) (*NymSignature, error) {
 	fmt.Println("NewNymSignature", string(msg))
+	version := NymSignatureV3
+	if opts != nil && opts.Version != 0 {
+		version = opts.Version
+	}
+	if version != NymSignatureV3 && version != NymSignatureV4 {
+		return nil, errors.Errorf("cannot create NewNymSignature: signatures are made in format %d or %d, not %d", NymSignatureV3, NymSignatureV4, version)
+	}
+	nymSign, _, err := newNymSignature(sk, cred, ipk, msg, opts, nil, version, rng)
+	return nymSign, err
+}
+
+// newNymSignature creates a NymSignature in the given format and returns with it the randomness rho_i of the
+// commitment to every committed hidden attribute, by the index of the attribute. In formats 2 and 3 the attributes
+// of the predicates and those in commit are committed, in formats 1 and 4 every hidden attribute is.
+// NewNymSignature makes signatures in formats 3 and 4 only, the legacy formats 1 and 2 are made by the tests,
+// which measure the formats against each other.
+func newNymSignature(sk *FP256BN.BIG, cred *Credential, ipk *IssuerPublicKey, msg []byte, opts *SignOpts, commit []int, version uint32, rng *amcl.RAND) (*NymSignature, map[int]*FP256BN.BIG, error) {
 	// Validate inputs
-	if sk == nil || cred == nil || ipk == nil || disclosure == nil || rng == nil {
-		return nil, errors.Errorf("cannot create NewNymSignature: received nil input")
+	if sk == nil || cred == nil || ipk == nil || opts == nil || opts.Disclosure == nil || rng == nil {
+		return nil, nil, errors.Errorf("cannot create NewNymSignature: received nil input")
+	}
+	scope, validAt, disclosure, predicates, sets := opts.Scope, opts.ValidAt, opts.Disclosure, opts.Predicates, opts.Sets
+	rhIndex, cri := opts.RhIndex, opts.Cri
+	if ipk.GetTracingPk() == nil {
+		return nil, nil, errors.Errorf("issuer public key has no tracing key")
+	}
+	if len(cred.Attrs) != len(ipk.GetHAttrs()) {
+		return nil, nil, errors.Errorf("credential has %d attributes, issuer public key expects %d", len(cred.Attrs), len(ipk.GetHAttrs()))
+	}
+	if len(disclosure) != len(cred.Attrs) {
+		return nil, nil, errors.Errorf("disclosure has %d entries, credential has %d attributes", len(disclosure), len(cred.Attrs))
+	}
+	if len(ipk.GetHValidity()) != numValidityKeys {
+		return nil, nil, errors.Errorf("issuer public key has no validity key")
+	}
+	if cred.GetKeyId() != ipk.GetKeyId() {
+		return nil, nil, errors.Errorf("credential is issued under issuer key %s, not %s", cred.GetKeyId(), ipk.GetKeyId())
+	}
+	if !knownFormat(version) {
+		return nil, nil, errors.Errorf("unknown NymSignature format %d", version)
+	}
+	if err := checkValidAt(cred, validAt); err != nil {
+		return nil, nil, err
+	}
+
+	revocationAlg := ALG_NO_REVOCATION
+	if cri != nil {
+		revocationAlg = RevocationAlgorithm(cri.RevocationAlg)
+	}
+	prover, err := getNonRevocationProver(revocationAlg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revocationAlg != ALG_NO_REVOCATION && !isIn(hiddenIndices(disclosure), rhIndex) {
+		return nil, nil, errors.Errorf("attribute %d is used as revocation handle and must be hidden", rhIndex)
+	}
+	if err := checkRangePredicates(predicates, hiddenIndices(disclosure)); err != nil {
+		return nil, nil, err
+	}
+	if err := checkSetPredicates(sets, hiddenIndices(disclosure)); err != nil {
+		return nil, nil, err
+	}
+	for _, index := range commit {
+		if !isIn(hiddenIndices(disclosure), index) {
+			return nil, nil, errors.Errorf("attribute %d is committed and must be hidden", index)
+		}
 	}
 
 	// Sample the randomness needed for the proof
@@ -40,7 +217,7 @@ func NewNymSignature(sk *FP256BN.BIG, cred *Credential, ipk *IssuerPublicKey, ms
 	v := RandModOrder(rng)
 	nonce := RandModOrder(rng)
 
-	Xi := GenG1.Mul(u)
+	Xi := genG1().Mul(u)
 	Eta := Xi.Mul(sk)
 
 	nymSign := new(NymSignature)
@@ -48,116 +225,514 @@ func NewNymSignature(sk *FP256BN.BIG, cred *Credential, ipk *IssuerPublicKey, ms
 	nymSign.Eta = EcpToProto(Eta)
 	nymSign.Xi = EcpToProto(Xi)
 	nymSign.Nonce = BigToBytes(nonce)
+	nymSign.Disclosure = disclosureBits(disclosure)
+	nymSign.ValidAt = validAt
+	nymSign.KeyId = ipk.GetKeyId()
+	nymSign.Version = version
 
-	HiddenIndices := hiddenIndices(disclosure)
-	for index := range cred.Creds {
-		if isIn(HiddenIndices, index) {
-			hide := new(HiddenAttribute)
-			Sigma1 := EcpFromProto(cred.Creds[index].A).Mul(v)
-			Sigma2 := EcpFromProto(cred.Creds[index].B).Mul(v)
-			Sigma3 := Sigma1.Mul(sk)
-
-			hide.Sigma_1 = EcpToProto(Sigma1)
-			hide.Sigma_2 = EcpToProto(Sigma2)
-			hide.Sigma_3 = EcpToProto(Sigma3)
-
-			a := RandModOrder(rng)
-			t1 := Sigma1.Mul(a)
-			t2 := Xi.Mul(a)
-
-			proofData := make([]byte, 18*FieldBytes+3+len(msg))
-			i := 0
-			i = appendBytesG1(proofData, i, t1)
-			i = appendBytesG1(proofData, i, t2)
-			i = appendBytesG1(proofData, i, Sigma1)
-			i = appendBytesG1(proofData, i, Xi)
-			i = appendBytesG1(proofData, i, Sigma3)
-			i = appendBytesG1(proofData, i, Eta)
-
-			// for signature
-			i = appendBytes(proofData, i, msg)
-
-			Ca := HashModOrder(proofData)
-
-			C := make([]byte, len(BigToBytes(Ca))+len(BigToBytes(nonce)))
-			i = 0
-			i = appendBytes(C, i, BigToBytes(Ca))
-			i = appendBytes(C, i, BigToBytes(nonce))
-			c := HashModOrder(C)
-
-			Sa := Modadd(a, FP256BN.Modmul(c, sk, GroupOrder), GroupOrder)
-
-			hide.ProofC = BigToBytes(c)
-			hide.ProofS = BigToBytes(Sa)
-
-			nymSign.Hides = append(nymSign.Hides, hide)
-		} else {
+	if cri != nil {
+		nymSign.RevocationEpochPk = cri.EpochPk
+		nymSign.RevocationPkSig = cri.EpochPkSig
+		nymSign.Epoch = cri.Epoch
+	}
+
+	// Randomize the credential
+	Sigma1 := EcpFromProto(cred.A).Mul(v)
+	Sigma2 := EcpFromProto(cred.B).Mul(v)
+	Sigma3 := Sigma1.Mul(sk)
+
+	// Encrypt the user public key g1^sk under the tracing key
+	TracingPk := EcpFromProto(ipk.GetTracingPk())
+	k := RandModOrder(rng)
+	TraceC1 := genG1().Mul(k)
+	TraceC2 := genG1().Mul2(sk, TracingPk, k)
+
+	// Prove that Sigma3, Eta, the tracing tag and the scope pseudonym share the same sk
+	a := RandModOrder(rng)
+	b := RandModOrder(rng)
+	t1 := Sigma1.Mul(a)
+	t2 := Xi.Mul(a)
+	t3 := genG1().Mul(b)
+	t4 := genG1().Mul2(a, TracingPk, b)
+
+	var scopeContrib *scopeContribution
+	if len(scope) > 0 {
+		H := scopeBase(scope)
+		ScopeNym := H.Mul(sk)
+		nymSign.Scope = scope
+		nymSign.ScopeNym = EcpToProto(ScopeNym)
+		scopeContrib = &scopeContribution{t: H.Mul(a), H: H, ScopeNym: ScopeNym, scope: scope}
+	}
+
+	// Reveal the disclosed attribute values, commit to the hidden ones and prove the predicates over them
+	for index := range cred.Attrs {
+		if disclosure[index] != 0 {
 			nymSign.Attrs = append(nymSign.Attrs, cred.Attrs[index])
 		}
+	}
+	if len(predicates) > 0 {
+		nymSign.RangeProofs = make([]*RangeProof, len(predicates))
+	}
+	if len(sets) > 0 {
+		nymSign.SetProofs = make([]*SetMembershipProof, len(sets))
+	}
+	var rhos map[int]*FP256BN.BIG
 
+	// In format 3 the proof of the hidden values is part of the proof of the signature. In format 4 the hidden
+	// values are proven on their own before, as their commitments change Sigma2, which the signature covers.
+	var aggregate *aggregateProver
+	var aggregateContrib *aggregateContribution
+	if !nymSign.aggregated() {
+		rhos, err = newHiddenProofsV1(nymSign, cred, ipk, Sigma1, Sigma2, validAt, predicates, sets, rhIndex, revocationAlg, prover, cri, msg, rng)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		for _, predicate := range predicates {
+			commit = append(commit, predicate.Attribute)
+		}
+		for _, predicate := range sets {
+			commit = append(commit, predicate.Attribute)
+		}
+		aggregate, err = newAggregateProver(nymSign, cred, ipk, Sigma1, Sigma2, commit, rhIndex, revocationAlg, prover, cri, rng)
+		if err != nil {
+			return nil, nil, err
+		}
+		aggregateContrib = aggregate.contrib
 	}
 
-	if cri != nil {
-		nymSign.RevocationEpochPk = cri.EpochPk
-		nymSign.RevocationPkSig = cri.EpochPkSig
-		nymSign.Epoch = cri.Epoch
+	t := nymSign.newTranscript(nymSignatureLabel)
+	t.appendG1("t1", t1)
+	t.appendG1("t2", t2)
+	t.appendG1("Sigma1", Sigma1)
+	if !t.legacy {
+		t.appendG1("Sigma2", Sigma2)
 	}
+	t.appendG1("Xi", Xi)
+	t.appendG1("Sigma3", Sigma3)
+	t.appendG1("Eta", Eta)
+	t.appendG1("t3", t3)
+	t.appendG1("t4", t4)
+	t.appendG1("TracingPk", TracingPk)
+	t.appendG1("TraceC1", TraceC1)
+	t.appendG1("TraceC2", TraceC2)
+	t.appendBytes("Disclosure", nymSign.Disclosure)
+
+	// bind the epoch and its signed key to the proof
+	t.appendInt64("Epoch", nymSign.Epoch)
+	t.appendBytes("RevocationPkSig", nymSign.RevocationPkSig)
+	scopeContrib.appendTo(t)
 
-	return nymSign, nil
+	// bind the time the credential is shown to be valid at and the issuer key
+	t.appendInt64("ValidAt", nymSign.ValidAt)
+	t.appendString("KeyId", nymSign.KeyId)
+	if !t.legacy {
+		t.appendBytes("ipk", ipk.GetHash())
+	}
+	if aggregateContrib != nil {
+		aggregateContrib.appendTo(t)
+	}
+
+	// for signature
+	t.appendBytes("msg", msg)
+	t.padLegacy(len(t.data) + nymLegacyPadding)
+
+	c := t.challengeWithNonce(nymSign.Nonce)
+	Sa := Modadd(a, FP256BN.Modmul(c, sk, GroupOrder), GroupOrder)
+	Sb := Modadd(b, FP256BN.Modmul(c, k, GroupOrder), GroupOrder)
+
+	nymSign.ProofC = BigToBytes(c)
+	nymSign.ProofS = BigToBytes(Sa)
+	nymSign.TraceC1 = EcpToProto(TraceC1)
+	nymSign.TraceC2 = EcpToProto(TraceC2)
+	nymSign.ProofSTrace = BigToBytes(Sb)
+
+	if nymSign.aggregated() {
+		aggregate.respond(nymSign, c)
+		if revocationAlg != ALG_NO_REVOCATION {
+			nymSign.NonRevocationProof, err = prover.getNonRevokedProof(c)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "failed to compute non-revoked proof")
+			}
+		}
+		rhos, err = aggregate.newCommittedProofs(nymSign, Sigma1, validAt, predicates, sets, msg, rng)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	nymSign.Sigma_1 = EcpToProto(Sigma1)
+	nymSign.Sigma_2 = EcpToProto(Sigma2)
+	nymSign.Sigma_3 = EcpToProto(Sigma3)
+
+	if revocationAlg == ALG_NO_REVOCATION {
+		nymSign.NonRevocationProof, err = prover.getNonRevokedProof(nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return nymSign, rhos, nil
 }
 
 // Ver verifies an idemix NymSignature
 // modify at 2020-03-12 16:09:53
 // delete the parameter: sk
-func (nym *NymSignature) Ver(ipk *IssuerPublicKey, msg []byte, revPk *ecdsa.PublicKey, epoch int) error {
+// The signature must disclose exactly opts.Disclosure, with the values in opts.AttributeValues.
+// If the signature uses a revocation algorithm, the non-revocation proof on the hidden attribute
+// at opts.RhIndex is checked against the epoch key signed with opts.RevPk for opts.Epoch.
+// The signature must prove that the credential is valid at the time opts.ValidAt, a credential that is
+// expired or not yet valid at opts.ValidAt is reported as ErrKindExpired.
+// The issuer public key is checked on every call, verifiers of many signatures under the same key
+// use a PreparedVerifier or an IssuerKeyring, which check it once.
+func (nym *NymSignature) Ver(ipk *IssuerPublicKey, msg []byte, opts *VerifyOpts) error {
 	fmt.Println("NewNymSignature Ver", string(msg))
-	Hides := nym.GetHides()
+	key, err := prepareKey(ipk, opts.allowLegacy())
+	if err != nil {
+		return wrapVerificationError(ErrKindInvalid, err, "cannot verify NymSignature")
+	}
+	check, err := nym.verifyProofs(key, 1, msg, opts)
+	if err != nil || check == nil {
+		return err
+	}
+	return check.verify(1)
+}
+
+// verifyProofs checks everything of a NymSignature but the pairing equation of formats 1 and 4, which it returns.
+// A signature in format 2 or 3 is checked completely, its pairing equation is part of its proof and the returned check is nil.
+// The proofs of the hidden attributes and of the validity window are checked on up to workers goroutines.
+func (nym *NymSignature) verifyProofs(key *preparedKey, workers int, msg []byte, opts *VerifyOpts) (*nymPairingCheck, error) {
+	if opts == nil {
+		return nil, verificationErrorf(ErrKindInvalid, "cannot verify NymSignature: received nil options")
+	}
+	scope, validAt, disclosure, predicates, sets := opts.Scope, opts.ValidAt, opts.Disclosure, opts.Predicates, opts.Sets
+	attributeValues, rhIndex, revPk, epoch := opts.AttributeValues, opts.RhIndex, opts.RevPk, opts.Epoch
+	ipk := key.ipk
+	NumAttrs := len(key.BarAttrs)
 
-	Eta := EcpFromProto(nym.GetEta())
-	Xi := EcpFromProto(nym.GetXi())
+	// Check that the signature discloses exactly what the verifier expects
+	if len(disclosure) != NumAttrs || len(attributeValues) != NumAttrs {
+		return nil, verificationErrorf(ErrKindInvalid, "expected disclosure and attribute values do not match the issuer public key")
+	}
+	if !bytes.Equal(nym.GetDisclosure(), disclosureBits(disclosure)) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature does not disclose the expected attributes")
+	}
+	if !bytes.Equal(nym.GetScope(), scope) || (len(scope) > 0) != (nym.GetScopeNym() != nil) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature is not made for the expected scope")
+	}
+	if validAt < 0 || nym.GetValidAt() != validAt {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature proves validity at %d, expected %d", nym.GetValidAt(), validAt)
+	}
+	if nym.GetKeyId() != ipk.GetKeyId() {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature is made under issuer key %s, not %s", nym.GetKeyId(), ipk.GetKeyId())
+	}
+	version := nym.formatVersion()
+	if !knownFormat(version) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature has unknown format %d", version)
+	}
+	if legacyFormat(version) && !opts.AllowLegacy {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature is in legacy format %d, which is only accepted with AllowLegacy", version)
+	}
+	if !nym.aggregated() && (len(nym.GetProofSHidden()) != 0 || len(nym.GetProofSBlind()) != 0 || len(nym.GetCommitments()) != 0) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature is not fit with the NymSignature format")
+	}
+	HiddenIndices := hiddenIndices(nym.GetDisclosure())
+	if len(nym.GetAttrs()) != NumAttrs-len(HiddenIndices) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature is not fit with the NymSignature format")
+	}
+	if err := checkRangePredicates(predicates, HiddenIndices); err != nil {
+		return nil, wrapVerificationError(ErrKindInvalid, err, "expected range predicates are invalid")
+	}
+	if len(nym.GetRangeProofs()) != len(predicates) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature has %d range proofs, expected %d", len(nym.GetRangeProofs()), len(predicates))
+	}
+	for k, predicate := range predicates {
+		if !predicate.matches(nym.RangeProofs[k]) {
+			return nil, verificationErrorf(ErrKindInvalid, "range proof %d does not prove the expected predicate", k)
+		}
+	}
+	if err := checkSetPredicates(sets, HiddenIndices); err != nil {
+		return nil, wrapVerificationError(ErrKindInvalid, err, "expected set predicates are invalid")
+	}
+	if len(nym.GetSetProofs()) != len(sets) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature has %d set membership proofs, expected %d", len(nym.GetSetProofs()), len(sets))
+	}
+	for k, predicate := range sets {
+		if !predicate.matches(nym.SetProofs[k]) {
+			return nil, verificationErrorf(ErrKindInvalid, "set membership proof %d does not prove the expected predicate", k)
+		}
+	}
+
+	// Check that the signature is made in the expected epoch under an epoch key signed by the revocation authority
+	revocationAlg := RevocationAlgorithm(nym.GetNonRevocationProof().GetRevocationAlg())
+	verifier, err := getNonRevocationVerifier(revocationAlg)
+	if err != nil {
+		return nil, wrapVerificationError(ErrKindInvalid, err, "NymSignature uses an unsupported revocation algorithm")
+	}
+	if revPk == nil {
+		if revocationAlg != ALG_NO_REVOCATION {
+			return nil, verificationErrorf(ErrKindInvalid, "a revocation public key is required to verify the non-revocation proof")
+		}
+	} else {
+		if nym.GetRevocationEpochPk() == nil || nym.GetRevocationPkSig() == nil {
+			return nil, verificationErrorf(ErrKindRevoked, "NymSignature carries no credential revocation information")
+		}
+		if nym.GetEpoch() < int64(epoch) {
+			return nil, verificationErrorf(ErrKindExpired, "NymSignature is made in stale epoch %d, current epoch is %d", nym.GetEpoch(), epoch)
+		}
+		if nym.GetEpoch() != int64(epoch) {
+			return nil, verificationErrorf(ErrKindInvalid, "NymSignature is made in epoch %d, current epoch is %d", nym.GetEpoch(), epoch)
+		}
+		err = VerifyEpochPK(revPk, nym.GetRevocationEpochPk(), nym.GetRevocationPkSig(), int(nym.GetEpoch()), revocationAlg)
+		if err != nil {
+			return nil, wrapVerificationError(ErrKindInvalid, err, "epoch key of NymSignature is invalid")
+		}
+	}
+	var epochPK *FP256BN.ECP2
+	if revocationAlg != ALG_NO_REVOCATION {
+		if !isIn(HiddenIndices, rhIndex) {
+			return nil, verificationErrorf(ErrKindInvalid, "attribute %d is used as revocation handle and must be hidden", rhIndex)
+		}
+		epochPK, err = Ecp2FromProtoChecked(nym.GetRevocationEpochPk())
+		if err != nil {
+			return nil, wrapVerificationError(ErrKindInvalid, err, "epoch key of NymSignature is malformed")
+		}
+	}
+
+	if key.TracingPk == nil {
+		return nil, verificationErrorf(ErrKindInvalid, "issuer public key has no tracing key")
+	}
+	TracingPk := ecpCopy(key.TracingPk)
 	Nonce := nym.Nonce
 
-	for _, hide := range Hides {
-		Sigma1 := EcpFromProto(hide.GetSigma_1())
-		Sigma2 := EcpFromProto(hide.GetSigma_2())
-		Sigma3 := EcpFromProto(hide.GetSigma_3())
-		ProofC := FP256BN.FromBytes(hide.GetProofC())
-		ProofS := FP256BN.FromBytes(hide.GetProofS())
+	// Decode the signature, rejecting group elements that are not on the curve and scalars that are not reduced
+	points := make([]*FP256BN.ECP, 7)
+	for k, p := range []*ECP{nym.GetEta(), nym.GetXi(), nym.GetSigma_1(), nym.GetSigma_2(), nym.GetSigma_3(), nym.GetTraceC1(), nym.GetTraceC2()} {
+		points[k], err = EcpFromProtoChecked(p)
+		if err != nil {
+			return nil, wrapVerificationError(ErrKindInvalid, err, "NymSignature is malformed")
+		}
+	}
+	Eta, Xi, Sigma1, Sigma2, Sigma3, TraceC1, TraceC2 := points[0], points[1], points[2], points[3], points[4], points[5], points[6]
+
+	scalars := make([]*FP256BN.BIG, 3)
+	for k, b := range [][]byte{nym.GetProofC(), nym.GetProofS(), nym.GetProofSTrace()} {
+		scalars[k], err = BigFromBytesChecked(b)
+		if err != nil {
+			return nil, wrapVerificationError(ErrKindInvalid, err, "NymSignature is malformed")
+		}
+	}
+	ProofC, ProofS, ProofSTrace := scalars[0], scalars[1], scalars[2]
+
+	t1 := Sigma1.Mul(ProofS)
+	t1.Add(Sigma3.Mul(FP256BN.Modneg(ProofC, GroupOrder)))
+
+	t2 := Xi.Mul(ProofS)
+	t2.Add(Eta.Mul(FP256BN.Modneg(ProofC, GroupOrder)))
+
+	t3 := genG1().Mul(ProofSTrace)
+	t3.Add(TraceC1.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t3 = g_1^{s_k} \cdot C_1^{-c}
+
+	t4 := genG1().Mul2(ProofS, TracingPk, ProofSTrace)
+	t4.Add(TraceC2.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t4 = g_1^{s_sk} \cdot Z^{s_k} \cdot C_2^{-c}
+
+	var scopeContrib *scopeContribution
+	if len(scope) > 0 {
+		ScopeNym, err := EcpFromProtoChecked(nym.GetScopeNym())
+		if err != nil {
+			return nil, wrapVerificationError(ErrKindInvalid, err, "scope pseudonym of NymSignature is malformed")
+		}
+		H := scopeBase(scope)
+		t5 := H.Mul(ProofS)
+		t5.Add(ScopeNym.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t5 = H(scope)^{s_sk} \cdot Nym^{-c}
+		scopeContrib = &scopeContribution{t: t5, H: H, ScopeNym: ScopeNym, scope: scope}
+	}
+
+	// Check the disclosed attribute values
+	disclosedValues := make([]*FP256BN.BIG, NumAttrs)
+	disclosed := 0
+	for index := 0; index < NumAttrs; index++ {
+		if isIn(HiddenIndices, index) {
+			continue
+		}
+		value := attributeValues[index]
+		if value == nil {
+			return nil, verificationErrorf(ErrKindInvalid, "no expected value for disclosed attribute %s", ipk.AttributeNames[index])
+		}
+		if !bytes.Equal(nym.Attrs[disclosed], BigToBytes(value)) {
+			return nil, verificationErrorf(ErrKindInvalid, "disclosed attribute %s does not have the expected value", ipk.AttributeNames[index])
+		}
+		disclosedValues[index] = value
+		disclosed++
+	}
+
+	// In formats 2 and 3 the proof of the hidden values is part of the proof of the signature
+	var aggregateContrib *aggregateContribution
+	var coms map[int]*FP256BN.ECP
+	if nym.aggregated() {
+		aggregateContrib, coms, err = verifyAggregateProof(nym, key, workers, Sigma1, Sigma2, Sigma3, ProofC, disclosedValues, rhIndex, revocationAlg, verifier, epochPK)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t := nym.newTranscript(nymSignatureLabel)
+	t.appendG1("t1", t1)
+	t.appendG1("t2", t2)
+	t.appendG1("Sigma1", Sigma1)
+	if !t.legacy {
+		t.appendG1("Sigma2", Sigma2)
+	}
+	t.appendG1("Xi", Xi)
+	t.appendG1("Sigma3", Sigma3)
+	t.appendG1("Eta", Eta)
+	t.appendG1("t3", t3)
+	t.appendG1("t4", t4)
+	t.appendG1("TracingPk", TracingPk)
+	t.appendG1("TraceC1", TraceC1)
+	t.appendG1("TraceC2", TraceC2)
+	t.appendBytes("Disclosure", nym.GetDisclosure())
+	t.appendInt64("Epoch", nym.GetEpoch())
+	t.appendBytes("RevocationPkSig", nym.GetRevocationPkSig())
+	scopeContrib.appendTo(t)
+	t.appendInt64("ValidAt", nym.GetValidAt())
+	t.appendString("KeyId", nym.GetKeyId())
+	if !t.legacy {
+		t.appendBytes("ipk", ipk.GetHash())
+	}
+	if aggregateContrib != nil {
+		aggregateContrib.appendTo(t)
+	}
+	t.appendBytes("msg", msg)
+	t.padLegacy(len(t.data) + nymLegacyPadding)
+
+	if *ProofC != *t.challengeWithNonce(Nonce) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature is not fit with the Issuer PublicKey")
+	}
 
-		t1 := Sigma1.Mul(ProofS)
-		t1.Add(Sigma3.Mul(FP256BN.Modneg(ProofC, GroupOrder)))
+	if nym.aggregated() {
+		return nil, verifyCommittedProofs(nym, coms, workers, Sigma1, validAt, predicates, sets, msg)
+	}
 
-		t2 := Xi.Mul(ProofS)
-		t2.Add(Eta.Mul(FP256BN.Modneg(ProofC, GroupOrder)))
+	check := &nymPairingCheck{
+		key:       key,
+		Sigma1:    Sigma1,
+		Sigma2:    Sigma2,
+		Sigma3:    Sigma3,
+		disclosed: disclosedValues,
+		hidden:    make([]*FP256BN.ECP, NumAttrs),
+		validity:  make([]*FP256BN.ECP, numValidityKeys),
+	}
 
-		proofData := make([]byte, 18*FieldBytes+3+len(msg))
-		i := 0
-		i = appendBytesG1(proofData, i, t1)
-		i = appendBytesG1(proofData, i, t2)
-		i = appendBytesG1(proofData, i, Sigma1)
-		i = appendBytesG1(proofData, i, Xi)
-		i = appendBytesG1(proofData, i, Sigma3)
-		i = appendBytesG1(proofData, i, Eta)
-		i = appendBytes(proofData, i, msg)
-		Ca := HashModOrder(proofData)
+	if err := verifyHiddenProofsV1(nym, check, workers, Sigma1, validAt, predicates, sets, rhIndex, revocationAlg, verifier, epochPK, msg); err != nil {
+		return nil, err
+	}
+	return check, nil
+}
 
-		C := make([]byte, len(BigToBytes(Ca))+len(Nonce))
-		i = 0
-		i = appendBytes(C, i, BigToBytes(Ca))
-		i = appendBytes(C, i, Nonce)
+// nymPairingCheck is the pairing equation of a NymSignature in format 1 or 4 that is left once its proofs are checked,
+// e(BarX \prod_{disclosed} BarAttr_i^{attr_i}, Sigma1) e(BarY, Sigma3) \prod_{hidden} e(BarAttr_i, Com_i) \prod_v e(BarValidity_v, Com_v) = e(g_2, Sigma2)
+type nymPairingCheck struct {
+	key                    *preparedKey
+	Sigma1, Sigma2, Sigma3 *FP256BN.ECP
+	disclosed              []*FP256BN.BIG // the value of every disclosed attribute by index, nil for hidden ones
+	hidden                 []*FP256BN.ECP // the commitment to every hidden attribute by index, nil for disclosed ones
+	validity               []*FP256BN.ECP // the commitments to the validity window
+}
 
-		if *ProofC != *HashModOrder(C) {
-			return errors.Errorf("NymSignature is not fit with the Issuer PublicKey")
+// verify checks the pairing equation, its Miller loops are computed on up to workers goroutines
+func (check *nymPairingCheck) verify(workers int) error {
+	// Fold the disclosed attribute values into BarX
+	BarX := FP256BN.NewECP2()
+	BarX.Copy(check.key.BarX)
+	for index, value := range check.disclosed {
+		if value != nil {
+			BarX.Add(ecp2Copy(check.key.BarAttrs[index]).Mul(value))
 		}
+	}
+	BarX.Affine()
 
-		left := FP256BN.Ate(Ecp2FromProto(ipk.GetBarX()), Sigma1)
-		left.Mul(FP256BN.Ate(Ecp2FromProto(ipk.GetBarY()), Sigma3))
-		left = FP256BN.Fexp(left)
-		right := FP256BN.Fexp(FP256BN.Ate(GenG2, Sigma2))
-		if !left.Equals(right) {
-			return errors.Errorf("NymSignature is not fit with the NymSignature format")
+	// e(BarX', Sigma1) e(BarY, Sigma3) \prod_{hidden} e(BarAttr_i, Com_i) \prod_v e(BarValidity_v, Com_v) e(g_2, -Sigma2) = 1
+	negSigma2 := FP256BN.NewECP()
+	negSigma2.Sub(check.Sigma2)
+	G2 := []*FP256BN.ECP2{BarX, check.key.BarY, genG2()}
+	G1 := []*FP256BN.ECP{check.Sigma1, check.Sigma3, negSigma2}
+	for index, Com := range check.hidden {
+		if Com != nil {
+			G2 = append(G2, check.key.BarAttrs[index])
+			G1 = append(G1, Com)
 		}
 	}
+	for v, Com := range check.validity {
+		G2 = append(G2, check.key.BarValidity[v])
+		G1 = append(G1, Com)
+	}
 
+	if !FP256BN.Fexp(multiPairing(G2, G1, workers)).Isunity() {
+		return verificationErrorf(ErrKindInvalid, "NymSignature is not fit with the NymSignature format")
+	}
 	return nil
 }
+
+// newPredicateProofs proves the range and set predicates over the hidden attribute at index,
+// which is committed in Com with randomness rho
+func newPredicateProofs(nymSign *NymSignature, index int, predicates []*RangePredicate, sets []*SetPredicate, attr *FP256BN.BIG, rho *FP256BN.BIG, Sigma1 *FP256BN.ECP, Com *FP256BN.ECP, msg []byte, rng *amcl.RAND) error {
+	var err error
+	for k, predicate := range predicates {
+		if predicate.Attribute != index {
+			continue
+		}
+		nymSign.RangeProofs[k], err = newRangeProof(nymSign, predicate, attr, rho, Sigma1, Com, msg, rng)
+		if err != nil {
+			return err
+		}
+	}
+	for k, predicate := range sets {
+		if predicate.Attribute != index {
+			continue
+		}
+		nymSign.SetProofs[k], err = newSetMembershipProof(nymSign, predicate, attr, rho, Sigma1, Com, msg, rng)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyPredicateProofs checks the range and set predicates over the hidden attribute at index, which is committed in Com
+func verifyPredicateProofs(nym *NymSignature, index int, predicates []*RangePredicate, sets []*SetPredicate, Sigma1 *FP256BN.ECP, Com *FP256BN.ECP, msg []byte) error {
+	for k, predicate := range predicates {
+		if predicate.Attribute != index {
+			continue
+		}
+		if err := verifyRangeProof(nym, nym.RangeProofs[k], RangeBits, Sigma1, Com, msg); err != nil {
+			return wrapVerificationError(ErrKindInvalid, err, "range predicate is not satisfied")
+		}
+	}
+	for k, predicate := range sets {
+		if predicate.Attribute != index {
+			continue
+		}
+		if err := verifySetMembershipProof(nym, nym.SetProofs[k], Sigma1, Com, msg); err != nil {
+			return wrapVerificationError(ErrKindInvalid, err, "set predicate is not satisfied")
+		}
+	}
+	return nil
+}
+
+// scopeContribution is the contribution of the scope pseudonym to the challenge of a NymSignature
+type scopeContribution struct {
+	t, H, ScopeNym *FP256BN.ECP
+	scope          []byte
+}
+
+// appendTo appends the contribution to the transcript of the signature, a signature without scope has none
+func (contrib *scopeContribution) appendTo(t *transcript) {
+	if contrib == nil {
+		return
+	}
+	t.appendG1("t5", contrib.t)
+	t.appendG1("H", contrib.H)
+	t.appendG1("ScopeNym", contrib.ScopeNym)
+	t.appendBytes("scope", contrib.scope)
+}
@@ -0,0 +1,2 @@
+
+C*A"uY#ԵeU<rw"'Nb3Op'(1(!Ϥf7&FG⡲,o O9KJ=/5h;	1^TVPo ^UO9	͞'U\bK
\ No newline at end of file
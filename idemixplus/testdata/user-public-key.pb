@@ -0,0 +1,5 @@
+
+Attr1
+Attr2
+Attr3
+RevocationHandle#!kz,{99iלsBm|IN4#!d;nGqnS-;*Ϻ_3L߉"C*A"uY#ԵeU<rw"'Nb3Op'(1(!Ϥf7&FG⡲,o*#!o+HyKrxq.[co^+2#!w0eIi/ۭ[A: ](tx0>e=d'4B ?_(LnU@4Ȅ^`6՘J @Bg=Ƣ!gcaen~M0W\MD%R#!'Iᔕk]SYz
\ No newline at end of file
@@ -0,0 +1,282 @@
+package idemixplus
+
+import (
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// Threshold arbitration splits the tracing secret z of the issuer among n arbitrators
+// with Shamir secret sharing, z_j = f(j) for a random polynomial f of degree k-1 with f(0) = z.
+// Every arbitrator opens the tracing tag (C_1, C_2) of a NymSignature partially with D_j = C_1^{z_j},
+// and k valid opening shares give C_1^z = \prod D_j^{\lambda_j} and with it the user public key C_2 \cdot C_1^{-z}.
+
+// SplitTracingKey splits the tracing secret of the issuer key into n shares, any threshold of which can trace
+// a signature. The tracing secret is removed from the issuer key, so that the issuer cannot trace alone.
+func SplitTracingKey(key *IssuerKey, threshold int, n int, rng *amcl.RAND) ([]*ArbitratorKey, *ArbitrationPublicKey, error) {
+	if key == nil || rng == nil {
+		return nil, nil, errors.Errorf("cannot split tracing key: received nil input")
+	}
+	if key.GetIsk().GetTracingSk() == nil {
+		return nil, nil, errors.Errorf("cannot split tracing key: issuer key has no tracing secret")
+	}
+	if threshold < 1 || threshold > n {
+		return nil, nil, errors.Errorf("cannot split tracing key: threshold %d is not in 1, ..., %d", threshold, n)
+	}
+
+	// f(x) = z + a_1 x + ... + a_{k-1} x^{k-1}
+	coefficients := make([]*FP256BN.BIG, threshold)
+	coefficients[0] = FP256BN.FromBytes(key.GetIsk().GetTracingSk())
+	for i := 1; i < threshold; i++ {
+		coefficients[i] = RandModOrder(rng)
+	}
+
+	keys := make([]*ArbitratorKey, n)
+	apk := &ArbitrationPublicKey{Threshold: int64(threshold)}
+	for j := 1; j <= n; j++ {
+		share := evalPolynomial(coefficients, int64(j))
+		keys[j-1] = &ArbitratorKey{Index: int64(j), Share: BigToBytes(share)}
+		apk.SharePks = append(apk.SharePks, EcpToProto(genG1().Mul(share)))
+	}
+
+	key.Isk.TracingSk = nil
+	return keys, apk, nil
+}
+
+// Check checks that the share public keys lie on a polynomial of degree threshold-1
+// through the tracing key of the issuer public key
+func (apk *ArbitrationPublicKey) Check(ipk *IssuerPublicKey) error {
+	n := len(apk.GetSharePks())
+	threshold := int(apk.GetThreshold())
+	if threshold < 1 || threshold > n {
+		return errors.Errorf("arbitration public key invalid: threshold %d is not in 1, ..., %d", threshold, n)
+	}
+	if ipk.GetTracingPk() == nil {
+		return errors.Errorf("issuer public key has no tracing key")
+	}
+
+	indices := make([]int64, threshold)
+	for i := range indices {
+		indices[i] = int64(i + 1)
+	}
+	if !interpolateG1(indices, apk.SharePks[:threshold], 0).Equals(EcpFromProto(ipk.GetTracingPk())) {
+		return errors.Errorf("arbitration public key invalid: shares do not match the tracing key")
+	}
+	for j := threshold + 1; j <= n; j++ {
+		if !interpolateG1(indices, apk.SharePks[:threshold], int64(j)).Equals(EcpFromProto(apk.SharePks[j-1])) {
+			return errors.Errorf("arbitration public key invalid: share %d is not consistent", j)
+		}
+	}
+	return nil
+}
+
+// NewOpeningShare opens the tracing tag of a NymSignature partially with the key of one arbitrator
+// and proves that the opening is correct
+func NewOpeningShare(ak *ArbitratorKey, anonymity *NymSignature, rng *amcl.RAND) (*OpeningShare, error) {
+	if ak == nil || ak.GetShare() == nil || anonymity == nil || rng == nil {
+		return nil, errors.Errorf("cannot create OpeningShare: received nil input")
+	}
+	if anonymity.GetTraceC1() == nil {
+		return nil, errors.Errorf("cannot create OpeningShare: no tracing tag")
+	}
+
+	share := FP256BN.FromBytes(ak.GetShare())
+	TraceC1, err := EcpFromProtoChecked(anonymity.GetTraceC1())
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create OpeningShare: tracing tag is malformed")
+	}
+	SharePk := genG1().Mul(share)
+	D := TraceC1.Mul(share)
+
+	// Prove that D and SharePk share the same z_j
+	r := RandModOrder(rng)
+	t1 := genG1().Mul(r)
+	t2 := TraceC1.Mul(r)
+
+	proofC := openingShareChallenge(ak.GetIndex(), t1, t2, SharePk, TraceC1, D)
+	proofS := Modadd(r, FP256BN.Modmul(proofC, share, GroupOrder), GroupOrder) // s = r + C \cdot z_j
+
+	return &OpeningShare{
+		Index:  ak.GetIndex(),
+		D:      EcpToProto(D),
+		ProofC: BigToBytes(proofC),
+		ProofS: BigToBytes(proofS),
+	}, nil
+}
+
+// VerifyOpeningShare checks that share opens the tracing tag of the NymSignature
+// with the key share of the arbitrator it names
+func (apk *ArbitrationPublicKey) VerifyOpeningShare(share *OpeningShare, anonymity *NymSignature) error {
+	if share == nil || share.GetD() == nil || anonymity == nil || anonymity.GetTraceC1() == nil {
+		return errors.Errorf("opening share invalid: received nil input")
+	}
+	if share.GetIndex() < 1 || share.GetIndex() > int64(len(apk.GetSharePks())) {
+		return errors.Errorf("opening share invalid: unknown arbitrator %d", share.GetIndex())
+	}
+
+	SharePk := EcpFromProto(apk.SharePks[share.GetIndex()-1])
+	TraceC1, err := EcpFromProtoChecked(anonymity.GetTraceC1())
+	if err != nil {
+		return errors.Wrap(err, "opening share invalid: tracing tag is malformed")
+	}
+	D, err := EcpFromProtoChecked(share.GetD())
+	if err != nil {
+		return errors.Wrapf(err, "opening share of arbitrator %d invalid", share.GetIndex())
+	}
+	ProofC, err := BigFromBytesChecked(share.GetProofC())
+	if err != nil {
+		return errors.Wrapf(err, "opening share of arbitrator %d invalid", share.GetIndex())
+	}
+	ProofS, err := BigFromBytesChecked(share.GetProofS())
+	if err != nil {
+		return errors.Wrapf(err, "opening share of arbitrator %d invalid", share.GetIndex())
+	}
+
+	// Recompute t-values using s-values
+	t1 := genG1().Mul(ProofS)
+	t1.Add(SharePk.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t1 = g_1^s \cdot (g_1^{z_j})^{-C}
+
+	t2 := TraceC1.Mul(ProofS)
+	t2.Add(D.Mul(FP256BN.Modneg(ProofC, GroupOrder))) // t2 = C_1^s \cdot D^{-C}
+
+	if *ProofC != *openingShareChallenge(share.GetIndex(), t1, t2, SharePk, TraceC1, D) {
+		return errors.Errorf("opening share of arbitrator %d invalid: zero knowledge proof does not verify", share.GetIndex())
+	}
+	return nil
+}
+
+// CombineOpeningShares traces the signer of a NymSignature from the opening shares of the arbitrators.
+// Invalid and repeated shares are ignored, it fails unless threshold valid shares are given.
+// Like Arbitration it only traces a signature that verifies on msg with opts under the issuer public key ipk.
+func CombineOpeningShares(apk *ArbitrationPublicKey, ipk *IssuerPublicKey, index *TraceIndex, anonymity *NymSignature, msg []byte, opts *VerifyOpts, shares []*OpeningShare) (*UserPublicKey, error) {
+	if apk == nil || ipk == nil || index == nil || anonymity == nil {
+		return nil, errors.Errorf("Cannot Arbitration AnonymousCredential: received nil input")
+	}
+	if err := anonymity.Ver(ipk, msg, opts); err != nil {
+		return nil, errors.WithMessage(err, "Cannot Arbitration AnonymousCredential")
+	}
+	upk, err := apk.openTracingTag(anonymity, shares)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Cannot Arbitration AnonymousCredential")
+	}
+	trace, err := index.lookup(upk)
+	if err != nil {
+		return nil, err
+	}
+	return trace.GetUpk(), nil
+}
+
+// VerifyThresholdOpening checks that the opening shares of threshold arbitrators open the tracing tag
+// of the NymSignature on msg to the user public key upk, as VerifyOpening does for an OpeningProof.
+// The opening of a signature that does not verify with opts is rejected.
+func VerifyThresholdOpening(apk *ArbitrationPublicKey, ipk *IssuerPublicKey, upk *UserPublicKey, anonymity *NymSignature, msg []byte, opts *VerifyOpts, opening *ThresholdOpening) error {
+	if apk == nil || ipk == nil || upk.GetUPK() == nil || anonymity == nil || opening == nil {
+		return errors.Errorf("threshold opening invalid: received nil input")
+	}
+	if err := anonymity.Ver(ipk, msg, opts); err != nil {
+		return errors.WithMessage(err, "threshold opening invalid")
+	}
+	UPK, err := EcpFromProtoChecked(upk.GetUPK())
+	if err != nil {
+		return errors.Wrap(err, "threshold opening invalid: user public key is malformed")
+	}
+	opened, err := apk.openTracingTag(anonymity, opening.GetShares())
+	if err != nil {
+		return errors.WithMessage(err, "threshold opening invalid")
+	}
+	if !opened.Equals(UPK) {
+		return errors.Errorf("threshold opening invalid: tracing tag does not open to the user public key")
+	}
+	return nil
+}
+
+// openTracingTag decrypts the tracing tag (C_1, C_2) of a NymSignature to C_2 \cdot (C_1^z)^{-1}
+// with threshold valid opening shares, invalid and repeated shares are ignored
+func (apk *ArbitrationPublicKey) openTracingTag(anonymity *NymSignature, shares []*OpeningShare) (*FP256BN.ECP, error) {
+	if anonymity.GetTraceC1() == nil || anonymity.GetTraceC2() == nil {
+		return nil, errors.Errorf("no tracing tag")
+	}
+	threshold := int(apk.GetThreshold())
+	var indices []int64
+	var openings []*ECP
+	for _, share := range shares {
+		if len(indices) == threshold {
+			break
+		}
+		if share == nil || isInInt64(indices, share.GetIndex()) {
+			continue
+		}
+		if apk.VerifyOpeningShare(share, anonymity) != nil {
+			continue
+		}
+		indices = append(indices, share.GetIndex())
+		openings = append(openings, share.GetD())
+	}
+	if threshold < 1 || len(indices) < threshold {
+		return nil, errors.Errorf("%d valid opening shares, %d needed", len(indices), threshold)
+	}
+
+	// C_2 \cdot (C_1^z)^{-1}
+	upk, err := EcpFromProtoChecked(anonymity.GetTraceC2())
+	if err != nil {
+		return nil, errors.Wrap(err, "tracing tag is malformed")
+	}
+	upk.Sub(interpolateG1(indices, openings, 0))
+	return upk, nil
+}
+
+// openingShareLabel is the label used in the ZKP of an opening share
+const openingShareLabel = "openingShare"
+
+// openingShareChallenge derives the challenge of an opening share proof
+func openingShareChallenge(index int64, t1, t2, sharePk, traceC1, d *FP256BN.ECP) *FP256BN.BIG {
+	t := newTranscript(openingShareLabel)
+	t.appendInt64("index", index)
+	t.appendG1("t1", t1)
+	t.appendG1("t2", t2)
+	t.appendG1("SharePk", sharePk)
+	t.appendG1("TraceC1", traceC1)
+	t.appendG1("D", d)
+	return t.challenge()
+}
+
+// lagrangeCoefficient returns \prod_{m \neq j} (x - m) / (j - m) over the given indices
+func lagrangeCoefficient(indices []int64, j int64, x int64) *FP256BN.BIG {
+	num := FP256BN.NewBIGint(1)
+	den := FP256BN.NewBIGint(1)
+	for _, m := range indices {
+		if m == j {
+			continue
+		}
+		num = FP256BN.Modmul(num, bigFromInt64(x-m), GroupOrder)
+		den = FP256BN.Modmul(den, bigFromInt64(j-m), GroupOrder)
+	}
+	den.Invmodp(GroupOrder)
+	return FP256BN.Modmul(num, den, GroupOrder)
+}
+
+// interpolateG1 returns g^{f(x)} from the points g^{f(j)} at the given indices
+func interpolateG1(indices []int64, points []*ECP, x int64) *FP256BN.ECP {
+	res := FP256BN.NewECP()
+	for i, j := range indices {
+		res.Add(EcpFromProto(points[i]).Mul(lagrangeCoefficient(indices, j, x)))
+	}
+	return res
+}
+
+// bigFromInt64 returns v mod GroupOrder
+func bigFromInt64(v int64) *FP256BN.BIG {
+	if v < 0 {
+		return FP256BN.Modneg(FP256BN.NewBIGint(int(-v)), GroupOrder)
+	}
+	return FP256BN.NewBIGint(int(v))
+}
+
+func isInInt64(list []int64, v int64) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,706 @@
+package idemixplus
+
+import (
+	"crypto/sha256"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// A threshold issuer consists of n issuers, any threshold of which issue credentials together,
+// while fewer of them learn nothing about the issuer secret.
+// The issuers run a distributed key generation (DKG) in which every issuer deals a Feldman verifiable
+// secret sharing of its contribution to the secrets x, y, y_1, ..., y_L of the issuer key, to the tracing
+// secret z and to the randomness of the proofs of knowledge in the issuer public key.
+// The secret of the issuer key is the sum of the contributions, and the share of issuer j is the sum of
+// the shares it was dealt.
+//
+// The sharings are committed to in G2, only the tracing secret is committed to in G1: g1^x and g1^y
+// next to the public g1^{y_i} would let anyone forge a credential. The issuer public key has the form
+// NewIssuerKey gives it, BarG1 is hashed from the key so that no one knows its discrete log, and the parts
+// in G1 are raised to the shared secrets in a second round: every issuer publishes a PartialIssuerPublicKey,
+// and every issuer combines threshold of them before it proves its share of the key with ProveKey.
+//
+// Credentials are issued on a base A = H(ipk, nonce, upk, attrs) no one knows the discrete log of.
+// The user sends A^{usk} with a proof that it matches the user public key, every issuer j signs
+// B_j = A^{x_j + \sum_i y_{i,j} \cdot attr_i} \cdot (A^{usk})^{y_j}, and the user interpolates threshold
+// partial signatures to a credential (A, B) under the combined issuer public key.
+
+// thresholdCredRequestLabel is the label used in the ZKP of a credential request to a threshold issuer
+const thresholdCredRequestLabel = "thresholdCredRequest"
+
+// thresholdBarG1Label is the label of the transcript BarG1 of a threshold issuer public key is hashed from
+const thresholdBarG1Label = "thresholdBarG1"
+
+// Indices of the secrets shared in a DKGDeal
+const (
+	dkgX     = iota // x
+	dkgY            // y
+	dkgRX           // randomness of the proof of knowledge of x
+	dkgRY           // randomness of the proof of knowledge of y
+	dkgZ            // tracing secret
+	dkgAttrs        // y_1, the secrets y_i of the attributes follow, then y_{nb} and y_{na} of the validity window
+)
+
+// NewDKGDeal creates the contribution of the issuer with index dealer in 1, ..., n to the distributed key
+// generation of a threshold issuer for the given attribute names
+func NewDKGDeal(dealer int, threshold int, n int, AttributeNames []string, rng *amcl.RAND) (*DKGDeal, error) {
+	if rng == nil {
+		return nil, errors.Errorf("cannot create DKGDeal: received nil input")
+	}
+	if threshold < 1 || threshold > n {
+		return nil, errors.Errorf("cannot create DKGDeal: threshold %d is not in 1, ..., %d", threshold, n)
+	}
+	if dealer < 1 || dealer > n {
+		return nil, errors.Errorf("cannot create DKGDeal: dealer %d is not in 1, ..., %d", dealer, n)
+	}
+	attributeNamesMap := map[string]bool{}
+	for _, name := range AttributeNames {
+		if attributeNamesMap[name] {
+			return nil, errors.Errorf("attribute %s appears multiple times in AttributeNames", name)
+		}
+		attributeNamesMap[name] = true
+	}
+
+	deal := &DKGDeal{
+		Dealer:         int64(dealer),
+		Threshold:      int64(threshold),
+		AttributeNames: AttributeNames,
+	}
+	for j := 1; j <= n; j++ {
+		deal.Shares = append(deal.Shares, &DKGShare{Recipient: int64(j)})
+	}
+
+	for s := 0; s < dkgAttrs+len(AttributeNames)+numValidityKeys; s++ {
+		coefficients := make([]*FP256BN.BIG, threshold)
+		commitment := new(DKGCommitment)
+		for k := range coefficients {
+			coefficients[k] = RandModOrder(rng)
+			if s == dkgZ {
+				commitment.G1 = append(commitment.G1, EcpToProto(genG1().Mul(coefficients[k])))
+			} else {
+				commitment.G2 = append(commitment.G2, Ecp2ToProto(genG2().Mul(coefficients[k])))
+			}
+		}
+		deal.Commitments = append(deal.Commitments, commitment)
+		for _, share := range deal.Shares {
+			share.Secrets = append(share.Secrets, BigToBytes(evalPolynomial(coefficients, share.Recipient)))
+		}
+	}
+	return deal, nil
+}
+
+// CompleteDKG checks the deals of all n issuers and derives the key share of the issuer with the given index
+// together with the threshold issuer public key. The issuer public key is complete once the partial keys of
+// threshold issuers are combined with ThresholdIssuerPublicKey.CombinePartialKeys, and the partial proofs
+// of threshold issuers with ThresholdIssuerPublicKey.Combine.
+func CompleteDKG(index int, deals []*DKGDeal) (*IssuerKeyShare, error) {
+	n := len(deals)
+	if n == 0 || deals[0] == nil {
+		return nil, errors.Errorf("cannot complete DKG: received no deals")
+	}
+	if index < 1 || index > n {
+		return nil, errors.Errorf("cannot complete DKG: issuer %d is not in 1, ..., %d", index, n)
+	}
+	threshold := int(deals[0].GetThreshold())
+	AttributeNames := deals[0].GetAttributeNames()
+	numSecrets := dkgAttrs + len(AttributeNames) + numValidityKeys
+	if threshold < 1 || threshold > n {
+		return nil, errors.Errorf("cannot complete DKG: threshold %d is not in 1, ..., %d", threshold, n)
+	}
+
+	secrets := make([]*FP256BN.BIG, numSecrets)
+	commitmentsG2 := make([][]*FP256BN.ECP2, numSecrets)
+	commitmentsZ := make([]*FP256BN.ECP, threshold)
+	for s := 0; s < numSecrets; s++ {
+		secrets[s] = FP256BN.NewBIGint(0)
+		commitmentsG2[s] = make([]*FP256BN.ECP2, threshold)
+		for k := 0; k < threshold; k++ {
+			commitmentsG2[s][k] = FP256BN.NewECP2()
+		}
+	}
+	for k := 0; k < threshold; k++ {
+		commitmentsZ[k] = FP256BN.NewECP()
+	}
+
+	dealers := map[int64]bool{}
+	for _, deal := range deals {
+		if deal == nil || deal.GetDealer() < 1 || deal.GetDealer() > int64(n) || dealers[deal.GetDealer()] {
+			return nil, errors.Errorf("cannot complete DKG: deals do not come from issuers 1, ..., %d", n)
+		}
+		dealers[deal.GetDealer()] = true
+		if err := checkDKGDeal(deal, index, threshold, AttributeNames, n); err != nil {
+			return nil, errors.Wrapf(err, "deal of issuer %d is invalid", deal.GetDealer())
+		}
+
+		share := deal.Shares[index-1]
+		for s, commitment := range deal.Commitments {
+			secrets[s] = Modadd(secrets[s], FP256BN.FromBytes(share.Secrets[s]), GroupOrder)
+			for k := 0; k < threshold; k++ {
+				if s == dkgZ {
+					commitmentsZ[k].Add(EcpFromProto(commitment.G1[k]))
+				} else {
+					commitmentsG2[s][k].Add(Ecp2FromProto(commitment.G2[k]))
+				}
+			}
+		}
+	}
+
+	tpk := &ThresholdIssuerPublicKey{Threshold: int64(threshold), Issuers: int64(n)}
+	for s := 0; s < numSecrets; s++ {
+		commitment := new(DKGCommitment)
+		for k := 0; k < threshold; k++ {
+			if s == dkgZ {
+				commitment.G1 = append(commitment.G1, EcpToProto(commitmentsZ[k]))
+			} else {
+				commitment.G2 = append(commitment.G2, Ecp2ToProto(commitmentsG2[s][k]))
+			}
+		}
+		tpk.Commitments = append(tpk.Commitments, commitment)
+	}
+
+	// The combined public key holds the constant terms of the shared polynomials in G2.
+	// Its parts in G1 are raised to secrets no one knows, they are combined from the partial keys.
+	ipk := new(IssuerPublicKey)
+	ipk.AttributeNames = AttributeNames
+	ipk.BarX = Ecp2ToProto(commitmentsG2[dkgX][0])
+	ipk.BarY = Ecp2ToProto(commitmentsG2[dkgY][0])
+	ipk.HSk = EcpToProto(hashToG1([]byte("HSk")))
+	ipk.HRand = EcpToProto(hashToG1([]byte("HRand")))
+	BarG1 := thresholdBarG1(commitmentsG2[dkgX][0], commitmentsG2[dkgY][0])
+	ipk.BarG1 = EcpToProto(BarG1)
+	ipk.TracingPk = EcpToProto(commitmentsZ[0])
+	for i := range AttributeNames {
+		ipk.BarAttrs = append(ipk.BarAttrs, Ecp2ToProto(commitmentsG2[dkgAttrs+i][0]))
+	}
+	for v := 0; v < numValidityKeys; v++ {
+		ipk.BarValidity = append(ipk.BarValidity, Ecp2ToProto(commitmentsG2[dkgAttrs+len(AttributeNames)+v][0]))
+	}
+	tpk.Ipk = ipk
+
+	isk := new(SecretKey)
+	isk.X = BigToBytes(secrets[dkgX])
+	isk.Y = BigToBytes(secrets[dkgY])
+	isk.TracingSk = BigToBytes(secrets[dkgZ])
+	for i := range AttributeNames {
+		isk.Attrs = append(isk.Attrs, BigToBytes(secrets[dkgAttrs+i]))
+	}
+	for v := 0; v < numValidityKeys; v++ {
+		isk.Validity = append(isk.Validity, BigToBytes(secrets[dkgAttrs+len(AttributeNames)+v]))
+	}
+
+	partial := &PartialIssuerPublicKey{
+		Index: int64(index),
+		BarG2: EcpToProto(BarG1.Mul(secrets[dkgX])),
+		BarG3: EcpToProto(BarG1.Mul(secrets[dkgY])),
+		TX:    EcpToProto(BarG1.Mul(secrets[dkgRX])),
+		TY:    EcpToProto(BarG1.Mul(secrets[dkgRY])),
+	}
+	for i := range AttributeNames {
+		partial.HAttrs = append(partial.HAttrs, EcpToProto(genG1().Mul(secrets[dkgAttrs+i])))
+	}
+	for v := 0; v < numValidityKeys; v++ {
+		partial.HValidity = append(partial.HValidity, EcpToProto(genG1().Mul(secrets[dkgAttrs+len(AttributeNames)+v])))
+	}
+
+	return &IssuerKeyShare{Index: int64(index), Isk: isk, Tpk: tpk, Partial: partial,
+		ProofRX: BigToBytes(secrets[dkgRX]), ProofRY: BigToBytes(secrets[dkgRY])}, nil
+}
+
+// checkDKGDeal checks that a deal is well-formed and that the shares dealt to the issuer with the given index
+// match the commitments
+func checkDKGDeal(deal *DKGDeal, index int, threshold int, AttributeNames []string, n int) error {
+	numSecrets := dkgAttrs + len(AttributeNames) + numValidityKeys
+	if int(deal.GetThreshold()) != threshold || !equalStrings(deal.GetAttributeNames(), AttributeNames) {
+		return errors.Errorf("deal is made for another threshold issuer")
+	}
+	if len(deal.GetCommitments()) != numSecrets || len(deal.GetShares()) != n {
+		return errors.Errorf("deal is not fit with the DKGDeal format")
+	}
+	share := deal.Shares[index-1]
+	if share.GetRecipient() != int64(index) || len(share.GetSecrets()) != numSecrets {
+		return errors.Errorf("deal has no share for issuer %d", index)
+	}
+
+	for s, commitment := range deal.Commitments {
+		if s == dkgZ {
+			if len(commitment.GetG1()) != threshold || len(commitment.GetG2()) != 0 {
+				return errors.Errorf("deal is not fit with the DKGDeal format")
+			}
+			for k := 0; k < threshold; k++ {
+				if _, err := EcpFromProtoChecked(commitment.G1[k]); err != nil {
+					return errors.WithMessagef(err, "commitment of secret %d invalid", s)
+				}
+			}
+			if !genG1().Mul(FP256BN.FromBytes(share.Secrets[s])).Equals(evalCommitmentG1(commitment.G1, int64(index))) {
+				return errors.Errorf("share of secret %d does not match the commitments", s)
+			}
+			continue
+		}
+		// the other secrets must not be committed to in G1, see the top of the file
+		if len(commitment.GetG2()) != threshold || len(commitment.GetG1()) != 0 {
+			return errors.Errorf("deal is not fit with the DKGDeal format")
+		}
+		for k := 0; k < threshold; k++ {
+			if _, err := Ecp2FromProtoChecked(commitment.G2[k]); err != nil {
+				return errors.WithMessagef(err, "commitment of secret %d invalid", s)
+			}
+		}
+		if !genG2().Mul(FP256BN.FromBytes(share.Secrets[s])).Equals(evalCommitmentG2(commitment.G2, int64(index))) {
+			return errors.Errorf("share of secret %d does not match the commitments", s)
+		}
+	}
+	return nil
+}
+
+// CombinePartialKeys completes the parts in G1 of the issuer public key with the partial keys of at least
+// threshold issuers, and derives the challenges of its proofs of knowledge. Invalid and repeated partial keys
+// are ignored. Every issuer combines the partial keys itself before it calls ProveKey.
+func (tpk *ThresholdIssuerPublicKey) CombinePartialKeys(partials []*PartialIssuerPublicKey) error {
+	ipk := tpk.GetIpk()
+	if ipk == nil || len(tpk.GetCommitments()) != dkgAttrs+len(ipk.GetAttributeNames())+numValidityKeys {
+		return errors.Errorf("threshold issuer public key is not fit with the format")
+	}
+	threshold := int(tpk.GetThreshold())
+
+	var indices []int64
+	var valid []*PartialIssuerPublicKey
+	for _, partial := range partials {
+		if len(indices) == threshold {
+			break
+		}
+		if partial == nil || partial.GetIndex() < 1 || partial.GetIndex() > tpk.GetIssuers() || isInInt64(indices, partial.GetIndex()) {
+			continue
+		}
+		if tpk.checkPartialKey(partial) != nil {
+			continue
+		}
+		indices = append(indices, partial.GetIndex())
+		valid = append(valid, partial)
+	}
+	if threshold < 1 || len(indices) < threshold {
+		return errors.Errorf("cannot combine issuer public key: %d valid partial keys, %d needed", len(indices), threshold)
+	}
+
+	// combine interpolates one part of the partial keys in the exponent
+	combine := func(part func(*PartialIssuerPublicKey) *ECP) *FP256BN.ECP {
+		points := make([]*ECP, len(valid))
+		for i, partial := range valid {
+			points[i] = part(partial)
+		}
+		return interpolateG1(indices, points, 0)
+	}
+	BarG2 := combine(func(partial *PartialIssuerPublicKey) *ECP { return partial.BarG2 })
+	BarG3 := combine(func(partial *PartialIssuerPublicKey) *ECP { return partial.BarG3 })
+	TX := combine(func(partial *PartialIssuerPublicKey) *ECP { return partial.TX })
+	TY := combine(func(partial *PartialIssuerPublicKey) *ECP { return partial.TY })
+	ipk.BarG2 = EcpToProto(BarG2)
+	ipk.BarG3 = EcpToProto(BarG3)
+	ipk.HAttrs, ipk.HValidity = nil, nil
+	for i := range ipk.GetAttributeNames() {
+		ipk.HAttrs = append(ipk.HAttrs, EcpToProto(combine(func(partial *PartialIssuerPublicKey) *ECP { return partial.HAttrs[i] })))
+	}
+	for v := 0; v < numValidityKeys; v++ {
+		ipk.HValidity = append(ipk.HValidity, EcpToProto(combine(func(partial *PartialIssuerPublicKey) *ECP { return partial.HValidity[v] })))
+	}
+
+	// The t-values of the proofs of knowledge are committed to by the shared randomness
+	BarG1 := EcpFromProto(ipk.GetBarG1())
+	ipk.ProofVersion = ProofVersionTranscript
+	proofCX := keyProofChallenge(issuerKeyXLabel, false, Ecp2FromProto(tpk.Commitments[dkgRX].G2[0]), TX, BarG1, Ecp2FromProto(ipk.GetBarX()), BarG2)
+	proofCY := keyProofChallenge(issuerKeyYLabel, false, Ecp2FromProto(tpk.Commitments[dkgRY].G2[0]), TY, BarG1, Ecp2FromProto(ipk.GetBarY()), BarG3)
+	ipk.ProofCX = BigToBytes(proofCX)
+	ipk.ProofCY = BigToBytes(proofCY)
+	return nil
+}
+
+// checkPartialKey checks that every part P of a partial key is raised to the share the commitments commit to,
+// e(P, g_2) = e(base, g_2^{f(j)}) for the base BarG1 or g_1 of the part
+func (tpk *ThresholdIssuerPublicKey) checkPartialKey(partial *PartialIssuerPublicKey) error {
+	ipk := tpk.GetIpk()
+	numAttrs := len(ipk.GetAttributeNames())
+	if len(partial.GetHAttrs()) != numAttrs || len(partial.GetHValidity()) != numValidityKeys {
+		return errors.Errorf("partial key is not fit with the format")
+	}
+	BarG1 := EcpFromProto(ipk.GetBarG1())
+	type part struct {
+		P      *ECP
+		base   *FP256BN.ECP
+		secret int
+	}
+	parts := []part{{partial.GetBarG2(), BarG1, dkgX}, {partial.GetBarG3(), BarG1, dkgY},
+		{partial.GetTX(), BarG1, dkgRX}, {partial.GetTY(), BarG1, dkgRY}}
+	for i, P := range partial.HAttrs {
+		parts = append(parts, part{P, genG1(), dkgAttrs + i})
+	}
+	for v, P := range partial.HValidity {
+		parts = append(parts, part{P, genG1(), dkgAttrs + numAttrs + v})
+	}
+
+	j := partial.GetIndex()
+	for _, p := range parts {
+		P, err := EcpFromProtoChecked(p.P)
+		if err != nil {
+			return errors.WithMessagef(err, "partial key of issuer %d invalid", j)
+		}
+		left := FP256BN.Fexp(FP256BN.Ate(genG2(), P))
+		right := FP256BN.Fexp(FP256BN.Ate(evalCommitmentG2(tpk.Commitments[p.secret].G2, j), p.base))
+		if !left.Equals(right) {
+			return errors.Errorf("partial key of issuer %d does not match the commitments", j)
+		}
+	}
+	return nil
+}
+
+// ProveKey creates the share of the issuer of the responses of the proofs of knowledge in the issuer public key,
+// once the issuer combined the partial keys into its own copy of the key
+func (share *IssuerKeyShare) ProveKey() (*PartialKeyProof, error) {
+	ipk := share.GetTpk().GetIpk()
+	if ipk.GetProofCX() == nil || ipk.GetProofCY() == nil {
+		return nil, errors.Errorf("cannot prove issuer key: the partial keys are not combined yet")
+	}
+	proofCX := FP256BN.FromBytes(ipk.GetProofCX())
+	proofCY := FP256BN.FromBytes(ipk.GetProofCY())
+	isk := share.GetIsk()
+	return &PartialKeyProof{
+		Index:   share.GetIndex(),
+		ProofSX: BigToBytes(Modadd(FP256BN.FromBytes(share.GetProofRX()), FP256BN.Modmul(proofCX, FP256BN.FromBytes(isk.GetX()), GroupOrder), GroupOrder)),
+		ProofSY: BigToBytes(Modadd(FP256BN.FromBytes(share.GetProofRY()), FP256BN.Modmul(proofCY, FP256BN.FromBytes(isk.GetY()), GroupOrder), GroupOrder)),
+	}, nil
+}
+
+// Combine completes the issuer public key with the partial proofs of at least threshold issuers.
+// Invalid and repeated partial proofs are ignored.
+func (tpk *ThresholdIssuerPublicKey) Combine(proofs []*PartialKeyProof) error {
+	ipk := tpk.GetIpk()
+	if ipk == nil || len(tpk.GetCommitments()) != dkgAttrs+len(ipk.GetAttributeNames())+numValidityKeys {
+		return errors.Errorf("threshold issuer public key is not fit with the format")
+	}
+	if ipk.GetProofCX() == nil || ipk.GetProofCY() == nil {
+		return errors.Errorf("cannot combine issuer public key: the partial keys are not combined yet")
+	}
+	threshold := int(tpk.GetThreshold())
+	proofCX := FP256BN.FromBytes(ipk.GetProofCX())
+	proofCY := FP256BN.FromBytes(ipk.GetProofCY())
+
+	var indices []int64
+	var proofsSX, proofsSY []*FP256BN.BIG
+	for _, proof := range proofs {
+		if len(indices) == threshold {
+			break
+		}
+		if proof == nil || proof.GetIndex() < 1 || proof.GetIndex() > tpk.GetIssuers() || isInInt64(indices, proof.GetIndex()) {
+			continue
+		}
+		j := proof.GetIndex()
+		proofSX := FP256BN.FromBytes(proof.GetProofSX())
+		proofSY := FP256BN.FromBytes(proof.GetProofSY())
+
+		// g_2^{s_j} = g_2^{r_j} \cdot (g_2^{x_j})^C
+		tX := evalCommitmentG2(tpk.Commitments[dkgRX].G2, j)
+		tX.Add(evalCommitmentG2(tpk.Commitments[dkgX].G2, j).Mul(proofCX))
+		tY := evalCommitmentG2(tpk.Commitments[dkgRY].G2, j)
+		tY.Add(evalCommitmentG2(tpk.Commitments[dkgY].G2, j).Mul(proofCY))
+		if !genG2().Mul(proofSX).Equals(tX) || !genG2().Mul(proofSY).Equals(tY) {
+			continue
+		}
+		indices = append(indices, j)
+		proofsSX = append(proofsSX, proofSX)
+		proofsSY = append(proofsSY, proofSY)
+	}
+	if threshold < 1 || len(indices) < threshold {
+		return errors.Errorf("cannot combine issuer public key: %d valid partial proofs, %d needed", len(indices), threshold)
+	}
+
+	ipk.ProofSX = BigToBytes(interpolateBig(indices, proofsSX))
+	ipk.ProofSY = BigToBytes(interpolateBig(indices, proofsSY))
+	if err := ipk.setKeyID(); err != nil {
+		return err
+	}
+	return ipk.Check()
+}
+
+// ArbitrationPublicKey returns the sharing of the tracing secret among the issuers
+func (tpk *ThresholdIssuerPublicKey) ArbitrationPublicKey() *ArbitrationPublicKey {
+	apk := &ArbitrationPublicKey{Threshold: tpk.GetThreshold()}
+	for j := int64(1); j <= tpk.GetIssuers(); j++ {
+		apk.SharePks = append(apk.SharePks, EcpToProto(evalCommitmentG1(tpk.Commitments[dkgZ].G1, j)))
+	}
+	return apk
+}
+
+// ArbitratorKey returns the share of the tracing secret of the issuer
+func (share *IssuerKeyShare) ArbitratorKey() *ArbitratorKey {
+	return &ArbitratorKey{Index: share.GetIndex(), Share: share.GetIsk().GetTracingSk()}
+}
+
+// NewThresholdCredRequest creates a credential request to a threshold issuer for the given attribute values
+// and validity window
+func NewThresholdCredRequest(sk *FP256BN.BIG, IssuerNonce []byte, ipk *IssuerPublicKey, attrs []*FP256BN.BIG, notBefore int64, notAfter int64, rng *amcl.RAND) *ThresholdCredRequest {
+	UPK := genG1().Mul(sk)
+	A := thresholdCredentialBase(ipk, IssuerNonce, UPK, signedValues(attrs, notBefore, notAfter))
+	ASk := A.Mul(sk)
+
+	// Prove that ASk and UPK share the same sk
+	r := RandModOrder(rng)
+	t1 := genG1().Mul(r)
+	t2 := A.Mul(r)
+
+	proofC := thresholdCredRequestChallenge(t1, t2, UPK, A, ASk, IssuerNonce, ipk)
+	proofS := Modadd(r, FP256BN.Modmul(proofC, sk, GroupOrder), GroupOrder) // s = r + C \cdot sk
+
+	return &ThresholdCredRequest{
+		IssuerNonce: IssuerNonce,
+		ASk:         EcpToProto(ASk),
+		ProofC:      BigToBytes(proofC),
+		ProofS:      BigToBytes(proofS),
+	}
+}
+
+// Check cryptographically verifies the credential request of the user with public key upk for the given attribute values
+// and validity window
+func (m *ThresholdCredRequest) Check(ipk *IssuerPublicKey, upk *UserPublicKey, attrs []*FP256BN.BIG, notBefore int64, notAfter int64) error {
+	if m.GetIssuerNonce() == nil || m.GetASk() == nil || m.GetProofC() == nil || m.GetProofS() == nil || upk.GetUPK() == nil {
+		return errors.Errorf("one of the proof values is undefined")
+	}
+	UPK := EcpFromProto(upk.GetUPK())
+	A := thresholdCredentialBase(ipk, m.GetIssuerNonce(), UPK, signedValues(attrs, notBefore, notAfter))
+	ASk, err := EcpFromProtoChecked(m.GetASk())
+	if err != nil {
+		return errors.Wrap(err, "credential request invalid")
+	}
+	ProofC, err := BigFromBytesChecked(m.GetProofC())
+	if err != nil {
+		return errors.Wrap(err, "credential request proof invalid")
+	}
+	ProofS, err := BigFromBytesChecked(m.GetProofS())
+	if err != nil {
+		return errors.Wrap(err, "credential request proof invalid")
+	}
+
+	// Recompute t-values using s-values
+	t1 := genG1().Mul(ProofS)
+	t1.Sub(UPK.Mul(ProofC)) // t1 = g_1^s / UPK^C
+
+	t2 := A.Mul(ProofS)
+	t2.Sub(ASk.Mul(ProofC)) // t2 = A^s / ASk^C
+
+	if *ProofC != *thresholdCredRequestChallenge(t1, t2, UPK, A, ASk, m.GetIssuerNonce(), ipk) {
+		return errors.Errorf("zero knowledge proof is invalid")
+	}
+	return nil
+}
+
+// NewPartialCredential issues the share of a credential of one issuer of a threshold issuer
+func NewPartialCredential(share *IssuerKeyShare, m *ThresholdCredRequest, upk *UserPublicKey, attrs []*FP256BN.BIG, notBefore int64, notAfter int64) (*PartialCredential, error) {
+	if share == nil || m == nil || upk == nil || attrs == nil {
+		return nil, errors.Errorf("cannot create PartialCredential: received nil input")
+	}
+	ipk := share.GetTpk().GetIpk()
+	if ipk.GetHash() == nil {
+		return nil, errors.Errorf("threshold issuer public key is not combined yet")
+	}
+	if len(attrs) != len(ipk.GetAttributeNames()) || len(attrs) != len(share.GetIsk().GetAttrs()) ||
+		len(share.GetIsk().GetValidity()) != numValidityKeys {
+		return nil, errors.Errorf("issuer key does not match the number of attribute values passed")
+	}
+	if err := checkValidityWindow(notBefore, notAfter); err != nil {
+		return nil, err
+	}
+
+	err := m.Check(ipk, upk, attrs, notBefore, notAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compute the exponent x_j + \sum_i y_{i,j} \cdot attr_i + y_{nb,j} \cdot notBefore + y_{na,j} \cdot notAfter
+	exp := FP256BN.NewBIGcopy(FP256BN.FromBytes(share.Isk.X))
+	for index, attribute := range attrs {
+		exp = Modadd(exp, FP256BN.Modmul(FP256BN.FromBytes(share.Isk.Attrs[index]), attribute, GroupOrder), GroupOrder)
+	}
+	for v, value := range validityValues(notBefore, notAfter) {
+		exp = Modadd(exp, FP256BN.Modmul(FP256BN.FromBytes(share.Isk.Validity[v]), value, GroupOrder), GroupOrder)
+	}
+
+	A := thresholdCredentialBase(ipk, m.GetIssuerNonce(), EcpFromProto(upk.GetUPK()), signedValues(attrs, notBefore, notAfter))
+	B := A.Mul2(exp, EcpFromProto(m.GetASk()), FP256BN.FromBytes(share.Isk.Y))
+
+	return &PartialCredential{Index: share.GetIndex(), B: EcpToProto(B)}, nil
+}
+
+// VerifyPartialCredential checks a partial credential against the verification key of the issuer that made it
+func (tpk *ThresholdIssuerPublicKey) VerifyPartialCredential(sk *FP256BN.BIG, m *ThresholdCredRequest, attrs []*FP256BN.BIG, notBefore int64, notAfter int64, partial *PartialCredential) error {
+	values := signedValues(attrs, notBefore, notAfter)
+	A := thresholdCredentialBase(tpk.GetIpk(), m.GetIssuerNonce(), genG1().Mul(sk), values)
+	return tpk.verifyPartialCredential(sk, A, values, partial)
+}
+
+// verifyPartialCredential checks a partial credential on the attribute values followed by the validity window
+func (tpk *ThresholdIssuerPublicKey) verifyPartialCredential(sk *FP256BN.BIG, A *FP256BN.ECP, values []*FP256BN.BIG, partial *PartialCredential) error {
+	if partial == nil || partial.GetB() == nil {
+		return errors.Errorf("partial credential invalid: received nil input")
+	}
+	j := partial.GetIndex()
+	if j < 1 || j > tpk.GetIssuers() {
+		return errors.Errorf("partial credential invalid: unknown issuer %d", j)
+	}
+	if len(values) != len(tpk.GetIpk().GetAttributeNames())+numValidityKeys || len(tpk.GetCommitments()) != dkgAttrs+len(values) {
+		return errors.Errorf("partial credential invalid: incorrect number of attribute values passed")
+	}
+
+	// - check e(A, BarX_j \cdot BarY_j^{sk} \cdot \prod_i BarAttr_{i,j}^{attr_i} \cdot \prod_v BarValidity_{v,j}^{validity_v}) = e(B_j, g_2)
+	BarY := evalCommitmentG2(tpk.Commitments[dkgY].G2, j).Mul(sk)
+	BarY.Add(evalCommitmentG2(tpk.Commitments[dkgX].G2, j))
+	for i, attr := range values {
+		BarY.Add(evalCommitmentG2(tpk.Commitments[dkgAttrs+i].G2, j).Mul(attr))
+	}
+	BarY.Affine()
+	left := FP256BN.Fexp(FP256BN.Ate(BarY, A))
+	right := FP256BN.Fexp(FP256BN.Ate(genG2(), EcpFromProto(partial.GetB())))
+	if !left.Equals(right) {
+		return errors.Errorf("partial credential of issuer %d is not cryptographically valid", j)
+	}
+	return nil
+}
+
+// AggregateCredential combines the partial credentials of at least threshold issuers to a credential that
+// verifies under the combined issuer public key. Invalid and repeated partial credentials are ignored.
+func AggregateCredential(tpk *ThresholdIssuerPublicKey, sk *FP256BN.BIG, m *ThresholdCredRequest, attrs []*FP256BN.BIG, notBefore int64, notAfter int64, partials []*PartialCredential) (*Credential, error) {
+	if tpk.GetIpk() == nil || sk == nil || m == nil || attrs == nil {
+		return nil, errors.Errorf("cannot aggregate Credential: received nil input")
+	}
+	if err := checkValidityWindow(notBefore, notAfter); err != nil {
+		return nil, err
+	}
+	ipk := tpk.GetIpk()
+	values := signedValues(attrs, notBefore, notAfter)
+	A := thresholdCredentialBase(ipk, m.GetIssuerNonce(), genG1().Mul(sk), values)
+
+	threshold := int(tpk.GetThreshold())
+	var indices []int64
+	var signatures []*ECP
+	for _, partial := range partials {
+		if len(indices) == threshold {
+			break
+		}
+		if partial == nil || isInInt64(indices, partial.GetIndex()) {
+			continue
+		}
+		if tpk.verifyPartialCredential(sk, A, values, partial) != nil {
+			continue
+		}
+		indices = append(indices, partial.GetIndex())
+		signatures = append(signatures, partial.GetB())
+	}
+	if threshold < 1 || len(indices) < threshold {
+		return nil, errors.Errorf("cannot aggregate Credential: %d valid partial credentials, %d needed", len(indices), threshold)
+	}
+
+	cred := new(Credential)
+	cred.A = EcpToProto(A)
+	cred.B = EcpToProto(interpolateG1(indices, signatures, 0))
+	for index, attribute := range attrs {
+		cred.Attrs = append(cred.Attrs, BigToBytes(attribute))
+		cred.AttributeNames = append(cred.AttributeNames, ipk.AttributeNames[index])
+	}
+	cred.NotBefore = notBefore
+	cred.NotAfter = notAfter
+	cred.KeyId = ipk.GetKeyId()
+	if err := cred.Ver(sk, ipk); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// thresholdCredentialBase derives the credential base A from the request, so that all issuers sign on the same A
+// and no one knows its discrete log
+func thresholdCredentialBase(ipk *IssuerPublicKey, IssuerNonce []byte, UPK *FP256BN.ECP, attrs []*FP256BN.BIG) *FP256BN.ECP {
+	data := make([]byte, len(ipk.GetHash())+len(IssuerNonce)+2*FieldBytes+1+len(attrs)*FieldBytes)
+	index := 0
+	index = appendBytes(data, index, ipk.GetHash())
+	index = appendBytes(data, index, IssuerNonce)
+	index = appendBytesG1(data, index, UPK)
+	for _, attr := range attrs {
+		index = appendBytesBig(data, index, attr)
+	}
+	return hashToG1(data)
+}
+
+// thresholdCredRequestChallenge derives the challenge of a ThresholdCredRequest
+func thresholdCredRequestChallenge(t1, t2, UPK, A, ASk *FP256BN.ECP, IssuerNonce []byte, ipk *IssuerPublicKey) *FP256BN.BIG {
+	t := newTranscript(thresholdCredRequestLabel)
+	t.appendG1("t1", t1)
+	t.appendG1("t2", t2)
+	t.appendG1("UPK", UPK)
+	t.appendG1("A", A)
+	t.appendG1("ASk", ASk)
+	t.appendBytes("IssuerNonce", IssuerNonce)
+	t.appendBytes("ipk", ipk.GetHash())
+	return t.challenge()
+}
+
+// thresholdBarG1 derives BarG1 of a threshold issuer public key from BarX and BarY,
+// so that no one knows its discrete log
+func thresholdBarG1(BarX, BarY *FP256BN.ECP2) *FP256BN.ECP {
+	t := newTranscript(thresholdBarG1Label)
+	t.appendG2("BarX", BarX)
+	t.appendG2("BarY", BarY)
+	return hashToG1(t.data)
+}
+
+// hashToG1 hashes data to an element of G1 no one knows the discrete log of
+func hashToG1(data []byte) *FP256BN.ECP {
+	digest := sha256.Sum256(data)
+	return FP256BN.ECP_mapit(digest[:])
+}
+
+// evalPolynomial returns f(x) for the polynomial f with the given coefficients
+func evalPolynomial(coefficients []*FP256BN.BIG, x int64) *FP256BN.BIG {
+	res := FP256BN.NewBIGint(0)
+	for k := len(coefficients) - 1; k >= 0; k-- {
+		res = Modadd(FP256BN.Modmul(res, bigFromInt64(x), GroupOrder), coefficients[k], GroupOrder)
+	}
+	return res
+}
+
+// evalCommitmentG1 returns g1^{f(x)} from the commitments g1^{a_k} to the coefficients of f
+func evalCommitmentG1(commitment []*ECP, x int64) *FP256BN.ECP {
+	res := FP256BN.NewECP()
+	for k := len(commitment) - 1; k >= 0; k-- {
+		res = res.Mul(bigFromInt64(x))
+		res.Add(EcpFromProto(commitment[k]))
+	}
+	return res
+}
+
+// evalCommitmentG2 returns g2^{f(x)} from the commitments g2^{a_k} to the coefficients of f
+func evalCommitmentG2(commitment []*ECP2, x int64) *FP256BN.ECP2 {
+	res := FP256BN.NewECP2()
+	for k := len(commitment) - 1; k >= 0; k-- {
+		res = res.Mul(bigFromInt64(x))
+		res.Add(Ecp2FromProto(commitment[k]))
+	}
+	return res
+}
+
+// interpolateBig returns f(0) from the values f(j) at the given indices
+func interpolateBig(indices []int64, values []*FP256BN.BIG) *FP256BN.BIG {
+	res := FP256BN.NewBIGint(0)
+	for i, j := range indices {
+		res = Modadd(res, FP256BN.Modmul(values[i], lagrangeCoefficient(indices, j, 0), GroupOrder), GroupOrder)
+	}
+	return res
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
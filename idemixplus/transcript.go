@@ -0,0 +1,134 @@
+package idemixplus
+
+import (
+	"encoding/binary"
+
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// A transcript is the Fiat-Shamir transcript of a zero-knowledge proof: the prover and the verifier append
+// the statement and the commitments of the prover to it in the same order and derive the challenge from it.
+// Every append is framed as the length of the label, the label, the length of the data and the data,
+// with lengths of 4 bytes big endian, and a transcript starts with transcriptProtocol and the label of the proof,
+// so that different proofs and different sequences of appends never hash the same data.
+// Points are appended compressed.
+//
+// Proofs made before transcripts hash proof data without labels and lengths, with uncompressed points,
+// and do not cover the whole statement. A legacy transcript reproduces that proof data, so that keys,
+// signatures and opening proofs made before can still be verified when the caller asks for it,
+// see CheckLegacy and VerifyOpts.AllowLegacy. Provers never make legacy proofs.
+type transcript struct {
+	legacy bool
+	data   []byte
+}
+
+// transcriptProtocol separates the transcripts of this package from other uses of the hash function
+const transcriptProtocol = "idemixplus/transcript/v1"
+
+// ProofVersionTranscript is the version of the proofs of keys, credential requests and opening proofs
+// whose challenge is derived from a transcript. Proofs made before have version 0.
+const ProofVersionTranscript uint32 = 1
+
+// checkProofVersion checks that a proof is of version ProofVersionTranscript, so that a prover cannot
+// have its proof checked against the proof data of a version that does not cover the whole statement
+func checkProofVersion(version uint32) error {
+	if version != ProofVersionTranscript {
+		return errors.Errorf("proof version %d is not supported, expected %d", version, ProofVersionTranscript)
+	}
+	return nil
+}
+
+// newTranscript starts the transcript of the proof with the given label
+func newTranscript(label string) *transcript {
+	t := &transcript{}
+	t.appendString("protocol", transcriptProtocol)
+	t.appendString("proof", label)
+	return t
+}
+
+// newLegacyTranscript starts the proof data of a proof made before transcripts
+func newLegacyTranscript() *transcript {
+	return &transcript{legacy: true}
+}
+
+// appendLegacyLabel appends the label the legacy proof data of a proof starts with,
+// transcripts are labelled when they are started
+func (t *transcript) appendLegacyLabel(label string) {
+	if t.legacy {
+		t.appendString("label", label)
+	}
+}
+
+// padLegacy pads legacy proof data with zeros to size bytes, or cuts it to size bytes.
+// Some proofs hashed a buffer of a fixed size that did not match the proof data written to it.
+func (t *transcript) padLegacy(size int) {
+	if !t.legacy {
+		return
+	}
+	data := make([]byte, size)
+	copy(data, t.data)
+	t.data = data
+}
+
+func (t *transcript) appendBytes(label string, data []byte) {
+	if !t.legacy {
+		t.appendLength(len(label))
+		t.data = append(t.data, label...)
+		t.appendLength(len(data))
+	}
+	t.data = append(t.data, data...)
+}
+
+func (t *transcript) appendLength(length int) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(length))
+	t.data = append(t.data, b[:]...)
+}
+
+func (t *transcript) appendString(label string, s string) {
+	t.appendBytes(label, []byte(s))
+}
+
+func (t *transcript) appendInt64(label string, v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	t.appendBytes(label, b[:])
+}
+
+func (t *transcript) appendBig(label string, B *FP256BN.BIG) {
+	t.appendBytes(label, BigToBytes(B))
+}
+
+func (t *transcript) appendG1(label string, P *FP256BN.ECP) {
+	if t.legacy {
+		t.appendBytes(label, EcpToBytes(P))
+		return
+	}
+	t.appendBytes(label, compressG1(P))
+}
+
+func (t *transcript) appendG2(label string, P *FP256BN.ECP2) {
+	if t.legacy {
+		b := make([]byte, 4*FieldBytes)
+		P.ToBytes(b)
+		t.appendBytes(label, b)
+		return
+	}
+	t.appendBytes(label, compressG2(P))
+}
+
+// challenge hashes the transcript into a challenge
+func (t *transcript) challenge() *FP256BN.BIG {
+	return HashModOrder(t.data)
+}
+
+// challengeWithNonce hashes the transcript and the nonce of the prover into a challenge.
+// Legacy proofs hash the nonce with the hash of the proof data.
+func (t *transcript) challengeWithNonce(nonce []byte) *FP256BN.BIG {
+	if t.legacy {
+		return nymChallenge(t.data, nonce)
+	}
+	t.appendBytes("nonce", nonce)
+	return t.challenge()
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/hyperledger/fabric-amcl/amcl"
 	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
 )
 
 // GenG1 is a generator of Group G1
@@ -27,7 +28,27 @@ var GenG2 = FP256BN.NewECP2fp2s(
 // GenGT is a generator of Group GT
 var GenGT = FP256BN.Fexp(FP256BN.Ate(GenG2, GenG1))
 
-// GroupOrder is the order of the groups
+// The amcl operations normalize the points and field elements they read in place, so a value must not be
+// used by more than one goroutine at a time. The package computes with copies of the generators and only
+// reads GenG1, GenG2 and GenGT, callers that share them between goroutines have to copy them as well.
+// A BIG modulus such as GroupOrder is copied by the amcl operations that take it, it is only read.
+
+// genG1 returns a copy of GenG1
+func genG1() *FP256BN.ECP {
+	return ecpCopy(GenG1)
+}
+
+// genG2 returns a copy of GenG2
+func genG2() *FP256BN.ECP2 {
+	return ecp2Copy(GenG2)
+}
+
+// genGT returns a copy of GenGT
+func genGT() *FP256BN.FP12 {
+	return FP256BN.NewFP12copy(GenGT)
+}
+
+// GroupOrder is the order of the groups, it must not be modified
 var GroupOrder = FP256BN.NewBIGints(FP256BN.CURVE_Order)
 
 // FieldBytes is the bytelength of the group order
@@ -54,6 +75,9 @@ func appendBytes(data []byte, index int, bytesToAdd []byte) int {
 	copy(data[index:], bytesToAdd)
 	return index + len(bytesToAdd)
 }
+
+// appendBytesG1 and appendBytesG2 append points without compression, challenges are derived from transcripts
+// instead, see transcript.go
 func appendBytesG1(data []byte, index int, E *FP256BN.ECP) int {
 	length := 2*FieldBytes + 1
 	E.ToBytes(data[index:index+length], false)
@@ -101,32 +125,136 @@ func BigToBytes(big *FP256BN.BIG) []byte {
 
 // EcpToProto converts a *amcl.ECP into the proto struct *ECP
 func EcpToProto(p *FP256BN.ECP) *ECP {
-	return &ECP{
-		X: BigToBytes(p.GetX()),
-		Y: BigToBytes(p.GetY())}
+	return &ECP{Compressed: compressG1(p)}
 }
 
-// EcpFromProto converts a proto struct *ECP into an *amcl.ECP
+// EcpFromProto converts a proto struct *ECP into an *amcl.ECP, a compressed point that does not decompress
+// is the point at infinity
 func EcpFromProto(p *ECP) *FP256BN.ECP {
+	if len(p.GetCompressed()) > 0 {
+		P, err := decompressG1(p.GetCompressed())
+		if err != nil {
+			return FP256BN.NewECP()
+		}
+		return P
+	}
 	return FP256BN.NewECPbigs(FP256BN.FromBytes(p.GetX()), FP256BN.FromBytes(p.GetY()))
 }
 
 // Ecp2ToProto converts a *amcl.ECP2 into the proto struct *ECP2
 func Ecp2ToProto(p *FP256BN.ECP2) *ECP2 {
-	return &ECP2{
-		Xa: BigToBytes(p.GetX().GetA()),
-		Xb: BigToBytes(p.GetX().GetB()),
-		Ya: BigToBytes(p.GetY().GetA()),
-		Yb: BigToBytes(p.GetY().GetB())}
+	return &ECP2{Compressed: compressG2(p)}
 }
 
-// Ecp2FromProto converts a proto struct *ECP2 into an *amcl.ECP2
+// Ecp2FromProto converts a proto struct *ECP2 into an *amcl.ECP2, a compressed point that does not decompress
+// is the point at infinity
 func Ecp2FromProto(p *ECP2) *FP256BN.ECP2 {
+	if len(p.GetCompressed()) > 0 {
+		P, err := decompressG2(p.GetCompressed())
+		if err != nil {
+			return FP256BN.NewECP2()
+		}
+		return P
+	}
 	return FP256BN.NewECP2fp2s(
 		FP256BN.NewFP2bigs(FP256BN.FromBytes(p.GetXa()), FP256BN.FromBytes(p.GetXb())),
 		FP256BN.NewFP2bigs(FP256BN.FromBytes(p.GetYa()), FP256BN.FromBytes(p.GetYb())))
 }
 
+// fieldModulus is the order of the field the curve is defined over, it is only read like GroupOrder
+var fieldModulus = FP256BN.NewBIGints(FP256BN.Modulus)
+
+// coordinateFromBytes parses a field element, rejecting encodings of the wrong length
+// and values that are not reduced modulo the field order
+func coordinateFromBytes(b []byte) (*FP256BN.BIG, error) {
+	if len(b) != FieldBytes {
+		return nil, errors.Errorf("coordinate has %d bytes, expected %d", len(b), FieldBytes)
+	}
+	c := FP256BN.FromBytes(b)
+	if FP256BN.Comp(c, fieldModulus) >= 0 {
+		return nil, errors.Errorf("coordinate is not reduced modulo the field order")
+	}
+	return c, nil
+}
+
+// BigFromBytesChecked parses a scalar, rejecting encodings of the wrong length
+// and values that are not reduced modulo the group order
+func BigFromBytesChecked(b []byte) (*FP256BN.BIG, error) {
+	if len(b) != FieldBytes {
+		return nil, errors.Errorf("scalar has %d bytes, expected %d", len(b), FieldBytes)
+	}
+	big := FP256BN.FromBytes(b)
+	if FP256BN.Comp(big, GroupOrder) >= 0 {
+		return nil, errors.Errorf("scalar is not reduced modulo the group order")
+	}
+	return big, nil
+}
+
+// EcpFromProtoChecked converts a proto struct *ECP from an untrusted source into an *amcl.ECP,
+// rejecting malformed coordinates and points that are not on the curve.
+// G1 has cofactor 1, so every point on the curve is in G1.
+func EcpFromProtoChecked(p *ECP) (*FP256BN.ECP, error) {
+	if p == nil {
+		return nil, errors.Errorf("G1 element is undefined")
+	}
+	if len(p.GetCompressed()) > 0 {
+		if len(p.GetX()) > 0 || len(p.GetY()) > 0 {
+			return nil, errors.Errorf("G1 element is malformed: it is both compressed and uncompressed")
+		}
+		return decompressG1(p.GetCompressed())
+	}
+	x, err := coordinateFromBytes(p.GetX())
+	if err != nil {
+		return nil, errors.Wrap(err, "G1 element is malformed")
+	}
+	y, err := coordinateFromBytes(p.GetY())
+	if err != nil {
+		return nil, errors.Wrap(err, "G1 element is malformed")
+	}
+	P := FP256BN.NewECPbigs(x, y)
+	if P.Is_infinity() {
+		return nil, errors.Errorf("G1 element is not on the curve")
+	}
+	return P, nil
+}
+
+// Ecp2FromProtoChecked converts a proto struct *ECP2 from an untrusted source into an *amcl.ECP2,
+// rejecting malformed coordinates, points that are not on the curve and points outside the subgroup of order q
+func Ecp2FromProtoChecked(p *ECP2) (*FP256BN.ECP2, error) {
+	if p == nil {
+		return nil, errors.Errorf("G2 element is undefined")
+	}
+	var P *FP256BN.ECP2
+	if len(p.GetCompressed()) > 0 {
+		if len(p.GetXa()) > 0 || len(p.GetXb()) > 0 || len(p.GetYa()) > 0 || len(p.GetYb()) > 0 {
+			return nil, errors.Errorf("G2 element is malformed: it is both compressed and uncompressed")
+		}
+		var err error
+		if P, err = decompressG2(p.GetCompressed()); err != nil {
+			return nil, err
+		}
+	} else {
+		var coordinates [4]*FP256BN.BIG
+		for i, b := range [][]byte{p.GetXa(), p.GetXb(), p.GetYa(), p.GetYb()} {
+			c, err := coordinateFromBytes(b)
+			if err != nil {
+				return nil, errors.Wrap(err, "G2 element is malformed")
+			}
+			coordinates[i] = c
+		}
+		P = FP256BN.NewECP2fp2s(
+			FP256BN.NewFP2bigs(coordinates[0], coordinates[1]),
+			FP256BN.NewFP2bigs(coordinates[2], coordinates[3]))
+		if P.Is_infinity() {
+			return nil, errors.Errorf("G2 element is not on the curve")
+		}
+	}
+	if !P.Mul(GroupOrder).Is_infinity() {
+		return nil, errors.Errorf("G2 element is not in the subgroup of order q")
+	}
+	return P, nil
+}
+
 // GetRand returns a new *amcl.RAND with a fresh seed
 func GetRand(seedLength int) *amcl.RAND {
 	b := make([]byte, seedLength)
@@ -0,0 +1,163 @@
+package idemixplus
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+)
+
+// Every credential is valid in a window [NotBefore, NotAfter] of seconds since the epoch.
+// The issuer signs the window with the key components y_{nb}, y_{na} as if it were two more attributes,
+// B = A^{x + y \cdot usk + \sum_i y_i \cdot attr_i + y_{nb} \cdot NotBefore + y_{na} \cdot NotAfter},
+// and a NymSignature always hides both of them and proves NotBefore <= ValidAt <= NotAfter with range proofs.
+
+// Indices of the validity key components
+const (
+	validityNotBefore = iota
+	validityNotAfter
+	numValidityKeys
+)
+
+// validityLabel is the label used in ZKP to identify that this ZKP is a proof of knowledge of the validity window
+const validityLabel = "validity"
+
+// validityRangeBits bounds the distance of the validity window to the time of a signature, 2^32 seconds are about 136 years
+const validityRangeBits = 32
+
+// checkValidityWindow checks that a validity window is not empty and fits the range proofs of the signatures
+func checkValidityWindow(notBefore int64, notAfter int64) error {
+	if notBefore < 0 || notAfter < notBefore {
+		return errors.Errorf("validity window [%d, %d] is invalid", notBefore, notAfter)
+	}
+	return nil
+}
+
+// validityValues returns the values signed with the validity key components
+func validityValues(notBefore int64, notAfter int64) []*FP256BN.BIG {
+	return []*FP256BN.BIG{bigFromInt64(notBefore), bigFromInt64(notAfter)}
+}
+
+// validityPredicates returns the predicates NotBefore <= validAt <= NotAfter, the validity window follows
+// the numAttrs attributes of the credential
+func validityPredicates(numAttrs int, validAt int64) []*RangePredicate {
+	return []*RangePredicate{
+		{Attribute: numAttrs + validityNotBefore, Bound: bigFromInt64(validAt), Upper: true, bits: validityRangeBits},
+		{Attribute: numAttrs + validityNotAfter, Bound: bigFromInt64(validAt), bits: validityRangeBits},
+	}
+}
+
+// newValidityKeys generates the validity key components of an issuer key
+func newValidityKeys(isk *SecretKey, ipk *IssuerPublicKey, rng *amcl.RAND) {
+	for v := 0; v < numValidityKeys; v++ {
+		yValidity := RandModOrder(rng)
+		isk.Validity = append(isk.Validity, BigToBytes(yValidity))
+		ipk.HValidity = append(ipk.HValidity, EcpToProto(genG1().Mul(yValidity)))
+		ipk.BarValidity = append(ipk.BarValidity, Ecp2ToProto(genG2().Mul(yValidity)))
+	}
+}
+
+// signedValues returns the attribute values followed by the validity window
+func signedValues(attrs []*FP256BN.BIG, notBefore int64, notAfter int64) []*FP256BN.BIG {
+	return append(append([]*FP256BN.BIG{}, attrs...), validityValues(notBefore, notAfter)...)
+}
+
+// checkValidAt checks that the validity window of the credential contains validAt
+func checkValidAt(cred *Credential, validAt int64) error {
+	if validAt < cred.GetNotBefore() || validAt > cred.GetNotAfter() {
+		return errors.Errorf("credential is valid from %d to %d, not at %d", cred.GetNotBefore(), cred.GetNotAfter(), validAt)
+	}
+	return nil
+}
+
+// newValidityProofs commits to the validity window of the credential as to two more hidden attributes in format 4,
+// folds the randomness of the commitments into Sigma2 and proves NotBefore <= validAt <= NotAfter
+func newValidityProofs(nymSign *NymSignature, cred *Credential, ipk *IssuerPublicKey, Sigma1 *FP256BN.ECP, Sigma2 *FP256BN.ECP, validAt int64, msg []byte, rng *amcl.RAND) error {
+	predicates := validityPredicates(len(cred.Attrs), validAt)
+	for v, value := range validityValues(cred.GetNotBefore(), cred.GetNotAfter()) {
+		rho := RandModOrder(rng)
+		Com := Sigma1.Mul2(value, genG1(), rho)
+		Sigma2.Add(EcpFromProto(ipk.HValidity[v]).Mul(rho))
+
+		// Prove knowledge of the opening of Com
+		rAttr := RandModOrder(rng)
+		rRand := RandModOrder(rng)
+		t := Sigma1.Mul2(rAttr, genG1(), rRand)
+		c := validityChallengeV1(nymSign, t, Sigma1, Com, msg)
+
+		proof, err := newRangeProof(nymSign, predicates[v], value, rho, Sigma1, Com, msg, rng)
+		if err != nil {
+			return err
+		}
+
+		hide := new(HiddenAttribute)
+		hide.Com = EcpToProto(Com)
+		hide.ProofC = BigToBytes(c)
+		hide.ProofSAttr = BigToBytes(Modadd(rAttr, FP256BN.Modmul(c, value, GroupOrder), GroupOrder))
+		hide.ProofSRand = BigToBytes(Modadd(rRand, FP256BN.Modmul(c, rho, GroupOrder), GroupOrder))
+		nymSign.ValidityHides = append(nymSign.ValidityHides, hide)
+		nymSign.ValidityProofs = append(nymSign.ValidityProofs, proof)
+	}
+	return nil
+}
+
+// checkValidityProofCount checks that a NymSignature in format 1 or 4 carries a commitment and a range proof for every validity value
+func checkValidityProofCount(nym *NymSignature) error {
+	if len(nym.GetValidityHides()) != numValidityKeys || len(nym.GetValidityProofs()) != numValidityKeys {
+		return verificationErrorf(ErrKindInvalid, "NymSignature carries no proof of its validity window")
+	}
+	return nil
+}
+
+// verifyValidityProof checks the commitment to the validity value v of a NymSignature in format 1 or 4 and that it bounds
+// validAt on its side of the window, and returns the commitment
+func verifyValidityProof(nym *NymSignature, v int, numAttrs int, Sigma1 *FP256BN.ECP, validAt int64, msg []byte) (*FP256BN.ECP, error) {
+	hide := nym.ValidityHides[v]
+	Com, err := EcpFromProtoChecked(hide.GetCom())
+	if err != nil {
+		return nil, wrapVerificationError(ErrKindInvalid, err, "commitment to the validity window is malformed")
+	}
+	scalars := make([]*FP256BN.BIG, 3)
+	for k, b := range [][]byte{hide.GetProofC(), hide.GetProofSAttr(), hide.GetProofSRand()} {
+		scalars[k], err = BigFromBytesChecked(b)
+		if err != nil {
+			return nil, wrapVerificationError(ErrKindInvalid, err, "proof of the validity window is malformed")
+		}
+	}
+	ProofC, ProofSAttr, ProofSRand := scalars[0], scalars[1], scalars[2]
+
+	t := Sigma1.Mul2(ProofSAttr, genG1(), ProofSRand)
+	t.Add(Com.Mul(FP256BN.Modneg(ProofC, GroupOrder)))
+	if *ProofC != *validityChallengeV1(nym, t, Sigma1, Com, msg) {
+		return nil, verificationErrorf(ErrKindInvalid, "NymSignature is not fit with the Issuer PublicKey")
+	}
+
+	if err := verifyValidityRange(nym, v, numAttrs, Sigma1, Com, validAt, msg); err != nil {
+		return nil, err
+	}
+	return Com, nil
+}
+
+// verifyValidityRange checks that the validity value v committed in Com bounds validAt on its side of the window
+func verifyValidityRange(nym *NymSignature, v int, numAttrs int, Sigma1 *FP256BN.ECP, Com *FP256BN.ECP, validAt int64, msg []byte) error {
+	if !validityPredicates(numAttrs, validAt)[v].matches(nym.ValidityProofs[v]) {
+		return verificationErrorf(ErrKindInvalid, "validity proof %d is not made for time %d", v, validAt)
+	}
+	if err := verifyRangeProof(nym, nym.ValidityProofs[v], validityRangeBits, Sigma1, Com, msg); err != nil {
+		return wrapVerificationError(ErrKindExpired, err, fmt.Sprintf("credential is not valid at %d", validAt))
+	}
+	return nil
+}
+
+// validityChallengeV1 derives the challenge of the proof of knowledge of a commitment to the validity window
+// of a NymSignature in format 1 or 4
+func validityChallengeV1(nym *NymSignature, tValue, Sigma1, Com *FP256BN.ECP, msg []byte) *FP256BN.BIG {
+	t := nym.newTranscript(validityLabel)
+	t.appendLegacyLabel(validityLabel)
+	t.appendG1("t", tValue)
+	t.appendG1("Sigma1", Sigma1)
+	t.appendG1("Com", Com)
+	t.appendBytes("msg", msg)
+	return t.challengeWithNonce(nym.GetNonce())
+}
@@ -0,0 +1,164 @@
+package idemixplus
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+// A wallet file keeps the identities of a user encrypted at rest. The key is derived from a password with argon2id,
+// whose parameters and salt are stored in the WalletFile, and the WalletContents are encrypted with AES-256-GCM,
+// which authenticates the parameters and the salt as well. A WalletFile is encoded like the other objects,
+// see WalletFile.Bytes and WalletFile.Text.
+
+// the argon2id parameters of new wallet files, as recommended by RFC 9106 for memory constrained environments
+const (
+	walletKDFTime    = 3
+	walletKDFMemory  = 64 * 1024
+	walletKDFThreads = 4
+)
+
+// the largest argon2id parameters a wallet file is opened with, so that a crafted file cannot exhaust memory or time
+const (
+	maxWalletKDFTime   = 16
+	maxWalletKDFMemory = 1024 * 1024
+)
+
+const (
+	walletSaltBytes = 16
+	walletKeyBytes  = 32
+)
+
+// SealWallet encrypts the identities of a wallet with a key derived from password
+func SealWallet(contents *WalletContents, password []byte) (*WalletFile, error) {
+	if contents == nil || len(password) == 0 {
+		return nil, errors.Errorf("cannot seal wallet: received nil input")
+	}
+	if err := contents.validate(); err != nil {
+		return nil, err
+	}
+	contents = proto.Clone(contents).(*WalletContents)
+	compressPoints(contents)
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(contents); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal wallet contents")
+	}
+
+	file := &WalletFile{KdfTime: walletKDFTime, KdfMemory: walletKDFMemory, KdfThreads: walletKDFThreads, Salt: make([]byte, walletSaltBytes)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return nil, errors.Wrap(err, "failed to sample wallet salt")
+	}
+	aead, err := file.aead(password)
+	if err != nil {
+		return nil, err
+	}
+	header, err := file.header()
+	if err != nil {
+		return nil, err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to sample wallet nonce")
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, buf.Bytes(), header)
+	return file, nil
+}
+
+// OpenWallet decrypts the identities of a wallet file with the password it was sealed with
+func OpenWallet(file *WalletFile, password []byte) (*WalletContents, error) {
+	if file == nil || len(password) == 0 {
+		return nil, errors.Errorf("cannot open wallet: received nil input")
+	}
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+	aead, err := file.aead(password)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.GetNonce()) != aead.NonceSize() {
+		return nil, errors.Errorf("wallet file is malformed")
+	}
+	header, err := file.header()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.GetNonce(), file.GetCiphertext(), header)
+	if err != nil {
+		return nil, errors.Errorf("cannot open wallet: wrong password or wallet file modified")
+	}
+	contents := new(WalletContents)
+	if err := proto.Unmarshal(plaintext, contents); err != nil {
+		return nil, errors.Wrap(err, "cannot open wallet")
+	}
+	if err := contents.validate(); err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
+
+// aead derives the key of the wallet file from password
+func (file *WalletFile) aead(password []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey(password, file.GetSalt(), file.GetKdfTime(), file.GetKdfMemory(), uint8(file.GetKdfThreads()), walletKeyBytes)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create wallet cipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+// header returns the encoding of the fields of the wallet file the encryption authenticates
+func (file *WalletFile) header() ([]byte, error) {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	err := buf.Marshal(&WalletFile{KdfTime: file.GetKdfTime(), KdfMemory: file.GetKdfMemory(), KdfThreads: file.GetKdfThreads(), Salt: file.GetSalt()})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal wallet file")
+	}
+	return buf.Bytes(), nil
+}
+
+func (contents *WalletContents) validate() error {
+	names := map[string]bool{}
+	for _, identity := range contents.GetIdentities() {
+		if err := identity.validate(); err != nil {
+			return err
+		}
+		if names[identity.GetName()] {
+			return errors.Errorf("wallet identity %s appears multiple times", identity.GetName())
+		}
+		names[identity.GetName()] = true
+	}
+	return nil
+}
+
+func (identity *WalletIdentity) validate() error {
+	if identity == nil || identity.GetName() == "" || identity.GetKey() == nil || identity.GetTrace() == nil {
+		return errors.Errorf("wallet identity is malformed")
+	}
+	if err := identity.GetKey().validate(); err != nil {
+		return errors.WithMessagef(err, "wallet identity %s invalid", identity.GetName())
+	}
+	if err := identity.GetTrace().validate(); err != nil {
+		return errors.WithMessagef(err, "wallet identity %s invalid", identity.GetName())
+	}
+	if identity.GetIpk() != nil {
+		if err := identity.GetIpk().validate(); err != nil {
+			return errors.WithMessagef(err, "wallet identity %s invalid", identity.GetName())
+		}
+	}
+	if identity.GetCredential() != nil {
+		if identity.GetIpk() == nil {
+			return errors.Errorf("wallet identity %s has a credential without issuer public key", identity.GetName())
+		}
+		if err := identity.GetCredential().validate(); err != nil {
+			return errors.WithMessagef(err, "wallet identity %s invalid", identity.GetName())
+		}
+	}
+	return nil
+}
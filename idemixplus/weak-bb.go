@@ -17,7 +17,7 @@ func WBBKeyGen(rng *amcl.RAND) (*FP256BN.BIG, *FP256BN.ECP2) {
 	// sample sk uniform from Zq
 	sk := RandModOrder(rng)
 	// set pk = g2^sk
-	pk := GenG2.Mul(sk)
+	pk := genG2().Mul(sk)
 	return sk, pk
 }
 
@@ -28,7 +28,7 @@ func WBBSign(sk *FP256BN.BIG, m *FP256BN.BIG) *FP256BN.ECP {
 	exp.Invmodp(GroupOrder)
 
 	// return signature sig = g1^(1/(m + sk))
-	return GenG1.Mul(exp)
+	return genG1().Mul(exp)
 }
 
 // WBBVerify verifies a weak Boneh-Boyen signature sig on message m with public key pk
@@ -39,10 +39,10 @@ func WBBVerify(pk *FP256BN.ECP2, sig *FP256BN.ECP, m *FP256BN.BIG) error {
 	// Set P = pk * g2^m
 	P := FP256BN.NewECP2()
 	P.Copy(pk)
-	P.Add(GenG2.Mul(m))
+	P.Add(genG2().Mul(m))
 	P.Affine()
 	// check that e(sig, pk * g2^m) = e(g1, g2)
-	if !FP256BN.Fexp(FP256BN.Ate(P, sig)).Equals(GenGT) {
+	if !FP256BN.Fexp(FP256BN.Ate(P, sig)).Equals(genGT()) {
 		return errors.Errorf("Weak-BB signature is invalid")
 	}
 	return nil
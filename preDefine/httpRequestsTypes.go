@@ -1,10 +1,15 @@
 package preDefine
 
 // ZJ requests
+
+// InitRequest initializes the issuer. Issuers above 0 selects the threshold mode: the issuer key is shared
+// among Issuers issuers by a distributed key generation, and Threshold of them issue a credential or trace a signature.
 type InitRequest struct {
 	User         string   `json:"user"`
 	Attributions []string `json:"attributions"`
 	Sed          int      `json:"sed"`
+	Threshold    int      `json:"threshold"`
+	Issuers      int      `json:"issuers"`
 }
 
 type UserInfoRequest struct {
@@ -12,25 +17,41 @@ type UserInfoRequest struct {
 	Trace string `json:"trace"`
 }
 
-type CreateCredentialRequestRequest struct {
-	User string `json:"user"`
-	Pri  string `json:"pri"`
+// InitUserRequest registers a user, Trace is the trace of the key the user generated in its wallet
+type InitUserRequest struct {
+	User  string `json:"user"`
+	Trace string `json:"trace"`
 }
 
+// CreateCredentialRequest requests a credential for the request Cr. In threshold mode Cr is a threshold credential
+// request and NotBefore is the one IssuerNonce handed out with the nonce.
 type CreateCredentialRequest struct {
+	User      string `json:"user"`
+	Cr        string `json:"cr"`
+	NotBefore int64  `json:"notBefore"`
+}
+
+// RevokeUserRequest revokes every credential issued to User
+type RevokeUserRequest struct {
 	User string `json:"user"`
-	Cr   string `json:"cr"`
 }
 
 type CredentialTraceRequest struct {
 	Sig           string `json:"sig"`
+	Msg           string `json:"msg"`
 	TransactionID string `json:"transactionID"`
+	// Pub and Proof are set to verify an opening instead of tracing, in threshold mode Proof is a threshold opening
+	Pub   string `json:"pub"`
+	Proof string `json:"proof"`
 }
 
 type VerifyRequest struct {
 	User   string `json:"user"`
 	Msg    string `json:"msg"`
 	Random string `json:"random"`
+	Scope  string `json:"scope"`
+	// ValidAt is the time in seconds since the epoch the signature proves the credential valid at
+	ValidAt int64 `json:"validAt"`
 }
 
 // Confidential requests
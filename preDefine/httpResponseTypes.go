@@ -1,46 +1,78 @@
 package preDefine
 
 // ZJ responses
-type CreateCredentialRequestResponse struct {
-	Code  string `json:"code"`
-	Msg   string `json:"msg"`
-	Cr    string `json:"cr"`
-	Spend int64  `json:"spend"`
+
+// IssuerNonceResponse carries the nonce of a credential request. In threshold mode the request is bound
+// to the values of the credential as well: the encoded attribute values Attrs and the validity window.
+type IssuerNonceResponse struct {
+	Code      string   `json:"code"`
+	Msg       string   `json:"msg"`
+	Nonce     string   `json:"nonce"`
+	Attrs     []string `json:"attrs"`
+	NotBefore int64    `json:"notBefore"`
+	NotAfter  int64    `json:"notAfter"`
 }
 
+// CreateCredentialResponse carries the credential, in threshold mode it carries the partial credentials
+// of the issuers instead, which the user aggregates to the credential
 type CreateCredentialResponse struct {
-	Code  string `json:"code"`
-	Msg   string `json:"msg"`
-	Cred  string `json:"cred"`
-	Spend int64  `json:"spend"`
+	Code     string   `json:"code"`
+	Msg      string   `json:"msg"`
+	Cred     string   `json:"cred"`
+	Partials []string `json:"partials"`
+	Spend    int64    `json:"spend"`
 }
 
 type CredentialTraceResponse struct {
 	Code  string `json:"code"`
 	Msg   string `json:"msg"`
 	Pub   string `json:"pub"`
+	Proof string `json:"proof"`
 	Spend int64  `json:"spend"`
 }
 
+// IssuerKeyResponse carries the issuer key. In threshold mode Pri is empty, Tpk is the threshold issuer public key
+// and Shares holds the key share of every issuer, which is handed to that issuer only.
 type IssuerKeyResponse struct {
-	Code  string `json:"code"`
-	Msg   string `json:"msg"`
-	Pub   string `json:"pub"`
-	Pri   string `json:"pri"`
-	Spend int64  `json:"spend"`
+	Code   string   `json:"code"`
+	Msg    string   `json:"msg"`
+	Pub    string   `json:"pub"`
+	Pri    string   `json:"pri"`
+	Tpk    string   `json:"tpk"`
+	Shares []string `json:"shares"`
+	KeyId  string   `json:"keyId"`
+	Spend  int64    `json:"spend"`
 }
 
+// AttributionsResponse names the attributes of the issuer key. A signature discloses what Disclosure
+// discloses and keeps the revocation handle at RhIndex hidden.
 type AttributionsResponse struct {
 	Code         string   `json:"code"`
 	Msg          string   `json:"msg"`
 	Attributions []string `json:"attributions"`
+	Disclosure   []byte   `json:"disclosure"`
+	RhIndex      int      `json:"rhIndex"`
+}
+
+// CRIResponse carries the credential revocation information of the current epoch
+type CRIResponse struct {
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+	Cri   string `json:"cri"`
+	Epoch int    `json:"epoch"`
+}
+
+// RevokeUserResponse carries the epoch that starts with the revocation
+type RevokeUserResponse struct {
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+	Epoch int    `json:"epoch"`
 }
 
 type UserKeyResponse struct {
 	Code  string `json:"code"`
 	Msg   string `json:"msg"`
 	Pub   string `json:"pub"`
-	Pri   string `json:"pri"`
 	Trace string `json:"trace"`
 	Spend int64  `json:"spend"`
 }
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FileStore is a Store that keeps the state in a log file on local disk and a copy of it in memory.
+// Every Write appends one record
+//
+//	length (4 bytes) | checksum (4 bytes) | entries
+//
+// where length is the length of the entries in big endian and checksum their CRC-32, and syncs the file
+// before it returns, so that a Write is on disk completely or not at all. OpenFileStore replays the records,
+// drops a last record that was not completely written, and compacts the log into a single record.
+// A file must not be opened by more than one FileStore at a time.
+type FileStore struct {
+	mem  *MemoryStore
+	mu   sync.Mutex // serializes the writes to the file
+	path string
+	file *os.File
+	size int64
+}
+
+const recordHeaderBytes = 8
+
+// maxNameBytes is the longest bucket and key a record holds
+const maxNameBytes = 0xffff
+
+// OpenFileStore opens the store in the file at path, it creates the file if it does not exist
+func OpenFileStore(path string) (*FileStore, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to open store")
+	}
+	s := &FileStore{mem: NewMemoryStore(), path: path}
+	if err := s.replay(raw); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// replay applies the records of the log to the copy in memory
+func (s *FileStore) replay(raw []byte) error {
+	for len(raw) > 0 {
+		if len(raw) < recordHeaderBytes {
+			return nil // the header of the last record was not completely written
+		}
+		length := binary.BigEndian.Uint32(raw)
+		if uint64(len(raw)) < recordHeaderBytes+uint64(length) {
+			return nil // the last record was not completely written
+		}
+		payload := raw[recordHeaderBytes : recordHeaderBytes+length]
+		last := uint64(len(raw)) == recordHeaderBytes+uint64(length)
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(raw[4:]) {
+			if last {
+				return nil
+			}
+			return errors.Errorf("failed to open store: record checksum does not match")
+		}
+		entries, err := decodeEntries(payload)
+		if err != nil {
+			return errors.WithMessage(err, "failed to open store")
+		}
+		s.mem.apply(entries)
+		raw = raw[recordHeaderBytes+length:]
+	}
+	return nil
+}
+
+// compact replaces the log by a single record holding every key
+func (s *FileStore) compact() error {
+	var entries []Entry
+	for bucket, keys := range s.mem.buckets {
+		for key, value := range keys {
+			entries = append(entries, Entry{Bucket: bucket, Key: key, Value: value})
+		}
+	}
+	var record []byte
+	if len(entries) > 0 {
+		record = encodeRecord(entries)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to compact store")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(record); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to compact store")
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open store")
+	}
+	s.size = int64(len(record))
+	return nil
+}
+
+// Get returns the value under key in bucket
+func (s *FileStore) Get(bucket string, key string) ([]byte, error) {
+	return s.mem.Get(bucket, key)
+}
+
+// ForEach calls fn with every key of bucket in order and its value. fn must not write to the store.
+func (s *FileStore) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	return s.mem.ForEach(bucket, fn)
+}
+
+// Write appends the entries to the log as a single record
+func (s *FileStore) Write(entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	for _, entry := range entries {
+		if len(entry.Bucket) > maxNameBytes || len(entry.Key) > maxNameBytes {
+			return errors.Errorf("cannot write to store: bucket or key longer than %d bytes", maxNameBytes)
+		}
+	}
+	record := encodeRecord(entries)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errors.Errorf("cannot write to store: store is closed")
+	}
+	_, err := s.file.Write(record)
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		// drop what was written of the record, so that the next records are not appended to a broken one
+		_ = s.file.Truncate(s.size)
+		return errors.Wrap(err, "failed to write to store")
+	}
+	s.size += int64(len(record))
+	return s.mem.Write(entries...)
+}
+
+// Close closes the file of the store
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return errors.Wrap(err, "failed to close store")
+}
+
+// encodeRecord encodes entries as a record of the log, an entry is encoded as
+//
+//	bucket length (2 bytes) | bucket | key length (2 bytes) | key | deleted (1 byte) | value length (4 bytes) | value
+func encodeRecord(entries []Entry) []byte {
+	record := make([]byte, recordHeaderBytes)
+	for _, entry := range entries {
+		record = appendString(record, entry.Bucket)
+		record = appendString(record, entry.Key)
+		if entry.Value == nil {
+			record = append(record, 1, 0, 0, 0, 0)
+			continue
+		}
+		record = append(record, 0)
+		record = appendUint32(record, uint32(len(entry.Value)))
+		record = append(record, entry.Value...)
+	}
+	binary.BigEndian.PutUint32(record, uint32(len(record)-recordHeaderBytes))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(record[recordHeaderBytes:]))
+	return record
+}
+
+func appendString(b []byte, s string) []byte {
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(len(s)))
+	return append(append(b, length[:]...), s...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func decodeEntries(payload []byte) ([]Entry, error) {
+	var entries []Entry
+	for len(payload) > 0 {
+		var entry Entry
+		var ok bool
+		if entry.Bucket, payload, ok = readString(payload); !ok {
+			return nil, errors.Errorf("record is malformed")
+		}
+		if entry.Key, payload, ok = readString(payload); !ok {
+			return nil, errors.Errorf("record is malformed")
+		}
+		if len(payload) < 5 {
+			return nil, errors.Errorf("record is malformed")
+		}
+		deleted, length := payload[0], binary.BigEndian.Uint32(payload[1:5])
+		payload = payload[5:]
+		if uint64(len(payload)) < uint64(length) || deleted > 1 {
+			return nil, errors.Errorf("record is malformed")
+		}
+		if deleted == 0 {
+			entry.Value = append([]byte{}, payload[:length]...)
+		}
+		payload = payload[length:]
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func readString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	length := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+length {
+		return "", nil, false
+	}
+	return string(b[2 : 2+length]), b[2+length:], true
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// A Store keeps the state of a server as values under keys, which are grouped in buckets.
+// MemoryStore keeps the state in memory only, FileStore keeps it in a file on local disk.
+// Implementations are safe for concurrent use.
+type Store interface {
+	// Get returns the value under key in bucket, or an error for which IsNotFound holds
+	Get(bucket string, key string) ([]byte, error)
+	// ForEach calls fn with every key of bucket in order and its value, until fn returns an error
+	ForEach(bucket string, fn func(key string, value []byte) error) error
+	// Write writes all entries or none of them
+	Write(entries ...Entry) error
+	Close() error
+}
+
+// Entry is a value to write under a key of a bucket, an entry without value deletes the key
+type Entry struct {
+	Bucket string
+	Key    string
+	Value  []byte
+}
+
+var errNotFound = errors.New("key not found")
+
+// IsNotFound tells whether an error of Get is because the key is not in the store
+func IsNotFound(err error) bool {
+	return errors.Cause(err) == errNotFound
+}
+
+// MemoryStore is a Store that keeps the state in memory
+type MemoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]map[string][]byte)}
+}
+
+// Get returns the value under key in bucket
+func (s *MemoryStore) Get(bucket string, key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, exists := s.buckets[bucket][key]
+	if !exists {
+		return nil, errors.WithMessagef(errNotFound, "%s/%s", bucket, key)
+	}
+	return append([]byte{}, value...), nil
+}
+
+// ForEach calls fn with every key of bucket in order and its value. fn must not write to the store.
+func (s *MemoryStore) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(key, append([]byte{}, s.buckets[bucket][key]...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes all entries
+func (s *MemoryStore) Write(entries ...Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apply(entries)
+	return nil
+}
+
+func (s *MemoryStore) apply(entries []Entry) {
+	for _, entry := range entries {
+		if entry.Value == nil {
+			delete(s.buckets[entry.Bucket], entry.Key)
+			continue
+		}
+		if s.buckets[entry.Bucket] == nil {
+			s.buckets[entry.Bucket] = make(map[string][]byte)
+		}
+		s.buckets[entry.Bucket][entry.Key] = append([]byte{}, entry.Value...)
+	}
+}
+
+// Close does nothing, the state of a MemoryStore is gone with it
+func (s *MemoryStore) Close() error {
+	return nil
+}
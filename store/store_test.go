@@ -0,0 +1,82 @@
+package store
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testStore(t *testing.T, s Store) {
+	_, err := s.Get("users", "alice")
+	assert.True(t, IsNotFound(err))
+	assert.NoError(t, s.Write(Entry{"users", "bob", []byte("b")}, Entry{"users", "alice", []byte("a")}, Entry{"keys", "1", []byte{}}))
+	value, err := s.Get("users", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a"), value)
+	value, err = s.Get("keys", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, value)
+
+	var keys []string
+	assert.NoError(t, s.ForEach("users", func(key string, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"alice", "bob"}, keys, "keys are visited in order")
+
+	assert.NoError(t, s.Write(Entry{Bucket: "users", Key: "bob"}))
+	_, err = s.Get("users", "bob")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	s, err := OpenFileStore(path)
+	assert.NoError(t, err)
+	testStore(t, s)
+	assert.NoError(t, s.Write(Entry{"users", "carol", []byte("c")}))
+	assert.NoError(t, s.Close())
+	assert.Error(t, s.Write(Entry{"users", "dave", []byte("d")}))
+
+	s, err = OpenFileStore(path)
+	assert.NoError(t, err)
+	value, err := s.Get("users", "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("c"), value)
+	_, err = s.Get("users", "bob")
+	assert.True(t, IsNotFound(err), "deletes are kept")
+
+	// a record that was not completely written is dropped with all its entries
+	assert.NoError(t, s.Write(Entry{"users", "dave", []byte("d")}))
+	assert.NoError(t, s.Close())
+	raw, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	torn := encodeRecord([]Entry{{"users", "erin", []byte("e")}, {"users", "alice", nil}})
+	assert.NoError(t, ioutil.WriteFile(path, append(raw, torn[:len(torn)-3]...), 0600))
+	s, err = OpenFileStore(path)
+	assert.NoError(t, err)
+	_, err = s.Get("users", "erin")
+	assert.True(t, IsNotFound(err))
+	_, err = s.Get("users", "alice")
+	assert.NoError(t, err)
+	_, err = s.Get("users", "dave")
+	assert.NoError(t, err)
+	assert.NoError(t, s.Close())
+
+	// a damaged record before the last one is not dropped silently
+	first := encodeRecord([]Entry{{"users", "frank", []byte("f")}})
+	first[len(first)-1] ^= 1
+	second := encodeRecord([]Entry{{"users", "grace", []byte("g")}})
+	assert.NoError(t, ioutil.WriteFile(path, append(first, second...), 0600))
+	_, err = OpenFileStore(path)
+	assert.Error(t, err)
+
+	_, err = OpenFileStore(filepath.Join(t.TempDir(), "missing", "state"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,147 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/pkg/errors"
+	"traceGo/idemixplus"
+)
+
+// A Keystore holds the identities of a user, each a Wallet under its name, and keeps them in an encrypted wallet file,
+// see idemixplus.SealWallet. Identities move between keystores by exporting them to a wallet file and importing it.
+// A Keystore is not safe for concurrent use.
+type Keystore struct {
+	wallets map[string]*Wallet
+	rng     *amcl.RAND
+}
+
+// NewKeystore creates an empty keystore, the wallets it loads use rng
+func NewKeystore(rng *amcl.RAND) *Keystore {
+	return &Keystore{wallets: make(map[string]*Wallet), rng: rng}
+}
+
+// Add adds a wallet to the keystore under the given name
+func (ks *Keystore) Add(name string, w *Wallet) error {
+	if name == "" || w == nil {
+		return errors.Errorf("cannot add wallet: received nil input")
+	}
+	if _, exists := ks.wallets[name]; exists {
+		return errors.Errorf("identity %s is already in the keystore", name)
+	}
+	ks.wallets[name] = w
+	return nil
+}
+
+// Get returns the wallet with the given name
+func (ks *Keystore) Get(name string) (*Wallet, error) {
+	w, exists := ks.wallets[name]
+	if !exists {
+		return nil, errors.Errorf("identity %s is not in the keystore", name)
+	}
+	return w, nil
+}
+
+// Remove removes the wallet with the given name
+func (ks *Keystore) Remove(name string) {
+	delete(ks.wallets, name)
+}
+
+// Names returns the names of the identities in the keystore in order
+func (ks *Keystore) Names() []string {
+	names := make([]string, 0, len(ks.wallets))
+	for name := range ks.wallets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export returns the wallet file of the identities with the given names, or of all identities if no name is given,
+// encrypted with password
+func (ks *Keystore) Export(password []byte, names ...string) ([]byte, error) {
+	if len(names) == 0 {
+		names = ks.Names()
+	}
+	contents := &idemixplus.WalletContents{}
+	for _, name := range names {
+		w, err := ks.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		contents.Identities = append(contents.Identities, w.Identity(name))
+	}
+	file, err := idemixplus.SealWallet(contents, password)
+	if err != nil {
+		return nil, err
+	}
+	return file.Bytes()
+}
+
+// Import adds the identities of a wallet file encrypted with password, it adds none of them
+// if one is already in the keystore or does not check
+func (ks *Keystore) Import(raw []byte, password []byte) error {
+	file, err := idemixplus.WalletFileFromBytes(raw)
+	if err != nil {
+		return err
+	}
+	contents, err := idemixplus.OpenWallet(file, password)
+	if err != nil {
+		return err
+	}
+	wallets := make(map[string]*Wallet, len(contents.GetIdentities()))
+	for _, identity := range contents.GetIdentities() {
+		if _, exists := ks.wallets[identity.GetName()]; exists {
+			return errors.Errorf("identity %s is already in the keystore", identity.GetName())
+		}
+		w, err := FromIdentity(identity, ks.rng)
+		if err != nil {
+			return err
+		}
+		wallets[identity.GetName()] = w
+	}
+	for name, w := range wallets {
+		ks.wallets[name] = w
+	}
+	return nil
+}
+
+// Save writes all identities to the wallet file at path encrypted with password, it replaces the file
+// only once the new one is written
+func (ks *Keystore) Save(path string, password []byte) error {
+	raw, err := ks.Export(password)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to save keystore")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(raw); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to save keystore")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), path), "failed to save keystore")
+}
+
+// LoadKeystore reads the keystore from the wallet file at path encrypted with password
+func LoadKeystore(path string, password []byte, rng *amcl.RAND) (*Keystore, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load keystore")
+	}
+	ks := NewKeystore(rng)
+	if err := ks.Import(raw, password); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
@@ -0,0 +1,134 @@
+package wallet
+
+import (
+	"bytes"
+
+	"github.com/hyperledger/fabric-amcl/amcl"
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/pkg/errors"
+	"traceGo/idemixplus"
+)
+
+// A Wallet keeps the user side of idemixplus on the client, so that the secret key of the user never leaves it.
+// The user registers the Trace of its key with the issuer and takes part in issuance with a credential request
+// for a nonce of the issuer; the issuer only ever sees the user public key, the trace and the request.
+// The wallet checks the credential it receives and makes the NymSignatures of the user.
+// A Wallet is not safe for concurrent use, as the random number generator it uses is not.
+type Wallet struct {
+	Key   *idemixplus.UserKey
+	Trace *idemixplus.Trace
+	// Ipk is the issuer public key of the credential, it is set by NewCredRequest
+	Ipk  *idemixplus.IssuerPublicKey
+	Cred *idemixplus.Credential
+	rng  *amcl.RAND
+}
+
+// NewWallet creates a wallet with a new user key for the given attribute names
+func NewWallet(AttributeNames []string, rng *amcl.RAND) (*Wallet, error) {
+	if rng == nil {
+		return nil, errors.Errorf("cannot create wallet: received nil input")
+	}
+	key, trace, err := idemixplus.NewUserKey(AttributeNames, rng)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{Key: key, Trace: trace, rng: rng}, nil
+}
+
+// FromIdentity restores a wallet from an identity of a wallet file. It checks that the user key and the trace
+// belong together and that the credential, if any, is valid for the secret key.
+func FromIdentity(identity *idemixplus.WalletIdentity, rng *amcl.RAND) (*Wallet, error) {
+	if identity == nil || identity.GetKey() == nil || rng == nil {
+		return nil, errors.Errorf("cannot restore wallet: received nil input")
+	}
+	w := &Wallet{Key: identity.GetKey(), Trace: identity.GetTrace(), Ipk: identity.GetIpk(), rng: rng}
+	if err := w.Trace.Check(); err != nil {
+		return nil, errors.WithMessagef(err, "identity %s invalid", identity.GetName())
+	}
+	UPK := idemixplus.EcpFromProto(w.Upk().GetUPK())
+	g1 := FP256BN.NewECP()
+	g1.Copy(idemixplus.GenG1) // amcl normalizes what it multiplies, wallets may be restored concurrently
+	if !g1.Mul(w.sk()).Equals(UPK) || !idemixplus.EcpFromProto(w.Trace.GetUpk().GetUPK()).Equals(UPK) {
+		return nil, errors.Errorf("identity %s invalid: user key does not match its trace", identity.GetName())
+	}
+	if identity.GetCredential() != nil {
+		if err := w.SetCredential(identity.GetCredential()); err != nil {
+			return nil, errors.WithMessagef(err, "identity %s invalid", identity.GetName())
+		}
+	}
+	return w, nil
+}
+
+// Identity returns the identity of the wallet under the given name, to keep it in a wallet file
+func (w *Wallet) Identity(name string) *idemixplus.WalletIdentity {
+	return &idemixplus.WalletIdentity{Name: name, Key: w.Key, Trace: w.Trace, Ipk: w.Ipk, Credential: w.Cred}
+}
+
+// Upk returns the user public key of the wallet
+func (w *Wallet) Upk() *idemixplus.UserPublicKey {
+	return w.Key.GetUpk()
+}
+
+// sk returns the secret key of the user
+func (w *Wallet) sk() *FP256BN.BIG {
+	return FP256BN.FromBytes(w.Key.GetUsk().GetX())
+}
+
+// NewCredRequest checks the issuer public key and creates a credential request for the nonce the issuer handed out
+func (w *Wallet) NewCredRequest(ipk *idemixplus.IssuerPublicKey, IssuerNonce []byte) (*idemixplus.CredRequest, error) {
+	if ipk == nil || len(IssuerNonce) == 0 {
+		return nil, errors.Errorf("cannot create credential request: received nil input")
+	}
+	if err := ipk.Check(); err != nil {
+		return nil, err
+	}
+	w.Ipk = ipk
+	return idemixplus.NewCredRequest(w.sk(), IssuerNonce, ipk, w.rng), nil
+}
+
+// SetCredential checks that the credential the issuer returned is valid for the secret key of the wallet and stores it
+func (w *Wallet) SetCredential(cred *idemixplus.Credential) error {
+	if cred == nil || w.Ipk == nil {
+		return errors.Errorf("cannot set credential: no credential or no credential request made")
+	}
+	if err := cred.Ver(w.sk(), w.Ipk); err != nil {
+		return errors.WithMessage(err, "credential from the issuer invalid")
+	}
+	w.Cred = cred
+	return nil
+}
+
+// NewThresholdCredRequest checks the issuer public key of a threshold issuer and creates a credential request
+// for the nonce, the attribute values and the validity window the issuer handed out
+func (w *Wallet) NewThresholdCredRequest(tpk *idemixplus.ThresholdIssuerPublicKey, IssuerNonce []byte, attrs []*FP256BN.BIG, notBefore int64, notAfter int64) (*idemixplus.ThresholdCredRequest, error) {
+	if tpk.GetIpk() == nil || len(IssuerNonce) == 0 || attrs == nil {
+		return nil, errors.Errorf("cannot create credential request: received nil input")
+	}
+	if err := tpk.GetIpk().Check(); err != nil {
+		return nil, err
+	}
+	w.Ipk = tpk.GetIpk()
+	return idemixplus.NewThresholdCredRequest(w.sk(), IssuerNonce, w.Ipk, attrs, notBefore, notAfter, w.rng), nil
+}
+
+// SetThresholdCredential aggregates the partial credentials the threshold issuers returned for the request
+// to a credential, which it checks like SetCredential does and stores
+func (w *Wallet) SetThresholdCredential(tpk *idemixplus.ThresholdIssuerPublicKey, m *idemixplus.ThresholdCredRequest, attrs []*FP256BN.BIG, notBefore int64, notAfter int64, partials []*idemixplus.PartialCredential) error {
+	if w.Ipk == nil || tpk.GetIpk().GetHash() == nil || !bytes.Equal(tpk.GetIpk().GetHash(), w.Ipk.GetHash()) {
+		return errors.Errorf("cannot set credential: no credential request made to the threshold issuer")
+	}
+	cred, err := idemixplus.AggregateCredential(tpk, w.sk(), m, attrs, notBefore, notAfter, partials)
+	if err != nil {
+		return errors.WithMessage(err, "credential from the issuers invalid")
+	}
+	w.Cred = cred
+	return nil
+}
+
+// Sign creates a NymSignature on msg with the credential of the wallet, see idemixplus.NewNymSignature for the options
+func (w *Wallet) Sign(msg []byte, opts *idemixplus.SignOpts) (*idemixplus.NymSignature, error) {
+	if w.Cred == nil {
+		return nil, errors.Errorf("cannot sign: wallet has no credential")
+	}
+	return idemixplus.NewNymSignature(w.sk(), w.Cred, w.Ipk, msg, opts, w.rng)
+}
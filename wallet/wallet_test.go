@@ -0,0 +1,117 @@
+package wallet
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-amcl/amcl/FP256BN"
+	"github.com/stretchr/testify/assert"
+	"traceGo/idemixplus"
+)
+
+func TestWallet(t *testing.T) {
+	rng := idemixplus.GetRand(32)
+	AttributeNames := []string{"Attr1", "Attr2"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1), FP256BN.NewBIGint(2)}
+	now := time.Now().Unix()
+	key, err := idemixplus.NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	nonces := idemixplus.NewIssuerNonceStore(time.Minute)
+
+	w, err := NewWallet(AttributeNames, rng)
+	assert.NoError(t, err)
+	_, err = w.Sign([]byte("msg"), &idemixplus.SignOpts{ValidAt: now, Disclosure: []byte{1, 0}, RhIndex: -1})
+	assert.Error(t, err, "a wallet signs with a credential only")
+
+	// the issuer registers the trace and issues the credential from public material only
+	assert.NoError(t, w.Trace.Check())
+	traces := idemixplus.NewTraceIndex()
+	assert.NoError(t, traces.Add(w.Trace))
+	m, err := w.NewCredRequest(key.Ipk, nonces.NewNonce(rng))
+	assert.NoError(t, err)
+	cred, err := idemixplus.NewCredential(key, nonces, m, w.Upk(), attrs, now-3600, now+3600, rng)
+	assert.NoError(t, err)
+
+	other, err := NewWallet(AttributeNames, rng)
+	assert.NoError(t, err)
+	_, err = other.NewCredRequest(key.Ipk, nonces.NewNonce(rng))
+	assert.NoError(t, err)
+	assert.Error(t, other.SetCredential(cred), "the credential is bound to the secret key of the wallet")
+	assert.NoError(t, w.SetCredential(cred))
+
+	sig, err := w.Sign([]byte("msg"), &idemixplus.SignOpts{ValidAt: now, Disclosure: []byte{1, 0}, RhIndex: -1})
+	assert.NoError(t, err)
+	opts := &idemixplus.VerifyOpts{ValidAt: now, Disclosure: []byte{1, 0}, AttributeValues: attrs, RhIndex: -1}
+	assert.NoError(t, sig.Ver(key.Ipk, []byte("msg"), opts))
+	upk, opening, err := idemixplus.Arbitration(key, traces, sig, []byte("msg"), opts, rng)
+	assert.NoError(t, err)
+	assert.Equal(t, w.Upk().GetHash(), upk.GetHash())
+	assert.NoError(t, idemixplus.VerifyOpening(key.Ipk, upk, sig, []byte("msg"), opts, opening))
+
+	// a trace must belong to its user public key
+	forged := &idemixplus.Trace{T: w.Trace.T, Upk: other.Upk()}
+	assert.Error(t, forged.Check())
+}
+
+func TestKeystore(t *testing.T) {
+	rng := idemixplus.GetRand(32)
+	AttributeNames := []string{"Attr1"}
+	attrs := []*FP256BN.BIG{FP256BN.NewBIGint(1)}
+	now := time.Now().Unix()
+	key, err := idemixplus.NewIssuerKey(AttributeNames, rng)
+	assert.NoError(t, err)
+	nonces := idemixplus.NewIssuerNonceStore(time.Minute)
+	password := []byte("password")
+
+	alice, err := NewWallet(AttributeNames, rng)
+	assert.NoError(t, err)
+	m, err := alice.NewCredRequest(key.Ipk, nonces.NewNonce(rng))
+	assert.NoError(t, err)
+	cred, err := idemixplus.NewCredential(key, nonces, m, alice.Upk(), attrs, now-3600, now+3600, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, alice.SetCredential(cred))
+	bob, err := NewWallet(AttributeNames, rng)
+	assert.NoError(t, err)
+
+	ks := NewKeystore(rng)
+	assert.NoError(t, ks.Add("alice", alice))
+	assert.NoError(t, ks.Add("bob", bob))
+	assert.Error(t, ks.Add("bob", alice))
+	path := filepath.Join(t.TempDir(), "wallet")
+	assert.NoError(t, ks.Save(path, password))
+
+	_, err = LoadKeystore(path, []byte("wrong"), rng)
+	assert.Error(t, err)
+	loaded, err := LoadKeystore(path, password, rng)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, loaded.Names())
+	restored, err := loaded.Get("alice")
+	assert.NoError(t, err)
+	sig, err := restored.Sign([]byte("msg"), &idemixplus.SignOpts{ValidAt: now, Disclosure: []byte{1}, RhIndex: -1})
+	assert.NoError(t, err)
+	assert.NoError(t, sig.Ver(key.Ipk, []byte("msg"), &idemixplus.VerifyOpts{ValidAt: now, Disclosure: []byte{1}, AttributeValues: attrs, RhIndex: -1}))
+	restored, err = loaded.Get("bob")
+	assert.NoError(t, err)
+	assert.Nil(t, restored.Cred)
+
+	// identities move between keystores in exported wallet files
+	exported, err := ks.Export(password, "bob")
+	assert.NoError(t, err)
+	other := NewKeystore(rng)
+	assert.NoError(t, other.Add("alice", alice))
+	assert.NoError(t, other.Import(exported, password))
+	assert.Equal(t, []string{"alice", "bob"}, other.Names())
+	assert.Error(t, other.Import(exported, password), "an identity is imported once")
+	_, err = ks.Export(password, "carol")
+	assert.Error(t, err)
+
+	// a wallet file whose identity does not belong together is rejected
+	forged := alice.Identity("mallory")
+	forged.Key = bob.Key
+	file, err := idemixplus.SealWallet(&idemixplus.WalletContents{Identities: []*idemixplus.WalletIdentity{forged}}, password)
+	assert.NoError(t, err)
+	raw, err := file.Bytes()
+	assert.NoError(t, err)
+	assert.Error(t, NewKeystore(rng).Import(raw, password))
+}